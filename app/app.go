@@ -11,7 +11,9 @@ import (
 	"github.com/leapzhao/json-store/config"
 	"github.com/leapzhao/json-store/database"
 	"github.com/leapzhao/json-store/logger"
+	"github.com/leapzhao/json-store/middleware"
 	"github.com/leapzhao/json-store/router"
+	"github.com/leapzhao/json-store/search"
 	"github.com/leapzhao/json-store/server"
 
 	"github.com/rs/zerolog/log"
@@ -21,6 +23,12 @@ type Application struct {
 	config *config.Config
 	store  database.JSONStore
 	server *server.Server
+	// indexer 搜索索引同步器，searchClient 索引同步和搜索接口共用的客户端，未开启搜索时均为nil
+	indexer      *search.Indexer
+	searchClient *search.Client
+	// watchdog 请求看门狗，未开启时为nil；stopWatchdog 关闭时停止其检查协程
+	watchdog     *middleware.Watchdog
+	stopWatchdog context.CancelFunc
 }
 
 // New 创建应用实例
@@ -36,38 +44,59 @@ func New() (*Application, error) {
 		return nil, fmt.Errorf("failed to init logger: %w", err)
 	}
 
+	if cfg.ConfigFile == "" {
+		log.Debug().Msg("Config file not found, using environment variables and defaults")
+	}
+	log.Info().
+		Str("environment", string(cfg.Environment)).
+		Str("config_file", cfg.ConfigFile).
+		Msg("Configuration loaded")
+
+	return &Application{
+		config: cfg,
+		server: server.New(*cfg),
+	}, nil
+}
+
+// initStore 创建数据库存储（包含迁移）并完成首次健康检查
+func (app *Application) initStore() error {
 	// 创建数据库存储
-	store, err := database.CreateStore(*cfg)
+	store, err := database.CreateStore(*app.config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create database store: %w", err)
+		return fmt.Errorf("failed to create database store: %w", err)
 	}
 
 	// 健康检查数据库连接
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := store.HealthCheck(ctx); err != nil {
-		return nil, fmt.Errorf("database health check failed: %w", err)
+		store.Close()
+		return fmt.Errorf("database health check failed: %w", err)
 	}
 
 	log.Info().
-		Str("database_type", cfg.Database.Type).
-		Str("database_host", cfg.Database.Host).
+		Str("database_type", app.config.Database.Type).
+		Str("database_host", app.config.Database.Host).
 		Msg("Database connection established")
 
-	return &Application{
-		config: cfg,
-		store:  store,
-	}, nil
+	// 开启搜索时写入的文档异步同步到搜索索引
+	if app.config.Search.Enabled {
+		app.searchClient = search.NewClient(*app.config)
+		app.indexer = search.NewIndexer(app.searchClient, app.config.Search.QueueSize, app.config.Search.MaxRetries)
+		store = search.NewIndexingStore(store, app.indexer)
+		log.Info().
+			Str("search_url", app.config.Search.URL).
+			Str("search_index", app.config.Search.Index).
+			Msg("Search indexing enabled")
+	}
+
+	app.store = store
+	return nil
 }
 
 // Start 启动应用
+// HTTP服务先启动并对所有请求返回503，数据库就绪后再切换到业务路由
 func (app *Application) Start() error {
-	// 初始化路由
-	ginRouter := router.Init(*app.config, app.store)
-
-	// 创建HTTP服务器
-	app.server = server.New(*app.config, ginRouter)
-
 	// 启动服务器
 	go func() {
 		if err := app.server.Start(); err != nil {
@@ -75,14 +104,46 @@ func (app *Application) Start() error {
 		}
 	}()
 
+	if err := app.initStore(); err != nil {
+		return err
+	}
+
+	// 请求看门狗，检查协程在关闭时停止
+	if threshold := app.config.Server.WatchdogThreshold; threshold > 0 {
+		app.watchdog = middleware.NewWatchdog(
+			time.Duration(threshold)*time.Second,
+			app.config.Server.WatchdogDumpStacks,
+			app.config.Server.WatchdogFailLiveness,
+		)
+		ctx, cancel := context.WithCancel(context.Background())
+		app.stopWatchdog = cancel
+		go app.watchdog.Run(ctx)
+	}
+
+	// 初始化路由并标记就绪
+	app.server.SetHandler(router.Init(*app.config, app.store, app.searchClient, app.watchdog))
+
 	return nil
 }
 
 // Shutdown 关闭应用
 func (app *Application) Shutdown() error {
+	if app.stopWatchdog != nil {
+		app.stopWatchdog()
+	}
+
+	// 发送完待同步的搜索索引文档
+	if app.indexer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		app.indexer.Close(ctx)
+		cancel()
+	}
+
 	// 关闭数据库连接
-	if err := app.store.Close(); err != nil {
-		log.Error().Err(err).Msg("Failed to close database connection")
+	if app.store != nil {
+		if err := app.store.Close(); err != nil {
+			log.Error().Err(err).Msg("Failed to close database connection")
+		}
 	}
 
 	log.Info().Msg("Application shutdown completed")
@@ -93,6 +154,9 @@ func (app *Application) Shutdown() error {
 func (app *Application) Run() error {
 	// 启动应用
 	if err := app.Start(); err != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		app.server.Shutdown(ctx)
 		return err
 	}
 
@@ -0,0 +1,160 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// memoryStore 导出导入用到的存储方法的内存实现，按ID保存文档，内容按原始字节去重
+type memoryStore struct {
+	database.JSONStore
+	docs   []*model.JSONDocument
+	labels map[string]string
+}
+
+func (s *memoryStore) find(id string) *model.JSONDocument {
+	for _, doc := range s.docs {
+		if doc.ID == id {
+			return doc
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) IterateDocuments(ctx context.Context, since time.Time, fn func(*model.JSONDocument) error) error {
+	for _, doc := range s.docs {
+		copied := *doc
+		if err := fn(&copied); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) DocumentLabels(ctx context.Context) (map[string][]string, error) {
+	labels := make(map[string][]string)
+	for label, id := range s.labels {
+		labels[id] = append(labels[id], label)
+	}
+	return labels, nil
+}
+
+func (s *memoryStore) StoreJSON(ctx context.Context, input model.StoreInput) (*model.JSONDocument, error) {
+	for _, doc := range s.docs {
+		if bytes.Equal(doc.JSONData, input.JSONData) {
+			if doc.ID != input.ID {
+				return nil, database.ErrDuplicateContent
+			}
+			return doc, nil
+		}
+	}
+	if s.find(input.ID) != nil {
+		return nil, database.ErrIDConflict
+	}
+
+	doc := &model.JSONDocument{
+		ID:       input.ID,
+		DocType:  input.DocType,
+		JSONData: input.JSONData,
+		Metadata: input.Metadata,
+		Tags:     input.Tags,
+	}
+	s.docs = append(s.docs, doc)
+	return doc, nil
+}
+
+func (s *memoryStore) SetLabel(ctx context.Context, label, id string) (*model.LabeledDocument, error) {
+	if s.labels == nil {
+		s.labels = make(map[string]string)
+	}
+	s.labels[label] = id
+	return &model.LabeledDocument{Label: label}, nil
+}
+
+func (s *memoryStore) RestoreTimestamps(ctx context.Context, id string, createdAt, updatedAt time.Time) error {
+	doc := s.find(id)
+	doc.CreatedAt, doc.UpdatedAt = createdAt, updatedAt
+	return nil
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	updated := created.Add(time.Hour)
+	source := &memoryStore{
+		docs: []*model.JSONDocument{
+			{
+				ID:        "00000000-0000-0000-0000-000000000001",
+				DocType:   "config",
+				JSONData:  []byte(`{"a":1,"html":"<b>"}`),
+				Metadata:  map[string]any{"env": "prod"},
+				Tags:      []string{"x", "y"},
+				CreatedAt: created,
+				UpdatedAt: updated,
+			},
+			{
+				// 非紧凑形式的原始字节需要原样恢复
+				ID:        "00000000-0000-0000-0000-000000000002",
+				JSONData:  []byte("{ \"b\" : 9223372036854775807 }\n"),
+				CreatedAt: created,
+				UpdatedAt: created,
+			},
+		},
+		labels: map[string]string{"current": "00000000-0000-0000-0000-000000000002"},
+	}
+
+	var buf bytes.Buffer
+	var exported int64
+	if err := writeNDJSON(context.Background(), source, &buf, time.Time{}, &exported); err != nil {
+		t.Fatalf("writeNDJSON error: %v", err)
+	}
+	if exported != 2 {
+		t.Fatalf("exported %d documents, want 2", exported)
+	}
+	backup := buf.Bytes()
+
+	target := &memoryStore{}
+	result := &model.ImportResult{}
+	if err := readNDJSON(context.Background(), target, bytes.NewReader(backup), result); err != nil {
+		t.Fatalf("readNDJSON error: %v", err)
+	}
+	if result.Records != 2 || result.Stored != 2 || result.Skipped != 0 || result.Failed != 0 {
+		t.Errorf("import result = %+v, want 2 records stored", result)
+	}
+	if !reflect.DeepEqual(target.docs, source.docs) {
+		for i := range source.docs {
+			t.Errorf("restored document %d = %+v (%s), want %+v (%s)", i,
+				target.docs[i], target.docs[i].JSONData, source.docs[i], source.docs[i].JSONData)
+		}
+	}
+	if !reflect.DeepEqual(target.labels, source.labels) {
+		t.Errorf("restored labels = %v, want %v", target.labels, source.labels)
+	}
+
+	// 重复导入：相同ID和内容返回已有文档
+	result = &model.ImportResult{}
+	if err := readNDJSON(context.Background(), target, bytes.NewReader(backup), result); err != nil {
+		t.Fatalf("re-import error: %v", err)
+	}
+	if result.Stored != 2 || len(target.docs) != 2 {
+		t.Errorf("re-import result = %+v with %d documents, want 2 stored and no new documents", result, len(target.docs))
+	}
+
+	// ID已被其他内容占用、内容已由其他ID保存时跳过
+	conflicting := &memoryStore{docs: []*model.JSONDocument{
+		{ID: "00000000-0000-0000-0000-000000000001", JSONData: []byte(`{"other":true}`)},
+		{ID: "00000000-0000-0000-0000-000000000003", JSONData: []byte("{ \"b\" : 9223372036854775807 }\n")},
+	}}
+	result = &model.ImportResult{}
+	if err := readNDJSON(context.Background(), conflicting, bytes.NewReader(backup), result); err != nil {
+		t.Fatalf("conflicting import error: %v", err)
+	}
+	if result.Skipped != 2 || result.Stored != 0 {
+		t.Errorf("conflicting import result = %+v, want 2 skipped", result)
+	}
+}
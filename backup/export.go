@@ -0,0 +1,130 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/rs/zerolog/log"
+)
+
+// countingWriter 统计写入的字节数
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// Export 将文档导出为gzip压缩的NDJSON并上传到S3
+// 文档按游标分页读取，边读边压缩边上传（分片上传），内存占用与文档总量无关
+// since非零时只导出该时间之后创建的文档，用于增量备份
+func Export(ctx context.Context, store database.JSONStore, client *s3.Client, bucket, prefix string, since time.Time) (*model.ExportResult, error) {
+	start := time.Now()
+	result := &model.ExportResult{
+		Bucket: bucket,
+		Key:    fmt.Sprintf("%sjson-store-%s.ndjson.gz", prefix, start.UTC().Format("20060102T150405Z")),
+	}
+	if !since.IsZero() {
+		result.Since = &since
+	}
+
+	reader, writer := io.Pipe()
+	counter := &countingWriter{w: writer}
+
+	produced := make(chan error, 1)
+	go func() {
+		err := writeNDJSON(ctx, store, counter, since, &result.Documents)
+		writer.CloseWithError(err)
+		produced <- err
+	}()
+
+	uploader := manager.NewUploader(client)
+	_, err := uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(result.Key),
+		Body:        reader,
+		ContentType: aws.String("application/gzip"),
+	})
+	if err != nil {
+		// 上传失败时关闭读端，让导出协程尽快退出
+		reader.CloseWithError(err)
+		<-produced
+		return nil, fmt.Errorf("failed to upload export: %w", err)
+	}
+
+	if err := <-produced; err != nil {
+		return nil, fmt.Errorf("failed to export documents: %w", err)
+	}
+
+	result.Bytes = counter.n
+	result.Duration = time.Since(start)
+
+	log.Info().
+		Str("bucket", bucket).
+		Str("key", result.Key).
+		Int64("documents", result.Documents).
+		Int64("bytes", result.Bytes).
+		Dur("duration", result.Duration).
+		Msg("Backup exported to S3")
+
+	return result, nil
+}
+
+// writeNDJSON 遍历文档并逐行写入gzip压缩的NDJSON
+// 标签在遍历前一次读出，遍历期间改动的标签以读取时为准
+func writeNDJSON(ctx context.Context, store database.JSONStore, w io.Writer, since time.Time, count *int64) error {
+	labels, err := store.DocumentLabels(ctx)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	encoder := json.NewEncoder(gz)
+	// 与rawDataFor使用的json.Compact一致，不转义 <、>、&
+	encoder.SetEscapeHTML(false)
+
+	err = store.IterateDocuments(ctx, since, func(doc *model.JSONDocument) error {
+		*count++
+		return encoder.Encode(model.ExportRecord{
+			ID:          doc.ID,
+			ContentHash: doc.ContentHash,
+			DocType:     doc.DocType,
+			JSONData:    json.RawMessage(doc.JSONData),
+			RawData:     rawDataFor(doc.JSONData),
+			Metadata:    doc.Metadata,
+			Tags:        doc.Tags,
+			Labels:      labels[doc.ID],
+			CreatedAt:   doc.CreatedAt,
+			UpdatedAt:   doc.UpdatedAt,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+// rawDataFor 文档不是紧凑形式时返回原始字节，是时返回nil，避免每条记录都保存两份内容
+func rawDataFor(data []byte) []byte {
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, data); err == nil && bytes.Equal(compacted.Bytes(), data) {
+		return nil
+	}
+	return data
+}
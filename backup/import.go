@@ -0,0 +1,126 @@
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/rs/zerolog/log"
+)
+
+// Import 从S3读取Export生成的压缩NDJSON并写入存储
+// 文档按导出时的ID写入，中断后重新执行同一对象是安全的：已导入的文档直接返回已有记录
+func Import(ctx context.Context, store database.JSONStore, client *s3.Client, bucket, key string) (*model.ImportResult, error) {
+	start := time.Now()
+	result := &model.ImportResult{Bucket: bucket, Key: key}
+
+	object, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backup object: %w", err)
+	}
+	defer object.Body.Close()
+
+	if err := readNDJSON(ctx, store, object.Body, result); err != nil {
+		return nil, err
+	}
+
+	result.Duration = time.Since(start)
+
+	log.Info().
+		Str("bucket", bucket).
+		Str("key", key).
+		Int64("records", result.Records).
+		Int64("stored", result.Stored).
+		Int64("skipped", result.Skipped).
+		Int64("failed", result.Failed).
+		Dur("duration", result.Duration).
+		Msg("Backup imported from S3")
+
+	return result, nil
+}
+
+// readNDJSON 逐条解码gzip压缩的NDJSON并恢复文档，不受单行长度限制，也不会一次读入整个对象
+func readNDJSON(ctx context.Context, store database.JSONStore, r io.Reader, result *model.ImportResult) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("failed to open gzip stream: %w", err)
+	}
+	defer gz.Close()
+
+	decoder := json.NewDecoder(gz)
+	for {
+		var record model.ExportRecord
+		if err := decoder.Decode(&record); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to decode record %d: %w", result.Records+1, err)
+		}
+		result.Records++
+
+		if err := restoreRecord(ctx, store, record, result); err != nil {
+			return fmt.Errorf("import stopped after %d records, re-run to resume: %w", result.Records-1, err)
+		}
+	}
+}
+
+// restoreRecord 按导出时的ID写入一条记录，再恢复标签和时间戳
+// ID已被其他内容使用或内容已由其他ID保存时跳过；内容不合法时计为失败；其余错误中止导入
+func restoreRecord(ctx context.Context, store database.JSONStore, record model.ExportRecord, result *model.ImportResult) error {
+	data := []byte(record.JSONData)
+	if len(record.RawData) > 0 {
+		data = record.RawData
+	}
+
+	doc, err := store.StoreJSON(ctx, model.StoreInput{
+		ID:       record.ID,
+		JSONData: data,
+		DocType:  record.DocType,
+		Metadata: record.Metadata,
+		Tags:     record.Tags,
+	})
+	switch {
+	case errors.Is(err, database.ErrIDConflict), errors.Is(err, database.ErrDuplicateContent):
+		log.Warn().Err(err).Str("id", record.ID).Msg("Skipped conflicting backup record")
+		result.Skipped++
+		return nil
+	case errors.Is(err, database.ErrInvalidDocument), errors.Is(err, database.ErrControlCharacter):
+		log.Warn().Err(err).Str("id", record.ID).Msg("Rejected backup record")
+		result.Failed++
+		return nil
+	case err != nil:
+		return err
+	}
+	result.Stored++
+
+	for _, label := range record.Labels {
+		if _, err := store.SetLabel(ctx, label, doc.ID); err != nil {
+			return fmt.Errorf("failed to restore label %s: %w", label, err)
+		}
+	}
+
+	// 早期导出的记录没有updated_at，按created_at恢复
+	if !record.CreatedAt.IsZero() {
+		updatedAt := record.UpdatedAt
+		if updatedAt.IsZero() {
+			updatedAt = record.CreatedAt
+		}
+		if err := store.RestoreTimestamps(ctx, doc.ID, record.CreatedAt, updatedAt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/leapzhao/json-store/config"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// NewS3Client 根据备份配置创建S3客户端
+// 未配置访问密钥时使用AWS默认凭证链（环境变量、共享配置、实例角色等）
+func NewS3Client(ctx context.Context, cfg config.Config) (*s3.Client, error) {
+	s3cfg := cfg.Backup.S3
+	if s3cfg.Bucket == "" {
+		return nil, fmt.Errorf("backup s3 bucket is not configured (backup.s3.bucket / BACKUP_S3_BUCKET)")
+	}
+
+	opts := []func(*awsconfig.LoadOptions) error{
+		awsconfig.WithRegion(s3cfg.Region),
+	}
+	if s3cfg.AccessKeyID != "" {
+		opts = append(opts, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(s3cfg.AccessKeyID, s3cfg.SecretAccessKey, ""),
+		))
+	}
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load aws config: %w", err)
+	}
+
+	return s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if s3cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(s3cfg.Endpoint)
+		}
+		o.UsePathStyle = s3cfg.UsePathStyle
+	}), nil
+}
@@ -0,0 +1,100 @@
+package backup
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/model"
+)
+
+// stubS3 只支持路径风格的单对象PUT和GET，按 /bucket/key 保存对象
+type stubS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (s *stubS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.objects[r.URL.Path] = body
+		w.Header().Set("ETag", `"stub"`)
+	case http.MethodGet:
+		body, ok := s.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `<Error><Code>NoSuchKey</Code></Error>`)
+			return
+		}
+		w.Write(body)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func TestExportImportS3(t *testing.T) {
+	stub := &stubS3{objects: map[string][]byte{}}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	var cfg config.Config
+	cfg.Backup.S3.Endpoint = server.URL
+	cfg.Backup.S3.Region = "us-east-1"
+	cfg.Backup.S3.Bucket = "backups"
+	cfg.Backup.S3.AccessKeyID = "test"
+	cfg.Backup.S3.SecretAccessKey = "test"
+	cfg.Backup.S3.UsePathStyle = true
+	ctx := context.Background()
+	client, err := NewS3Client(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	source := &memoryStore{docs: []*model.JSONDocument{
+		{ID: "00000000-0000-0000-0000-000000000011", JSONData: []byte(`{"n":1}`), CreatedAt: created, UpdatedAt: created},
+		{ID: "00000000-0000-0000-0000-000000000012", JSONData: []byte(`{"n":2}`), CreatedAt: created, UpdatedAt: created},
+	}}
+
+	exported, err := Export(ctx, source, client, "backups", "daily/", time.Time{})
+	if err != nil {
+		t.Fatalf("Export error: %v", err)
+	}
+	if exported.Documents != 2 || !strings.HasPrefix(exported.Key, "daily/") {
+		t.Errorf("export result = %+v, want 2 documents under daily/", exported)
+	}
+	object, ok := stub.objects["/backups/"+exported.Key]
+	if !ok {
+		t.Fatalf("object %s was not written, have %v", exported.Key, stub.objects)
+	}
+	if int64(len(object)) != exported.Bytes {
+		t.Errorf("object has %d bytes, export reported %d", len(object), exported.Bytes)
+	}
+
+	target := &memoryStore{}
+	imported, err := Import(ctx, target, client, "backups", exported.Key)
+	if err != nil {
+		t.Fatalf("Import error: %v", err)
+	}
+	if imported.Records != 2 || imported.Stored != 2 || len(target.docs) != 2 {
+		t.Errorf("import result = %+v with %d documents, want 2 stored", imported, len(target.docs))
+	}
+	for i, doc := range target.docs {
+		if doc.ID != source.docs[i].ID || string(doc.JSONData) != string(source.docs[i].JSONData) {
+			t.Errorf("restored document %d = %s %s, want %s %s", i, doc.ID, doc.JSONData, source.docs[i].ID, source.docs[i].JSONData)
+		}
+	}
+}
@@ -1,10 +1,16 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net"
 	"os"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
 )
 
@@ -17,15 +23,180 @@ const (
 	EnvDefault Environment = "local"
 )
 
+// 去重方式
+const (
+	// DedupNormalized 按规范化后的内容去重，键顺序和空白不同的文档视为相同
+	DedupNormalized = "normalized"
+	// DedupRaw 按原始字节去重
+	DedupRaw = "raw"
+)
+
+// 内容哈希中数字的规范化方式
+const (
+	// NumberHandlingFloat64 数字经float64规范化，超过2^53的相邻整数哈希相同；与引入该选项之前存储的哈希一致
+	NumberHandlingFloat64 = "float64"
+	// NumberHandlingExact 数字按原始文本规范化，并保存请求原始字节，读取时返回与写入完全相同的整数
+	NumberHandlingExact = "exact"
+)
+
+// 批量存储中空json_data的处理方式
+const (
+	// BatchEmptyReject 整个请求返回400
+	BatchEmptyReject = "reject"
+	// BatchEmptySkip 该条记为失败（EMPTY_JSON_DATA），其余文档照常存储
+	BatchEmptySkip = "skip"
+)
+
+// 去重范围
+const (
+	// DedupScopeGlobal 全局去重，相同内容只保存一份
+	DedupScopeGlobal = "global"
+	// DedupScopePerType 按文档类型分别去重，唯一约束为(doc_type, 哈希列)
+	DedupScopePerType = "per_type"
+)
+
+// JSON无法规范化时内容哈希的计算方式
+const (
+	// NormalizeFailureHashRaw 按原始字节计算内容哈希，文档照常存储
+	NormalizeFailureHashRaw = "hash_raw"
+	// NormalizeFailureReject 拒绝存储，返回400
+	NormalizeFailureReject = "reject"
+)
+
+// JSON字符串中控制字符（U+0000至U+001F，\t、\n、\r除外）的处理方式
+// PostgreSQL的JSONB不接受U+0000而MySQL接受，统一在存储层处理使两种数据库行为一致
+const (
+	// ControlCharsRejectNull 含U+0000的文档返回400，其余控制字符照常存储
+	ControlCharsRejectNull = "reject_null"
+	// ControlCharsReject 含任意控制字符的文档返回400
+	ControlCharsReject = "reject"
+	// ControlCharsStrip 删除字符串中的控制字符后存储，内容哈希按删除后的内容计算
+	ControlCharsStrip = "strip"
+)
+
+// 存储时自动写入的metadata字段
+const (
+	// AutoMetadataSourceIP 客户端IP（按trusted_proxies解析）
+	AutoMetadataSourceIP = "source_ip"
+	// AutoMetadataRequestID 请求ID
+	AutoMetadataRequestID = "request_id"
+	// AutoMetadataUserAgent 请求的User-Agent
+	AutoMetadataUserAgent = "user_agent"
+	// AutoMetadataEnvironment 运行环境
+	AutoMetadataEnvironment = "environment"
+	// AutoMetadataIngestedAt 服务端接收时间（UTC）
+	AutoMetadataIngestedAt = "ingested_at"
+)
+
+// JSON路径索引的列类型
+const (
+	// JSONIndexString 字符串，生成列为VARCHAR(255)
+	JSONIndexString = "string"
+	// JSONIndexInteger 整数，生成列为BIGINT
+	JSONIndexInteger = "integer"
+	// JSONIndexNumber 浮点数，生成列为DOUBLE
+	JSONIndexNumber = "number"
+)
+
+// JSONPathIndex MySQL上为json_data中指定路径建立的索引，以存储生成列加B树索引实现
+type JSONPathIndex struct {
+	// Name 索引名称，生成列为 jp_<name>，索引为 idx_jp_<name>
+	Name string `mapstructure:"name"`
+	// Path JSON路径，如 $.id、$.user.name、$.tags[0]
+	Path string `mapstructure:"path"`
+	// Type 列类型：string（默认）、integer或number，取值无法转换时该列为NULL
+	Type string `mapstructure:"type"`
+}
+
+var (
+	jsonIndexNamePattern = regexp.MustCompile(`^[a-z0-9_]{1,48}$`)
+	jsonIndexPathPattern = regexp.MustCompile(`^\$(\.[A-Za-z_][A-Za-z0-9_]*|\[[0-9]+\])+$`)
+	dsnParamNamePattern  = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+	routeNamePattern     = regexp.MustCompile(`^(GET|POST|PUT|DELETE|PATCH) /\S*$`)
+)
+
+// dsnReservedParams 各数据库DSN中由其他配置项或代码依赖决定的参数，不能通过database.params覆盖
+var dsnReservedParams = map[string][]string{
+	"postgres": {"host", "port", "user", "password", "dbname", "sslmode"},
+	"mysql":    {"charset", "parsetime", "loc", "checkconnliveness"},
+}
+
 type Config struct {
 	Environment Environment `mapstructure:"environment"`
 
+	// ConfigFile 实际加载的配置文件路径，仅使用环境变量时为空
+	ConfigFile string `mapstructure:"-"`
+
 	Server struct {
 		Port         string `mapstructure:"port"`
 		Host         string `mapstructure:"host"`
 		ReadTimeout  int    `mapstructure:"read_timeout"`
 		WriteTimeout int    `mapstructure:"write_timeout"`
 		IdleTimeout  int    `mapstructure:"idle_timeout"`
+		EnablePprof  bool   `mapstructure:"enable_pprof"`
+		// ResponseEnvelope 为true时所有JSON响应包装为 {data, error, meta} 信封格式
+		ResponseEnvelope bool `mapstructure:"response_envelope"`
+		// ServiceInfo 为true时 GET / 返回服务信息，GET /api/v1 列出可用接口；为false时两者返回404
+		ServiceInfo bool `mapstructure:"service_info"`
+		// DeepReadyCheck 为true时就绪检查额外验证数据库可写（写入后回滚，会带来少量写负载）
+		DeepReadyCheck bool `mapstructure:"deep_ready_check"`
+		// UnixSocket 设置后监听该Unix域套接字路径而不是TCP端口，不能与port同时设置
+		UnixSocket string `mapstructure:"unix_socket"`
+		// MaxConcurrentWrites 写请求最大并发数，0表示不限制；WriteQueueSize 超出并发时的最大排队数
+		MaxConcurrentWrites int `mapstructure:"max_concurrent_writes"`
+		WriteQueueSize      int `mapstructure:"write_queue_size"`
+		// MaxConcurrentRequests /api下同时处理的请求数上限（不区分客户端），0表示不限制；
+		// 达到上限时最多等待RequestQueueTimeoutMs毫秒，仍无空位则返回503
+		MaxConcurrentRequests int `mapstructure:"max_concurrent_requests"`
+		RequestQueueTimeoutMs int `mapstructure:"request_queue_timeout_ms"`
+		// ResponseTimeoutMs 各路由组的响应超时（毫秒），超时返回503并取消数据库查询，0表示不限制
+		// Reads为/api/v1下的读接口，Admin为只读的管理接口；写接口不支持，超时后写入可能已经提交
+		ResponseTimeoutMs struct {
+			Reads int `mapstructure:"reads"`
+			Admin int `mapstructure:"admin"`
+		} `mapstructure:"response_timeout_ms"`
+		// AllowedContentTypes 写接口允许的Content-Type（不含参数），+json 后缀类型总是允许
+		AllowedContentTypes []string `mapstructure:"allowed_content_types"`
+		// RequestIDHeaders 读取请求ID的请求头，按优先级排列，如 X-Request-ID、X-Correlation-ID、traceparent
+		RequestIDHeaders []string `mapstructure:"request_id_headers"`
+		// ShortHashLength 响应中short_hash的长度（内容哈希的十六进制前缀），0表示不返回也不支持短哈希查询
+		ShortHashLength int `mapstructure:"short_hash_length"`
+		// EscapeHTML 为false时文档相关的响应（文档、列表、规范化、平铺）不再把 <、>、& 转义为 \u003c 等，
+		// 客户端得到与存储内容一致的字符；响应若会被直接嵌入HTML页面应保持默认的true
+		EscapeHTML bool `mapstructure:"escape_html"`
+		// MaxElements 单个文档允许的最大值数量（对象、数组、标量各计1），0表示不限制
+		MaxElements int `mapstructure:"max_elements"`
+		// BatchEmptyData 批量存储中json_data缺失、为空或为null时的处理方式：reject或skip
+		BatchEmptyData string `mapstructure:"batch_empty_data"`
+		// MaxResponseBytes 单个文档响应的最大字节数，超过时返回413，0表示不限制
+		MaxResponseBytes int64 `mapstructure:"max_response_bytes"`
+		// BatchGetMaxIDs 批量获取单次请求最多的ID数，超过100时按每100个拆分查询
+		BatchGetMaxIDs int `mapstructure:"batch_get_max_ids"`
+		// BatchGetConcurrency 拆分后同时执行的查询数
+		BatchGetConcurrency int `mapstructure:"batch_get_concurrency"`
+		// UploadMaxFileBytes multipart上传中单个文件的最大字节数，超过时该文件记为失败
+		// UploadMaxTotalBytes 整个上传请求体的最大字节数，超过时整个请求返回413
+		UploadMaxFileBytes  int64 `mapstructure:"upload_max_file_bytes"`
+		UploadMaxTotalBytes int64 `mapstructure:"upload_max_total_bytes"`
+		// AttachmentMaxBytes 单个附件的最大字节数，超过时返回413
+		AttachmentMaxBytes int64 `mapstructure:"attachment_max_bytes"`
+		// AutoMetadata 新建文档时自动写入metadata的来源信息
+		AutoMetadata struct {
+			// Fields 写入的字段：source_ip、request_id、user_agent、environment、ingested_at，为空表示关闭
+			Fields []string `mapstructure:"fields"`
+			// Reserved Fields中不允许客户端覆盖的字段，其余字段客户端提供同名键时以客户端的值为准
+			Reserved []string `mapstructure:"reserved"`
+		} `mapstructure:"auto_metadata"`
+		// MetadataMaxKeys metadata最多的顶层键数，MetadataMaxBytes metadata序列化后的最大字节数，0表示不限制
+		// MetadataDisallowedKeys 禁止使用的metadata键名（正则表达式，匹配任意部分即拒绝）
+		MetadataMaxKeys        int      `mapstructure:"metadata_max_keys"`
+		MetadataMaxBytes       int      `mapstructure:"metadata_max_bytes"`
+		MetadataDisallowedKeys []string `mapstructure:"metadata_disallowed_keys"`
+		// WatchdogThreshold 请求超过该时长（秒）仍未完成时告警，0表示关闭
+		// WatchdogDumpStacks 告警时附带goroutine堆栈；WatchdogFailLiveness 存在卡住的请求时/health返回503
+		WatchdogThreshold    int  `mapstructure:"watchdog_threshold"`
+		WatchdogDumpStacks   bool `mapstructure:"watchdog_dump_stacks"`
+		WatchdogFailLiveness bool `mapstructure:"watchdog_fail_liveness"`
 	} `mapstructure:"server"`
 
 	Database struct {
@@ -38,6 +209,66 @@ type Config struct {
 		SSLMode   string `mapstructure:"ssl_mode"`
 		MaxConns  int    `mapstructure:"max_conns"`
 		IdleConns int    `mapstructure:"idle_conns"`
+		// AllowInsecureSSL 允许生产环境使用ssl_mode=disable，默认拒绝启动
+		AllowInsecureSSL bool `mapstructure:"allow_insecure_ssl"`
+		// AllowDuplicateContent 为true时不再按内容去重，相同内容每次存储都生成新ID，
+		// 并移除content_hash上的唯一约束；切回false时若已有重复数据会导致启动失败
+		AllowDuplicateContent bool `mapstructure:"allow_duplicate_content"`
+		// DedupMode 去重依据：normalized（content_hash）或raw（raw_hash），唯一约束随之切换；
+		// 从raw切回normalized时若已有规范化后相同的文档会导致启动失败
+		DedupMode string `mapstructure:"dedup_mode"`
+		// NormalizeFailure 文档能通过JSON校验但无法规范化时的处理：hash_raw（按原始字节计算content_hash）或reject
+		NormalizeFailure string `mapstructure:"normalize_failure"`
+		// ControlChars JSON字符串（键或值）中控制字符的处理：reject_null、reject或strip
+		ControlChars string `mapstructure:"control_chars"`
+		// DedupScope 去重范围：global或per_type，唯一约束随之切换；
+		// 从per_type切回global时若已有不同类型下相同的文档会导致启动失败，原约束保持不变
+		DedupScope string `mapstructure:"dedup_scope"`
+		// SizeHistogramBuckets 统计接口中文档大小直方图的桶上界（字节，严格升序）
+		SizeHistogramBuckets []int64 `mapstructure:"size_histogram_buckets"`
+		// AgeHistogramHours 统计接口中文档年龄（按created_at）分布的桶上界（小时，严格升序），用于规划数据保留策略
+		AgeHistogramHours []int64 `mapstructure:"age_histogram_hours"`
+		// PreserveRawBytes 为true时额外保存请求原始字节，读取时原样返回（不经数据库JSON类型重新序列化），
+		// 用于保留超出double精度的大整数；内容哈希仍基于规范化形式计算，去重语义不变
+		PreserveRawBytes bool `mapstructure:"preserve_raw_bytes"`
+		// NumberHandling 内容哈希中数字的规范化方式：float64（默认）或exact，exact同时开启原始字节保存；
+		// 已有文档保留原来的哈希，切换后含大整数或不同写法数字（如1.0）的文档重新提交时不再命中旧文档
+		NumberHandling string `mapstructure:"number_handling"`
+		// StoreNormalized 为true时保存规范化形式（键排序、去除空白），size按规范化形式计算，
+		// 格式不同但内容相同的文档存储结果一致，不再取决于第一个写入者；无法规范化的文档仍按原样保存
+		StoreNormalized bool `mapstructure:"store_normalized"`
+		// ConnectTimeout 启动时等待数据库可连接的最长时间（秒）
+		ConnectTimeout int `mapstructure:"connect_timeout"`
+		// SlowQueryMs 存储操作耗时超过该阈值（毫秒）时记录慢查询警告，0表示关闭
+		SlowQueryMs int `mapstructure:"slow_query_ms"`
+		// ChunkThreshold 文档大小超过该值（字节）时拆分写入json_document_chunks表，0表示关闭；
+		// 分块后每个插入语句只携带一块，驱动构造请求时不再拷贝整个文档
+		ChunkThreshold int64 `mapstructure:"chunk_threshold"`
+		// ChunkSize 分块存储时每块的大小（字节）
+		ChunkSize int `mapstructure:"chunk_size"`
+		// CoalesceWrites 为true时内容相同的并发写入共享一次数据库操作（allow_duplicate_content开启时无效）
+		CoalesceWrites bool `mapstructure:"coalesce_writes"`
+		// TrackAccess 为true时按ID读取文档会累加access_count并更新last_accessed_at，读请求因此变为写操作
+		TrackAccess bool `mapstructure:"track_access"`
+		// ConnMaxIdleTime 连接池中连接的最长空闲时间（秒），应小于数据库或代理的空闲断开时间，0表示不限制
+		ConnMaxIdleTime int `mapstructure:"conn_max_idle_time"`
+		// JSONIndexes 仅MySQL：按JSON路径建立的索引，启动时与数据库同步，
+		// 从配置中移除或修改路径、类型时会删除（并重建）对应的生成列和索引；
+		// 压缩或分块存储的文档内容不在json_data中，其生成列为NULL
+		JSONIndexes []JSONPathIndex `mapstructure:"json_indexes"`
+		// MaxReplicationLagBytes 仅PostgreSQL：连接的是备库且已接收未回放的WAL超过该字节数时，
+		// 就绪检查报告degraded并返回503，0表示只报告不摘除
+		MaxReplicationLagBytes int64 `mapstructure:"max_replication_lag_bytes"`
+		// ChangesLagMs 增量同步（/json/changes）只返回updated_at早于数据库当前时间减该值（毫秒）的文档。
+		// updated_at取写入开始的时间，提交较晚的事务的文档可能排在已返回的游标之前而被跳过，
+		// 该值应大于最长的写事务耗时；0表示不等待
+		ChangesLagMs int `mapstructure:"changes_lag_ms"`
+		// StatementTimeoutMs 每个连接建立时设置的服务端语句超时（毫秒），由数据库强制中止超时的语句，0表示不限制；
+		// PostgreSQL为statement_timeout（迁移除外），MySQL为max_execution_time（只对SELECT生效）
+		StatementTimeoutMs int `mapstructure:"statement_timeout_ms"`
+		// Params 追加到DSN的驱动参数，如PostgreSQL的application_name、statement_timeout，
+		// MySQL的readTimeout、writeTimeout；值会被转义，参数名只能包含字母、数字和下划线
+		Params map[string]string `mapstructure:"params"`
 	} `mapstructure:"database"`
 
 	Logging struct {
@@ -51,7 +282,81 @@ type Config struct {
 		CertFile    string   `mapstructure:"cert_file"`
 		KeyFile     string   `mapstructure:"key_file"`
 		CorsOrigins []string `mapstructure:"cors_origins"`
+		// TrustedProxies 可信代理的IP或CIDR，只有来自这些地址的X-Forwarded-For才用于解析客户端IP；
+		// 为空表示不信任任何代理，直接使用连接的对端地址
+		TrustedProxies []string `mapstructure:"trusted_proxies"`
+		// AdminUsername、AdminPassword 管理接口和pprof的基本认证账号；生产环境没有默认值，未设置时拒绝启动
+		AdminUsername string `mapstructure:"admin_username"`
+		AdminPassword string `mapstructure:"admin_password"`
+		// CorsMaxAge 预检请求结果的缓存时间（秒），写入Access-Control-Max-Age
+		CorsMaxAge int `mapstructure:"cors_max_age"`
+		// CorsAllowCredentials 允许携带Cookie等凭证，此时响应回显具体Origin，不能与通配符 * 同时使用
+		CorsAllowCredentials bool `mapstructure:"cors_allow_credentials"`
+		// RateLimit 按客户端IP（按TrustedProxies解析）限流，每window秒补充requests个请求额度，最多累积burst个；
+		// requests为0表示不限流。默认值随环境变化：本地不限流，测试环境宽松，生产环境严格
+		RateLimit struct {
+			Requests int `mapstructure:"requests"`
+			Burst    int `mapstructure:"burst"`
+			Window   int `mapstructure:"window"`
+		} `mapstructure:"rate_limit"`
+		// DailyQuota 每个客户端IP每个UTC自然日最多新建的文档数（命中去重的不计），0表示不限制；
+		// 计数保存在内存中，多实例部署时每个实例分别计算
+		DailyQuota int `mapstructure:"daily_quota"`
+		// Headers 所有响应附带的安全头，设为空字符串可关闭单个头；HSTS只在enable_https时发送，hsts_max_age为0时关闭
+		Headers struct {
+			ContentTypeOptions    string `mapstructure:"content_type_options"`
+			FrameOptions          string `mapstructure:"frame_options"`
+			ReferrerPolicy        string `mapstructure:"referrer_policy"`
+			HSTSMaxAge            int    `mapstructure:"hsts_max_age"`
+			HSTSIncludeSubdomains bool   `mapstructure:"hsts_include_subdomains"`
+		} `mapstructure:"headers"`
 	} `mapstructure:"security"`
+
+	Backup struct {
+		// S3 备份使用的S3兼容对象存储，Endpoint为空时使用AWS默认地址
+		S3 struct {
+			Endpoint        string `mapstructure:"endpoint"`
+			Region          string `mapstructure:"region"`
+			Bucket          string `mapstructure:"bucket"`
+			Prefix          string `mapstructure:"prefix"`
+			AccessKeyID     string `mapstructure:"access_key_id"`
+			SecretAccessKey string `mapstructure:"secret_access_key"`
+			// UsePathStyle MinIO等自建存储通常需要路径风格访问
+			UsePathStyle bool `mapstructure:"use_path_style"`
+		} `mapstructure:"s3"`
+	} `mapstructure:"backup"`
+
+	Search struct {
+		// Enabled 开启后写入和更新的文档异步同步到OpenSearch/Elasticsearch索引，并提供全文搜索接口
+		Enabled  bool   `mapstructure:"enabled"`
+		URL      string `mapstructure:"url"`
+		Index    string `mapstructure:"index"`
+		Username string `mapstructure:"username"`
+		Password string `mapstructure:"password"`
+		// QueueSize 待同步队列长度，队列满时丢弃并记录警告，不阻塞写入
+		QueueSize int `mapstructure:"queue_size"`
+		// MaxRetries 索引服务不可用时单个文档的最大重试次数，重试间隔指数增长
+		MaxRetries int `mapstructure:"max_retries"`
+		// Timeout 单次请求超时（秒）
+		Timeout int `mapstructure:"timeout"`
+	} `mapstructure:"search"`
+
+	Maintenance struct {
+		// Window 重型维护任务（如压缩存量文档）只在该时间段内执行，start、end为空时不限制
+		Window struct {
+			// Start、End 每天的开始和结束时间（HH:MM），end早于start表示跨越零点
+			Start string `mapstructure:"start"`
+			End   string `mapstructure:"end"`
+			// Timezone IANA时区名，如 Asia/Shanghai
+			Timezone string `mapstructure:"timezone"`
+		} `mapstructure:"window"`
+	} `mapstructure:"maintenance"`
+
+	Routes struct {
+		// Disabled 关闭的接口，格式为"方法 路径"，与GET /api/v1列出的写法一致，如"POST /api/v1/json/upload"；
+		// 关闭的接口不会注册，请求返回404，也不出现在接口列表中
+		Disabled []string `mapstructure:"disabled"`
+	} `mapstructure:"routes"`
 }
 
 // LoadConfig 加载配置，支持多环境
@@ -71,7 +376,7 @@ func LoadConfig() (*Config, error) {
 	viper.AddConfigPath(".")
 
 	// 设置默认值
-	setDefaults()
+	setDefaults(env)
 
 	// 读取环境变量（优先于配置文件）
 	bindEnvVars()
@@ -79,24 +384,35 @@ func LoadConfig() (*Config, error) {
 	// 读取配置文件
 	if err := viper.ReadInConfig(); err != nil {
 		// 如果配置文件不存在，仅使用环境变量和默认值
+		// 此时日志尚未初始化，由调用方根据ConfigFile是否为空记录
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
 			return nil, fmt.Errorf("failed to read config: %w", err)
 		}
-		fmt.Printf("Config file not found, using environment variables and defaults\n")
 	}
 
 	var config Config
 	if err := viper.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	config.ConfigFile = viper.ConfigFileUsed()
+
+	// 端口默认值只在未设置unix_socket时生效，否则无法区分用户是否同时设置了两者
+	if config.Server.Port == "" && config.Server.UnixSocket == "" {
+		config.Server.Port = defaultServerPort
+	}
+
+	// 环境名可能是别名或大小写不同（如 APP_ENV=prod），统一后生产环境的检查只需比较EnvProduct
+	if normalized, ok := parseEnvironment(string(config.Environment)); ok {
+		config.Environment = normalized
+	} else {
+		config.Environment = env
+	}
 
 	// 验证配置
 	if err := validateConfig(&config); err != nil {
 		return nil, fmt.Errorf("config validation failed: %w", err)
 	}
 
-	fmt.Printf("Loaded configuration for environment: %s\n", env)
-
 	return &config, nil
 }
 
@@ -107,16 +423,24 @@ func GetEnvironment() Environment {
 		env = string(EnvDefault)
 	}
 
-	switch strings.ToLower(env) {
+	if parsed, ok := parseEnvironment(env); ok {
+		return parsed
+	}
+	log.Warn().Str("env", env).Str("default", string(EnvDefault)).Msg("Unknown environment, using default")
+	return EnvDefault
+}
+
+// parseEnvironment 把环境名及其别名（不区分大小写）转换为Environment，无法识别时返回false
+func parseEnvironment(name string) (Environment, bool) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
 	case "local", "dev", "development":
-		return EnvLocal
+		return EnvLocal, true
 	case "test", "staging":
-		return EnvTest
-	case "prod", "production":
-		return EnvProduct
+		return EnvTest, true
+	case "product", "prod", "production":
+		return EnvProduct, true
 	default:
-		fmt.Printf("Unknown environment: %s, using default: %s\n", env, EnvDefault)
-		return EnvDefault
+		return "", false
 	}
 }
 
@@ -135,22 +459,78 @@ func IsLocal() bool {
 	return GetEnvironment() == EnvLocal
 }
 
-func setDefaults() {
+// defaultServerPort 未设置unix_socket和port时监听的TCP端口
+const defaultServerPort = "8080"
+
+func setDefaults(env Environment) {
 	viper.SetDefault("environment", EnvLocal)
 
 	// 服务器配置默认值
-	viper.SetDefault("server.port", "8080")
 	viper.SetDefault("server.host", "0.0.0.0")
 	viper.SetDefault("server.read_timeout", 10)
 	viper.SetDefault("server.write_timeout", 10)
 	viper.SetDefault("server.idle_timeout", 60)
+	viper.SetDefault("server.enable_pprof", false)
+	viper.SetDefault("server.response_envelope", false)
+	viper.SetDefault("server.deep_ready_check", false)
+	viper.SetDefault("server.service_info", true)
+	viper.SetDefault("server.max_concurrent_writes", 0)
+	viper.SetDefault("server.write_queue_size", 0)
+	viper.SetDefault("server.max_concurrent_requests", 0)
+	viper.SetDefault("server.request_queue_timeout_ms", 100)
+	viper.SetDefault("server.response_timeout_ms.reads", 0)
+	viper.SetDefault("server.response_timeout_ms.admin", 0)
+	viper.SetDefault("server.allowed_content_types", []string{"application/json"})
+	viper.SetDefault("server.request_id_headers", []string{"X-Request-ID"})
+	viper.SetDefault("server.short_hash_length", 0)
+	viper.SetDefault("server.escape_html", true)
+	viper.SetDefault("server.max_elements", 0)
+	viper.SetDefault("server.max_response_bytes", 0)
+	viper.SetDefault("server.batch_empty_data", BatchEmptyReject)
+	viper.SetDefault("server.batch_get_max_ids", 100)
+	viper.SetDefault("server.batch_get_concurrency", 4)
+	viper.SetDefault("server.upload_max_file_bytes", 10<<20)
+	viper.SetDefault("server.upload_max_total_bytes", 50<<20)
+	viper.SetDefault("server.attachment_max_bytes", 10<<20)
+	viper.SetDefault("server.auto_metadata.fields", []string{})
+	viper.SetDefault("server.auto_metadata.reserved", []string{})
+	viper.SetDefault("server.metadata_max_keys", 0)
+	viper.SetDefault("server.metadata_max_bytes", 0)
+	viper.SetDefault("server.metadata_disallowed_keys", []string{})
+	viper.SetDefault("server.watchdog_threshold", 60)
+	viper.SetDefault("server.watchdog_dump_stacks", false)
+	viper.SetDefault("server.watchdog_fail_liveness", false)
 
 	// 数据库默认值
 	viper.SetDefault("database.type", "postgres")
 	viper.SetDefault("database.port", 5432)
-	viper.SetDefault("database.ssl_mode", "disable")
+	viper.SetDefault("database.ssl_mode", defaultSSLMode(env))
+	viper.SetDefault("database.allow_insecure_ssl", false)
 	viper.SetDefault("database.max_conns", 25)
 	viper.SetDefault("database.idle_conns", 5)
+	viper.SetDefault("database.allow_duplicate_content", false)
+	viper.SetDefault("database.dedup_mode", DedupNormalized)
+	viper.SetDefault("database.normalize_failure", NormalizeFailureHashRaw)
+	viper.SetDefault("database.control_chars", ControlCharsRejectNull)
+	viper.SetDefault("database.dedup_scope", DedupScopeGlobal)
+	viper.SetDefault("database.preserve_raw_bytes", false)
+	viper.SetDefault("database.number_handling", NumberHandlingFloat64)
+	viper.SetDefault("database.store_normalized", false)
+	viper.SetDefault("database.connect_timeout", 30)
+	viper.SetDefault("database.slow_query_ms", 200)
+	viper.SetDefault("database.chunk_threshold", 0)
+	viper.SetDefault("database.chunk_size", 1<<20)
+	viper.SetDefault("database.coalesce_writes", true)
+	viper.SetDefault("database.track_access", false)
+	viper.SetDefault("database.conn_max_idle_time", 60)
+	viper.SetDefault("database.max_replication_lag_bytes", 0)
+	viper.SetDefault("database.statement_timeout_ms", 0)
+	viper.SetDefault("database.changes_lag_ms", 5000)
+	viper.SetDefault("database.size_histogram_buckets", []int64{
+		1 << 10, 1 << 12, 1 << 14, 1 << 16, 1 << 18, 1 << 20, 1 << 22,
+	})
+	// 1天、7天、30天
+	viper.SetDefault("database.age_histogram_hours", []int64{24, 24 * 7, 24 * 30})
 
 	// 日志默认值
 	viper.SetDefault("logging.level", "info")
@@ -160,6 +540,39 @@ func setDefaults() {
 	// 安全默认值
 	viper.SetDefault("security.enable_https", false)
 	viper.SetDefault("security.cors_origins", []string{"*"})
+	viper.SetDefault("security.trusted_proxies", []string{"127.0.0.1", "::1"})
+	viper.SetDefault("security.cors_max_age", 43200)
+	viper.SetDefault("security.cors_allow_credentials", false)
+	if env != EnvProduct {
+		viper.SetDefault("security.admin_username", "admin")
+		viper.SetDefault("security.admin_password", "secret")
+	}
+	requests, burst := defaultRateLimit(env)
+	viper.SetDefault("security.rate_limit.requests", requests)
+	viper.SetDefault("security.rate_limit.burst", burst)
+	viper.SetDefault("security.rate_limit.window", 1)
+	viper.SetDefault("security.daily_quota", 0)
+	viper.SetDefault("security.headers.content_type_options", "nosniff")
+	viper.SetDefault("security.headers.frame_options", "DENY")
+	viper.SetDefault("security.headers.referrer_policy", "no-referrer")
+	viper.SetDefault("security.headers.hsts_max_age", 31536000)
+	viper.SetDefault("security.headers.hsts_include_subdomains", false)
+
+	// 备份默认值
+	viper.SetDefault("backup.s3.region", "us-east-1")
+	viper.SetDefault("backup.s3.prefix", "json-store/")
+	viper.SetDefault("backup.s3.use_path_style", false)
+
+	viper.SetDefault("maintenance.window.timezone", "UTC")
+
+	viper.SetDefault("search.enabled", false)
+	viper.SetDefault("search.url", "http://localhost:9200")
+	viper.SetDefault("search.index", "json-documents")
+	viper.SetDefault("search.queue_size", 10000)
+	viper.SetDefault("search.max_retries", 5)
+	viper.SetDefault("search.timeout", 5)
+
+	viper.SetDefault("routes.disabled", []string{})
 }
 
 func bindEnvVars() {
@@ -168,6 +581,37 @@ func bindEnvVars() {
 
 	viper.BindEnv("server.port", "SERVER_PORT")
 	viper.BindEnv("server.host", "SERVER_HOST")
+	viper.BindEnv("server.enable_pprof", "ENABLE_PPROF")
+	viper.BindEnv("server.response_envelope", "RESPONSE_ENVELOPE")
+	viper.BindEnv("server.deep_ready_check", "DEEP_READY_CHECK")
+	viper.BindEnv("server.unix_socket", "SERVER_UNIX_SOCKET")
+	viper.BindEnv("server.max_concurrent_writes", "SERVER_MAX_CONCURRENT_WRITES")
+	viper.BindEnv("server.write_queue_size", "SERVER_WRITE_QUEUE_SIZE")
+	viper.BindEnv("server.max_concurrent_requests", "SERVER_MAX_CONCURRENT_REQUESTS")
+	viper.BindEnv("server.service_info", "SERVER_SERVICE_INFO")
+	viper.BindEnv("server.request_queue_timeout_ms", "SERVER_REQUEST_QUEUE_TIMEOUT_MS")
+	viper.BindEnv("server.response_timeout_ms.reads", "SERVER_RESPONSE_TIMEOUT_READS_MS")
+	viper.BindEnv("server.response_timeout_ms.admin", "SERVER_RESPONSE_TIMEOUT_ADMIN_MS")
+	viper.BindEnv("server.allowed_content_types", "SERVER_ALLOWED_CONTENT_TYPES")
+	viper.BindEnv("server.request_id_headers", "SERVER_REQUEST_ID_HEADERS")
+	viper.BindEnv("server.short_hash_length", "SERVER_SHORT_HASH_LENGTH")
+	viper.BindEnv("server.escape_html", "SERVER_ESCAPE_HTML")
+	viper.BindEnv("server.max_elements", "SERVER_MAX_ELEMENTS")
+	viper.BindEnv("server.max_response_bytes", "SERVER_MAX_RESPONSE_BYTES")
+	viper.BindEnv("server.batch_empty_data", "SERVER_BATCH_EMPTY_DATA")
+	viper.BindEnv("server.batch_get_max_ids", "SERVER_BATCH_GET_MAX_IDS")
+	viper.BindEnv("server.batch_get_concurrency", "SERVER_BATCH_GET_CONCURRENCY")
+	viper.BindEnv("server.upload_max_file_bytes", "SERVER_UPLOAD_MAX_FILE_BYTES")
+	viper.BindEnv("server.upload_max_total_bytes", "SERVER_UPLOAD_MAX_TOTAL_BYTES")
+	viper.BindEnv("server.attachment_max_bytes", "SERVER_ATTACHMENT_MAX_BYTES")
+	viper.BindEnv("server.auto_metadata.fields", "SERVER_AUTO_METADATA_FIELDS")
+	viper.BindEnv("server.auto_metadata.reserved", "SERVER_AUTO_METADATA_RESERVED")
+	viper.BindEnv("server.metadata_max_keys", "SERVER_METADATA_MAX_KEYS")
+	viper.BindEnv("server.metadata_max_bytes", "SERVER_METADATA_MAX_BYTES")
+	viper.BindEnv("server.metadata_disallowed_keys", "SERVER_METADATA_DISALLOWED_KEYS")
+	viper.BindEnv("server.watchdog_threshold", "SERVER_WATCHDOG_THRESHOLD")
+	viper.BindEnv("server.watchdog_dump_stacks", "SERVER_WATCHDOG_DUMP_STACKS")
+	viper.BindEnv("server.watchdog_fail_liveness", "SERVER_WATCHDOG_FAIL_LIVENESS")
 
 	viper.BindEnv("database.type", "DB_TYPE")
 	viper.BindEnv("database.host", "DB_HOST")
@@ -176,6 +620,24 @@ func bindEnvVars() {
 	viper.BindEnv("database.password", "DB_PASSWORD")
 	viper.BindEnv("database.name", "DB_NAME")
 	viper.BindEnv("database.ssl_mode", "DB_SSL_MODE")
+	viper.BindEnv("database.allow_insecure_ssl", "DB_ALLOW_INSECURE_SSL")
+	viper.BindEnv("database.allow_duplicate_content", "DB_ALLOW_DUPLICATE_CONTENT")
+	viper.BindEnv("database.dedup_mode", "DB_DEDUP_MODE")
+	viper.BindEnv("database.normalize_failure", "DB_NORMALIZE_FAILURE")
+	viper.BindEnv("database.control_chars", "DB_CONTROL_CHARS")
+	viper.BindEnv("database.dedup_scope", "DB_DEDUP_SCOPE")
+	viper.BindEnv("database.preserve_raw_bytes", "DB_PRESERVE_RAW_BYTES")
+	viper.BindEnv("database.number_handling", "DB_NUMBER_HANDLING")
+	viper.BindEnv("database.store_normalized", "DB_STORE_NORMALIZED")
+	viper.BindEnv("database.connect_timeout", "DB_CONNECT_TIMEOUT")
+	viper.BindEnv("database.slow_query_ms", "DB_SLOW_QUERY_MS")
+	viper.BindEnv("database.chunk_threshold", "DB_CHUNK_THRESHOLD")
+	viper.BindEnv("database.chunk_size", "DB_CHUNK_SIZE")
+	viper.BindEnv("database.coalesce_writes", "DB_COALESCE_WRITES")
+	viper.BindEnv("database.track_access", "DB_TRACK_ACCESS")
+	viper.BindEnv("database.conn_max_idle_time", "DB_CONN_MAX_IDLE_TIME")
+	viper.BindEnv("database.max_replication_lag_bytes", "DB_MAX_REPLICATION_LAG_BYTES")
+	viper.BindEnv("database.statement_timeout_ms", "DB_STATEMENT_TIMEOUT_MS")
 
 	viper.BindEnv("logging.level", "LOG_LEVEL")
 	viper.BindEnv("logging.format", "LOG_FORMAT")
@@ -184,15 +646,389 @@ func bindEnvVars() {
 	viper.BindEnv("security.enable_https", "ENABLE_HTTPS")
 	viper.BindEnv("security.cert_file", "CERT_FILE")
 	viper.BindEnv("security.key_file", "KEY_FILE")
+	viper.BindEnv("security.cors_origins", "CORS_ORIGINS")
+	viper.BindEnv("security.trusted_proxies", "TRUSTED_PROXIES")
+	viper.BindEnv("security.admin_username", "ADMIN_USERNAME")
+	viper.BindEnv("security.admin_password", "ADMIN_PASSWORD")
+	viper.BindEnv("security.cors_max_age", "CORS_MAX_AGE")
+	viper.BindEnv("security.cors_allow_credentials", "CORS_ALLOW_CREDENTIALS")
+	viper.BindEnv("security.rate_limit.requests", "RATE_LIMIT_REQUESTS")
+	viper.BindEnv("security.rate_limit.burst", "RATE_LIMIT_BURST")
+	viper.BindEnv("security.rate_limit.window", "RATE_LIMIT_WINDOW")
+	viper.BindEnv("security.daily_quota", "DAILY_QUOTA")
+	viper.BindEnv("security.headers.content_type_options", "SECURITY_CONTENT_TYPE_OPTIONS")
+	viper.BindEnv("security.headers.frame_options", "SECURITY_FRAME_OPTIONS")
+	viper.BindEnv("security.headers.referrer_policy", "SECURITY_REFERRER_POLICY")
+	viper.BindEnv("security.headers.hsts_max_age", "SECURITY_HSTS_MAX_AGE")
+	viper.BindEnv("security.headers.hsts_include_subdomains", "SECURITY_HSTS_INCLUDE_SUBDOMAINS")
+
+	viper.BindEnv("backup.s3.endpoint", "BACKUP_S3_ENDPOINT")
+	viper.BindEnv("backup.s3.region", "BACKUP_S3_REGION")
+	viper.BindEnv("backup.s3.bucket", "BACKUP_S3_BUCKET")
+	viper.BindEnv("backup.s3.prefix", "BACKUP_S3_PREFIX")
+	viper.BindEnv("backup.s3.access_key_id", "BACKUP_S3_ACCESS_KEY_ID")
+	viper.BindEnv("backup.s3.secret_access_key", "BACKUP_S3_SECRET_ACCESS_KEY")
+	viper.BindEnv("backup.s3.use_path_style", "BACKUP_S3_USE_PATH_STYLE")
+
+	viper.BindEnv("maintenance.window.start", "MAINTENANCE_WINDOW_START")
+	viper.BindEnv("maintenance.window.end", "MAINTENANCE_WINDOW_END")
+	viper.BindEnv("maintenance.window.timezone", "MAINTENANCE_WINDOW_TIMEZONE")
+
+	viper.BindEnv("search.enabled", "SEARCH_ENABLED")
+	viper.BindEnv("search.url", "SEARCH_URL")
+	viper.BindEnv("search.index", "SEARCH_INDEX")
+	viper.BindEnv("search.username", "SEARCH_USERNAME")
+	viper.BindEnv("search.password", "SEARCH_PASSWORD")
+	viper.BindEnv("search.queue_size", "SEARCH_QUEUE_SIZE")
+	viper.BindEnv("search.max_retries", "SEARCH_MAX_RETRIES")
+	viper.BindEnv("search.timeout", "SEARCH_TIMEOUT")
+
+	viper.BindEnv("routes.disabled", "ROUTES_DISABLED")
+}
+
+// defaultSSLMode 生产环境默认加密数据库连接，本地和测试环境默认不加密
+func defaultSSLMode(env Environment) string {
+	if env == EnvProduct {
+		return "require"
+	}
+	return "disable"
+}
+
+// defaultRateLimit 各环境默认的每秒请求数和突发容量，本地环境不限流
+func defaultRateLimit(env Environment) (requests, burst int) {
+	switch env {
+	case EnvProduct:
+		return 50, 100
+	case EnvTest:
+		return 500, 1000
+	default:
+		return 0, 0
+	}
 }
 
+// validateConfig 检查配置，收集全部问题后一次返回，避免逐个修改逐个重启
 func validateConfig(cfg *Config) error {
-	if cfg.Server.Port == "" {
-		return fmt.Errorf("server port is required")
+	var errs []error
+	if cfg.Server.Port == "" && cfg.Server.UnixSocket == "" {
+		errs = append(errs, fmt.Errorf("server port is required"))
+	}
+
+	var missing []string
+	if cfg.Database.Host == "" {
+		missing = append(missing, "host (DB_HOST)")
+	}
+	if cfg.Database.Name == "" {
+		missing = append(missing, "name (DB_NAME)")
+	}
+	if len(missing) > 0 {
+		source := "config file " + cfg.ConfigFile + " or environment"
+		if cfg.ConfigFile == "" {
+			source = "environment (no config file found)"
+		}
+		errs = append(errs, fmt.Errorf("database %s required but not set in %s", strings.Join(missing, " and "), source))
+	}
+
+	if cfg.Environment == EnvProduct && strings.EqualFold(cfg.Database.SSLMode, "disable") && !cfg.Database.AllowInsecureSSL {
+		errs = append(errs, fmt.Errorf("database ssl_mode=disable is not allowed in production, set allow_insecure_ssl (DB_ALLOW_INSECURE_SSL) to override"))
+	}
+
+	for i, upper := range cfg.Database.SizeHistogramBuckets {
+		if upper <= 0 || (i > 0 && upper <= cfg.Database.SizeHistogramBuckets[i-1]) {
+			errs = append(errs, fmt.Errorf("database size_histogram_buckets must be positive and strictly increasing"))
+			break
+		}
+	}
+
+	for i, upper := range cfg.Database.AgeHistogramHours {
+		if upper <= 0 || (i > 0 && upper <= cfg.Database.AgeHistogramHours[i-1]) {
+			errs = append(errs, fmt.Errorf("database age_histogram_hours must be positive and strictly increasing"))
+			break
+		}
+	}
+
+	if cfg.Database.MaxReplicationLagBytes < 0 {
+		errs = append(errs, fmt.Errorf("database max_replication_lag_bytes must not be negative"))
+	}
+
+	if cfg.Database.StatementTimeoutMs < 0 {
+		errs = append(errs, fmt.Errorf("database statement_timeout_ms must not be negative"))
+	}
+
+	if cfg.Database.ChangesLagMs < 0 {
+		errs = append(errs, fmt.Errorf("database changes_lag_ms must not be negative"))
+	}
+
+	if cfg.Security.CorsAllowCredentials {
+		for _, origin := range cfg.Security.CorsOrigins {
+			if origin == "*" {
+				errs = append(errs, fmt.Errorf("security cors_allow_credentials cannot be used with wildcard cors_origins, list the allowed origins explicitly"))
+				break
+			}
+		}
+	}
+
+	if cfg.Security.AdminUsername == "" || cfg.Security.AdminPassword == "" {
+		errs = append(errs, fmt.Errorf("security admin_username (ADMIN_USERNAME) and admin_password (ADMIN_PASSWORD) are required"))
+	}
+
+	for _, proxy := range cfg.Security.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				errs = append(errs, fmt.Errorf("security trusted_proxies entry %q is not a valid IP or CIDR", proxy))
+			}
+		}
+	}
+
+	if cfg.Security.CorsMaxAge < 0 {
+		errs = append(errs, fmt.Errorf("security cors_max_age must not be negative"))
+	}
+
+	rateLimit := cfg.Security.RateLimit
+	if rateLimit.Requests < 0 || rateLimit.Burst < 0 {
+		errs = append(errs, fmt.Errorf("security rate_limit requests and burst must not be negative"))
+	}
+	if rateLimit.Requests > 0 && rateLimit.Window <= 0 {
+		errs = append(errs, fmt.Errorf("security rate_limit window must be positive when requests is set"))
+	}
+
+	if cfg.Security.DailyQuota < 0 {
+		errs = append(errs, fmt.Errorf("security daily_quota must not be negative"))
+	}
+
+	if cfg.Security.Headers.HSTSMaxAge < 0 {
+		errs = append(errs, fmt.Errorf("security headers hsts_max_age must not be negative"))
+	}
+
+	if cfg.Server.ShortHashLength != 0 && (cfg.Server.ShortHashLength < 8 || cfg.Server.ShortHashLength > 63) {
+		errs = append(errs, fmt.Errorf("server short_hash_length must be 0 or between 8 and 63"))
+	}
+
+	if cfg.Search.Enabled {
+		if cfg.Search.URL == "" || cfg.Search.Index == "" {
+			errs = append(errs, fmt.Errorf("search url and index are required when search is enabled"))
+		}
+		if cfg.Search.QueueSize <= 0 || cfg.Search.MaxRetries < 0 || cfg.Search.Timeout <= 0 {
+			errs = append(errs, fmt.Errorf("search queue_size and timeout must be positive and max_retries must not be negative"))
+		}
+	}
+
+	if err := validateMaintenanceWindow(cfg); err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, route := range cfg.Routes.Disabled {
+		if !routeNamePattern.MatchString(route) {
+			errs = append(errs, fmt.Errorf("routes disabled entry %q must be \"METHOD /path\", e.g. \"POST /api/v1/json/upload\"", route))
+		}
+	}
+
+	if cfg.Server.BatchEmptyData != BatchEmptyReject && cfg.Server.BatchEmptyData != BatchEmptySkip {
+		errs = append(errs, fmt.Errorf("server batch_empty_data must be %q or %q", BatchEmptyReject, BatchEmptySkip))
+	}
+
+	if cfg.Server.MaxConcurrentRequests < 0 || cfg.Server.RequestQueueTimeoutMs < 0 {
+		errs = append(errs, fmt.Errorf("server max_concurrent_requests and request_queue_timeout_ms must not be negative"))
+	}
+
+	if cfg.Server.ResponseTimeoutMs.Reads < 0 || cfg.Server.ResponseTimeoutMs.Admin < 0 {
+		errs = append(errs, fmt.Errorf("server response_timeout_ms.reads and response_timeout_ms.admin must not be negative"))
+	}
+
+	if cfg.Server.MaxResponseBytes < 0 {
+		errs = append(errs, fmt.Errorf("server max_response_bytes must not be negative"))
+	}
+
+	if cfg.Server.BatchGetMaxIDs < 1 || cfg.Server.BatchGetConcurrency < 1 {
+		errs = append(errs, fmt.Errorf("server batch_get_max_ids and batch_get_concurrency must be positive"))
+	}
+
+	if cfg.Server.UploadMaxFileBytes < 1 || cfg.Server.UploadMaxTotalBytes < 1 {
+		errs = append(errs, fmt.Errorf("server upload_max_file_bytes and upload_max_total_bytes must be positive"))
+	}
+
+	if cfg.Server.AttachmentMaxBytes < 1 {
+		errs = append(errs, fmt.Errorf("server attachment_max_bytes must be positive"))
+	}
+
+	if err := validateAutoMetadata(cfg); err != nil {
+		errs = append(errs, err)
+	}
+
+	if cfg.Server.MetadataMaxKeys < 0 || cfg.Server.MetadataMaxBytes < 0 {
+		errs = append(errs, fmt.Errorf("server metadata_max_keys and metadata_max_bytes must not be negative"))
+	}
+
+	for _, pattern := range cfg.Server.MetadataDisallowedKeys {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("server metadata_disallowed_keys entry %q is not a valid regular expression: %w", pattern, err))
+		}
+	}
+
+	if cfg.Database.DedupMode != DedupNormalized && cfg.Database.DedupMode != DedupRaw {
+		errs = append(errs, fmt.Errorf("database dedup_mode must be %q or %q", DedupNormalized, DedupRaw))
+	}
+
+	if cfg.Database.NormalizeFailure != NormalizeFailureHashRaw && cfg.Database.NormalizeFailure != NormalizeFailureReject {
+		errs = append(errs, fmt.Errorf("database normalize_failure must be %q or %q", NormalizeFailureHashRaw, NormalizeFailureReject))
+	}
+
+	if cfg.Database.ControlChars != ControlCharsRejectNull && cfg.Database.ControlChars != ControlCharsReject && cfg.Database.ControlChars != ControlCharsStrip {
+		errs = append(errs, fmt.Errorf("database control_chars must be %q, %q or %q", ControlCharsRejectNull, ControlCharsReject, ControlCharsStrip))
+	}
+
+	if cfg.Database.DedupScope != DedupScopeGlobal && cfg.Database.DedupScope != DedupScopePerType {
+		errs = append(errs, fmt.Errorf("database dedup_scope must be %q or %q", DedupScopeGlobal, DedupScopePerType))
+	}
+
+	if cfg.Database.NumberHandling != NumberHandlingFloat64 && cfg.Database.NumberHandling != NumberHandlingExact {
+		errs = append(errs, fmt.Errorf("database number_handling must be %q or %q", NumberHandlingFloat64, NumberHandlingExact))
+	}
+
+	names := make(map[string]bool, len(cfg.Database.JSONIndexes))
+	for _, idx := range cfg.Database.JSONIndexes {
+		if !jsonIndexNamePattern.MatchString(idx.Name) {
+			errs = append(errs, fmt.Errorf("database json_indexes name %q must match %s", idx.Name, jsonIndexNamePattern))
+		}
+		if names[idx.Name] {
+			errs = append(errs, fmt.Errorf("database json_indexes name %q is duplicated", idx.Name))
+		}
+		names[idx.Name] = true
+		if !jsonIndexPathPattern.MatchString(idx.Path) {
+			errs = append(errs, fmt.Errorf("database json_indexes path %q must be a simple path like $.a.b or $.a[0]", idx.Path))
+		}
+		if idx.Type != "" && idx.Type != JSONIndexString && idx.Type != JSONIndexInteger && idx.Type != JSONIndexNumber {
+			errs = append(errs, fmt.Errorf("database json_indexes type must be %q, %q or %q", JSONIndexString, JSONIndexInteger, JSONIndexNumber))
+		}
+	}
+
+	for name := range cfg.Database.Params {
+		if !dsnParamNamePattern.MatchString(name) {
+			errs = append(errs, fmt.Errorf("database params name %q must match %s", name, dsnParamNamePattern))
+		}
+		for _, reserved := range dsnReservedParams[cfg.Database.Type] {
+			if strings.EqualFold(name, reserved) {
+				errs = append(errs, fmt.Errorf("database params cannot override %q, it is set by other database options", name))
+				break
+			}
+		}
+	}
+
+	if cfg.Database.ChunkThreshold > 0 && cfg.Database.ChunkSize <= 0 {
+		errs = append(errs, fmt.Errorf("database chunk_size must be positive when chunk_threshold is set"))
+	}
+
+	errs = append(errs, validateContradictions(cfg)...)
+
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	return fmt.Errorf("%d problems:\n%w", len(errs), errors.Join(errs...))
+}
+
+// validateContradictions 检查单独合法、组合起来相互矛盾或不会生效的设置
+func validateContradictions(cfg *Config) []error {
+	var errs []error
+
+	if sec := cfg.Security; sec.EnableHTTPS {
+		if sec.CertFile == "" || sec.KeyFile == "" {
+			errs = append(errs, fmt.Errorf("security enable_https requires both cert_file (CERT_FILE) and key_file (KEY_FILE)"))
+		}
+		for _, file := range []string{sec.CertFile, sec.KeyFile} {
+			if file == "" {
+				continue
+			}
+			if _, err := os.Stat(file); err != nil {
+				errs = append(errs, fmt.Errorf("security enable_https: %w", err))
+			}
+		}
+	}
+
+	if cfg.Server.UnixSocket != "" && cfg.Server.Port != "" {
+		errs = append(errs, fmt.Errorf("server unix_socket and port (SERVER_PORT) cannot both be set, unix_socket replaces the TCP listener"))
+	}
+
+	// 允许重复内容时不做去重，去重方式和范围的设置不会生效
+	if db := cfg.Database; db.AllowDuplicateContent {
+		if db.DedupScope == DedupScopePerType {
+			errs = append(errs, fmt.Errorf("database dedup_scope %q has no effect when allow_duplicate_content is enabled", DedupScopePerType))
+		}
+		if db.DedupMode == DedupRaw {
+			errs = append(errs, fmt.Errorf("database dedup_mode %q has no effect when allow_duplicate_content is enabled", DedupRaw))
+		}
+	}
+
+	// 保存规范化形式后原始字节不再保留
+	if db := cfg.Database; db.StoreNormalized {
+		if db.PreserveRawBytes {
+			errs = append(errs, fmt.Errorf("database store_normalized and preserve_raw_bytes cannot both be enabled"))
+		}
+		if db.NumberHandling == NumberHandlingExact {
+			errs = append(errs, fmt.Errorf("database store_normalized cannot be used with number_handling %q", NumberHandlingExact))
+		}
+		if db.DedupMode == DedupRaw {
+			errs = append(errs, fmt.Errorf("database store_normalized cannot be used with dedup_mode %q", DedupRaw))
+		}
+	}
+
+	if cfg.Database.MaxReplicationLagBytes > 0 && cfg.Database.Type != "postgres" {
+		errs = append(errs, fmt.Errorf("database max_replication_lag_bytes is only supported for postgres, not %q", cfg.Database.Type))
+	}
+
+	if cfg.Server.UploadMaxFileBytes > cfg.Server.UploadMaxTotalBytes {
+		errs = append(errs, fmt.Errorf("server upload_max_file_bytes (%d) must not exceed upload_max_total_bytes (%d)",
+			cfg.Server.UploadMaxFileBytes, cfg.Server.UploadMaxTotalBytes))
+	}
+
+	return errs
+}
+
+// validateAutoMetadata 检查自动metadata字段是否受支持，reserved必须是fields的子集
+func validateAutoMetadata(cfg *Config) error {
+	auto := cfg.Server.AutoMetadata
+	supported := []string{
+		AutoMetadataSourceIP, AutoMetadataRequestID, AutoMetadataUserAgent,
+		AutoMetadataEnvironment, AutoMetadataIngestedAt,
+	}
+
+	enabled := make(map[string]bool, len(auto.Fields))
+	for _, field := range auto.Fields {
+		if !slices.Contains(supported, field) {
+			return fmt.Errorf("server auto_metadata field %q is not supported, use one of %s", field, strings.Join(supported, ", "))
+		}
+		enabled[field] = true
+	}
+	for _, field := range auto.Reserved {
+		if !enabled[field] {
+			return fmt.Errorf("server auto_metadata reserved field %q must also be listed in fields", field)
+		}
+	}
+	return nil
+}
+
+// validateMaintenanceWindow 检查维护时间段的格式，start和end需要同时设置
+func validateMaintenanceWindow(cfg *Config) error {
+	window := cfg.Maintenance.Window
+	if window.Start == "" && window.End == "" {
+		return nil
+	}
+
+	start, err := time.Parse("15:04", window.Start)
+	if err != nil {
+		return fmt.Errorf("maintenance window start must be HH:MM: %q", window.Start)
+	}
+	end, err := time.Parse("15:04", window.End)
+	if err != nil {
+		return fmt.Errorf("maintenance window end must be HH:MM: %q", window.End)
+	}
+	if start.Equal(end) {
+		return fmt.Errorf("maintenance window start and end must differ")
 	}
 
-	if cfg.Database.Host == "" || cfg.Database.Name == "" {
-		return fmt.Errorf("database host and name are required")
+	if _, err := time.LoadLocation(window.Timezone); err != nil {
+		return fmt.Errorf("maintenance window timezone %q is invalid: %w", window.Timezone, err)
 	}
 
 	return nil
@@ -0,0 +1,635 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// validConfig 通过validateConfig的最小配置，与setDefaults的非生产默认值一致
+func validConfig() *Config {
+	cfg := &Config{Environment: EnvLocal}
+	cfg.Server.Port = "8080"
+	cfg.Server.BatchEmptyData = BatchEmptyReject
+	cfg.Server.BatchGetMaxIDs = 100
+	cfg.Server.BatchGetConcurrency = 4
+	cfg.Server.UploadMaxFileBytes = 10 << 20
+	cfg.Server.UploadMaxTotalBytes = 50 << 20
+	cfg.Server.AttachmentMaxBytes = 10 << 20
+	cfg.Database.Type = "postgres"
+	cfg.Database.Host = "localhost"
+	cfg.Database.Name = "json_store"
+	cfg.Database.DedupMode = DedupNormalized
+	cfg.Database.NormalizeFailure = NormalizeFailureHashRaw
+	cfg.Database.ControlChars = ControlCharsRejectNull
+	cfg.Database.DedupScope = DedupScopeGlobal
+	cfg.Database.NumberHandling = NumberHandlingFloat64
+	cfg.Security.AdminUsername = "admin"
+	cfg.Security.AdminPassword = "secret"
+	return cfg
+}
+
+func TestValidateConfigAdminAccount(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      Environment
+		username string
+		password string
+		wantErr  bool
+	}{
+		{name: "local with account", env: EnvLocal, username: "admin", password: "secret"},
+		{name: "production with account", env: EnvProduct, username: "ops", password: "s3cret"},
+		{name: "production without account", env: EnvProduct, wantErr: true},
+		{name: "production without password", env: EnvProduct, username: "ops", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Environment = tt.env
+			cfg.Database.SSLMode = "require"
+			cfg.Security.AdminUsername = tt.username
+			cfg.Security.AdminPassword = tt.password
+			err := validateConfig(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), "admin_username") {
+				t.Errorf("error %q does not mention admin_username", err)
+			}
+		})
+	}
+}
+
+func TestSetDefaultsAdminAccount(t *testing.T) {
+	// 生产环境没有默认账号，其他环境沿用本地开发用的admin/secret
+	for _, env := range []Environment{EnvLocal, EnvTest, EnvProduct} {
+		t.Run(string(env), func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			setDefaults(env)
+			username := viper.GetString("security.admin_username")
+			if env == EnvProduct && username != "" {
+				t.Errorf("admin_username default = %q, want none in production", username)
+			}
+			if env != EnvProduct && username == "" {
+				t.Errorf("admin_username has no default in %s", env)
+			}
+		})
+	}
+}
+
+func TestLoadConfigEnvOnly(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{name: "database from env", env: map[string]string{"DB_HOST": "db.internal", "DB_NAME": "store"}},
+		{name: "missing host", env: map[string]string{"DB_NAME": "store"}, wantErr: "DB_HOST"},
+		{name: "missing both", env: map[string]string{}, wantErr: "host (DB_HOST) and name (DB_NAME)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			// 空目录中没有配置文件，只能使用环境变量和默认值
+			t.Setenv("CONFIG_PATH", t.TempDir())
+			t.Setenv("APP_ENV", "local")
+			for _, key := range []string{"DB_HOST", "DB_NAME"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			cfg, err := LoadConfig()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadConfig() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				if !strings.Contains(err.Error(), "no config file found") {
+					t.Errorf("error %q does not say that no config file was found", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if cfg.ConfigFile != "" {
+				t.Errorf("ConfigFile = %q, want empty", cfg.ConfigFile)
+			}
+			if cfg.Database.Host != "db.internal" || cfg.Database.Name != "store" {
+				t.Errorf("database = %s/%s, want db.internal/store", cfg.Database.Host, cfg.Database.Name)
+			}
+			if cfg.Server.Port != defaultServerPort {
+				t.Errorf("port = %q, want default %q", cfg.Server.Port, defaultServerPort)
+			}
+		})
+	}
+}
+
+func TestSetDefaultsOptInLimits(t *testing.T) {
+	// 升级后已有的部署不应被新加的限制拒绝，这些限制默认关闭
+	keys := []string{
+		"server.max_concurrent_writes",
+		"server.write_queue_size",
+		"server.max_elements",
+		"server.metadata_max_keys",
+		"server.metadata_max_bytes",
+	}
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	setDefaults(EnvProduct)
+	for _, key := range keys {
+		if got := viper.GetInt(key); got != 0 {
+			t.Errorf("%s default = %d, want 0 (disabled)", key, got)
+		}
+	}
+}
+
+func TestValidateConfigCORS(t *testing.T) {
+	tests := []struct {
+		name        string
+		origins     []string
+		credentials bool
+		maxAge      int
+		wantErr     string
+	}{
+		{name: "wildcard without credentials", origins: []string{"*"}},
+		{name: "explicit origin with credentials", origins: []string{"https://app.example.com"}, credentials: true},
+		{name: "wildcard with credentials", origins: []string{"https://app.example.com", "*"}, credentials: true, wantErr: "cors_allow_credentials"},
+		{name: "negative max age", origins: []string{"*"}, maxAge: -1, wantErr: "cors_max_age"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Security.CorsOrigins = tt.origins
+			cfg.Security.CorsAllowCredentials = tt.credentials
+			cfg.Security.CorsMaxAge = tt.maxAge
+			err := validateConfig(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateConfig() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("validateConfig() error = %v, want mention of %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSetDefaultsSSLMode(t *testing.T) {
+	want := map[Environment]string{EnvLocal: "disable", EnvTest: "disable", EnvProduct: "require"}
+	for env, mode := range want {
+		t.Run(string(env), func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			setDefaults(env)
+			if got := viper.GetString("database.ssl_mode"); got != mode {
+				t.Errorf("ssl_mode default = %q, want %q", got, mode)
+			}
+		})
+	}
+}
+
+func TestValidateConfigSSLMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      Environment
+		sslMode  string
+		insecure bool
+		wantErr  bool
+	}{
+		{name: "production require", env: EnvProduct, sslMode: "require"},
+		{name: "production disable", env: EnvProduct, sslMode: "disable", wantErr: true},
+		{name: "production disable uppercase", env: EnvProduct, sslMode: "DISABLE", wantErr: true},
+		{name: "production disable with override", env: EnvProduct, sslMode: "disable", insecure: true},
+		{name: "local disable", env: EnvLocal, sslMode: "disable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Environment = tt.env
+			cfg.Database.SSLMode = tt.sslMode
+			cfg.Database.AllowInsecureSSL = tt.insecure
+			err := validateConfig(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), "allow_insecure_ssl") {
+				t.Errorf("error %q does not mention allow_insecure_ssl", err)
+			}
+		})
+	}
+}
+
+func TestParseEnvironment(t *testing.T) {
+	// 生产环境的检查依赖规范化后的名称，别名都必须映射到EnvProduct
+	for _, name := range []string{"product", "prod", "Production", " PROD "} {
+		if env, ok := parseEnvironment(name); !ok || env != EnvProduct {
+			t.Errorf("parseEnvironment(%q) = %q, %v, want %q", name, env, ok, EnvProduct)
+		}
+	}
+	if _, ok := parseEnvironment("qa"); ok {
+		t.Error("parseEnvironment(\"qa\") succeeded, want unknown")
+	}
+}
+
+func TestSetDefaultsRateLimit(t *testing.T) {
+	// 生产环境默认限流，本地环境不限流（requests为0时路由不安装限流中间件）
+	tests := []struct {
+		env          Environment
+		wantRequests int
+		wantBurst    int
+	}{
+		{env: EnvProduct, wantRequests: 50, wantBurst: 100},
+		{env: EnvTest, wantRequests: 500, wantBurst: 1000},
+		{env: EnvLocal, wantRequests: 0, wantBurst: 0},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.env), func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			setDefaults(tt.env)
+			requests := viper.GetInt("security.rate_limit.requests")
+			burst := viper.GetInt("security.rate_limit.burst")
+			if requests != tt.wantRequests || burst != tt.wantBurst {
+				t.Errorf("rate_limit = %d/%d, want %d/%d", requests, burst, tt.wantRequests, tt.wantBurst)
+			}
+			if window := viper.GetInt("security.rate_limit.window"); window != 1 {
+				t.Errorf("rate_limit.window = %d, want 1", window)
+			}
+		})
+	}
+}
+
+func TestValidateConfigRateLimit(t *testing.T) {
+	cfg := validConfig()
+	cfg.Security.RateLimit.Requests = 10
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "window") {
+		t.Errorf("validateConfig() error = %v, want window to be required", err)
+	}
+	cfg.Security.RateLimit.Window = 1
+	if err := validateConfig(cfg); err != nil {
+		t.Errorf("validateConfig() error = %v", err)
+	}
+	cfg.Security.RateLimit.Burst = -1
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "negative") {
+		t.Errorf("validateConfig() error = %v, want negative burst rejected", err)
+	}
+}
+
+func TestValidateConfigJSONIndexes(t *testing.T) {
+	tests := []struct {
+		name    string
+		index   JSONPathIndex
+		wantErr string
+	}{
+		{name: "valid", index: JSONPathIndex{Name: "user_id", Path: "$.user.id", Type: JSONIndexInteger}},
+		{name: "array element", index: JSONPathIndex{Name: "first_tag", Path: "$.tags[0]"}},
+		// 路径会拼入DDL，不能包含引号等字符
+		{name: "quote in path", index: JSONPathIndex{Name: "x", Path: "$.a') STORED; DROP TABLE t; --"}, wantErr: "path"},
+		{name: "bad name", index: JSONPathIndex{Name: "x-y", Path: "$.a"}, wantErr: "name"},
+		{name: "bad type", index: JSONPathIndex{Name: "x", Path: "$.a", Type: "date"}, wantErr: "type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Database.JSONIndexes = []JSONPathIndex{tt.index}
+			err := validateConfig(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateConfig() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), "json_indexes "+tt.wantErr) {
+				t.Fatalf("validateConfig() error = %v, want json_indexes %s error", err, tt.wantErr)
+			}
+		})
+	}
+
+	cfg := validConfig()
+	cfg.Database.JSONIndexes = []JSONPathIndex{{Name: "a", Path: "$.a"}, {Name: "a", Path: "$.b"}}
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "duplicated") {
+		t.Errorf("validateConfig() error = %v, want duplicated name", err)
+	}
+}
+
+func TestValidateConfigSecurityHeaders(t *testing.T) {
+	cfg := validConfig()
+	cfg.Security.Headers.HSTSMaxAge = -1
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "hsts_max_age") {
+		t.Errorf("validateConfig() error = %v, want hsts_max_age error", err)
+	}
+}
+
+func TestValidateConfigDailyQuota(t *testing.T) {
+	cfg := validConfig()
+	cfg.Security.DailyQuota = -1
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "daily_quota") {
+		t.Errorf("validateConfig() error = %v, want daily_quota error", err)
+	}
+}
+
+func TestValidateConfigChangesLag(t *testing.T) {
+	cfg := validConfig()
+	cfg.Database.ChangesLagMs = -1
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "changes_lag_ms") {
+		t.Errorf("validateConfig() error = %v, want changes_lag_ms error", err)
+	}
+}
+
+func TestValidateConfigReplicationLag(t *testing.T) {
+	cfg := validConfig()
+	cfg.Database.MaxReplicationLagBytes = -1
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "max_replication_lag_bytes") {
+		t.Errorf("validateConfig() error = %v, want max_replication_lag_bytes error", err)
+	}
+}
+
+func TestValidateConfigDSNParams(t *testing.T) {
+	tests := []struct {
+		dbType  string
+		params  map[string]string
+		wantErr string
+	}{
+		{dbType: "postgres", params: map[string]string{"application_name": "json-store"}},
+		{dbType: "mysql", params: map[string]string{"readtimeout": "5s"}},
+		{dbType: "postgres", params: map[string]string{"bad name": "x"}, wantErr: "must match"},
+		// 由其他配置项决定的参数不能覆盖
+		{dbType: "postgres", params: map[string]string{"sslmode": "disable"}, wantErr: "cannot override"},
+		{dbType: "mysql", params: map[string]string{"parseTime": "false"}, wantErr: "cannot override"},
+	}
+	for _, tt := range tests {
+		cfg := validConfig()
+		cfg.Database.Type = tt.dbType
+		cfg.Database.Params = tt.params
+		err := validateConfig(cfg)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s %v: validateConfig() error = %v", tt.dbType, tt.params, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s %v: validateConfig() error = %v, want %q", tt.dbType, tt.params, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateConfigStatementTimeout(t *testing.T) {
+	cfg := validConfig()
+	cfg.Database.StatementTimeoutMs = -1
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "statement_timeout_ms") {
+		t.Errorf("validateConfig() error = %v, want statement_timeout_ms error", err)
+	}
+}
+
+func TestValidateConfigNormalizeFailure(t *testing.T) {
+	for _, mode := range []string{NormalizeFailureHashRaw, NormalizeFailureReject} {
+		cfg := validConfig()
+		cfg.Database.NormalizeFailure = mode
+		if err := validateConfig(cfg); err != nil {
+			t.Errorf("normalize_failure=%s: validateConfig() error = %v", mode, err)
+		}
+	}
+	cfg := validConfig()
+	cfg.Database.NormalizeFailure = "ignore"
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "normalize_failure") {
+		t.Errorf("validateConfig() error = %v, want normalize_failure error", err)
+	}
+}
+
+func TestValidateConfigAutoMetadata(t *testing.T) {
+	tests := []struct {
+		fields   []string
+		reserved []string
+		wantErr  string
+	}{
+		{fields: []string{AutoMetadataSourceIP, AutoMetadataIngestedAt}, reserved: []string{AutoMetadataSourceIP}},
+		{fields: []string{"api_key_name"}, wantErr: "not supported"},
+		// 保留字段必须是已开启的字段
+		{fields: []string{AutoMetadataSourceIP}, reserved: []string{AutoMetadataUserAgent}, wantErr: "must also be listed"},
+	}
+	for _, tt := range tests {
+		cfg := validConfig()
+		cfg.Server.AutoMetadata.Fields = tt.fields
+		cfg.Server.AutoMetadata.Reserved = tt.reserved
+		err := validateConfig(cfg)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("fields %v reserved %v: validateConfig() error = %v", tt.fields, tt.reserved, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("fields %v reserved %v: validateConfig() error = %v, want %q", tt.fields, tt.reserved, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateConfigContradictions(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr string
+	}{
+		{
+			name:    "https without certificate",
+			modify:  func(cfg *Config) { cfg.Security.EnableHTTPS = true },
+			wantErr: "enable_https requires both cert_file",
+		},
+		{
+			name:    "unix socket with port",
+			modify:  func(cfg *Config) { cfg.Server.UnixSocket = "/run/json-store.sock" },
+			wantErr: "unix_socket and port",
+		},
+		{
+			name: "per_type dedup without dedup",
+			modify: func(cfg *Config) {
+				cfg.Database.AllowDuplicateContent = true
+				cfg.Database.DedupScope = DedupScopePerType
+			},
+			wantErr: `dedup_scope "per_type" has no effect`,
+		},
+		{
+			name: "raw dedup without dedup",
+			modify: func(cfg *Config) {
+				cfg.Database.AllowDuplicateContent = true
+				cfg.Database.DedupMode = DedupRaw
+			},
+			wantErr: `dedup_mode "raw" has no effect`,
+		},
+		{
+			name:    "replication lag on mysql",
+			modify:  func(cfg *Config) { cfg.Database.Type, cfg.Database.MaxReplicationLagBytes = "mysql", 1<<20 },
+			wantErr: "max_replication_lag_bytes is only supported for postgres",
+		},
+		{
+			name:    "upload file larger than request",
+			modify:  func(cfg *Config) { cfg.Server.UploadMaxFileBytes = cfg.Server.UploadMaxTotalBytes + 1 },
+			wantErr: "upload_max_file_bytes",
+		},
+	}
+
+	// 每种矛盾单独出现时都被报告
+	all := validConfig()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(cfg)
+			if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateConfig() error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+		tt.modify(all)
+	}
+
+	// 同时出现时一次全部报告
+	err := validateConfig(all)
+	if err == nil {
+		t.Fatal("validateConfig() succeeded, want aggregated error")
+	}
+	for _, tt := range tests {
+		if !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("aggregated error does not mention %q:\n%v", tt.wantErr, err)
+		}
+	}
+}
+
+func TestLoadConfigUnixSocket(t *testing.T) {
+	tests := []struct {
+		name    string
+		port    string
+		wantErr string
+	}{
+		// 只设置unix_socket时不使用默认端口
+		{name: "socket only"},
+		{name: "socket and port", port: "9090", wantErr: "unix_socket and port"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			t.Setenv("CONFIG_PATH", t.TempDir())
+			t.Setenv("APP_ENV", "local")
+			t.Setenv("DB_HOST", "db.internal")
+			t.Setenv("DB_NAME", "store")
+			t.Setenv("SERVER_UNIX_SOCKET", "/run/json-store.sock")
+			t.Setenv("SERVER_PORT", tt.port)
+
+			cfg, err := LoadConfig()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadConfig() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if cfg.Server.Port != "" {
+				t.Errorf("port = %q, want empty", cfg.Server.Port)
+			}
+		})
+	}
+}
+
+func TestValidateConfigStoreNormalized(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr string
+	}{
+		{name: "alone", modify: func(cfg *Config) {}},
+		{name: "with preserve_raw_bytes", modify: func(cfg *Config) { cfg.Database.PreserveRawBytes = true }, wantErr: "preserve_raw_bytes"},
+		{name: "with raw dedup", modify: func(cfg *Config) { cfg.Database.DedupMode = DedupRaw }, wantErr: "dedup_mode"},
+		{name: "with exact numbers", modify: func(cfg *Config) { cfg.Database.NumberHandling = NumberHandlingExact }, wantErr: "number_handling"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Database.StoreNormalized = true
+			tt.modify(cfg)
+			err := validateConfig(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateConfig() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateConfig() error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateConfigConcurrencyLimit(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.MaxConcurrentRequests = -1
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "max_concurrent_requests") {
+		t.Errorf("validateConfig() error = %v, want max_concurrent_requests error", err)
+	}
+}
+
+func TestValidateConfigResponseTimeout(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.ResponseTimeoutMs.Admin = -1
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "response_timeout_ms") {
+		t.Errorf("validateConfig() error = %v, want response_timeout_ms error", err)
+	}
+}
+
+func TestValidateConfigControlChars(t *testing.T) {
+	for _, policy := range []string{ControlCharsRejectNull, ControlCharsReject, ControlCharsStrip} {
+		cfg := validConfig()
+		cfg.Database.ControlChars = policy
+		if err := validateConfig(cfg); err != nil {
+			t.Errorf("control_chars %q: %v", policy, err)
+		}
+	}
+	cfg := validConfig()
+	cfg.Database.ControlChars = "escape"
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "control_chars") {
+		t.Errorf("validateConfig() error = %v, want control_chars error", err)
+	}
+}
+
+func TestValidateConfigDisabledRoutes(t *testing.T) {
+	cfg := validConfig()
+	cfg.Routes.Disabled = []string{"POST /api/v1/json/upload", "GET /api/v1/json/:id"}
+	if err := validateConfig(cfg); err != nil {
+		t.Errorf("validateConfig() error = %v, want nil", err)
+	}
+
+	for _, entry := range []string{"/api/v1/json/upload", "post /api/v1/json", "POST api/v1/json"} {
+		cfg := validConfig()
+		cfg.Routes.Disabled = []string{entry}
+		if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "routes disabled entry") {
+			t.Errorf("entry %q: validateConfig() error = %v, want routes disabled error", entry, err)
+		}
+	}
+}
+
+func TestValidateConfigAgeHistogram(t *testing.T) {
+	tests := []struct {
+		hours   []int64
+		wantErr bool
+	}{
+		{hours: nil},
+		{hours: []int64{24, 168, 720}},
+		{hours: []int64{24, 24}, wantErr: true},
+		{hours: []int64{168, 24}, wantErr: true},
+		{hours: []int64{0, 24}, wantErr: true},
+	}
+	for _, tt := range tests {
+		cfg := validConfig()
+		cfg.Database.AgeHistogramHours = tt.hours
+		err := validateConfig(cfg)
+		if tt.wantErr != (err != nil && strings.Contains(err.Error(), "age_histogram_hours")) {
+			t.Errorf("age_histogram_hours %v: validateConfig() error = %v, wantErr %v", tt.hours, err, tt.wantErr)
+		}
+	}
+}
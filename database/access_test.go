@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPostgresStoreTrackAccess(t *testing.T) {
+	const id = "00000000-0000-0000-0000-0000000000ac"
+	const gets = 3
+	data := []byte(`{"a":1}`)
+
+	for _, track := range []bool{false, true} {
+		matcher := &recordingMatcher{}
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(matcher))
+		if err != nil {
+			t.Fatal(err)
+		}
+		store := &PostgresStore{db: db, opts: Options{TrackAccess: track}, stmts: newStatementCache(db)}
+		ctx := context.Background()
+
+		for i := 0; i < gets; i++ {
+			mock.ExpectQuery("WHERE id = \\$1").WithArgs(id).WillReturnRows(postgresDocumentRow(id, "h", data))
+			if _, err := store.GetJSONByID(ctx, id); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// 每次读取都应在同一条语句中累加一次计数，关闭时不产生写操作
+		updates := matcher.count("SET access_count = access_count + 1")
+		want := 0
+		if track {
+			want = gets
+		}
+		if updates != want {
+			t.Errorf("TrackAccess=%v: %d access updates, want %d; issued %q", track, updates, want, matcher.issued)
+		}
+		if track && matcher.count("RETURNING") != gets {
+			t.Errorf("access update does not return the document; issued %q", matcher.issued)
+		}
+
+		// 调试信息中读出数据库累加后的计数
+		accessed := time.Now()
+		mock.ExpectQuery("d.access_count, d.last_accessed_at").WithArgs(id).
+			WillReturnRows(sqlmock.NewRows([]string{"raw", "compression", "chunks", "raw_bytes", "stored", "access_count", "last_accessed_at"}).
+				AddRow(int64(len(data)), "", 0, false, int64(len(data)), int64(updates), accessed))
+		info, err := store.GetStorageInfo(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.AccessCount != int64(want) || info.LastAccessedAt == nil || !info.LastAccessedAt.Equal(accessed) {
+			t.Errorf("TrackAccess=%v: storage info access = %d at %v, want %d at %v", track, info.AccessCount, info.LastAccessedAt, want, accessed)
+		}
+
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		db.Close()
+	}
+}
+
+func TestMySQLStoreTrackAccess(t *testing.T) {
+	const id = "00000000-0000-0000-0000-0000000000ad"
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	store := &MySQLStore{db: db, opts: Options{TrackAccess: true}}
+
+	// MySQL没有RETURNING，累加和读取在同一事务中完成，且不改变updated_at
+	mock.ExpectBegin()
+	mock.ExpectExec("SET access_count = access_count \\+ 1, last_accessed_at = CURRENT_TIMESTAMP, updated_at = updated_at").
+		WithArgs(id).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("FROM json_documents").WithArgs(id).WillReturnRows(postgresDocumentRow(id, "h", []byte(`{}`)))
+	mock.ExpectCommit()
+
+	if _, err := store.GetJSONByID(context.Background(), id); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
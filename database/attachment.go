@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/leapzhao/json-store/model"
+)
+
+// ErrAttachmentNotFound 文档没有该名称的附件
+var ErrAttachmentNotFound = errors.New("attachment not found")
+
+// attachmentQueries 附件读写使用的SQL，按数据库方言提供
+// 附件单独成表，不参与文档的去重、压缩和分块
+type attachmentQueries struct {
+	// Upsert 参数：文档ID、名称、Content-Type、大小、内容哈希、数据；同名附件已存在时覆盖
+	Upsert string
+	// SelectInfo 参数：文档ID、名称，返回Content-Type、大小、内容哈希、更新时间
+	SelectInfo string
+	// Select 参数：文档ID、名称，返回SelectInfo的各列和数据
+	Select string
+}
+
+// storeAttachment 保存文档的附件并返回附件信息，文档不存在时返回ErrDocumentNotFound
+func storeAttachment(ctx context.Context, db *sql.DB, queries attachmentQueries, idExists string, documentID, name, contentType string, data []byte) (*model.Attachment, error) {
+	var count int
+	if err := db.QueryRowContext(ctx, idExists, documentID).Scan(&count); err != nil {
+		return nil, fmt.Errorf("failed to check document: %w", err)
+	}
+	if count == 0 {
+		return nil, ErrDocumentNotFound
+	}
+
+	if _, err := db.ExecContext(ctx, queries.Upsert,
+		documentID, name, contentType, int64(len(data)), calculateRawHash(data), data,
+	); err != nil {
+		return nil, fmt.Errorf("failed to store attachment: %w", err)
+	}
+
+	attachment := &model.Attachment{DocumentID: documentID, Name: name}
+	if err := db.QueryRowContext(ctx, queries.SelectInfo, documentID, name).Scan(
+		&attachment.ContentType, &attachment.Size, &attachment.ContentHash, &attachment.UpdatedAt,
+	); err != nil {
+		return nil, fmt.Errorf("failed to read stored attachment: %w", err)
+	}
+	return attachment, nil
+}
+
+// getAttachment 读取附件信息和数据，不存在时返回ErrAttachmentNotFound
+func getAttachment(ctx context.Context, db *sql.DB, queries attachmentQueries, documentID, name string) (*model.Attachment, error) {
+	attachment := &model.Attachment{DocumentID: documentID, Name: name}
+	err := db.QueryRowContext(ctx, queries.Select, documentID, name).Scan(
+		&attachment.ContentType, &attachment.Size, &attachment.ContentHash, &attachment.UpdatedAt, &attachment.Data,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+	return attachment, nil
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPostgresStoreAttachment(t *testing.T) {
+	const id = "00000000-0000-0000-0000-0000000000b1"
+	data := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff}
+	hash := calculateRawHash(data)
+	updated := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	t.Run("store and get", func(t *testing.T) {
+		store, mock := newMockPostgresStore(t, Options{})
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM json_documents WHERE id = \\$1").WithArgs(id).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		// 内容哈希由存储层计算，与文档的原始字节哈希算法相同
+		mock.ExpectExec("INSERT INTO json_attachments").
+			WithArgs(id, "photo.png", "image/png", int64(len(data)), hash, data).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT content_type, size, content_hash, updated_at FROM json_attachments").WithArgs(id, "photo.png").
+			WillReturnRows(sqlmock.NewRows([]string{"content_type", "size", "content_hash", "updated_at"}).
+				AddRow("image/png", int64(len(data)), hash, updated))
+		mock.ExpectQuery("SELECT content_type, size, content_hash, updated_at, data FROM json_attachments").WithArgs(id, "photo.png").
+			WillReturnRows(sqlmock.NewRows([]string{"content_type", "size", "content_hash", "updated_at", "data"}).
+				AddRow("image/png", int64(len(data)), hash, updated, data))
+
+		stored, err := store.StoreAttachment(ctx, id, "photo.png", "image/png", data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.ContentHash != hash || stored.Size != int64(len(data)) {
+			t.Errorf("stored = %+v, want hash %s size %d", stored, hash, len(data))
+		}
+
+		got, err := store.GetAttachment(ctx, id, "photo.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.Data, data) || got.ContentType != "image/png" {
+			t.Errorf("GetAttachment = %q (%s), want %q (image/png)", got.Data, got.ContentType, data)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("unknown document", func(t *testing.T) {
+		store, mock := newMockPostgresStore(t, Options{})
+		// 文档不存在时不写入附件
+		mock.ExpectQuery("FROM json_documents WHERE id").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		if _, err := store.StoreAttachment(ctx, id, "photo.png", "image/png", data); !errors.Is(err, ErrDocumentNotFound) {
+			t.Errorf("err = %v, want ErrDocumentNotFound", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("missing attachment", func(t *testing.T) {
+		store, mock := newMockPostgresStore(t, Options{})
+		mock.ExpectQuery("FROM json_attachments").WillReturnRows(sqlmock.NewRows([]string{"content_type"}))
+		if _, err := store.GetAttachment(ctx, id, "missing.bin"); !errors.Is(err, ErrAttachmentNotFound) {
+			t.Errorf("err = %v, want ErrAttachmentNotFound", err)
+		}
+	})
+}
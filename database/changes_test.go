@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPostgresStoreModifiedSince(t *testing.T) {
+	const lag = 5 * time.Second
+	store, mock := newMockPostgresStore(t, Options{ChangesLag: lag})
+	ctx := context.Background()
+	const (
+		created = "00000000-0000-0000-0000-000000000001"
+		updated = "00000000-0000-0000-0000-000000000002"
+	)
+	start := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	row := func(rows *sqlmock.Rows, id string, updatedAt time.Time) *sqlmock.Rows {
+		return rows.AddRow(id, "h", "", []byte(`{}`), int64(2), start, updatedAt, nil, "", nil, nil, 0, nil)
+	}
+	columns := []string{
+		"id", "content_hash", "doc_type", "json_data", "size", "created_at", "updated_at",
+		"metadata", "compression", "compressed_data", "raw_data", "chunk_count", "tags",
+	}
+
+	// 首次请求从零游标开始；数据库按updated_at, id排序并排除lag内的修改
+	mock.ExpectQuery(`WHERE \(updated_at, id\) > \(\$1, \$2\)\s+AND updated_at <= clock_timestamp\(\) - \$3::float8 \* INTERVAL '1 microsecond'\s+ORDER BY updated_at, id`).
+		WithArgs(time.Time{}, zeroUUID, lag.Microseconds(), 10).
+		WillReturnRows(row(row(sqlmock.NewRows(columns), created, start.Add(time.Second)), updated, start.Add(2*time.Second)))
+	page, err := store.GetJSONModifiedSince(ctx, time.Time{}, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 || page[0].ID != created || page[1].ID != updated {
+		t.Fatalf("first page = %v, want [%s %s]", page, created, updated)
+	}
+
+	// 之后的请求从上一页最后一个文档的 (updated_at, id) 继续，修改过的文档以新的updated_at再次出现
+	last := page[1]
+	mock.ExpectQuery("ORDER BY updated_at, id").
+		WithArgs(last.UpdatedAt, last.ID, lag.Microseconds(), 10).
+		WillReturnRows(row(sqlmock.NewRows(columns), created, start.Add(3*time.Second)))
+	page, err = store.GetJSONModifiedSince(ctx, last.UpdatedAt, last.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 1 || page[0].ID != created || !page[0].UpdatedAt.Equal(start.Add(3*time.Second)) {
+		t.Errorf("second page = %v, want the updated %s", page, created)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMySQLStoreModifiedSinceLag(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// lag为0时不保留最近的修改
+	store := &MySQLStore{db: db}
+
+	mock.ExpectQuery(`AND updated_at <= NOW\(6\) - INTERVAL \? MICROSECOND`).
+		WithArgs(time.Time{}, zeroUUID, int64(0), 5).
+		WillReturnRows(sqlmock.NewRows(nil))
+	if _, err := store.GetJSONModifiedSince(context.Background(), time.Time{}, "", 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
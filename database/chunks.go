@@ -0,0 +1,165 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/leapzhao/json-store/model"
+)
+
+// chunkedPlaceholder 分块存储时json_data列中保留的占位内容
+const chunkedPlaceholder = "null"
+
+// chunkQueries 分块存储使用的SQL，按数据库方言提供
+type chunkQueries struct {
+	// InsertDocument 参数：ID、哈希、类型、占位内容、大小、分块数、SimHash、原始字节哈希、metadata、标签（JSON数组）
+	InsertDocument string
+	// SelectDocument 参数：ID，返回文档查询列
+	SelectDocument string
+	// InsertChunk 参数：文档ID、序号、数据
+	InsertChunk string
+	// SelectChunks 参数：文档ID，按序号升序返回数据
+	SelectChunks string
+}
+
+// shouldChunk 文档大小超过分块阈值时使用分块存储
+func (o Options) shouldChunk(size int64) bool {
+	return o.ChunkThreshold > 0 && o.ChunkSize > 0 && size > o.ChunkThreshold
+}
+
+// insertChunkedDocument 在事务中插入分块存储的文档
+// 主表只保存元数据和占位内容，正文按chunkSize切分写入json_document_chunks
+func insertChunkedDocument(
+	ctx context.Context,
+	tx *sql.Tx,
+	queries chunkQueries,
+	scan func(rowScanner) (*model.JSONDocument, error),
+	id, hash string,
+	input model.StoreInput,
+	chunkSize int,
+) (*model.JSONDocument, error) {
+	data := input.JSONData
+	metadata, err := documentMetadata(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, queries.InsertDocument,
+		id, hash, nullString(input.DocType), chunkedPlaceholder, int64(len(data)),
+		chunkCount(len(data), chunkSize), documentSimHash(data), calculateRawHash(data), metadata, documentTags(input),
+	); err != nil {
+		return nil, fmt.Errorf("failed to insert chunked document: %w", err)
+	}
+
+	if err := insertChunks(ctx, tx, queries.InsertChunk, id, data, chunkSize); err != nil {
+		return nil, err
+	}
+
+	doc, err := scan(tx.QueryRowContext(ctx, queries.SelectDocument, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunked document: %w", err)
+	}
+	doc.JSONData = data
+
+	return doc, nil
+}
+
+// chunkCount 按chunkSize切分size字节需要的分块数
+func chunkCount(size, chunkSize int) int {
+	return (size + chunkSize - 1) / chunkSize
+}
+
+// insertChunks 按chunkSize切分数据并逐块写入
+func insertChunks(ctx context.Context, tx *sql.Tx, query, id string, data []byte, chunkSize int) error {
+	count := chunkCount(len(data), chunkSize)
+	for seq := 0; seq < count; seq++ {
+		end := (seq + 1) * chunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+		if _, err := tx.ExecContext(ctx, query, id, seq, data[seq*chunkSize:end]); err != nil {
+			return fmt.Errorf("failed to insert chunk %d: %w", seq, err)
+		}
+	}
+	return nil
+}
+
+// storeChunkedDocument 在独立事务中插入分块存储的文档
+func storeChunkedDocument(
+	ctx context.Context,
+	db *sql.DB,
+	queries chunkQueries,
+	scan func(rowScanner) (*model.JSONDocument, error),
+	id, hash string,
+	input model.StoreInput,
+	chunkSize int,
+) (*model.JSONDocument, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	doc, err := insertChunkedDocument(ctx, tx, queries, scan, id, hash, input, chunkSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return doc, nil
+}
+
+// loadChunks 为分块存储的文档重组内容，非分块文档不做处理
+// 分块逐行读取并追加到预分配的缓冲区，不会同时持有全部分块的副本
+func loadChunks(ctx context.Context, db queryer, query string, docs ...*model.JSONDocument) error {
+	for _, doc := range docs {
+		if doc.ChunkCount == 0 {
+			continue
+		}
+
+		data, err := readChunks(ctx, db, query, doc)
+		if err != nil {
+			return fmt.Errorf("failed to load chunks for document %s: %w", doc.ID, err)
+		}
+		doc.JSONData = data
+	}
+
+	return nil
+}
+
+// readChunks 按序号读取单个文档的全部分块
+func readChunks(ctx context.Context, db queryer, query string, doc *model.JSONDocument) ([]byte, error) {
+	rows, err := db.QueryContext(ctx, query, doc.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buf := bytes.NewBuffer(make([]byte, 0, doc.Size))
+	count := 0
+	for rows.Next() {
+		// RawBytes引用驱动内部缓冲区，只在下一次Next之前有效，立即拷贝到buf
+		var chunk sql.RawBytes
+		if err := rows.Scan(&chunk); err != nil {
+			return nil, err
+		}
+		buf.Write(chunk)
+		count++
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if count != doc.ChunkCount || int64(buf.Len()) != doc.Size {
+		return nil, fmt.Errorf("incomplete chunks: got %d chunks/%d bytes, want %d chunks/%d bytes",
+			count, buf.Len(), doc.ChunkCount, doc.Size)
+	}
+
+	return buf.Bytes(), nil
+}
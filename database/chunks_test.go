@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/leapzhao/json-store/model"
+)
+
+// chunkedDocumentRow 分块存储的文档行：json_data为占位内容，正文在分块表中
+func chunkedDocumentRow(id string, size int64, chunks int) *sqlmock.Rows {
+	now := time.Now()
+	return sqlmock.NewRows([]string{
+		"id", "content_hash", "doc_type", "json_data", "size", "created_at", "updated_at",
+		"metadata", "compression", "compressed_data", "raw_data", "chunk_count", "tags",
+	}).AddRow(id, "h", "", []byte(chunkedPlaceholder), size, now, now, nil, "", nil, nil, chunks, nil)
+}
+
+func TestPostgresStoreChunkedRoundTrip(t *testing.T) {
+	store, mock := newMockPostgresStore(t, Options{AllowDuplicateContent: true, ChunkThreshold: 16, ChunkSize: 10})
+	ctx := context.Background()
+	const id = "00000000-0000-0000-0000-0000000000ee"
+	data := []byte(`{"text":"` + strings.Repeat("abc", 9) + `"}`)
+	wantChunks := chunkCount(len(data), 10)
+
+	// 写入：主表只有占位内容，正文按chunk_size切分后逐块插入
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO json_documents").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), chunkedPlaceholder, int64(len(data)), wantChunks,
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	chunks := make([]*capturedArg, wantChunks)
+	for seq := range chunks {
+		chunks[seq] = &capturedArg{}
+		mock.ExpectExec("INSERT INTO json_document_chunks").
+			WithArgs(sqlmock.AnyArg(), seq, chunks[seq]).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectQuery("FROM json_documents WHERE id = \\$1").WillReturnRows(chunkedDocumentRow(id, int64(len(data)), wantChunks))
+	mock.ExpectCommit()
+
+	if _, err := store.StoreJSON(ctx, model.StoreInput{JSONData: data}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 读取：按序号重组写入的分块
+	rows := sqlmock.NewRows([]string{"data"})
+	for _, chunk := range chunks {
+		part, _ := chunk.value.([]byte)
+		if len(part) > 10 {
+			t.Errorf("chunk of %d bytes exceeds chunk_size", len(part))
+		}
+		rows.AddRow(part)
+	}
+	mock.ExpectQuery("FROM json_documents WHERE id = \\$1").WithArgs(id).
+		WillReturnRows(chunkedDocumentRow(id, int64(len(data)), wantChunks))
+	mock.ExpectQuery("FROM json_document_chunks WHERE document_id = \\$1 ORDER BY seq").WithArgs(id).WillReturnRows(rows)
+
+	doc, err := store.GetJSONByID(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(doc.JSONData) != string(data) {
+		t.Errorf("read %s, want %s", doc.JSONData, data)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLoadChunksIncomplete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// 缺少分块时报错，不能返回截断的内容
+	mock.ExpectQuery("FROM json_document_chunks").
+		WillReturnRows(sqlmock.NewRows([]string{"data"}).AddRow([]byte("0123456789")))
+	doc := &model.JSONDocument{ID: "id", Size: 15, ChunkCount: 2}
+	if err := loadChunks(context.Background(), db, postgresChunkQueries.SelectChunks, doc); err == nil {
+		t.Errorf("loadChunks succeeded with a missing chunk, got %q", doc.JSONData)
+	}
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/leapzhao/json-store/model"
+)
+
+// ErrIDConflict 客户端指定的ID已被其他文档使用
+var ErrIDConflict = errors.New("document ID already exists")
+
+// newDocumentID 返回客户端指定的ID，未指定时生成新的UUID
+func newDocumentID(input model.StoreInput) string {
+	if input.ID != "" {
+		return input.ID
+	}
+	return uuid.New().String()
+}
+
+// clientIDExisting 命中去重的已有文档是否可以作为指定ID写入的结果
+// 相同ID已保存相同内容时视为重试，返回已有文档；内容由其他ID保存时返回ErrDuplicateContent
+func clientIDExisting(input model.StoreInput, existing *model.JSONDocument) error {
+	if input.ID != "" && existing.ID != input.ID {
+		return fmt.Errorf("%w: %s", ErrDuplicateContent, existing.ID)
+	}
+	return nil
+}
+
+// checkIDAvailable 客户端指定ID时检查该ID未被占用，query参数为ID，返回匹配的行数
+func checkIDAvailable(ctx context.Context, db *sql.DB, query, id string) error {
+	var count int
+	if err := db.QueryRowContext(ctx, query, id).Scan(&count); err != nil {
+		return fmt.Errorf("failed to check document ID: %w", err)
+	}
+	if count > 0 {
+		return ErrIDConflict
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/leapzhao/json-store/model"
+)
+
+func TestPostgresStoreClientID(t *testing.T) {
+	const id = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	data := []byte(`{"custom":true}`)
+	ctx := context.Background()
+
+	// 指定ID未被占用时用该ID插入
+	store, mock := newMockPostgresStore(t, Options{})
+	mock.ExpectQuery("WHERE content_hash").WillReturnRows(sqlmock.NewRows(nil))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM json_documents WHERE id = \\$1").WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("INSERT INTO json_documents").
+		WithArgs(id, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(postgresDocumentRow(id, "h", data))
+	doc, err := store.StoreJSON(ctx, model.StoreInput{ID: id, JSONData: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.ID != id {
+		t.Errorf("stored id = %s, want %s", doc.ID, id)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	// ID已被其他文档占用时不插入
+	store, mock = newMockPostgresStore(t, Options{})
+	mock.ExpectQuery("WHERE content_hash").WillReturnRows(sqlmock.NewRows(nil))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM json_documents WHERE id = \\$1").WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	if _, err := store.StoreJSON(ctx, model.StoreInput{ID: id, JSONData: data}); !errors.Is(err, ErrIDConflict) {
+		t.Errorf("StoreJSON with a taken id: err = %v, want ErrIDConflict", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestClientIDExisting(t *testing.T) {
+	const id = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	tests := []struct {
+		name       string
+		inputID    string
+		existingID string
+		wantErr    error
+	}{
+		{name: "generated id", inputID: "", existingID: id},
+		// 同一ID重试写入相同内容时返回已有文档
+		{name: "retry", inputID: id, existingID: id},
+		{name: "content under other id", inputID: id, existingID: "00000000-0000-0000-0000-000000000001", wantErr: ErrDuplicateContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := clientIDExisting(model.StoreInput{ID: tt.inputID}, &model.JSONDocument{ID: tt.existingID})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("clientIDExisting() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
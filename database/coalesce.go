@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/leapzhao/json-store/model"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// coalescingStore 合并内容相同的并发写入
+// 同一文档被大量客户端同时写入时（如配置下发），只有一个请求执行规范化、哈希和数据库操作，
+// 其余请求等待并共享结果；其他方法直接委托给底层存储
+type coalescingStore struct {
+	JSONStore
+	group singleflight.Group
+}
+
+func newCoalescingStore(store JSONStore) *coalescingStore {
+	return &coalescingStore{JSONStore: store}
+}
+
+func (s *coalescingStore) StoreJSON(ctx context.Context, input model.StoreInput) (*model.JSONDocument, error) {
+	// 按原始字节计算key，避免在合并前做规范化；类型或指定ID不同的写入不合并
+	// metadata不参与key，与命中去重时一样只保留实际执行写入的那次请求的metadata
+	sum := sha256.Sum256(input.JSONData)
+	key := input.ID + ":" + input.DocType + ":" + hex.EncodeToString(sum[:])
+
+	// 共享的写入不随首个请求取消而中断，但保留请求ID等上下文值
+	shared := context.WithoutCancel(ctx)
+	leader := false
+	ch := s.group.DoChan(key, func() (any, error) {
+		leader = true
+		return s.JSONStore.StoreJSON(shared, input)
+	})
+
+	// 各请求按自己的上下文停止等待，写入在后台继续，其余等待者仍能拿到结果
+	var res singleflight.Result
+	select {
+	case res = <-ch:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if res.Err != nil {
+		return nil, res.Err
+	}
+
+	// 返回副本，调用方修改响应字段时互不影响
+	doc := *res.Val.(*model.JSONDocument)
+	// 合并进其他请求的写入对本请求而言是已存在的内容
+	if !leader {
+		doc.Existing = true
+	}
+	return &doc, nil
+}
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/leapzhao/json-store/model"
+)
+
+// slowInsertStore 统计StoreJSON的调用次数，写入在release关闭前不返回
+type slowInsertStore struct {
+	JSONStore
+	inserts atomic.Int32
+	release chan struct{}
+}
+
+func (s *slowInsertStore) StoreJSON(ctx context.Context, input model.StoreInput) (*model.JSONDocument, error) {
+	n := s.inserts.Add(1)
+	<-s.release
+	return &model.JSONDocument{ID: input.DocType + string(rune('0'+n)), JSONData: input.JSONData}, nil
+}
+
+func TestCoalescingStoreConcurrentIdentical(t *testing.T) {
+	const n = 20
+	inner := &slowInsertStore{release: make(chan struct{})}
+	store := newCoalescingStore(inner)
+	data := []byte(`{"config":"fan-out"}`)
+
+	docs := make([]*model.JSONDocument, n)
+	var started, done sync.WaitGroup
+	started.Add(n)
+	done.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			defer done.Done()
+			started.Done()
+			doc, err := store.StoreJSON(context.Background(), model.StoreInput{JSONData: data})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			docs[i] = doc
+		}(i)
+	}
+	started.Wait()
+	// 给所有请求加入同一次写入的时间，再让写入完成
+	time.Sleep(50 * time.Millisecond)
+	close(inner.release)
+	done.Wait()
+
+	if got := inner.inserts.Load(); got != 1 {
+		t.Errorf("%d inserts for %d identical concurrent stores, want 1", got, n)
+	}
+	created := 0
+	for _, doc := range docs {
+		if doc == nil {
+			continue
+		}
+		if doc.ID != docs[0].ID {
+			t.Errorf("got IDs %s and %s, want all stores to share one document", doc.ID, docs[0].ID)
+		}
+		if !doc.Existing {
+			created++
+		}
+	}
+	if created != 1 {
+		t.Errorf("%d results reported a new document, want 1", created)
+	}
+}
+
+func TestCoalescingStoreDifferentType(t *testing.T) {
+	inner := &slowInsertStore{release: make(chan struct{})}
+	close(inner.release)
+	store := newCoalescingStore(inner)
+	data := []byte(`{}`)
+
+	// 类型不同的相同内容按per_type可能是不同的文档，不能合并
+	var wg sync.WaitGroup
+	for _, docType := range []string{"a", "b"} {
+		wg.Add(1)
+		go func(docType string) {
+			defer wg.Done()
+			store.StoreJSON(context.Background(), model.StoreInput{JSONData: data, DocType: docType})
+		}(docType)
+	}
+	wg.Wait()
+	if got := inner.inserts.Load(); got != 2 {
+		t.Errorf("%d inserts for two types, want 2", got)
+	}
+}
+
+func TestCoalescingStoreFollowerCancelled(t *testing.T) {
+	inner := &slowInsertStore{release: make(chan struct{})}
+	store := newCoalescingStore(inner)
+	data := []byte(`{"config":"fan-out"}`)
+
+	leaderDone := make(chan error, 1)
+	go func() {
+		_, err := store.StoreJSON(context.Background(), model.StoreInput{JSONData: data})
+		leaderDone <- err
+	}()
+	// 等首个请求开始写入后再加入
+	for inner.inserts.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	followerDone := make(chan error, 1)
+	go func() {
+		_, err := store.StoreJSON(ctx, model.StoreInput{JSONData: data})
+		followerDone <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	// 写入仍未完成，被取消的请求也必须立即返回
+	select {
+	case err := <-followerDone:
+		if err != context.Canceled {
+			t.Errorf("cancelled follower err = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("cancelled follower still waiting for the shared write")
+	}
+
+	close(inner.release)
+	if err := <-leaderDone; err != nil {
+		t.Errorf("leader err = %v, want nil", err)
+	}
+	if got := inner.inserts.Load(); got != 1 {
+		t.Errorf("%d inserts, want 1", got)
+	}
+}
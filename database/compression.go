@@ -0,0 +1,177 @@
+package database
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/leapzhao/json-store/model"
+)
+
+// compressionGzip 压缩存储的编码名称，写入compression列
+const compressionGzip = "gzip"
+
+// unqueryableCountQuery 统计内容不在json_data列中的文档（分块存储），两种数据库通用
+// 这些文档的json_data为占位内容，按JSON字段过滤、分组以及MySQL的路径索引生成列都看不到其内容；
+// 压缩只作用于raw_data，json_data保持不变，压缩的文档照常可查询
+const unqueryableCountQuery = `SELECT COUNT(*) FROM json_documents WHERE chunk_count > 0`
+
+// countUnqueryable 返回内容不能按JSON字段查询的文档数
+func countUnqueryable(ctx context.Context, db *sql.DB) (int64, error) {
+	var count int64
+	if err := db.QueryRowContext(ctx, unqueryableCountQuery).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count chunked documents: %w", err)
+	}
+	return count, nil
+}
+
+// compressData gzip压缩数据
+func compressData(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressData 按编码解压数据
+func decompressData(codec string, data []byte) ([]byte, error) {
+	switch codec {
+	case compressionGzip:
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(reader)
+	default:
+		return nil, fmt.Errorf("unsupported compression: %s", codec)
+	}
+}
+
+// restoreJSONData 读取时还原文档内容：优先原始字节，其次解压压缩后的原始字节
+// 两者都没有时直接使用json_data列
+func restoreJSONData(doc *model.JSONDocument, compressed, raw []byte) error {
+	if len(raw) > 0 {
+		doc.JSONData = raw
+		return nil
+	}
+
+	if doc.Compression == "" {
+		return nil
+	}
+
+	data, err := decompressData(doc.Compression, compressed)
+	if err != nil {
+		return fmt.Errorf("failed to decompress document %s: %w", doc.ID, err)
+	}
+	doc.JSONData = data
+	return nil
+}
+
+// compressQueries 压缩任务使用的SQL，按数据库方言提供
+// 只压缩raw_data中保存的原始字节：json_data是字段查询、分组和MySQL路径索引生成列的来源，保持不变
+type compressQueries struct {
+	// Select 参数：最小大小、批次大小；返回ID和raw_data
+	Select string
+	// Update 参数：压缩数据、编码、ID；写入compressed_data并清空raw_data，
+	// 必须带 compression IS NULL 条件保证只更新一次，不修改updated_at
+	Update string
+	// Remaining 参数：最小大小
+	Remaining string
+	// Stats 无参数，返回压缩文档数和实际存储字节数
+	Stats string
+}
+
+// compressBatch 压缩一批保存了原始字节、尚未压缩的大文档
+// 每行通过单条UPDATE原子切换，并发读取要么看到原始字节要么看到完整的压缩数据，查询结果不受影响；
+// 只选择未压缩的行，任务中断后重新执行即可继续
+func compressBatch(ctx context.Context, db *sql.DB, queries compressQueries, minSize int64, batchSize int) (*model.CompressResult, error) {
+	start := time.Now()
+	result := &model.CompressResult{Batches: 1}
+
+	type pending struct {
+		id   string
+		data []byte
+	}
+
+	rows, err := db.QueryContext(ctx, queries.Select, minSize, batchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select documents to compress: %w", err)
+	}
+
+	batch := make([]pending, 0, batchSize)
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.data); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan document to compress: %w", err)
+		}
+		batch = append(batch, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating documents to compress: %w", err)
+	}
+
+	for _, p := range batch {
+		compressed, err := compressData(p.data)
+		if err != nil {
+			ctxLogger(ctx).Error().Err(err).Str("id", p.id).Msg("Failed to compress document")
+			result.Failed++
+			continue
+		}
+
+		res, err := db.ExecContext(ctx, queries.Update, compressed, compressionGzip, p.id)
+		if err != nil {
+			ctxLogger(ctx).Error().Err(err).Str("id", p.id).Msg("Failed to update compressed document")
+			result.Failed++
+			continue
+		}
+
+		// 0行表示已被其他任务压缩
+		if affected, _ := res.RowsAffected(); affected == 1 {
+			result.Compressed++
+			result.BytesBefore += int64(len(p.data))
+			result.BytesAfter += int64(len(compressed))
+		}
+	}
+
+	if err := db.QueryRowContext(ctx, queries.Remaining, minSize).Scan(&result.Remaining); err != nil {
+		return nil, fmt.Errorf("failed to count remaining documents: %w", err)
+	}
+	result.Done = result.Remaining == 0
+	result.Duration = time.Since(start)
+
+	ctxLogger(ctx).Info().
+		Int64("compressed", result.Compressed).
+		Int64("failed", result.Failed).
+		Int64("bytes_before", result.BytesBefore).
+		Int64("bytes_after", result.BytesAfter).
+		Int64("remaining", result.Remaining).
+		Msg("Compression batch completed")
+
+	return result, nil
+}
+
+// queryCompressionStats 查询实际存储字节数和压缩文档数，并计算整体压缩比
+// query 返回压缩文档数和存储字节数两列
+func queryCompressionStats(ctx context.Context, db *sql.DB, query string, stats *model.DatabaseStats) error {
+	if err := db.QueryRowContext(ctx, query).Scan(&stats.CompressedDocuments, &stats.StoredSize); err != nil {
+		return err
+	}
+
+	if stats.StoredSize > 0 {
+		stats.CompressionRatio = float64(stats.TotalSize) / float64(stats.StoredSize)
+	}
+
+	return nil
+}
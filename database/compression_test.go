@@ -0,0 +1,140 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/base64"
+	"math/rand"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/leapzhao/json-store/model"
+)
+
+// capturedArg 匹配任意参数并保存其值，用于把写入的数据交给之后的读取
+type capturedArg struct {
+	value driver.Value
+}
+
+func (a *capturedArg) Match(v driver.Value) bool {
+	a.value = v
+	return true
+}
+
+func TestCompressDocumentsReadable(t *testing.T) {
+	store, mock := newMockPostgresStore(t, Options{PreserveRawBytes: true})
+	ctx := context.Background()
+	const id = "00000000-0000-0000-0000-0000000000aa"
+	// 原始字节保留了空白，json_data为数据库规范化后的形式
+	original := []byte(`{ "items" : "` + strings.Repeat("x", 4096) + `" }`)
+	normalized := []byte(`{"items": "` + strings.Repeat("x", 4096) + `"}`)
+
+	// 已有的未压缩大文档：只选择保存了原始字节的文档，更新只写compressed_data并清空raw_data，不修改json_data
+	mock.ExpectQuery("SELECT id, raw_data FROM json_documents\\s+WHERE compression IS NULL AND raw_data IS NOT NULL AND chunk_count = 0 AND size >= \\$1").
+		WithArgs(1024, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "raw_data"}).AddRow(id, original))
+	compressed := &capturedArg{}
+	mock.ExpectExec("UPDATE json_documents\\s+SET compressed_data = \\$1, compression = \\$2, raw_data = NULL\\s+WHERE").
+		WithArgs(compressed, compressionGzip, id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM json_documents WHERE compression IS NULL AND raw_data IS NOT NULL").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	result, err := store.CompressDocuments(ctx, 1024, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Compressed != 1 || !result.Done {
+		t.Errorf("result = %+v, want 1 compressed and done", result)
+	}
+	data, _ := compressed.value.([]byte)
+	if len(data) == 0 || len(data) >= len(original) {
+		t.Fatalf("compressed %d bytes to %d", len(original), len(data))
+	}
+
+	// 之后读取到的是标记为gzip的压缩行，json_data保持原样，字段查询不受影响
+	now := time.Now()
+	mock.ExpectQuery("FROM json_documents WHERE id = \\$1").WithArgs(id).WillReturnRows(sqlmock.NewRows([]string{
+		"id", "content_hash", "doc_type", "json_data", "size", "created_at", "updated_at",
+		"metadata", "compression", "compressed_data", "raw_data", "chunk_count", "tags",
+	}).AddRow(id, "h", "", normalized, int64(len(original)), now, now,
+		nil, compressionGzip, data, nil, 0, nil))
+
+	doc, err := store.GetJSONByID(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Compression != compressionGzip {
+		t.Errorf("compression = %q, want %q", doc.Compression, compressionGzip)
+	}
+	if !bytes.Equal(doc.JSONData, original) {
+		t.Errorf("read %d bytes after compression, want the original %d raw bytes", len(doc.JSONData), len(original))
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCompressQueriesKeepJSONData(t *testing.T) {
+	// 压缩不能改变json_data，否则压缩的文档会从字段查询和路径索引中消失
+	for name, queries := range map[string]compressQueries{"postgres": postgresCompressQueries, "mysql": mysqlCompressQueries} {
+		if strings.Contains(queries.Update, "json_data") {
+			t.Errorf("%s compress update modifies json_data: %s", name, queries.Update)
+		}
+	}
+	if strings.Contains(unqueryableCountQuery, "compression") {
+		t.Errorf("compressed documents counted as unqueryable: %s", unqueryableCountQuery)
+	}
+}
+
+func TestQueryCompressionStats(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// 一个可压缩的文档按压缩后大小存储，一个随机内容的文档不压缩，按原大小存储
+	compressible := []byte(`{"items":"` + strings.Repeat("x", 8192) + `"}`)
+	noise := make([]byte, 4096)
+	rand.New(rand.NewSource(1)).Read(noise)
+	incompressible := []byte(`"` + base64.StdEncoding.EncodeToString(noise) + `"`)
+	compressed, err := compressData(compressible)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := int64(len(compressed) + len(incompressible))
+
+	mock.ExpectQuery(regexp.QuoteMeta(postgresCompressQueries.Stats)).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "stored"}).AddRow(1, stored))
+
+	stats := &model.DatabaseStats{TotalSize: int64(len(compressible) + len(incompressible))}
+	if err := queryCompressionStats(context.Background(), db, postgresCompressQueries.Stats, stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.CompressedDocuments != 1 || stats.StoredSize != stored {
+		t.Errorf("compressed %d, stored %d, want 1 and %d", stats.CompressedDocuments, stats.StoredSize, stored)
+	}
+	want := float64(stats.TotalSize) / float64(stored)
+	if stats.CompressionRatio != want || want <= 1 {
+		t.Errorf("compression ratio = %v, want %v (> 1)", stats.CompressionRatio, want)
+	}
+
+	// 没有文档时不除以零
+	mock.ExpectQuery(regexp.QuoteMeta(postgresCompressQueries.Stats)).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "stored"}).AddRow(0, 0))
+	empty := &model.DatabaseStats{}
+	if err := queryCompressionStats(context.Background(), db, postgresCompressQueries.Stats, empty); err != nil {
+		t.Fatal(err)
+	}
+	if empty.CompressionRatio != 0 {
+		t.Errorf("empty compression ratio = %v, want 0", empty.CompressionRatio)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
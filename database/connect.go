@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	connectInitialBackoff = 200 * time.Millisecond
+	connectMaxBackoff     = 5 * time.Second
+)
+
+// waitForDatabase 启动时重试连接数据库，使用带抖动的指数退避，直到成功或超过timeout
+// 编排环境中应用和数据库同时启动，数据库可能尚未就绪
+func waitForDatabase(ping func(ctx context.Context) error, timeout time.Duration, dbType string) error {
+	deadline := time.Now().Add(timeout)
+	backoff := connectInitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), connectMaxBackoff)
+		err := ping(ctx)
+		cancel()
+		if err == nil {
+			if attempt > 1 {
+				log.Info().Str("database_type", dbType).Int("attempt", attempt).Msg("Database connection succeeded")
+			}
+			return nil
+		}
+
+		// 在[backoff/2, backoff)区间内随机等待，避免多个实例同时重试
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)))
+		if time.Now().Add(wait).After(deadline) {
+			return fmt.Errorf("failed to ping %s after %d attempts: %w", dbType, attempt, err)
+		}
+
+		log.Warn().
+			Err(err).
+			Str("database_type", dbType).
+			Int("attempt", attempt).
+			Dur("retry_in", wait).
+			Msg("Database not ready, retrying")
+
+		time.Sleep(wait)
+
+		backoff *= 2
+		if backoff > connectMaxBackoff {
+			backoff = connectMaxBackoff
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWaitForDatabase(t *testing.T) {
+	errNotReady := errors.New("connection refused")
+	tests := []struct {
+		name         string
+		failures     int
+		timeout      time.Duration
+		wantAttempts int
+		wantErr      bool
+	}{
+		{name: "ready", failures: 0, timeout: time.Second, wantAttempts: 1},
+		{name: "ready after two failures", failures: 2, timeout: 10 * time.Second, wantAttempts: 3},
+		// 下一次等待会超过期限时不再重试
+		{name: "timeout", failures: 100, timeout: 0, wantAttempts: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			ping := func(ctx context.Context) error {
+				attempts++
+				if attempts <= tt.failures {
+					return errNotReady
+				}
+				return nil
+			}
+
+			err := waitForDatabase(ping, tt.timeout, "postgres")
+			if attempts != tt.wantAttempts {
+				t.Errorf("%d ping attempts, want %d", attempts, tt.wantAttempts)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, errNotReady) || !strings.Contains(err.Error(), "after 1 attempts") {
+					t.Errorf("waitForDatabase() error = %v, want the last ping error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("waitForDatabase() error = %v", err)
+			}
+		})
+	}
+}
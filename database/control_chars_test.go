@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/model"
+)
+
+func TestStoreJSONControlChars(t *testing.T) {
+	withNull := []byte(`{"name":"a\u0000b"}`)
+	stripped := []byte(`{"name":"ab"}`)
+	ctx := context.Background()
+
+	// 两种数据库对同一文档的处理一致：拒绝时不访问数据库，删除时按删除后的内容去重和写入
+	backends := []struct {
+		name  string
+		store func(t *testing.T, opts Options) (JSONStore, sqlmock.Sqlmock)
+	}{
+		{name: "postgres", store: func(t *testing.T, opts Options) (JSONStore, sqlmock.Sqlmock) {
+			return newMockPostgresStore(t, opts)
+		}},
+		{name: "mysql", store: func(t *testing.T, opts Options) (JSONStore, sqlmock.Sqlmock) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { db.Close() })
+			return &MySQLStore{db: db, opts: opts, stmts: newStatementCache(db)}, mock
+		}},
+	}
+	for _, backend := range backends {
+		t.Run(backend.name+"/reject_null", func(t *testing.T) {
+			store, mock := backend.store(t, Options{ControlChars: config.ControlCharsRejectNull})
+			_, err := store.StoreJSON(ctx, model.StoreInput{JSONData: withNull})
+			if !errors.Is(err, ErrControlCharacter) {
+				t.Fatalf("err = %v, want ErrControlCharacter", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+
+		t.Run(backend.name+"/strip", func(t *testing.T) {
+			opts := Options{ControlChars: config.ControlCharsStrip}
+			store, mock := backend.store(t, opts)
+			hash, err := opts.contentHash(stripped)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 删除后与不含控制字符的文档哈希相同，命中已有文档
+			mock.ExpectQuery("WHERE content_hash = ").WithArgs(opts.dedupHash(hash, calculateRawHash(stripped))).
+				WillReturnRows(postgresDocumentRow("00000000-0000-0000-0000-0000000000c9", hash, stripped))
+
+			doc, err := store.StoreJSON(ctx, model.StoreInput{JSONData: withNull})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !doc.Existing || doc.ContentHash != hash {
+				t.Errorf("doc = existing %v hash %s, want dedup hit on %s", doc.Existing, doc.ContentHash, hash)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestStoredInputControlChars(t *testing.T) {
+	controlled := []byte(`{"a":"x\u0001"}`)
+	tests := []struct {
+		policy  string
+		want    string
+		wantErr bool
+	}{
+		// reject_null只拒绝U+0000，其余控制字符照常存储
+		{policy: config.ControlCharsRejectNull, want: string(controlled)},
+		{policy: config.ControlCharsReject, wantErr: true},
+		{policy: config.ControlCharsStrip, want: `{"a":"x"}`},
+	}
+	for _, tt := range tests {
+		input, err := Options{ControlChars: tt.policy}.storedInput(model.StoreInput{JSONData: controlled})
+		if tt.wantErr {
+			if !errors.Is(err, ErrControlCharacter) {
+				t.Errorf("%s: err = %v, want ErrControlCharacter", tt.policy, err)
+			}
+			continue
+		}
+		if err != nil || string(input.JSONData) != tt.want {
+			t.Errorf("%s: storedInput() = %s, %v; want %s", tt.policy, input.JSONData, err, tt.want)
+		}
+	}
+}
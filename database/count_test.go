@@ -0,0 +1,68 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPostgresStoreCountJSON(t *testing.T) {
+	store, mock := newMockPostgresStore(t, Options{})
+	ctx := context.Background()
+
+	// 估算只执行EXPLAIN，类型值以转义后的字面量出现在语句中
+	mock.ExpectQuery(`EXPLAIN \(FORMAT JSON\) SELECT 1 FROM json_documents WHERE doc_type = 'o''brien'`).
+		WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).
+			AddRow([]byte(`[{"Plan": {"Node Type": "Seq Scan", "Plan Rows": 1234.0}}]`)))
+	estimated, err := store.CountJSON(ctx, "o'brien", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if estimated != 1234 {
+		t.Errorf("estimated = %d, want 1234", estimated)
+	}
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM json_documents`).WithArgs("").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(42)))
+	exact, err := store.CountJSON(ctx, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exact != 42 {
+		t.Errorf("exact = %d, want 42", exact)
+	}
+
+	// 无法解析的计划返回错误而不是0
+	mock.ExpectQuery("EXPLAIN").WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).AddRow([]byte(`[]`)))
+	if _, err := store.CountJSON(ctx, "", true); err == nil {
+		t.Error("CountJSON with an empty plan succeeded, want error")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMySQLStoreCountJSONEstimate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	store := &MySQLStore{db: db}
+
+	mock.ExpectQuery(`EXPLAIN FORMAT=JSON SELECT 1 FROM json_documents WHERE doc_type = \?`).WithArgs("order").
+		WillReturnRows(sqlmock.NewRows([]string{"EXPLAIN"}).
+			AddRow([]byte(`{"query_block": {"table": {"table_name": "json_documents", "rows_examined_per_scan": 77}}}`)))
+	estimated, err := store.CountJSON(context.Background(), "order", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if estimated != 77 {
+		t.Errorf("estimated = %d, want 77", estimated)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/leapzhao/json-store/model"
+)
+
+// dedupCountingStore 统计写入的文档中新建和命中去重的数量，用于评估去重节省的存储
+// 包在最外层，按调用方实际收到的结果计数：合并写入的跟随请求、按标签存储时命中已有内容同样算作命中；
+// 原子批量存储只在整体提交成功后计数
+type dedupCountingStore struct {
+	JSONStore
+	inserted atomic.Int64
+	deduped  atomic.Int64
+}
+
+func newDedupCountingStore(store JSONStore) *dedupCountingStore {
+	return &dedupCountingStore{JSONStore: store}
+}
+
+func (s *dedupCountingStore) record(docs ...*model.JSONDocument) {
+	for _, doc := range docs {
+		if doc.Existing {
+			s.deduped.Add(1)
+		} else {
+			s.inserted.Add(1)
+		}
+	}
+}
+
+// DedupStats 返回进程启动以来的去重计数
+func (s *dedupCountingStore) DedupStats() model.DedupStats {
+	stats := model.DedupStats{
+		Inserted: s.inserted.Load(),
+		Deduped:  s.deduped.Load(),
+	}
+	if total := stats.Inserted + stats.Deduped; total > 0 {
+		stats.HitRatio = float64(stats.Deduped) / float64(total)
+	}
+	return stats
+}
+
+func (s *dedupCountingStore) StoreJSON(ctx context.Context, input model.StoreInput) (*model.JSONDocument, error) {
+	doc, err := s.JSONStore.StoreJSON(ctx, input)
+	if err == nil {
+		s.record(doc)
+	}
+	return doc, err
+}
+
+func (s *dedupCountingStore) StoreJSONBatch(ctx context.Context, inputs []model.StoreInput) ([]*model.JSONDocument, error) {
+	docs, err := s.JSONStore.StoreJSONBatch(ctx, inputs)
+	if err == nil {
+		s.record(docs...)
+	}
+	return docs, err
+}
+
+func (s *dedupCountingStore) StoreJSONTransaction(ctx context.Context, inputs []model.StoreInput) ([]*model.JSONDocument, error) {
+	docs, err := s.JSONStore.StoreJSONTransaction(ctx, inputs)
+	if err == nil {
+		s.record(docs...)
+	}
+	return docs, err
+}
+
+func (s *dedupCountingStore) StoreByLabel(ctx context.Context, label string, input model.StoreInput) (*model.LabeledDocument, error) {
+	result, err := s.JSONStore.StoreByLabel(ctx, label, input)
+	if err == nil {
+		s.record(result.Document)
+	}
+	return result, err
+}
+
+func (s *dedupCountingStore) GetMetrics(ctx context.Context) (*model.DatabaseMetrics, error) {
+	metrics, err := s.JSONStore.GetMetrics(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats := s.DedupStats()
+	metrics.DocumentsInserted = stats.Inserted
+	metrics.DocumentsDeduped = stats.Deduped
+	metrics.DedupHitRatio = stats.HitRatio
+	return metrics, nil
+}
+
+// DedupStatsOf 返回CreateStore创建的存储的去重计数，外层包装（如搜索索引）通过Unwrap返回被包装的存储
+// 找不到计数层时返回false
+func DedupStatsOf(store JSONStore) (model.DedupStats, bool) {
+	for {
+		if counting, ok := store.(*dedupCountingStore); ok {
+			return counting.DedupStats(), true
+		}
+		wrapper, ok := store.(interface{ Unwrap() JSONStore })
+		if !ok {
+			return model.DedupStats{}, false
+		}
+		store = wrapper.Unwrap()
+	}
+}
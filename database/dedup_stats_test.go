@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/leapzhao/json-store/model"
+)
+
+// fixedMetricsStore GetMetrics返回空指标，其余操作交给被包装的存储
+type fixedMetricsStore struct {
+	JSONStore
+}
+
+func (s fixedMetricsStore) GetMetrics(ctx context.Context) (*model.DatabaseMetrics, error) {
+	return &model.DatabaseMetrics{}, nil
+}
+
+// unwrappingStore 模拟搜索索引等外层包装
+type unwrappingStore struct {
+	JSONStore
+}
+
+func (s unwrappingStore) Unwrap() JSONStore { return s.JSONStore }
+
+func TestDedupCountingStore(t *testing.T) {
+	const first = "00000000-0000-0000-0000-0000000000e5"
+	const second = "00000000-0000-0000-0000-0000000000e6"
+	repeated := []byte(`{"sku":"A-1"}`)
+	other := []byte(`{"sku":"B-2"}`)
+	ctx := context.Background()
+
+	postgres, mock := newMockPostgresStore(t, Options{})
+	store := newDedupCountingStore(fixedMetricsStore{postgres})
+
+	// 同一内容写入四次：首次新建，其余三次命中去重；另一内容新建一次
+	mock.ExpectQuery("WHERE content_hash = \\$1").WillReturnRows(sqlmock.NewRows(nil))
+	mock.ExpectQuery("INSERT INTO json_documents").WillReturnRows(postgresDocumentRow(first, "h1", repeated))
+	for i := 0; i < 3; i++ {
+		mock.ExpectQuery("WHERE content_hash = \\$1").WillReturnRows(postgresDocumentRow(first, "h1", repeated))
+	}
+	mock.ExpectQuery("WHERE content_hash = \\$1").WillReturnRows(sqlmock.NewRows(nil))
+	mock.ExpectQuery("INSERT INTO json_documents").WillReturnRows(postgresDocumentRow(second, "h2", other))
+	for _, data := range [][]byte{repeated, repeated, repeated, repeated, other} {
+		if _, err := store.StoreJSON(ctx, model.StoreInput{JSONData: data}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 失败的写入不计数
+	mock.ExpectQuery("WHERE content_hash = \\$1").WillReturnError(errors.New("connection reset"))
+	if _, err := store.StoreJSON(ctx, model.StoreInput{JSONData: other}); err == nil {
+		t.Fatal("StoreJSON succeeded, want error")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	want := model.DedupStats{Inserted: 2, Deduped: 3, HitRatio: 0.6}
+	if got := store.DedupStats(); got != want {
+		t.Errorf("DedupStats() = %+v, want %+v", got, want)
+	}
+	if got, ok := DedupStatsOf(unwrappingStore{store}); !ok || got != want {
+		t.Errorf("DedupStatsOf(wrapped) = %+v, %v; want %+v, true", got, ok, want)
+	}
+	if _, ok := DedupStatsOf(postgres); ok {
+		t.Error("DedupStatsOf found counters on a store without the counting layer")
+	}
+
+	metrics, err := store.GetMetrics(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metrics.DocumentsInserted != 2 || metrics.DocumentsDeduped != 3 || metrics.DedupHitRatio != 0.6 {
+		t.Errorf("metrics = inserted %d deduped %d ratio %v, want 2, 3, 0.6",
+			metrics.DocumentsInserted, metrics.DocumentsDeduped, metrics.DedupHitRatio)
+	}
+}
+
+func TestDedupCountingStoreEmpty(t *testing.T) {
+	// 没有写入时命中率为0而不是NaN
+	if got := newDedupCountingStore(nil).DedupStats(); got != (model.DedupStats{}) {
+		t.Errorf("DedupStats() = %+v, want zero", got)
+	}
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// mysqlDriverParams go-sql-driver/mysql区分大小写的参数名，配置经viper读取后键名为小写，需还原
+// 不在表中的参数由驱动作为会话系统变量设置，MySQL系统变量名不区分大小写
+var mysqlDriverParams = func() map[string]string {
+	names := []string{
+		"allowAllFiles", "allowCleartextPasswords", "allowFallbackToPlaintext", "allowNativePasswords",
+		"allowOldPasswords", "clientFoundRows", "collation", "columnsWithAlias", "interpolateParams",
+		"maxAllowedPacket", "multiStatements", "readTimeout", "rejectReadOnly", "serverPubKey",
+		"timeout", "tls", "writeTimeout",
+	}
+	m := make(map[string]string, len(names))
+	for _, name := range names {
+		m[strings.ToLower(name)] = name
+	}
+	return m
+}()
+
+// sortedParamNames 按名称排序，使生成的DSN稳定
+func sortedParamNames(params map[string]string) []string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// appendPostgresParams 以 key='value' 形式追加参数，值中的反斜杠和单引号转义，不能借空格注入其他参数
+// lib/pq把不认识的参数（如statement_timeout）作为会话运行时参数发送
+func appendPostgresParams(connStr string, params map[string]string) string {
+	var b strings.Builder
+	b.WriteString(connStr)
+	for _, name := range sortedParamNames(params) {
+		value := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(params[name])
+		b.WriteString(" " + name + "='" + value + "'")
+	}
+	return b.String()
+}
+
+// appendMySQLParams 以 &key=value 形式追加参数，值经URL编码，不能借&注入其他参数
+func appendMySQLParams(connStr string, params map[string]string) string {
+	var b strings.Builder
+	b.WriteString(connStr)
+	for _, name := range sortedParamNames(params) {
+		key := name
+		if canonical, ok := mysqlDriverParams[strings.ToLower(name)]; ok {
+			key = canonical
+		}
+		b.WriteString("&" + key + "=" + url.QueryEscape(params[name]))
+	}
+	return b.String()
+}
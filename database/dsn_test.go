@@ -0,0 +1,54 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+func TestAppendPostgresParams(t *testing.T) {
+	const base = "host=localhost port=5432 user=app password=secret dbname=json_store sslmode=disable"
+	params := map[string]string{
+		"statement_timeout": "5000",
+		"application_name":  `json store`,
+		// 引号和反斜杠被转义，不能借此追加新的参数
+		"options": `x' sslmode='require \`,
+	}
+	got := appendPostgresParams(base, params)
+	want := base + ` application_name='json store' options='x\' sslmode=\'require \\' statement_timeout='5000'`
+	if got != want {
+		t.Errorf("appendPostgresParams() =\n%s\nwant\n%s", got, want)
+	}
+	if _, err := pq.NewConnector(got); err != nil {
+		t.Errorf("lib/pq rejected the connection string: %v", err)
+	}
+}
+
+func TestAppendMySQLParams(t *testing.T) {
+	const base = "app:secret@tcp(localhost:3306)/json_store?charset=utf8mb4&parseTime=true&loc=Local&checkConnLiveness=true"
+	// 键名经viper读取后是小写的
+	params := map[string]string{
+		"readtimeout":  "5s",
+		"writetimeout": "10s",
+		"sql_mode":     "'STRICT_ALL_TABLES'&allowAllFiles=true",
+	}
+	got := appendMySQLParams(base, params)
+
+	cfg, err := mysql.ParseDSN(got)
+	if err != nil {
+		t.Fatalf("ParseDSN(%s): %v", got, err)
+	}
+	// 驱动参数还原为区分大小写的名称后生效
+	if cfg.ReadTimeout != 5*time.Second || cfg.WriteTimeout != 10*time.Second {
+		t.Errorf("read/write timeout = %v/%v, want 5s/10s; dsn %s", cfg.ReadTimeout, cfg.WriteTimeout, got)
+	}
+	// 值中的&被编码，不能注入其他参数
+	if cfg.AllowAllFiles {
+		t.Errorf("allowAllFiles injected through a value; dsn %s", got)
+	}
+	if v := cfg.Params["sql_mode"]; v != "'STRICT_ALL_TABLES'&allowAllFiles=true" {
+		t.Errorf("sql_mode = %q; dsn %s", v, got)
+	}
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/leapzhao/json-store/model"
+)
+
+// ErrInvalidExplain 查询模板不存在或参数无效
+var ErrInvalidExplain = errors.New("invalid explain request")
+
+// explainListLimit list模板使用的分页大小，与列表接口的上限一致
+const explainListLimit = 100
+
+// explainTemplate 查询模板的必需和可选参数
+type explainTemplate struct {
+	required []string
+	optional []string
+}
+
+// explainTemplates 可供EXPLAIN的查询模板；get_by_id、get_by_hash、list、count与对应接口执行的SQL相同，
+// json_field、metadata_filter按顶层字段等值过滤，用于确认为这类查询建立的索引是否生效
+var explainTemplates = map[string]explainTemplate{
+	model.ExplainGetByID:        {required: []string{"id"}},
+	model.ExplainGetByHash:      {required: []string{"hash"}},
+	model.ExplainList:           {optional: []string{"doc_type", "tag"}},
+	model.ExplainCount:          {optional: []string{"doc_type"}},
+	model.ExplainJSONField:      {required: []string{"key", "value"}},
+	model.ExplainMetadataFilter: {required: []string{"key", "value"}},
+}
+
+// validateExplain 检查模板名和参数，不认识的参数同样视为错误，避免调用方误以为参数已生效
+func validateExplain(template string, params map[string]string) error {
+	t, ok := explainTemplates[template]
+	if !ok {
+		names := make([]string, 0, len(explainTemplates))
+		for name := range explainTemplates {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("%w: unknown template %q, use one of %s", ErrInvalidExplain, template, strings.Join(names, ", "))
+	}
+
+	allowed := make(map[string]bool)
+	for _, name := range t.required {
+		if params[name] == "" {
+			return fmt.Errorf("%w: template %s requires parameter %q", ErrInvalidExplain, template, name)
+		}
+		allowed[name] = true
+	}
+	for _, name := range t.optional {
+		allowed[name] = true
+	}
+	for name := range params {
+		if !allowed[name] {
+			return fmt.Errorf("%w: template %s does not accept parameter %q", ErrInvalidExplain, template, name)
+		}
+	}
+
+	if id, ok := params["id"]; ok {
+		if _, err := uuid.Parse(id); err != nil {
+			return fmt.Errorf("%w: id must be a UUID", ErrInvalidExplain)
+		}
+	}
+	if len(params["key"]) > 128 || len(params["doc_type"]) > 64 || len(params["tag"]) > model.MaxTagLength {
+		return fmt.Errorf("%w: key must be at most 128, doc_type at most 64 and tag at most %d characters", ErrInvalidExplain, model.MaxTagLength)
+	}
+	return nil
+}
+
+// explainResult 组装EXPLAIN结果，plan为数据库返回的JSON格式执行计划，query合并空白后便于阅读
+func explainResult(template, query string, plan []byte) *model.ExplainResult {
+	return &model.ExplainResult{
+		Template: template,
+		Query:    strings.Join(strings.Fields(query), " "),
+		Plan:     plan,
+	}
+}
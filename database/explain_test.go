@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/leapzhao/json-store/model"
+)
+
+func TestValidateExplain(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		params   map[string]string
+		wantErr  bool
+	}{
+		{name: "metadata filter", template: model.ExplainMetadataFilter, params: map[string]string{"key": "env", "value": "prod"}},
+		{name: "list without params", template: model.ExplainList},
+		{name: "unknown template", template: "drop_table", wantErr: true},
+		{name: "missing parameter", template: model.ExplainMetadataFilter, params: map[string]string{"key": "env"}, wantErr: true},
+		{name: "unexpected parameter", template: model.ExplainCount, params: map[string]string{"tag": "x"}, wantErr: true},
+		{name: "id not a UUID", template: model.ExplainGetByID, params: map[string]string{"id": "1 OR 1=1"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateExplain(tt.template, tt.params)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("validateExplain() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidExplain) {
+				t.Errorf("error %v is not ErrInvalidExplain", err)
+			}
+		})
+	}
+}
+
+func TestPostgresStoreExplainMetadataFilter(t *testing.T) {
+	store, mock := newMockPostgresStore(t, Options{})
+	plan := `[{"Plan":{"Node Type":"Bitmap Heap Scan","Relation Name":"json_documents"}}]`
+
+	// EXPLAIN不能绑定参数，值按字面量转义后代入，值中的 $1 不会被再次替换
+	mock.ExpectQuery(regexp.QuoteMeta(`EXPLAIN (FORMAT JSON) SELECT`) + `.*` +
+		regexp.QuoteMeta(`WHERE metadata->>'env' = 'it''s $1'`)).
+		WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).AddRow([]byte(plan)))
+
+	result, err := store.Explain(context.Background(), model.ExplainMetadataFilter, map[string]string{"key": "env", "value": "it's $1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !json.Valid(result.Plan) || string(result.Plan) != plan {
+		t.Errorf("plan = %s, want %s", result.Plan, plan)
+	}
+	if result.Template != model.ExplainMetadataFilter {
+		t.Errorf("template = %q", result.Template)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMySQLStoreExplainMetadataFilter(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	store := &MySQLStore{db: db}
+
+	// MySQL的EXPLAIN可以使用占位符，键按JSON路径引用
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN FORMAT=JSON SELECT")).
+		WithArgs(`$."env"`, "prod").
+		WillReturnRows(sqlmock.NewRows([]string{"EXPLAIN"}).AddRow([]byte(`{"query_block":{"select_id":1}}`)))
+
+	result, err := store.Explain(context.Background(), model.ExplainMetadataFilter, map[string]string{"key": "env", "value": "prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result.Plan) != `{"query_block":{"select_id":1}}` {
+		t.Errorf("plan = %s", result.Plan)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// maxFacets FacetCounts最多单独返回的取值数，其余计入facetOther
+const maxFacets = 50
+
+// facetOther 超出maxFacets的取值合并后的键
+const facetOther = "other"
+
+// queryFacetCounts 按文档数降序读取各取值的计数，超出maxFacets的部分合并为facetOther
+// query 返回取值和计数两列，按计数降序排列
+func queryFacetCounts(ctx context.Context, db *sql.DB, query string, args ...any) (map[string]int64, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query facets: %w", err)
+	}
+	defer rows.Close()
+
+	facets := make(map[string]int64)
+	n := 0
+	for rows.Next() {
+		var value string
+		var count int64
+		if err := rows.Scan(&value, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan facet: %w", err)
+		}
+
+		if n < maxFacets {
+			facets[value] += count
+		} else {
+			facets[facetOther] += count
+		}
+		n++
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating facets: %w", err)
+	}
+
+	return facets, nil
+}
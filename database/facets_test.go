@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPostgresStoreFacetCounts(t *testing.T) {
+	store, mock := newMockPostgresStore(t, Options{})
+	ctx := context.Background()
+
+	// metadata中env为prod的3个、staging的2个、dev的1个，没有env的文档由查询排除
+	mock.ExpectQuery("SELECT metadata->>\\$1::text AS value").WithArgs("env").
+		WillReturnRows(sqlmock.NewRows([]string{"value", "count"}).
+			AddRow("prod", 3).AddRow("staging", 2).AddRow("dev", 1))
+	facets, err := store.FacetCounts(ctx, "env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int64{"prod": 3, "staging": 2, "dev": 1}
+	if fmt.Sprint(facets) != fmt.Sprint(want) {
+		t.Errorf("facets = %v, want %v", facets, want)
+	}
+
+	// 超过maxFacets的取值合并为other
+	rows := sqlmock.NewRows([]string{"value", "count"})
+	for i := 0; i < maxFacets+3; i++ {
+		rows.AddRow(fmt.Sprintf("tenant-%02d", i), 2)
+	}
+	mock.ExpectQuery("SELECT metadata->>\\$1::text AS value").WithArgs("tenant").WillReturnRows(rows)
+	facets, err = store.FacetCounts(ctx, "tenant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(facets) != maxFacets+1 {
+		t.Errorf("got %d facets, want %d", len(facets), maxFacets+1)
+	}
+	if facets[facetOther] != 6 {
+		t.Errorf("other = %d, want 6", facets[facetOther])
+	}
+	if facets["tenant-00"] != 2 {
+		t.Errorf("tenant-00 = %d, want 2", facets["tenant-00"])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -16,26 +16,43 @@ const (
 // CreateStore 工厂方法，根据配置创建对应的存储实例
 func CreateStore(cfg config.Config) (JSONStore, error) {
 	dbCfg := cfg.Database
+	opts := optionsFromConfig(cfg)
+
+	var store JSONStore
+	var err error
 
 	switch DatabaseType(dbCfg.Type) {
 	case Postgres:
-		return NewPostgresStore(
+		store, err = NewPostgresStore(
 			dbCfg.Host,
 			dbCfg.Port,
 			dbCfg.User,
 			dbCfg.Password,
 			dbCfg.Name,
 			dbCfg.SSLMode,
+			opts,
 		)
 	case MySQL:
-		return NewMySQLStore(
+		store, err = NewMySQLStore(
 			dbCfg.Host,
 			dbCfg.Port,
 			dbCfg.User,
 			dbCfg.Password,
 			dbCfg.Name,
+			opts,
 		)
 	default:
 		return nil, fmt.Errorf("unsupported database type: %s", dbCfg.Type)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 允许重复内容时每次写入都应生成新记录，不能合并
+	if opts.CoalesceWrites && !opts.AllowDuplicateContent {
+		store = newCoalescingStore(store)
+	}
+
+	// 去重计数包在最外层，合并写入的结果同样计入
+	return newDedupCountingStore(store), nil
 }
@@ -0,0 +1,321 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/model"
+	"github.com/leapzhao/json-store/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrInvalidDocument 文档无法规范化（normalize_failure为reject时）
+var ErrInvalidDocument = errors.New("document cannot be normalized")
+
+// ErrControlCharacter 文档的字符串中含有control_chars不允许的控制字符
+var ErrControlCharacter = errors.New("document contains a disallowed control character")
+
+// contentHash 计算内容哈希，基于规范化后的JSON，键顺序和空白不同的文档哈希相同
+// 无法规范化时按配置回退为原始字节哈希，或返回ErrInvalidDocument
+func (o Options) contentHash(data []byte) (string, error) {
+	if !o.RejectUnnormalizable {
+		hash, _ := utils.CalculateHash(data, o.ExactNumbers)
+		return hash, nil
+	}
+
+	hash, err := utils.CalculateHashStrict(data, o.ExactNumbers)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+	return hash, nil
+}
+
+// storedInput 按control_chars处理字符串中的控制字符，不允许时返回ErrControlCharacter；
+// 开启StoreNormalized时再把内容替换为规范化形式，不转义 <、>、&
+// 无法规范化的文档（包括非法JSON）原样返回，由后续的校验和contentHash处理
+func (o Options) storedInput(input model.StoreInput) (model.StoreInput, error) {
+	switch o.ControlChars {
+	case config.ControlCharsStrip:
+		input.JSONData = utils.StripControlChars(input.JSONData, false)
+	case config.ControlCharsReject, config.ControlCharsRejectNull:
+		if err := utils.FindControlChar(input.JSONData, o.ControlChars == config.ControlCharsRejectNull); err != nil {
+			return input, fmt.Errorf("%w: %v", ErrControlCharacter, err)
+		}
+	}
+
+	if !o.StoreNormalized {
+		return input, nil
+	}
+	if normalized, err := utils.NormalizeJSONNoEscape(input.JSONData); err == nil {
+		input.JSONData = normalized
+	}
+	return input, nil
+}
+
+// calculateRawHash 计算原始字节哈希，键顺序或空白不同的文档哈希不同
+func calculateRawHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// dedupColumn 去重使用的哈希列，也是唯一约束所在的列
+func (o Options) dedupColumn() string {
+	if o.DedupByRawBytes {
+		return "raw_hash"
+	}
+	return "content_hash"
+}
+
+// dedupHash 返回去重使用的哈希值
+func (o Options) dedupHash(hash, rawHash string) string {
+	if o.DedupByRawBytes {
+		return rawHash
+	}
+	return hash
+}
+
+// dedupCondition 去重查找的WHERE条件及其参数
+// per_type时同时匹配文档类型，未指定类型的文档视为同一类型；placeholder按参数序号生成占位符，从first开始编号
+func (o Options) dedupCondition(hash, rawHash, docType string, placeholder func(int) string, first int) (string, []any) {
+	condition := o.dedupColumn() + " = " + placeholder(first)
+	args := []any{o.dedupHash(hash, rawHash)}
+	if o.DedupPerType {
+		condition += " AND COALESCE(doc_type, '') = " + placeholder(first+1)
+		args = append(args, docType)
+	}
+	return condition, args
+}
+
+// dedupConstraint 去重相关的唯一约束
+type dedupConstraint struct {
+	// column 哈希列
+	column string
+	// perType 为true时约束在(doc_type, column)上，doc_type为NULL按空字符串处理
+	perType bool
+	// unique 当前设置下是否需要该约束
+	unique bool
+}
+
+// dedupConstraints 列出所有去重相关的唯一约束，需要的排在前面
+// 调整时先建立新约束再移除旧约束，建立失败（已有重复数据）时旧约束保持不变
+func (o Options) dedupConstraints() []dedupConstraint {
+	var required, obsolete []dedupConstraint
+	for _, column := range []string{"content_hash", "raw_hash"} {
+		for _, perType := range []bool{false, true} {
+			c := dedupConstraint{
+				column:  column,
+				perType: perType,
+				unique:  !o.AllowDuplicateContent && column == o.dedupColumn() && perType == o.DedupPerType,
+			}
+			if c.unique {
+				required = append(required, c)
+			} else {
+				obsolete = append(obsolete, c)
+			}
+		}
+	}
+	return append(required, obsolete...)
+}
+
+// backfillPageSize 迁移中回填文档派生列时每页读取的文档数
+const backfillPageSize = 500
+
+// backfillColumns 回填查询的列，顺序与readBackfillPage一致，两种数据库通用
+const backfillColumns = `id, content_hash, json_data, COALESCE(compression, ''), compressed_data, raw_data, chunk_count, size`
+
+// rawHashBackfill 回填raw_hash使用的SQL，按数据库方言提供
+type rawHashBackfill struct {
+	// Select 参数：起始ID（不含）、页大小；按ID升序返回raw_hash为空的文档，列同readBackfillPage
+	Select string
+	// SelectChunks 参数：文档ID，按序号升序返回数据
+	SelectChunks string
+	// Update 参数：raw_hash、ID
+	Update string
+}
+
+// backfillRawHash 为新增的raw_hash列回填历史文档的哈希，按ID分页读取，内存占用与表大小无关
+// 保存了原始字节（raw_data或分块）的文档按原始字节计算；
+// 其余文档的原始字节已经丢失，按数据库中的存储形式计算
+func backfillRawHash(tx *sql.Tx, queries rawHashBackfill) error {
+	return forEachBackfillPage(tx, queries.Select, func(doc *model.JSONDocument) error {
+		var hash string
+		if doc.ChunkCount > 0 {
+			var err error
+			if hash, err = rawHashOfChunks(tx, queries.SelectChunks, doc.ID); err != nil {
+				return fmt.Errorf("failed to read chunks for document %s: %w", doc.ID, err)
+			}
+		} else {
+			hash = calculateRawHash(doc.JSONData)
+		}
+
+		if _, err := tx.Exec(queries.Update, hash, doc.ID); err != nil {
+			return fmt.Errorf("failed to update raw_hash for document %s: %w", doc.ID, err)
+		}
+		return nil
+	})
+}
+
+// simHashBackfill 回填simhash使用的SQL，按数据库方言提供
+type simHashBackfill struct {
+	// Select 参数：起始ID（不含）、页大小；按ID升序返回simhash为空的文档，列同readBackfillPage
+	Select string
+	// SelectChunks 参数：文档ID，按序号升序返回数据
+	SelectChunks string
+	// Update 参数：simhash、ID
+	Update string
+}
+
+// backfillSimHash 为simhash列上线前写入的文档计算SimHash，使其可以参与相似文档查询
+// 无法计算的文档（非法JSON）保持NULL
+func backfillSimHash(tx *sql.Tx, queries simHashBackfill) error {
+	var updated int
+	err := forEachBackfillPage(tx, queries.Select, func(doc *model.JSONDocument) error {
+		if err := loadChunks(context.Background(), tx, queries.SelectChunks, doc); err != nil {
+			return err
+		}
+
+		simhash := documentSimHash(doc.JSONData)
+		if !simhash.Valid {
+			return nil
+		}
+		if _, err := tx.Exec(queries.Update, simhash, doc.ID); err != nil {
+			return fmt.Errorf("failed to update simhash for document %s: %w", doc.ID, err)
+		}
+		updated++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info().Int("updated", updated).Msg("SimHash backfilled")
+	return nil
+}
+
+// forEachBackfillPage 按ID分页读取query返回的文档并逐个调用fn，fn中可以在同一事务中执行其他语句
+// 分块文档的内容不在结果中，需要时由fn自行读取
+func forEachBackfillPage(tx *sql.Tx, query string, fn func(doc *model.JSONDocument) error) error {
+	after := zeroUUID
+	for {
+		docs, err := readBackfillPage(tx, query, after)
+		if err != nil {
+			return err
+		}
+
+		for _, doc := range docs {
+			if err := fn(doc); err != nil {
+				return err
+			}
+		}
+
+		if len(docs) < backfillPageSize {
+			return nil
+		}
+		after = docs[len(docs)-1].ID
+	}
+}
+
+// readBackfillPage 读取ID大于after的一页文档并还原未分块文档的内容
+// query返回backfillColumns；同一事务内不能在遍历结果集时执行其他语句，因此先读完整页再处理
+func readBackfillPage(tx *sql.Tx, query, after string) ([]*model.JSONDocument, error) {
+	rows, err := tx.Query(query, after, backfillPageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query documents: %w", err)
+	}
+	defer rows.Close()
+
+	var docs []*model.JSONDocument
+	for rows.Next() {
+		doc := &model.JSONDocument{}
+		var compressed, raw []byte
+		if err := rows.Scan(&doc.ID, &doc.ContentHash, &doc.JSONData, &doc.Compression, &compressed, &raw,
+			&doc.ChunkCount, &doc.Size); err != nil {
+			return nil, fmt.Errorf("failed to scan document: %w", err)
+		}
+
+		if doc.ChunkCount == 0 {
+			if err := restoreJSONData(doc, compressed, raw); err != nil {
+				return nil, fmt.Errorf("failed to restore document %s: %w", doc.ID, err)
+			}
+		}
+		docs = append(docs, doc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating documents: %w", err)
+	}
+
+	return docs, nil
+}
+
+// rawHashOfChunks 按序号读取分块并计算原始字节哈希
+func rawHashOfChunks(tx *sql.Tx, query, id string) (string, error) {
+	rows, err := tx.Query(query, id)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	hasher := sha256.New()
+	for rows.Next() {
+		var chunk sql.RawBytes
+		if err := rows.Scan(&chunk); err != nil {
+			return "", err
+		}
+		hasher.Write(chunk)
+	}
+
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// findExistingHashes 用一条 content_hash IN (...) 查询返回已存储的哈希及最早创建的文档ID
+func findExistingHashes(ctx context.Context, db *sql.DB, placeholder func(int) string, hashes []string) (map[string]string, error) {
+	if len(hashes) > model.MaxExistsHashes {
+		return nil, fmt.Errorf("batch size exceeds limit of %d", model.MaxExistsHashes)
+	}
+
+	existing := make(map[string]string)
+	if len(hashes) == 0 {
+		return existing, nil
+	}
+
+	placeholders := make([]string, len(hashes))
+	args := make([]any, len(hashes))
+	for i, hash := range hashes {
+		placeholders[i] = placeholder(i + 1)
+		args[i] = hash
+	}
+
+	// 按创建时间升序，允许重复内容时保留每个哈希最早的文档
+	query := `SELECT content_hash, id FROM json_documents WHERE content_hash IN (` +
+		strings.Join(placeholders, ",") + `) ORDER BY created_at, id`
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check hashes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hash, id string
+		if err := rows.Scan(&hash, &id); err != nil {
+			return nil, fmt.Errorf("failed to scan hash: %w", err)
+		}
+		if _, ok := existing[hash]; !ok {
+			existing[hash] = id
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return existing, nil
+}
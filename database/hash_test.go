@@ -0,0 +1,208 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/leapzhao/json-store/model"
+)
+
+func TestPostgresStoreDedupMode(t *testing.T) {
+	compact := []byte(`{"a":1,"b":[1,2]}`)
+	spaced := []byte(`{ "a" : 1, "b" : [ 1, 2 ] }`)
+	tests := []struct {
+		name          string
+		raw           bool
+		column        string
+		wantDuplicate bool
+	}{
+		// 规范化后相同，第二次命中去重
+		{name: "normalized", column: "content_hash", wantDuplicate: true},
+		// 字节不同，按原始字节去重时作为新文档
+		{name: "raw", raw: true, column: "raw_hash", wantDuplicate: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{DedupByRawBytes: tt.raw}
+			store, mock := newMockPostgresStore(t, opts)
+			ctx := context.Background()
+
+			lookupArg := func(data []byte) string {
+				hash, err := opts.contentHash(data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return opts.dedupHash(hash, calculateRawHash(data))
+			}
+			lookup := regexp.QuoteMeta("WHERE " + tt.column + " = $1")
+
+			mock.ExpectQuery(lookup).WithArgs(lookupArg(compact)).WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectQuery("INSERT INTO json_documents").
+				WillReturnRows(postgresDocumentRow("00000000-0000-0000-0000-000000000001", "h", compact))
+			if _, err := store.StoreJSON(ctx, model.StoreInput{JSONData: compact}); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.wantDuplicate {
+				mock.ExpectQuery(lookup).WithArgs(lookupArg(compact)).
+					WillReturnRows(postgresDocumentRow("00000000-0000-0000-0000-000000000001", "h", compact))
+			} else {
+				mock.ExpectQuery(lookup).WithArgs(lookupArg(spaced)).WillReturnRows(sqlmock.NewRows(nil))
+				mock.ExpectQuery("INSERT INTO json_documents").
+					WillReturnRows(postgresDocumentRow("00000000-0000-0000-0000-000000000002", "h", spaced))
+			}
+			doc, err := store.StoreJSON(ctx, model.StoreInput{JSONData: spaced})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if doc.Existing != tt.wantDuplicate {
+				t.Errorf("second store Existing = %v, want %v", doc.Existing, tt.wantDuplicate)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestDedupConstraintsMode(t *testing.T) {
+	// 唯一约束只在选定的哈希列上
+	for _, raw := range []bool{false, true} {
+		opts := Options{DedupByRawBytes: raw}
+		required := opts.dedupConstraints()[0]
+		if !required.unique || required.column != opts.dedupColumn() {
+			t.Errorf("DedupByRawBytes=%v: first constraint %+v, want unique on %s", raw, required, opts.dedupColumn())
+		}
+	}
+}
+
+func TestPostgresStoreDedupScope(t *testing.T) {
+	data := []byte(`{"name":"same"}`)
+	tests := []struct {
+		name          string
+		perType       bool
+		wantDuplicate bool
+	}{
+		// 全局去重：不同类型的相同内容也返回已有文档
+		{name: "global", wantDuplicate: true},
+		// 按类型去重：每个类型各保存一份
+		{name: "per_type", perType: true, wantDuplicate: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{DedupPerType: tt.perType}
+			store, mock := newMockPostgresStore(t, opts)
+			ctx := context.Background()
+			hash, err := opts.contentHash(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lookupArgs := func(docType string) []driver.Value {
+				if tt.perType {
+					return []driver.Value{hash, docType}
+				}
+				return []driver.Value{hash}
+			}
+
+			mock.ExpectQuery("WHERE content_hash = \\$1").WithArgs(lookupArgs("invoice")...).WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectQuery("INSERT INTO json_documents").
+				WillReturnRows(postgresDocumentRow("00000000-0000-0000-0000-000000000001", hash, data))
+			if _, err := store.StoreJSON(ctx, model.StoreInput{JSONData: data, DocType: "invoice"}); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.wantDuplicate {
+				mock.ExpectQuery("WHERE content_hash = \\$1 LIMIT 1").WithArgs(lookupArgs("order")...).
+					WillReturnRows(postgresDocumentRow("00000000-0000-0000-0000-000000000001", hash, data))
+			} else {
+				mock.ExpectQuery("WHERE content_hash = \\$1 AND COALESCE\\(doc_type, ''\\) = \\$2").
+					WithArgs(lookupArgs("order")...).WillReturnRows(sqlmock.NewRows(nil))
+				mock.ExpectQuery("INSERT INTO json_documents").
+					WillReturnRows(postgresDocumentRow("00000000-0000-0000-0000-000000000002", hash, data))
+			}
+			doc, err := store.StoreJSON(ctx, model.StoreInput{JSONData: data, DocType: "order"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if doc.Existing != tt.wantDuplicate {
+				t.Errorf("second store Existing = %v, want %v", doc.Existing, tt.wantDuplicate)
+			}
+
+			// 唯一约束跟随作用域
+			required := opts.dedupConstraints()[0]
+			if !required.unique || required.perType != tt.perType {
+				t.Errorf("required constraint = %+v, want unique with perType %v", required, tt.perType)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestFindExistingHashes(t *testing.T) {
+	stored := strings.Repeat("a", 64)
+	missing := strings.Repeat("b", 64)
+	ctx := context.Background()
+
+	t.Run("postgres", func(t *testing.T) {
+		store, mock := newMockPostgresStore(t, Options{})
+		// 一条IN查询检查全部哈希；允许重复内容时同一哈希返回多行，取最早的文档
+		mock.ExpectQuery(regexp.QuoteMeta("WHERE content_hash IN ($1,$2) ORDER BY created_at, id")).
+			WithArgs(stored, missing).
+			WillReturnRows(sqlmock.NewRows([]string{"content_hash", "id"}).
+				AddRow(stored, "00000000-0000-0000-0000-0000000000a1").
+				AddRow(stored, "00000000-0000-0000-0000-0000000000a2"))
+
+		existing, err := store.FindExistingHashes(ctx, []string{stored, missing})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(existing) != 1 || existing[stored] != "00000000-0000-0000-0000-0000000000a1" {
+			t.Errorf("existing = %v, want only %s -> earliest id", existing, stored)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("mysql", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		store := &MySQLStore{db: db}
+		mock.ExpectQuery(regexp.QuoteMeta("WHERE content_hash IN (?,?)")).WithArgs(missing, stored).
+			WillReturnRows(sqlmock.NewRows([]string{"content_hash", "id"}).AddRow(stored, "00000000-0000-0000-0000-0000000000a3"))
+
+		existing, err := store.FindExistingHashes(ctx, []string{missing, stored})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := existing[missing]; ok || existing[stored] != "00000000-0000-0000-0000-0000000000a3" {
+			t.Errorf("existing = %v", existing)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("limits", func(t *testing.T) {
+		store, mock := newMockPostgresStore(t, Options{})
+		// 空列表和超出上限时不查询数据库
+		if existing, err := store.FindExistingHashes(ctx, nil); err != nil || len(existing) != 0 {
+			t.Errorf("empty list: %v, %v", existing, err)
+		}
+		if _, err := store.FindExistingHashes(ctx, make([]string, model.MaxExistsHashes+1)); err == nil {
+			t.Error("oversized batch succeeded, want error")
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/leapzhao/json-store/model"
+)
+
+// sizeHistogramQuery 构建文档大小分布查询，桶上界为配置中的整数，直接内联到CASE中
+func sizeHistogramQuery(buckets []int64) string {
+	var cases strings.Builder
+	for i, upper := range buckets {
+		fmt.Fprintf(&cases, " WHEN size <= %d THEN %d", upper, i)
+	}
+
+	return fmt.Sprintf(`
+		SELECT bucket, COUNT(*) as count
+		FROM (
+			SELECT CASE%s ELSE %d END AS bucket
+			FROM json_documents
+		) b
+		GROUP BY bucket
+	`, cases.String(), len(buckets))
+}
+
+// querySizeHistogram 查询文档大小分布，返回每个桶内（非累计）的文档数
+func querySizeHistogram(ctx context.Context, db *sql.DB, buckets []int64) ([]model.SizeBucket, error) {
+	if len(buckets) == 0 {
+		return nil, nil
+	}
+
+	histogram := make([]model.SizeBucket, len(buckets)+1)
+	for i, upper := range buckets {
+		histogram[i].UpperBound = strconv.FormatInt(upper, 10)
+	}
+	histogram[len(buckets)].UpperBound = "+Inf"
+
+	rows, err := db.QueryContext(ctx, sizeHistogramQuery(buckets))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get size histogram: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bucket int
+		var count int64
+		if err := rows.Scan(&bucket, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan size histogram: %w", err)
+		}
+		if bucket >= 0 && bucket < len(histogram) {
+			histogram[bucket].Count = count
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating size histogram: %w", err)
+	}
+
+	return histogram, nil
+}
+
+// 文档年龄不超过给定小时数的条件，按数据库方言提供；created_at与CURRENT_TIMESTAMP使用同一会话时区
+const (
+	postgresAgeCondition = "created_at >= CURRENT_TIMESTAMP - INTERVAL '%d hours'"
+	mysqlAgeCondition    = "created_at >= CURRENT_TIMESTAMP - INTERVAL %d HOUR"
+)
+
+// ageHistogramQuery 构建文档年龄分布查询，桶上界（小时）为配置中的整数，直接内联到CASE中
+// created_at晚于当前时间（时钟偏差）的文档计入第一个桶
+func ageHistogramQuery(condition string, buckets []int64) string {
+	var cases strings.Builder
+	for i, upper := range buckets {
+		fmt.Fprintf(&cases, " WHEN "+condition+" THEN %d", upper, i)
+	}
+
+	return fmt.Sprintf(`
+		SELECT bucket, COUNT(*) as count, COALESCE(SUM(size), 0) as size
+		FROM (
+			SELECT CASE%s ELSE %d END AS bucket, size
+			FROM json_documents
+		) b
+		GROUP BY bucket
+	`, cases.String(), len(buckets))
+}
+
+// queryAgeHistogram 查询文档年龄分布，返回每个桶内（非累计）的文档数和总大小
+func queryAgeHistogram(ctx context.Context, db *sql.DB, condition string, buckets []int64) ([]model.AgeBucket, error) {
+	if len(buckets) == 0 {
+		return nil, nil
+	}
+
+	histogram := make([]model.AgeBucket, len(buckets)+1)
+	for i, upper := range buckets {
+		histogram[i].UpperBound = strconv.FormatInt(upper, 10)
+	}
+	histogram[len(buckets)].UpperBound = "+Inf"
+
+	rows, err := db.QueryContext(ctx, ageHistogramQuery(condition, buckets))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get age histogram: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bucket int
+		var count, size int64
+		if err := rows.Scan(&bucket, &count, &size); err != nil {
+			return nil, fmt.Errorf("failed to scan age histogram: %w", err)
+		}
+		if bucket >= 0 && bucket < len(histogram) {
+			histogram[bucket].Count = count
+			histogram[bucket].Size = size
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating age histogram: %w", err)
+	}
+
+	return histogram, nil
+}
@@ -0,0 +1,161 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/leapzhao/json-store/model"
+)
+
+func TestQuerySizeHistogram(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// 数据库按CASE分组，没有文档的桶不返回行
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT CASE WHEN size <= 1024 THEN 0 WHEN size <= 4096 THEN 1 ELSE 2 END AS bucket")).
+		WillReturnRows(sqlmock.NewRows([]string{"bucket", "count"}).AddRow(0, 3).AddRow(2, 1))
+
+	histogram, err := querySizeHistogram(context.Background(), db, []int64{1024, 4096})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []model.SizeBucket{{UpperBound: "1024", Count: 3}, {UpperBound: "4096"}, {UpperBound: "+Inf", Count: 1}}
+	if fmt.Sprint(histogram) != fmt.Sprint(want) {
+		t.Errorf("histogram = %v, want %v", histogram, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestQuerySizeHistogramDisabled(t *testing.T) {
+	// 未配置桶时不查询数据库
+	histogram, err := querySizeHistogram(context.Background(), nil, nil)
+	if err != nil || histogram != nil {
+		t.Errorf("querySizeHistogram = %v, %v; want nil, nil", histogram, err)
+	}
+}
+
+func TestAgeHistogramQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition string
+		buckets   []int64
+		wantCase  string
+	}{
+		{
+			name:      "postgres",
+			condition: postgresAgeCondition,
+			buckets:   []int64{24, 168, 720},
+			wantCase: "CASE" +
+				" WHEN created_at >= CURRENT_TIMESTAMP - INTERVAL '24 hours' THEN 0" +
+				" WHEN created_at >= CURRENT_TIMESTAMP - INTERVAL '168 hours' THEN 1" +
+				" WHEN created_at >= CURRENT_TIMESTAMP - INTERVAL '720 hours' THEN 2" +
+				" ELSE 3 END AS bucket",
+		},
+		{
+			name:      "mysql",
+			condition: mysqlAgeCondition,
+			buckets:   []int64{1, 48},
+			wantCase: "CASE" +
+				" WHEN created_at >= CURRENT_TIMESTAMP - INTERVAL 1 HOUR THEN 0" +
+				" WHEN created_at >= CURRENT_TIMESTAMP - INTERVAL 48 HOUR THEN 1" +
+				" ELSE 2 END AS bucket",
+		},
+		{
+			name:      "single bucket",
+			condition: mysqlAgeCondition,
+			buckets:   []int64{24},
+			wantCase:  "CASE WHEN created_at >= CURRENT_TIMESTAMP - INTERVAL 24 HOUR THEN 0 ELSE 1 END AS bucket",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := ageHistogramQuery(tt.condition, tt.buckets)
+			if !strings.Contains(query, tt.wantCase) {
+				t.Errorf("ageHistogramQuery = %s, want it to contain %s", query, tt.wantCase)
+			}
+			if strings.Contains(query, "%!") {
+				t.Errorf("ageHistogramQuery has a formatting error: %s", query)
+			}
+			if !strings.Contains(query, "GROUP BY bucket") {
+				t.Errorf("ageHistogramQuery = %s, want it grouped by bucket", query)
+			}
+		})
+	}
+}
+
+func TestQueryAgeHistogram(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// 按创建时间倒推的文档：与CASE相同，取第一个满足 created_at >= now - 上界 的桶
+	buckets := []int64{24, 24 * 7, 24 * 30}
+	now := time.Now()
+	seeded := []struct {
+		age  time.Duration
+		size int64
+	}{
+		{age: -time.Minute, size: 10}, // 时钟偏差导致created_at晚于当前时间，计入第一个桶
+		{age: 2 * time.Hour, size: 20},
+		{age: 3 * 24 * time.Hour, size: 30},
+		{age: 6 * 24 * time.Hour, size: 40},
+		{age: 90 * 24 * time.Hour, size: 50},
+	}
+	counts := make([]int64, len(buckets)+1)
+	sizes := make([]int64, len(buckets)+1)
+	for _, doc := range seeded {
+		createdAt := now.Add(-doc.age)
+		bucket := len(buckets)
+		for i, upper := range buckets {
+			if !createdAt.Before(now.Add(-time.Duration(upper) * time.Hour)) {
+				bucket = i
+				break
+			}
+		}
+		counts[bucket]++
+		sizes[bucket] += doc.size
+	}
+	// 数据库只返回有文档的桶
+	rows := sqlmock.NewRows([]string{"bucket", "count", "size"})
+	for i := range counts {
+		if counts[i] > 0 {
+			rows.AddRow(i, counts[i], sizes[i])
+		}
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("INTERVAL '720 hours' THEN 2 ELSE 3 END AS bucket")).WillReturnRows(rows)
+
+	histogram, err := queryAgeHistogram(context.Background(), db, postgresAgeCondition, buckets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []model.AgeBucket{
+		{UpperBound: "24", Count: 2, Size: 30},
+		{UpperBound: "168", Count: 2, Size: 70},
+		{UpperBound: "720"},
+		{UpperBound: "+Inf", Count: 1, Size: 50},
+	}
+	if fmt.Sprint(histogram) != fmt.Sprint(want) {
+		t.Errorf("histogram = %v, want %v", histogram, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	// 未配置桶时不查询数据库
+	if histogram, err := queryAgeHistogram(context.Background(), nil, postgresAgeCondition, nil); err != nil || histogram != nil {
+		t.Errorf("queryAgeHistogram without buckets = %v, %v; want nil, nil", histogram, err)
+	}
+}
@@ -0,0 +1,146 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/leapzhao/json-store/model"
+)
+
+// argSizeServer 记录单条语句交给驱动的最大参数（字节），即驱动需要在内存中持有并发送的最大值
+type argSizeServer struct {
+	largest atomic.Int64
+}
+
+func (s *argSizeServer) Connect(ctx context.Context) (driver.Conn, error) {
+	return &argSizeConn{server: s}, nil
+}
+
+func (s *argSizeServer) Driver() driver.Driver { return nil }
+
+type argSizeConn struct {
+	server *argSizeServer
+}
+
+func (c *argSizeConn) Prepare(query string) (driver.Stmt, error) {
+	return &argSizeStmt{server: c.server}, nil
+}
+
+func (c *argSizeConn) Close() error              { return nil }
+func (c *argSizeConn) Begin() (driver.Tx, error) { return argSizeTx{}, nil }
+
+type argSizeTx struct{}
+
+func (argSizeTx) Commit() error   { return nil }
+func (argSizeTx) Rollback() error { return nil }
+
+type argSizeStmt struct {
+	server *argSizeServer
+}
+
+func (s *argSizeStmt) record(args []driver.Value) {
+	for _, arg := range args {
+		var size int64
+		switch v := arg.(type) {
+		case []byte:
+			size = int64(len(v))
+		case string:
+			size = int64(len(v))
+		}
+		for {
+			largest := s.server.largest.Load()
+			if size <= largest || s.server.largest.CompareAndSwap(largest, size) {
+				break
+			}
+		}
+	}
+}
+
+func (s *argSizeStmt) Close() error  { return nil }
+func (s *argSizeStmt) NumInput() int { return -1 }
+
+func (s *argSizeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.record(args)
+	return driver.RowsAffected(1), nil
+}
+
+// Query 返回一行非分块文档，作为INSERT ... RETURNING和写入后读取的结果
+func (s *argSizeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.record(args)
+	return &documentRows{}, nil
+}
+
+// newArgSizeStore 允许重复内容的PostgresStore，跳过去重查询只测量写入本身
+func newArgSizeStore(chunkSize int) (*PostgresStore, *argSizeServer) {
+	server := &argSizeServer{}
+	db := sql.OpenDB(server)
+	opts := Options{AllowDuplicateContent: true}
+	if chunkSize > 0 {
+		opts.ChunkThreshold = int64(chunkSize)
+		opts.ChunkSize = chunkSize
+	}
+	return &PostgresStore{db: db, opts: opts, stmts: newStatementCache(db)}, server
+}
+
+// largeDocument 约size字节的文档
+func largeDocument(size int) []byte {
+	return []byte(`{"payload":"` + strings.Repeat("x", size) + `"}`)
+}
+
+func TestChunkedInsertBoundsDriverArguments(t *testing.T) {
+	const chunkSize = 64 << 10
+	data := largeDocument(4 << 20)
+
+	standard, standardServer := newArgSizeStore(0)
+	defer standard.Close()
+	if _, err := standard.StoreJSON(context.Background(), model.StoreInput{JSONData: data}); err != nil {
+		t.Fatal(err)
+	}
+	// 普通写入把整个文档作为一个参数交给驱动
+	if got := standardServer.largest.Load(); got < int64(len(data)) {
+		t.Errorf("standard insert: largest argument %d bytes, want at least the %d byte document", got, len(data))
+	}
+
+	chunked, chunkedServer := newArgSizeStore(chunkSize)
+	defer chunked.Close()
+	if _, err := chunked.StoreJSON(context.Background(), model.StoreInput{JSONData: data}); err != nil {
+		t.Fatal(err)
+	}
+	// 分块写入时单条语句的参数不超过chunk_size，与文档大小无关
+	if got := chunkedServer.largest.Load(); got > chunkSize {
+		t.Errorf("chunked insert: largest argument %d bytes, want at most chunk_size %d", got, chunkSize)
+	}
+}
+
+// BenchmarkLargeInsert 比较大文档普通写入和分块写入的内存：
+// B/op为整个写入的分配量，max-arg-bytes为单条语句交给驱动的最大参数，决定驱动发送时的峰值缓冲
+func BenchmarkLargeInsert(b *testing.B) {
+	data := largeDocument(8 << 20)
+	for _, bc := range []struct {
+		name      string
+		chunkSize int
+	}{
+		{name: "standard", chunkSize: 0},
+		{name: "chunked", chunkSize: 256 << 10},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			store, server := newArgSizeStore(bc.chunkSize)
+			defer store.Close()
+			ctx := context.Background()
+
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := store.StoreJSON(ctx, model.StoreInput{JSONData: data}); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(server.largest.Load()), "max-arg-bytes")
+		})
+	}
+}
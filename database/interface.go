@@ -2,6 +2,7 @@ package database
 
 import (
 	"context"
+	"time"
 
 	"github.com/leapzhao/json-store/model"
 )
@@ -9,32 +10,113 @@ import (
 // JSONStore 存储接口定义
 type JSONStore interface {
 	// StoreJSON 存储JSON，如果已存在则返回已有ID
-	StoreJSON(ctx context.Context, jsonData []byte) (*model.JSONDocument, error)
+	StoreJSON(ctx context.Context, input model.StoreInput) (*model.JSONDocument, error)
 
 	// StoreJSONBatch 批量存储JSON
-	StoreJSONBatch(ctx context.Context, jsonDataList [][]byte) ([]*model.JSONDocument, error)
+	StoreJSONBatch(ctx context.Context, inputs []model.StoreInput) ([]*model.JSONDocument, error)
 
-	// GetJSONByID 根据ID获取JSON
+	// UpdateJSON 替换指定文档的内容，文档不存在时返回ErrDocumentNotFound
+	// 未开启allow_duplicate_content且内容已由其他文档保存时返回ErrDuplicateContent
+	UpdateJSON(ctx context.Context, id string, input model.StoreInput) (*model.JSONDocument, error)
+
+	// StoreByLabel 存储内容并把标签指向该文档，指向的文档变化时标签版本号加1并保留历史版本
+	StoreByLabel(ctx context.Context, label string, input model.StoreInput) (*model.LabeledDocument, error)
+
+	// StoreJSONTransaction 在一个事务中存储全部文档，任意一个失败则全部回滚，结果与inputs按位置对应
+	StoreJSONTransaction(ctx context.Context, inputs []model.StoreInput) ([]*model.JSONDocument, error)
+
+	// UpdateMetadataBatch 在一个事务中把metadata合并到多个文档（顶层键覆盖，值为null时删除该键，嵌套对象不递归合并），结果与updates按位置对应
+	UpdateMetadataBatch(ctx context.Context, updates []model.MetadataUpdate) ([]model.MetadataUpdateResult, error)
+
+	// GetJSONByID 根据ID获取JSON，开启track_access时同时累加访问计数
 	GetJSONByID(ctx context.Context, id string) (*model.JSONDocument, error)
 
-	// GetJSONBatch 批量获取JSON
+	// GetJSONByLabel 获取标签指定版本（version为0时为当前版本）的文档，同时返回版本号
+	// 标签或版本不存在时返回ErrLabelNotFound
+	GetJSONByLabel(ctx context.Context, label string, version int) (*model.JSONDocument, int, error)
+
+	// DocumentLabels 返回当前指向各文档的标签，键为文档ID，不含标签的历史版本；用于备份导出
+	DocumentLabels(ctx context.Context) (map[string][]string, error)
+
+	// SetLabel 把标签指向已有文档，指向的文档变化时版本号加1；文档不存在时返回ErrDocumentNotFound
+	SetLabel(ctx context.Context, label, id string) (*model.LabeledDocument, error)
+
+	// RestoreTimestamps 把文档的创建和修改时间设为给定值，用于从备份恢复
+	RestoreTimestamps(ctx context.Context, id string, createdAt, updatedAt time.Time) error
+
+	// StoreAttachment 保存文档的二进制附件，同名附件已存在时覆盖；文档不存在时返回ErrDocumentNotFound
+	StoreAttachment(ctx context.Context, documentID, name, contentType string, data []byte) (*model.Attachment, error)
+
+	// GetAttachment 读取附件信息和数据，不存在时返回ErrAttachmentNotFound
+	GetAttachment(ctx context.Context, documentID, name string) (*model.Attachment, error)
+
+	// GetJSONBatch 批量获取JSON，只返回找到的文档，不保证与ids顺序一致
 	GetJSONBatch(ctx context.Context, ids []string) ([]*model.JSONDocument, error)
 
 	// GetJSONByHash 根据哈希值获取JSON
 	GetJSONByHash(ctx context.Context, hash string) (*model.JSONDocument, error)
 
+	// FindExistingHashes 返回hashes中已存储的内容哈希及对应的文档ID，不存在的哈希不在结果中
+	// 同一内容有多个文档时返回最早创建的一个；最多MaxExistsHashes个哈希
+	FindExistingHashes(ctx context.Context, hashes []string) (map[string]string, error)
+
+	// FindHashesByPrefix 查找以prefix开头的不同内容哈希，最多返回limit个
+	FindHashesByPrefix(ctx context.Context, prefix string, limit int) ([]string, error)
+
+	// GetStorageInfo 获取文档的存储诊断信息
+	GetStorageInfo(ctx context.Context, id string) (*model.StorageInfo, error)
+
+	// FindNearDuplicates 查找SimHash与给定值汉明距离不超过maxDistance的其他文档，按距离升序
+	// 没有SimHash的文档（内容不是合法JSON）不参与比较
+	FindNearDuplicates(ctx context.Context, excludeID string, simhash uint64, maxDistance, limit int) ([]model.NearDuplicate, error)
+
+	// LargestDocuments 按大小降序返回前limit个文档的摘要，不含内容
+	LargestDocuments(ctx context.Context, limit int) ([]model.DocumentSummary, error)
+
+	// FacetCounts 按metadata顶层键metaKey的取值统计文档数，不含该键或取值为null的文档不计入
+	// 最多返回50个取值，其余合并计入 "other"；metadata不受压缩和分块存储影响，统计覆盖全部文档
+	FacetCounts(ctx context.Context, metaKey string) (map[string]int64, error)
+
+	// ListJSON 按条件列出JSON
+	ListJSON(ctx context.Context, filter model.ListFilter) ([]*model.JSONDocument, error)
+
+	// GetJSONModifiedSince 按(updated_at, id)升序返回在since之后修改的文档，用于增量同步
+	// afterID非空时返回(updated_at, id)大于(since, afterID)的文档，避免分页边界上同一时刻的文档被跳过
+	// 最近database.changes_lag_ms内修改的文档不返回，待写事务提交后再由之后的请求返回
+	GetJSONModifiedSince(ctx context.Context, since time.Time, afterID string, limit int) ([]*model.JSONDocument, error)
+
+	// CountJSON 统计指定类型（为空时为全部）的文档数
+	// estimate为true时返回查询计划的估算行数，不扫描表，结果依赖统计信息的新旧
+	CountJSON(ctx context.Context, docType string, estimate bool) (int64, error)
+
+	// Explain 返回查询模板的执行计划（数据库的JSON格式），模板或参数无效时返回ErrInvalidExplain
+	Explain(ctx context.Context, template string, params map[string]string) (*model.ExplainResult, error)
+
 	// GetStats 获取统计信息
 	GetStats(ctx context.Context) (*model.DatabaseStats, error)
 
 	// GetMetrics 获取性能指标
 	GetMetrics(ctx context.Context) (*model.DatabaseMetrics, error)
 
+	// ReplicationStatus 连接的是备库时返回复制状态，主库或不支持的数据库返回nil
+	ReplicationStatus(ctx context.Context) (*model.ReplicationStatus, error)
+
+	// IterateDocuments 按创建时间顺序遍历文档，since非零时只包含该时间之后创建的文档
+	// fn返回错误时停止遍历并返回该错误
+	IterateDocuments(ctx context.Context, since time.Time, fn func(*model.JSONDocument) error) error
+
+	// CompressDocuments 压缩一批大小不低于minSize的未压缩文档
+	CompressDocuments(ctx context.Context, minSize int64, batchSize int) (*model.CompressResult, error)
+
 	// Close 关闭数据库连接
 	Close() error
 
 	// HealthCheck 健康检查
 	HealthCheck(ctx context.Context) error
 
+	// WriteCheck 写入检查，在事务中写入后回滚，验证数据库可写
+	WriteCheck(ctx context.Context) error
+
 	// Migrate 数据库迁移
 	Migrate() error
 }
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/leapzhao/json-store/model"
+)
+
+// iteratePageSize 遍历文档时每页读取的行数
+const iteratePageSize = 500
+
+// zeroUUID 游标初始ID，小于任何实际生成的ID
+const zeroUUID = "00000000-0000-0000-0000-000000000000"
+
+// iterateDocuments 按 (created_at, id) 游标分页遍历文档
+// query 参数：游标时间、游标ID、每页行数，需按 created_at, id 升序返回文档查询列
+// 每页读取完成后释放连接再回调，回调耗时不会占用数据库连接
+func iterateDocuments(
+	ctx context.Context,
+	db *sql.DB,
+	query string,
+	scan func(rowScanner) (*model.JSONDocument, error),
+	chunkQuery string,
+	since time.Time,
+	fn func(*model.JSONDocument) error,
+) error {
+	cursorTime, cursorID := since, zeroUUID
+
+	for {
+		page, err := queryDocumentPage(ctx, db, query, scan, iteratePageSize, cursorTime, cursorID, iteratePageSize)
+		if err != nil {
+			return err
+		}
+
+		if err := loadChunks(ctx, db, chunkQuery, page...); err != nil {
+			return err
+		}
+
+		for _, doc := range page {
+			if err := fn(doc); err != nil {
+				return err
+			}
+		}
+
+		if len(page) < iteratePageSize {
+			return nil
+		}
+
+		last := page[len(page)-1]
+		cursorTime, cursorID = last.CreatedAt, last.ID
+	}
+}
+
+// queryDocumentPage 读取游标之后的一页文档，args为query的参数，limit为其中的行数
+func queryDocumentPage(
+	ctx context.Context,
+	db *sql.DB,
+	query string,
+	scan func(rowScanner) (*model.JSONDocument, error),
+	limit int,
+	args ...any,
+) ([]*model.JSONDocument, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query documents: %w", err)
+	}
+	defer rows.Close()
+
+	page := make([]*model.JSONDocument, 0, limit)
+	for rows.Next() {
+		doc, err := scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan document: %w", err)
+		}
+		page = append(page, doc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return page, nil
+}
+
+// modifiedSince 按 (updated_at, id) 游标读取一页修改过的文档并加载分块
+// query 参数：游标时间、游标ID、等待时长（微秒）、行数；afterID为空时从since（含）开始
+// updated_at晚于数据库当前时间减lag的文档不返回，留到之后的请求，
+// 避免开始较早、提交较晚的事务中的文档落在已返回的游标之前
+func modifiedSince(
+	ctx context.Context,
+	db *sql.DB,
+	query string,
+	scan func(rowScanner) (*model.JSONDocument, error),
+	chunkQuery string,
+	since time.Time,
+	afterID string,
+	lag time.Duration,
+	limit int,
+) ([]*model.JSONDocument, error) {
+	if afterID == "" {
+		afterID = zeroUUID
+	}
+
+	page, err := queryDocumentPage(ctx, db, query, scan, limit, since, afterID, lag.Microseconds(), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := loadChunks(ctx, db, chunkQuery, page...); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
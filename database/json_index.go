@@ -0,0 +1,173 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/leapzhao/json-store/config"
+	"github.com/rs/zerolog/log"
+)
+
+// jsonIndexColumnPrefix 路径索引生成列的前缀，同步时带该前缀但不在配置中的列会被删除
+const jsonIndexColumnPrefix = "jp_"
+
+// jsonIndexReturning 各索引类型对应的列类型和JSON_VALUE的RETURNING类型
+var jsonIndexReturning = map[string][2]string{
+	config.JSONIndexString:  {"VARCHAR(255)", "CHAR(255)"},
+	config.JSONIndexInteger: {"BIGINT", "SIGNED"},
+	config.JSONIndexNumber:  {"DOUBLE", "DOUBLE"},
+}
+
+// jsonIndexType 索引类型，未配置时为string
+func jsonIndexType(idx config.JSONPathIndex) string {
+	if idx.Type == "" {
+		return config.JSONIndexString
+	}
+	return idx.Type
+}
+
+// jsonIndexColumn 路径索引的生成列名
+func jsonIndexColumn(idx config.JSONPathIndex) string {
+	return jsonIndexColumnPrefix + idx.Name
+}
+
+// jsonIndexComment 记录在生成列注释中的定义，与配置不一致时重建该列
+func jsonIndexComment(idx config.JSONPathIndex) string {
+	return "json_index:" + idx.Path + ":" + jsonIndexType(idx)
+}
+
+// syncJSONIndexes 按配置建立、重建或删除JSON路径上的存储生成列及其B树索引
+// 生成列使用JSON_VALUE（MySQL 8.0.21+），取值缺失或无法转换为列类型时为NULL，不影响写入
+func (s *MySQLStore) syncJSONIndexes() error {
+	rows, err := s.db.Query(`
+		SELECT COLUMN_NAME, COLUMN_COMMENT
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE()
+			AND TABLE_NAME = 'json_documents'
+			AND COLUMN_NAME LIKE 'jp\_%'
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to list json index columns: %w", err)
+	}
+	existing := make(map[string]string)
+	for rows.Next() {
+		var column, comment string
+		if err := rows.Scan(&column, &comment); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan json index column: %w", err)
+		}
+		existing[column] = comment
+	}
+	if err := rows.Close(); err != nil {
+		return fmt.Errorf("failed to list json index columns: %w", err)
+	}
+
+	configured := make(map[string]bool, len(s.opts.JSONIndexes))
+	for _, idx := range s.opts.JSONIndexes {
+		column := jsonIndexColumn(idx)
+		configured[column] = true
+
+		comment, ok := existing[column]
+		if ok && comment == jsonIndexComment(idx) {
+			if err := s.ensureJSONIndex(column); err != nil {
+				return err
+			}
+			continue
+		}
+		if ok {
+			if err := s.dropJSONIndexColumn(column); err != nil {
+				return err
+			}
+			log.Warn().Str("column", column).Str("path", idx.Path).Msg("JSON index definition changed, rebuilding")
+		}
+		if err := s.addJSONIndexColumn(idx); err != nil {
+			return err
+		}
+	}
+
+	for column := range existing {
+		if configured[column] {
+			continue
+		}
+		if err := s.dropJSONIndexColumn(column); err != nil {
+			return err
+		}
+		log.Warn().Str("column", column).Msg("Dropped JSON index no longer in config")
+	}
+
+	return nil
+}
+
+// addJSONIndexColumn 添加生成列和索引，存量数据在ALTER TABLE时计算
+func (s *MySQLStore) addJSONIndexColumn(idx config.JSONPathIndex) error {
+	column := jsonIndexColumn(idx)
+	types := jsonIndexReturning[jsonIndexType(idx)]
+	// 路径和注释已由配置校验限制为安全字符，可以直接拼入DDL
+	query := fmt.Sprintf(`
+		ALTER TABLE json_documents
+		ADD COLUMN %s %s GENERATED ALWAYS AS (
+			JSON_VALUE(json_data, '%s' RETURNING %s NULL ON EMPTY NULL ON ERROR)
+		) STORED COMMENT '%s',
+		ADD INDEX idx_%s (%s)
+	`, column, types[0], idx.Path, types[1], jsonIndexComment(idx), column, column)
+
+	if _, err := s.db.Exec(query); err != nil {
+		return fmt.Errorf("failed to add json index %s on %s: %w", idx.Name, idx.Path, err)
+	}
+	log.Info().Str("column", column).Str("path", idx.Path).Msg("Created JSON index")
+	return nil
+}
+
+// ensureJSONIndex 生成列已存在但索引被手工删除时补建索引
+func (s *MySQLStore) ensureJSONIndex(column string) error {
+	var count int
+	err := s.db.QueryRow(`
+		SELECT COUNT(*)
+		FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = DATABASE()
+			AND TABLE_NAME = 'json_documents'
+			AND INDEX_NAME = ?
+	`, "idx_"+column).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to check index idx_%s: %w", column, err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	return ignoreDuplicateIndex(s.db.Exec(`ALTER TABLE json_documents ADD INDEX idx_` + column + ` (` + column + `)`))
+}
+
+// dropJSONIndexColumn 删除生成列，列上的索引随之删除
+func (s *MySQLStore) dropJSONIndexColumn(column string) error {
+	if !strings.HasPrefix(column, jsonIndexColumnPrefix) {
+		return fmt.Errorf("refusing to drop non json index column %s", column)
+	}
+	if _, err := s.db.Exec(`ALTER TABLE json_documents DROP COLUMN ` + column); err != nil {
+		return fmt.Errorf("failed to drop json index column %s: %w", column, err)
+	}
+	return nil
+}
+
+// dropIndexIfExists 索引存在时才执行ALTER TABLE DROP INDEX
+func dropIndexIfExists(tx *sql.Tx, table, index string) error {
+	var count int
+	err := tx.QueryRow(`
+		SELECT COUNT(*)
+		FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = DATABASE()
+			AND TABLE_NAME = ?
+			AND INDEX_NAME = ?
+	`, table, index).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to check index %s: %w", index, err)
+	}
+
+	if count == 0 {
+		return nil
+	}
+
+	_, err = tx.Exec(`ALTER TABLE ` + table + ` DROP INDEX ` + index)
+	return err
+}
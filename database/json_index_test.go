@@ -0,0 +1,59 @@
+package database
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/leapzhao/json-store/config"
+)
+
+func TestSyncJSONIndexes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	unchanged := config.JSONPathIndex{Name: "user", Path: "$.user.name"}
+	retyped := config.JSONPathIndex{Name: "order_id", Path: "$.order.id", Type: config.JSONIndexInteger}
+	added := config.JSONPathIndex{Name: "price", Path: "$.price", Type: config.JSONIndexNumber}
+	store := &MySQLStore{db: db, opts: Options{JSONIndexes: []config.JSONPathIndex{unchanged, retyped, added}}}
+
+	// 数据库中：user与配置一致，order_id原为string，stale已从配置中移除
+	mock.ExpectQuery("FROM information_schema.COLUMNS").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME", "COLUMN_COMMENT"}).
+			AddRow("jp_user", jsonIndexComment(unchanged)).
+			AddRow("jp_order_id", "json_index:$.order.id:string").
+			AddRow("jp_stale", "json_index:$.stale:string"))
+
+	// 一致的列只检查索引是否还在
+	mock.ExpectQuery("FROM information_schema.STATISTICS").WithArgs("idx_jp_user").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	// 定义变化的列先删除再按新类型重建
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE json_documents DROP COLUMN jp_order_id")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`ADD COLUMN jp_order_id BIGINT GENERATED ALWAYS AS \(\s*JSON_VALUE\(json_data, '\$\.order\.id' RETURNING SIGNED NULL ON EMPTY NULL ON ERROR\)\s*\) STORED COMMENT 'json_index:\$\.order\.id:integer',\s*ADD INDEX idx_jp_order_id \(jp_order_id\)`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	// 新配置的索引
+	mock.ExpectExec(`ADD COLUMN jp_price DOUBLE GENERATED ALWAYS AS \(\s*JSON_VALUE\(json_data, '\$\.price' RETURNING DOUBLE`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	// 不在配置中的列被删除
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE json_documents DROP COLUMN jp_stale")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := store.syncJSONIndexes(); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDropJSONIndexColumnPrefix(t *testing.T) {
+	// 只删除路径索引的生成列，不能误删普通列
+	store := &MySQLStore{}
+	if err := store.dropJSONIndexColumn("json_data"); err == nil {
+		t.Error("dropJSONIndexColumn(json_data) succeeded, want refusal")
+	}
+}
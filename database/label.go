@@ -0,0 +1,175 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/leapzhao/json-store/model"
+)
+
+// ErrLabelNotFound 标签不存在，或标签没有指定的版本
+var ErrLabelNotFound = errors.New("label not found")
+
+// labelQueries 标签读写使用的SQL，按数据库方言提供
+// 标签单独成表而不是文档表的一列：内容去重时多个标签可能指向同一个文档
+type labelQueries struct {
+	// Ensure 参数：标签，不存在时插入版本号为0、未指向文档的行，已存在时不做任何事
+	Ensure string
+	// Lock 参数：标签，锁定该行并返回当前文档ID（未指向文档时为空字符串）和版本号
+	Lock string
+	// Update 参数：文档ID、版本号、标签
+	Update string
+	// InsertVersion 参数：标签、版本号、文档ID
+	InsertVersion string
+	// SelectCurrent 参数：标签，返回当前文档ID和版本号
+	SelectCurrent string
+	// SelectVersion 参数：标签、版本号，返回该版本的文档ID
+	SelectVersion string
+}
+
+// storeLabel 在事务中把标签指向doc，指向的文档发生变化时版本号加1并记录历史
+func storeLabel(ctx context.Context, db *sql.DB, queries labelQueries, label string, doc *model.JSONDocument) (*model.LabeledDocument, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := storeLabelTx(ctx, tx, queries, label, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return result, nil
+}
+
+// storeByLabel 在同一个事务中存储文档并把标签指向它，写入文档或更新标签失败时都不留下任何数据
+func storeByLabel(
+	ctx context.Context,
+	db *sql.DB,
+	queries transactionQueries,
+	chunks chunkQueries,
+	labels labelQueries,
+	scan func(rowScanner) (*model.JSONDocument, error),
+	opts Options,
+	label string,
+	input model.StoreInput,
+) (*model.LabeledDocument, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	doc, err := storeDocumentTx(ctx, tx, queries, chunks, scan, opts, input)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := storeLabelTx(ctx, tx, labels, label, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return result, nil
+}
+
+// storeLabelTx 在事务tx中更新标签，由调用方提交
+// 先插入占位行再加锁，同一标签的首次并发写入也会排队而不是主键冲突；占位行与版本在同一事务中提交
+func storeLabelTx(ctx context.Context, tx *sql.Tx, queries labelQueries, label string, doc *model.JSONDocument) (*model.LabeledDocument, error) {
+	if _, err := tx.ExecContext(ctx, queries.Ensure, label); err != nil {
+		return nil, fmt.Errorf("failed to create label: %w", err)
+	}
+
+	var currentID string
+	var version int
+	if err := tx.QueryRowContext(ctx, queries.Lock, label).Scan(&currentID, &version); err != nil {
+		return nil, fmt.Errorf("failed to lock label: %w", err)
+	}
+
+	result := &model.LabeledDocument{
+		Label:    label,
+		Version:  version,
+		Document: doc,
+	}
+	// 内容未变化（命中去重返回同一文档）时不产生新版本
+	if currentID == doc.ID {
+		return result, nil
+	}
+
+	result.Version = version + 1
+	result.Changed = true
+	if _, err := tx.ExecContext(ctx, queries.Update, doc.ID, result.Version, label); err != nil {
+		return nil, fmt.Errorf("failed to update label: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, queries.InsertVersion, label, result.Version, doc.ID); err != nil {
+		return nil, fmt.Errorf("failed to record label version: %w", err)
+	}
+
+	return result, nil
+}
+
+// setLabel 把标签指向ID为id的已有文档，读取文档时不计入访问次数
+func setLabel(ctx context.Context, db *sql.DB, queries labelQueries, label, id string,
+	get func(context.Context, string) (*model.JSONDocument, error)) (*model.LabeledDocument, error) {
+	doc, err := get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDocumentNotFound, err)
+	}
+	return storeLabel(ctx, db, queries, label, doc)
+}
+
+// resolveLabel 返回标签指定版本（version为0时为当前版本）指向的文档ID和版本号
+func resolveLabel(ctx context.Context, db *sql.DB, queries labelQueries, label string, version int) (string, int, error) {
+	var id string
+	var err error
+	if version == 0 {
+		err = db.QueryRowContext(ctx, queries.SelectCurrent, label).Scan(&id, &version)
+	} else {
+		err = db.QueryRowContext(ctx, queries.SelectVersion, label, version).Scan(&id)
+	}
+	if err == sql.ErrNoRows {
+		return "", 0, ErrLabelNotFound
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to resolve label: %w", err)
+	}
+	return id, version, nil
+}
+
+// documentLabelsQuery 列出当前指向文档的标签，两种数据库通用
+const documentLabelsQuery = `SELECT label, document_id FROM json_labels WHERE document_id IS NOT NULL ORDER BY label`
+
+// documentLabels 返回当前指向各文档的标签，键为文档ID
+func documentLabels(ctx context.Context, db *sql.DB) (map[string][]string, error) {
+	rows, err := db.QueryContext(ctx, documentLabelsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query labels: %w", err)
+	}
+	defer rows.Close()
+
+	labels := make(map[string][]string)
+	for rows.Next() {
+		var label, id string
+		if err := rows.Scan(&label, &id); err != nil {
+			return nil, fmt.Errorf("failed to scan label: %w", err)
+		}
+		labels[id] = append(labels[id], label)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating labels: %w", err)
+	}
+
+	return labels, nil
+}
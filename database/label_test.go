@@ -0,0 +1,233 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/leapzhao/json-store/model"
+)
+
+func TestPostgresStoreByLabel(t *testing.T) {
+	const id = "00000000-0000-0000-0000-0000000000c1"
+	data := []byte(`{"env":"prod"}`)
+	lockRow := func(currentID string, version int) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"document_id", "version"}).AddRow(currentID, version)
+	}
+
+	t.Run("new document", func(t *testing.T) {
+		store, mock := newMockPostgresStore(t, Options{})
+		// 文档写入、标签更新和历史版本在同一个事务中
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT id FROM json_documents WHERE content_hash = \\$1").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectExec("INSERT INTO json_documents").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("FROM json_documents WHERE id = \\$1").WillReturnRows(postgresDocumentRow(id, "h", data))
+		mock.ExpectExec("INSERT INTO json_labels").WithArgs("config").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("FROM json_labels WHERE label = \\$1 FOR UPDATE").WithArgs("config").WillReturnRows(lockRow("", 0))
+		mock.ExpectExec("UPDATE json_labels").WithArgs(id, 1, "config").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO json_label_versions").WithArgs("config", 1, id).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		result, err := store.StoreByLabel(context.Background(), "config", model.StoreInput{JSONData: data})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Version != 1 || !result.Changed || result.Document.ID != id {
+			t.Errorf("result = version %d changed %v id %s, want version 1 changed true id %s",
+				result.Version, result.Changed, result.Document.ID, id)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("unchanged content", func(t *testing.T) {
+		store, mock := newMockPostgresStore(t, Options{})
+		// 命中去重且标签已指向该文档时不更新标签也不记录新版本
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT id FROM json_documents WHERE content_hash = \\$1").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+		mock.ExpectQuery("FROM json_documents WHERE id = \\$1").WillReturnRows(postgresDocumentRow(id, "h", data))
+		mock.ExpectExec("INSERT INTO json_labels").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("FOR UPDATE").WillReturnRows(lockRow(id, 3))
+		mock.ExpectCommit()
+
+		result, err := store.StoreByLabel(context.Background(), "config", model.StoreInput{JSONData: data})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Version != 3 || result.Changed || !result.Document.Existing {
+			t.Errorf("result = version %d changed %v existing %v, want version 3 unchanged existing",
+				result.Version, result.Changed, result.Document.Existing)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("label update failure rolls back document", func(t *testing.T) {
+		store, mock := newMockPostgresStore(t, Options{})
+		// 标签更新失败时已插入的文档随事务回滚，不提交
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT id FROM json_documents WHERE content_hash = \\$1").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectExec("INSERT INTO json_documents").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("FROM json_documents WHERE id = \\$1").WillReturnRows(postgresDocumentRow(id, "h", data))
+		mock.ExpectExec("INSERT INTO json_labels").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("FOR UPDATE").WillReturnRows(lockRow("", 0))
+		mock.ExpectExec("UPDATE json_labels").WillReturnError(errors.New("deadlock detected"))
+		mock.ExpectRollback()
+
+		if _, err := store.StoreByLabel(context.Background(), "config", model.StoreInput{JSONData: data}); err == nil {
+			t.Fatal("StoreByLabel succeeded, want error")
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("invalid document", func(t *testing.T) {
+		store, mock := newMockPostgresStore(t, Options{})
+		// 无效文档在写入前失败，标签不被创建
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		if _, err := store.StoreByLabel(context.Background(), "config", model.StoreInput{JSONData: []byte(`{"env":`)}); err == nil {
+			t.Fatal("StoreByLabel succeeded, want error")
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestMySQLStoreByLabel(t *testing.T) {
+	const id = "00000000-0000-0000-0000-0000000000c2"
+	data := []byte(`{"env":"staging"}`)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	store := &MySQLStore{db: db}
+
+	// 已有标签指向旧文档：版本号加1，与文档写入在同一事务中提交
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM json_documents WHERE content_hash = \\?").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectExec("INSERT INTO json_documents").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("FROM json_documents WHERE id = \\?").WillReturnRows(postgresDocumentRow(id, "h", data))
+	mock.ExpectExec("INSERT INTO json_labels").WithArgs("config").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM json_labels WHERE label = \\? FOR UPDATE").WithArgs("config").
+		WillReturnRows(sqlmock.NewRows([]string{"document_id", "version"}).AddRow("00000000-0000-0000-0000-0000000000c0", 4))
+	mock.ExpectExec("UPDATE json_labels").WithArgs(id, 5, "config").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO json_label_versions").WithArgs("config", 5, id).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	result, err := store.StoreByLabel(context.Background(), "config", model.StoreInput{JSONData: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Version != 5 || !result.Changed {
+		t.Errorf("result = version %d changed %v, want version 5 changed", result.Version, result.Changed)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPostgresStoreGetJSONByLabel(t *testing.T) {
+	const current = "00000000-0000-0000-0000-0000000000c3"
+	const previous = "00000000-0000-0000-0000-0000000000c4"
+	tests := []struct {
+		name        string
+		version     int
+		expect      func(sqlmock.Sqlmock)
+		wantID      string
+		wantVersion int
+		wantErr     error
+	}{
+		{
+			name: "current version",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM json_labels WHERE label = \\$1 AND document_id IS NOT NULL").WithArgs("config").
+					WillReturnRows(sqlmock.NewRows([]string{"document_id", "version"}).AddRow(current, 2))
+				mock.ExpectQuery("FROM json_documents WHERE id = \\$1").WithArgs(current).
+					WillReturnRows(postgresDocumentRow(current, "h2", []byte(`{"v":2}`)))
+			},
+			wantID: current, wantVersion: 2,
+		},
+		{
+			name:    "historical version",
+			version: 1,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM json_label_versions WHERE label = \\$1 AND version = \\$2").WithArgs("config", 1).
+					WillReturnRows(sqlmock.NewRows([]string{"document_id"}).AddRow(previous))
+				mock.ExpectQuery("FROM json_documents WHERE id = \\$1").WithArgs(previous).
+					WillReturnRows(postgresDocumentRow(previous, "h1", []byte(`{"v":1}`)))
+			},
+			wantID: previous, wantVersion: 1,
+		},
+		{
+			// 标签不存在，或文档删除后标签被置空
+			name: "unknown label",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM json_labels").WillReturnRows(sqlmock.NewRows([]string{"document_id", "version"}))
+			},
+			wantErr: ErrLabelNotFound,
+		},
+		{
+			name:    "unknown version",
+			version: 9,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM json_label_versions").WillReturnRows(sqlmock.NewRows([]string{"document_id"}))
+			},
+			wantErr: ErrLabelNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, mock := newMockPostgresStore(t, Options{})
+			tt.expect(mock)
+
+			doc, version, err := store.GetJSONByLabel(context.Background(), "config", tt.version)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if doc.ID != tt.wantID || version != tt.wantVersion {
+					t.Errorf("got %s version %d, want %s version %d", doc.ID, version, tt.wantID, tt.wantVersion)
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestLabelTablesOnDelete(t *testing.T) {
+	for name, migrations := range map[string][]migration{
+		"postgres": postgresMigrations,
+		"mysql":    mysqlMigrations,
+	} {
+		var statements string
+		for _, m := range migrations {
+			if m.Description == "create label tables" {
+				statements = strings.Join(m.Statements, "\n")
+			}
+		}
+		// 文档删除时标签置空，指向该文档的历史版本一并删除
+		for _, want := range []string{
+			"REFERENCES json_documents(id) ON DELETE SET NULL",
+			"REFERENCES json_documents(id) ON DELETE CASCADE",
+		} {
+			if !strings.Contains(statements, want) {
+				t.Errorf("%s label tables lack %q", name, want)
+			}
+		}
+	}
+}
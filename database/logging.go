@@ -0,0 +1,36 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/leapzhao/json-store/logger"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// ctxLogger 返回带有请求ID的logger，ctx中没有请求ID时（如后台任务）使用全局logger
+func ctxLogger(ctx context.Context) *zerolog.Logger {
+	if requestID := logger.RequestIDFromContext(ctx); requestID != "" {
+		l := logger.WithContext(requestID)
+		return &l
+	}
+	return &log.Logger
+}
+
+// trackQuery 记录存储操作耗时，超过阈值时输出慢查询警告
+// 用法：defer trackQuery(ctx, threshold, "GetJSONByID")()
+func trackQuery(ctx context.Context, threshold time.Duration, operation string) func() {
+	start := time.Now()
+	return func() {
+		elapsed := time.Since(start)
+		if threshold > 0 && elapsed > threshold {
+			ctxLogger(ctx).Warn().
+				Str("operation", operation).
+				Dur("duration", elapsed).
+				Dur("threshold", threshold).
+				Msg("Slow query detected")
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package database
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/logger"
+	"github.com/leapzhao/json-store/middleware"
+	"github.com/leapzhao/json-store/model"
+	"github.com/rs/zerolog/log"
+)
+
+// captureLogs 把全局logger输出到临时文件，返回读取已写入日志行的函数
+func captureLogs(t *testing.T) func() []map[string]any {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "store.log")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous, stdout := log.Logger, os.Stdout
+	// json格式输出到stdout时logger.Init使用当时的os.Stdout
+	os.Stdout = file
+	var cfg config.Config
+	cfg.Logging.Level = "info"
+	cfg.Logging.Format = "json"
+	cfg.Logging.OutputPath = "stdout"
+	err = logger.Init(cfg)
+	os.Stdout = stdout
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		log.Logger = previous
+		file.Close()
+	})
+
+	return func() []map[string]any {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		var lines []map[string]any
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var line map[string]any
+			if json.Unmarshal(scanner.Bytes(), &line) == nil {
+				lines = append(lines, line)
+			}
+		}
+		return lines
+	}
+}
+
+func TestStoreLogsCarryRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	readLogs := captureLogs(t)
+	store, mock := newMockPostgresStore(t, Options{AllowDuplicateContent: true})
+	mock.ExpectQuery("INSERT INTO json_documents").
+		WillReturnRows(postgresDocumentRow("00000000-0000-0000-0000-0000000000cc", "h", []byte(`{}`)))
+
+	router := gin.New()
+	router.Use(middleware.RequestID(nil))
+	router.POST("/json", func(c *gin.Context) {
+		if _, err := store.StoreJSON(c.Request.Context(), model.StoreInput{JSONData: []byte(`{}`)}); err != nil {
+			t.Error(err)
+		}
+	})
+	req := httptest.NewRequest(http.MethodPost, "/json", nil)
+	req.Header.Set("X-Request-ID", "req-124")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	found := false
+	for _, line := range readLogs() {
+		if line["message"] == "JSON stored in PostgreSQL" {
+			found = true
+			if line["request_id"] != "req-124" {
+				t.Errorf("store log request_id = %v, want req-124", line["request_id"])
+			}
+		}
+	}
+	if !found {
+		t.Error("store did not log the insert")
+	}
+}
+
+func TestSlowQueryWarning(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold time.Duration
+		delay     time.Duration
+		wantWarn  bool
+	}{
+		{name: "slow", threshold: 5 * time.Millisecond, delay: 20 * time.Millisecond, wantWarn: true},
+		{name: "fast", threshold: time.Second, delay: 0},
+		{name: "disabled", threshold: 0, delay: 20 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readLogs := captureLogs(t)
+			store, mock := newMockPostgresStore(t, Options{SlowQueryThreshold: tt.threshold})
+			const id = "00000000-0000-0000-0000-0000000000dd"
+			mock.ExpectQuery("FROM json_documents WHERE id = \\$1").
+				WillDelayFor(tt.delay).
+				WillReturnRows(postgresDocumentRow(id, "h", []byte(`{}`)))
+			if _, err := store.GetJSONByID(context.Background(), id); err != nil {
+				t.Fatal(err)
+			}
+
+			warned := false
+			for _, line := range readLogs() {
+				if line["message"] == "Slow query detected" {
+					warned = true
+					if line["level"] != "warn" || line["operation"] != "GetJSONByID" {
+						t.Errorf("slow query log = %v, want a warning for GetJSONByID", line)
+					}
+				}
+			}
+			if warned != tt.wantWarn {
+				t.Errorf("slow query warning = %v, want %v", warned, tt.wantWarn)
+			}
+		})
+	}
+}
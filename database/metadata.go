@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/leapzhao/json-store/model"
+)
+
+// documentMetadata 新建文档时metadata列的值，未提供时为空对象
+func documentMetadata(input model.StoreInput) (string, error) {
+	if len(input.Metadata) == 0 {
+		return "{}", nil
+	}
+	data, err := json.Marshal(input.Metadata)
+	if err != nil {
+		return "", fmt.Errorf("invalid metadata: %w", err)
+	}
+	return string(data), nil
+}
+
+// documentTags 标签去重并排序后序列化为JSON数组，由各方言的INSERT转换为标签列的类型
+func documentTags(input model.StoreInput) string {
+	if len(input.Tags) == 0 {
+		return "[]"
+	}
+	tags := slices.Clone(input.Tags)
+	slices.Sort(tags)
+	data, _ := json.Marshal(slices.Compact(tags))
+	return string(data)
+}
+
+// metadataQueries 批量合并metadata使用的SQL，按数据库方言提供
+// 合并在Go中完成，两种数据库的语义一致（见mergeMetadata）
+type metadataQueries struct {
+	// Lock 参数：ID，锁定要更新的行并返回当前metadata
+	Lock string
+	// Set 参数：合并后的metadata（JSON）、ID
+	Set string
+}
+
+// mergeMetadata 浅合并：patch的顶层键覆盖current中的同名键，值为null时删除该键
+// 嵌套对象整体替换，不递归合并；值按原始JSON保留，不经过数字转换
+func mergeMetadata(current []byte, patch map[string]any) ([]byte, error) {
+	merged := make(map[string]json.RawMessage)
+	if len(current) > 0 {
+		if err := json.Unmarshal(current, &merged); err != nil {
+			return nil, fmt.Errorf("invalid stored metadata: %w", err)
+		}
+		if merged == nil {
+			merged = make(map[string]json.RawMessage)
+		}
+	}
+	for key, value := range patch {
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		merged[key] = data
+	}
+	return json.Marshal(merged)
+}
+
+// mergeMetadataBatch 在一个事务中逐个合并metadata，单个文档不存在不影响其他文档
+// 结果与updates按位置一一对应
+func mergeMetadataBatch(ctx context.Context, db *sql.DB, queries metadataQueries, updates []model.MetadataUpdate) ([]model.MetadataUpdateResult, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	results := make([]model.MetadataUpdateResult, len(updates))
+	for i, update := range updates {
+		results[i].ID = update.ID
+
+		// 非UUID的ID在PostgreSQL中会报错并中止整个事务，提前按不存在处理
+		if _, err := uuid.Parse(update.ID); err != nil {
+			results[i].Error = "NOT_FOUND"
+			continue
+		}
+
+		var current []byte
+		if err := tx.QueryRowContext(ctx, queries.Lock, update.ID).Scan(&current); err != nil {
+			if err == sql.ErrNoRows {
+				results[i].Error = "NOT_FOUND"
+				continue
+			}
+			return nil, fmt.Errorf("failed to lock document %s: %w", update.ID, err)
+		}
+
+		metadata, err := mergeMetadata(current, update.Metadata)
+		if err != nil {
+			results[i].Error = "INVALID_METADATA"
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, queries.Set, metadata, update.ID); err != nil {
+			return nil, fmt.Errorf("failed to merge metadata for document %s: %w", update.ID, err)
+		}
+		results[i].Success = true
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return results, nil
+}
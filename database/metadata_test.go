@@ -0,0 +1,68 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/leapzhao/json-store/model"
+)
+
+func TestMergeMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		current string
+		patch   map[string]any
+		want    string
+	}{
+		{name: "no stored metadata", current: "", patch: map[string]any{"a": 1}, want: `{"a":1}`},
+		{name: "overwrites top-level key", current: `{"a":1,"b":2}`, patch: map[string]any{"a": "x"}, want: `{"a":"x","b":2}`},
+		{name: "null deletes key", current: `{"a":1,"b":2}`, patch: map[string]any{"a": nil, "c": nil}, want: `{"b":2}`},
+		{
+			name:    "nested object is replaced",
+			current: `{"owner":{"id":1,"name":"a"}}`,
+			patch:   map[string]any{"owner": map[string]any{"id": 2}},
+			want:    `{"owner":{"id":2}}`,
+		},
+		{name: "keeps stored large integer", current: `{"id":9007199254740993}`, patch: map[string]any{"b": true}, want: `{"b":true,"id":9007199254740993}`},
+		{name: "stored null", current: `null`, patch: map[string]any{"a": 1}, want: `{"a":1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeMetadata([]byte(tt.current), tt.patch)
+			if err != nil {
+				t.Fatalf("mergeMetadata error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("mergeMetadata = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostgresStoreWritesMetadata(t *testing.T) {
+	store, mock := newMockPostgresStore(t, Options{AllowDuplicateContent: true})
+	data := []byte(`{"a":1}`)
+
+	// metadata作为第9个参数写入，未提供时为空对象
+	tests := []struct {
+		metadata map[string]any
+		want     string
+	}{
+		{metadata: map[string]any{"source_ip": "192.0.2.7", "owner": "team-a"}, want: `{"owner":"team-a","source_ip":"192.0.2.7"}`},
+		{metadata: nil, want: `{}`},
+	}
+	for _, tt := range tests {
+		mock.ExpectQuery("INSERT INTO json_documents").
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+				sqlmock.AnyArg(), sqlmock.AnyArg(), tt.want, sqlmock.AnyArg()).
+			WillReturnRows(postgresDocumentRow("00000000-0000-0000-0000-000000000192", "h", data))
+		if _, err := store.StoreJSON(context.Background(), model.StoreInput{JSONData: data, Metadata: tt.metadata}); err != nil {
+			t.Fatalf("metadata %v: %v", tt.metadata, err)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
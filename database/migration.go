@@ -0,0 +1,171 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+)
+
+// migration 单个数据库迁移步骤
+type migration struct {
+	Version     int
+	Description string
+	// Statements 按顺序执行的SQL语句
+	Statements []string
+	// Apply 自定义迁移逻辑，在Statements之后执行（可选）
+	Apply func(tx *sql.Tx) error
+}
+
+// migrationDialect 不同数据库的迁移版本表语句
+type migrationDialect struct {
+	// Lock 获取迁移锁，成功时返回1；锁属于当前连接，多个实例同时启动时依次执行迁移
+	Lock string
+	// Unlock 释放迁移锁
+	Unlock        string
+	CreateTable   string
+	InsertVersion string
+	// DisableTimeout 迁移事务开始时执行，取消statement_timeout，避免大表上建索引等操作被中止（可选）
+	DisableTimeout string
+}
+
+// migrationLockName 迁移锁的名称，PostgreSQL的咨询锁使用其哈希值
+const migrationLockName = "json_store_schema_migrations"
+
+var postgresMigrationDialect = migrationDialect{
+	Lock:   `SELECT 1 FROM pg_advisory_lock(hashtext('` + migrationLockName + `'))`,
+	Unlock: `SELECT pg_advisory_unlock(hashtext('` + migrationLockName + `'))`,
+	CreateTable: `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INT PRIMARY KEY,
+			description VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`,
+	InsertVersion:  `INSERT INTO schema_migrations (version, description) VALUES ($1, $2)`,
+	DisableTimeout: `SET LOCAL statement_timeout = 0`,
+}
+
+var mysqlMigrationDialect = migrationDialect{
+	// 等待其他实例完成迁移，超时返回0
+	Lock:   `SELECT GET_LOCK('` + migrationLockName + `', 600)`,
+	Unlock: `SELECT RELEASE_LOCK('` + migrationLockName + `')`,
+	CreateTable: `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INT PRIMARY KEY,
+			description VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
+	`,
+	InsertVersion: `INSERT INTO schema_migrations (version, description) VALUES (?, ?)`,
+}
+
+// runMigrations 按版本顺序执行尚未记录的迁移
+// 持有迁移锁时才读取已执行的版本，全部迁移在持有锁的连接上执行，同时启动的实例不会重复执行同一迁移
+func runMigrations(db *sql.DB, dialect migrationDialect, migrations []migration) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection for migrations: %w", err)
+	}
+	defer conn.Close()
+
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, dialect.Lock).Scan(&locked); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	if locked.Int64 != 1 {
+		return errors.New("failed to acquire migration lock: timed out waiting for another instance")
+	}
+	defer func() {
+		if _, err := conn.ExecContext(ctx, dialect.Unlock); err != nil {
+			log.Warn().Err(err).Msg("Failed to release migration lock")
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, dialect.CreateTable); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if applied[m.Version] {
+			continue
+		}
+
+		if err := applyMigration(ctx, conn, dialect, m); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Description, err)
+		}
+
+		log.Info().
+			Int("version", m.Version).
+			Str("description", m.Description).
+			Msg("Database migration applied")
+	}
+
+	return nil
+}
+
+// appliedVersions 获取已执行的迁移版本
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int]bool, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to scan migration version: %w", err)
+		}
+		applied[version] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating migration versions: %w", err)
+	}
+
+	return applied, nil
+}
+
+// applyMigration 在事务中执行单个迁移并记录版本
+// 注意：MySQL的DDL会隐式提交，因此每个迁移步骤本身也应保持幂等
+func applyMigration(ctx context.Context, conn *sql.Conn, dialect migrationDialect, m migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if dialect.DisableTimeout != "" {
+		if _, err := tx.Exec(dialect.DisableTimeout); err != nil {
+			return err
+		}
+	}
+
+	for _, stmt := range m.Statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	if m.Apply != nil {
+		if err := m.Apply(tx); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(dialect.InsertVersion, m.Version, m.Description); err != nil {
+		return fmt.Errorf("failed to record migration version: %w", err)
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,198 @@
+package database
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// expectMigrationTable 每次runMigrations开始时获取迁移锁、建表并读取已执行的版本
+func expectMigrationTable(mock sqlmock.Sqlmock, dialect migrationDialect, applied ...int) {
+	mock.ExpectQuery(regexp.QuoteMeta(dialect.Lock)).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(1))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	rows := sqlmock.NewRows([]string{"version"})
+	for _, version := range applied {
+		rows.AddRow(version)
+	}
+	mock.ExpectQuery("SELECT version FROM schema_migrations").WillReturnRows(rows)
+}
+
+// expectMigrationUnlock runMigrations结束时释放迁移锁
+func expectMigrationUnlock(mock sqlmock.Sqlmock, dialect migrationDialect) {
+	mock.ExpectExec(regexp.QuoteMeta(dialect.Unlock)).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestRunMigrationsTwiceIsNoop(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	applies := 0
+	migrations := []migration{
+		{Version: 1, Description: "create table", Statements: []string{"CREATE TABLE t (id INT)"}},
+		{Version: 2, Description: "backfill", Apply: func(tx *sql.Tx) error {
+			applies++
+			return nil
+		}},
+	}
+	insertVersion := regexp.QuoteMeta(postgresMigrationDialect.InsertVersion)
+
+	// 第一次：两个迁移各在自己的事务中执行，版本各记录一次
+	expectMigrationTable(mock, postgresMigrationDialect)
+	mock.ExpectBegin()
+	mock.ExpectExec("SET LOCAL statement_timeout = 0").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE t (id INT)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(insertVersion).WithArgs(1, "create table").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("SET LOCAL statement_timeout = 0").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(insertVersion).WithArgs(2, "backfill").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectMigrationUnlock(mock, postgresMigrationDialect)
+	if err := runMigrations(db, postgresMigrationDialect, migrations); err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+
+	// 第二次：版本都已记录，不开启事务也不再写schema_migrations
+	expectMigrationTable(mock, postgresMigrationDialect, 1, 2)
+	expectMigrationUnlock(mock, postgresMigrationDialect)
+	if err := runMigrations(db, postgresMigrationDialect, migrations); err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+
+	if applies != 1 {
+		t.Errorf("Apply ran %d times, want 1", applies)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRunMigrationsAllAppliedIsNoop(t *testing.T) {
+	dialects := []struct {
+		name       string
+		dialect    migrationDialect
+		migrations []migration
+	}{
+		{"postgres", postgresMigrationDialect, postgresMigrations},
+		{"mysql", mysqlMigrationDialect, mysqlMigrations},
+	}
+	for _, d := range dialects {
+		t.Run(d.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			var versions []int
+			for _, m := range d.migrations {
+				versions = append(versions, m.Version)
+			}
+			// 任何未预期的Begin或Exec都会使runMigrations返回错误
+			expectMigrationTable(mock, d.dialect, versions...)
+			expectMigrationUnlock(mock, d.dialect)
+			if err := runMigrations(db, d.dialect, d.migrations); err != nil {
+				t.Fatal(err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestRunMigrationsLockTimeout(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// 其他实例持有锁直到超时：不建表、不读取版本，也不执行任何迁移
+	mock.ExpectQuery(regexp.QuoteMeta(mysqlMigrationDialect.Lock)).
+		WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(0))
+	migrations := []migration{{Version: 1, Description: "create table", Statements: []string{"CREATE TABLE t (id INT)"}}}
+	if err := runMigrations(db, mysqlMigrationDialect, migrations); err == nil {
+		t.Fatal("runMigrations succeeded without the migration lock")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRunMigrationsFailureReleasesLock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// 迁移失败时回滚并释放锁，其他实例可以重试
+	expectMigrationTable(mock, postgresMigrationDialect)
+	mock.ExpectBegin()
+	mock.ExpectExec("SET LOCAL statement_timeout = 0").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE t (id INT)")).WillReturnError(sql.ErrConnDone)
+	mock.ExpectRollback()
+	expectMigrationUnlock(mock, postgresMigrationDialect)
+
+	migrations := []migration{{Version: 1, Description: "create table", Statements: []string{"CREATE TABLE t (id INT)"}}}
+	if err := runMigrations(db, postgresMigrationDialect, migrations); err == nil {
+		t.Fatal("runMigrations succeeded, want the statement error")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMigrationVersionsUnique(t *testing.T) {
+	for name, migrations := range map[string][]migration{
+		"postgres": postgresMigrations,
+		"mysql":    mysqlMigrations,
+	} {
+		// schema_migrations以version为主键，版本必须从1开始连续递增，每个版本只出现一次
+		for i, m := range migrations {
+			if m.Version != i+1 {
+				t.Errorf("%s migration %d has version %d, want %d", name, i, m.Version, i+1)
+			}
+		}
+	}
+}
+
+func TestPostgresRawHashBackfillKeepsUpdatedAt(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var applied []int
+	for _, m := range postgresMigrations {
+		if m.Version != 8 {
+			applied = append(applied, m.Version)
+		}
+	}
+	// 版本8回填raw_hash期间停用updated_at触发器，回填后重新启用
+	expectMigrationTable(mock, postgresMigrationDialect, applied...)
+	mock.ExpectBegin()
+	mock.ExpectExec("SET LOCAL statement_timeout = 0").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ADD COLUMN IF NOT EXISTS raw_hash").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DISABLE TRIGGER update_json_documents_updated_at").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("WHERE raw_hash IS NULL").WillReturnRows(sqlmock.NewRows(nil))
+	mock.ExpectExec("ENABLE TRIGGER update_json_documents_updated_at").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(postgresMigrationDialect.InsertVersion)).WithArgs(8, "add raw_hash column").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectMigrationUnlock(mock, postgresMigrationDialect)
+
+	if err := runMigrations(db, postgresMigrationDialect, postgresMigrations); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
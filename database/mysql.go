@@ -1,121 +1,607 @@
 package database
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/leapzhao/json-store/model"
+	"strconv"
 	"strings"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 )
 
 type MySQLStore struct {
-	db *sql.DB
+	db    *sql.DB
+	opts  Options
+	stmts *statementCache
 }
 
-func NewMySQLStore(host string, port int, user, password, dbname string) (*MySQLStore, error) {
+func NewMySQLStore(host string, port int, user, password, dbname string, opts Options) (*MySQLStore, error) {
 	connStr := fmt.Sprintf(
-		"%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=true&loc=Local",
+		"%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=true&loc=Local&checkConnLiveness=true",
 		user, password, host, port, dbname,
 	)
+	connStr = appendMySQLParams(connStr, opts.DSNParams)
 
-	db, err := sql.Open("mysql", connStr)
+	cfg, err := mysql.ParseDSN(connStr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to mysql: %w", err)
 	}
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to mysql: %w", err)
+	}
+	db := sql.OpenDB(withSessionInit(connector, mysqlSessionInit(opts)...))
 
-	// 测试连接
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping mysql: %w", err)
+	// 测试连接（数据库未就绪时重试）
+	if err := waitForDatabase(db.PingContext, opts.ConnectTimeout, "mysql"); err != nil {
+		db.Close()
+		return nil, err
 	}
 
-	// 设置连接池
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	// 设置连接池（驱动在复用前检查连接存活，checkConnLiveness）
+	configurePool(db, opts)
 
-	store := &MySQLStore{db: db}
+	store := &MySQLStore{db: db, opts: opts}
 
 	// 执行迁移
 	if err := store.Migrate(); err != nil {
 		return nil, fmt.Errorf("failed to migrate: %w", err)
 	}
 
+	// 表结构就绪后才能准备语句
+	store.stmts = newStatementCache(db, mysqlHotQueries(opts)...)
+
 	log.Info().Msg("MySQL connection established")
 	return store, nil
 }
 
+// mysqlSessionInit 新连接上执行的会话设置，max_execution_time只限制只读SELECT
+func mysqlSessionInit(opts Options) []string {
+	if opts.StatementTimeout <= 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("SET SESSION max_execution_time = %d", opts.StatementTimeout.Milliseconds())}
+}
+
 func (s *MySQLStore) Migrate() error {
-	query := `
-	CREATE TABLE IF NOT EXISTS json_documents (
-		id VARCHAR(36) PRIMARY KEY,
-		content_hash VARCHAR(64) UNIQUE NOT NULL,
-		json_data JSON NOT NULL,
-		size BIGINT NOT NULL DEFAULT 0,
-		metadata JSON DEFAULT (JSON_OBJECT()),
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-		INDEX idx_content_hash (content_hash),
-		INDEX idx_created_at (created_at)
-	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
-	
-	-- MySQL 8.0+ 支持JSON索引
-	ALTER TABLE json_documents
-	ADD INDEX idx_json_data ((CAST(json_data AS CHAR(255))));
-	`
+	if err := runMigrations(s.db, mysqlMigrationDialect, mysqlMigrations); err != nil {
+		return err
+	}
+
+	if err := s.syncDedupConstraints(); err != nil {
+		return err
+	}
+
+	return s.syncJSONIndexes()
+}
+
+// syncDedupConstraints 根据去重设置调整content_hash和raw_hash上的唯一索引
+// 不允许重复内容时只有去重使用的列带唯一索引（per_type时为按类型的唯一索引），允许时都不带
+func (s *MySQLStore) syncDedupConstraints() error {
+	for _, c := range s.opts.dedupConstraints() {
+		// 列级UNIQUE约束在MySQL中生成与列同名的唯一索引，恢复时沿用同样的命名
+		index, columns := c.column, c.column
+		if c.perType {
+			// 函数索引使未指定类型（NULL）的文档之间同样去重，需要MySQL 8.0.13+
+			index, columns = "doc_type_"+c.column, "(COALESCE(doc_type, '')), "+c.column
+		}
+		if err := s.syncUniqueIndex(index, columns, c.unique); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncUniqueIndex 移除或建立指定的唯一索引，columns为索引的键定义
+func (s *MySQLStore) syncUniqueIndex(index, columns string, unique bool) error {
+	var count int
+	err := s.db.QueryRow(`
+		SELECT COUNT(*)
+		FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = DATABASE()
+			AND TABLE_NAME = 'json_documents'
+			AND INDEX_NAME = ?
+			AND NON_UNIQUE = 0
+	`, index).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to check %s unique index: %w", index, err)
+	}
+
+	switch {
+	case !unique && count > 0:
+		if _, err := s.db.Exec(`ALTER TABLE json_documents DROP INDEX ` + index); err != nil {
+			return fmt.Errorf("failed to drop %s unique index: %w", index, err)
+		}
+		log.Warn().Str("index", index).Msg("Dropped unique index, no longer used for dedup")
+	case unique && count == 0:
+		if _, err := s.db.Exec(`ALTER TABLE json_documents ADD UNIQUE INDEX ` + index + ` (` + columns + `)`); err != nil {
+			return fmt.Errorf("failed to restore %s unique index (duplicate rows may exist): %w", index, err)
+		}
+		log.Info().Str("index", index).Msg("Restored unique index for dedup")
+	}
+
+	return nil
+}
+
+// mysqlPlaceholder MySQL参数占位符
+func mysqlPlaceholder(int) string {
+	return "?"
+}
+
+// mysqlMigrations MySQL迁移步骤，只能追加新版本，不能修改已发布的步骤
+var mysqlMigrations = []migration{
+	{
+		Version:     1,
+		Description: "create json_documents table",
+		Statements: []string{`
+			CREATE TABLE IF NOT EXISTS json_documents (
+				id VARCHAR(36) PRIMARY KEY,
+				content_hash VARCHAR(64) UNIQUE NOT NULL,
+				json_data JSON NOT NULL,
+				size BIGINT NOT NULL DEFAULT 0,
+				metadata JSON DEFAULT (JSON_OBJECT()),
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				INDEX idx_content_hash (content_hash),
+				INDEX idx_created_at (created_at)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+		},
+	},
+	{
+		// MySQL 8.0+ 支持JSON索引
+		Version:     2,
+		Description: "add json_data index",
+		Apply: func(tx *sql.Tx) error {
+			return addIndexIfNotExists(tx, "json_documents", "idx_json_data", `
+				ALTER TABLE json_documents
+				ADD INDEX idx_json_data ((CAST(json_data AS CHAR(255))))
+			`)
+		},
+	},
+	{
+		Version:     3,
+		Description: "add doc_type column",
+		Apply: func(tx *sql.Tx) error {
+			if err := addColumnIfNotExists(tx, "json_documents", "doc_type", `
+				ALTER TABLE json_documents ADD COLUMN doc_type VARCHAR(64) NULL AFTER content_hash
+			`); err != nil {
+				return err
+			}
+			return addIndexIfNotExists(tx, "json_documents", "idx_doc_type", `
+				ALTER TABLE json_documents ADD INDEX idx_doc_type (doc_type)
+			`)
+		},
+	},
+	{
+		Version:     4,
+		Description: "add compression columns",
+		Apply: func(tx *sql.Tx) error {
+			if err := addColumnIfNotExists(tx, "json_documents", "compression", `
+				ALTER TABLE json_documents ADD COLUMN compression VARCHAR(16) NULL
+			`); err != nil {
+				return err
+			}
+			return addColumnIfNotExists(tx, "json_documents", "compressed_data", `
+				ALTER TABLE json_documents ADD COLUMN compressed_data LONGBLOB NULL
+			`)
+		},
+	},
+	{
+		Version:     5,
+		Description: "add raw_data column",
+		Apply: func(tx *sql.Tx) error {
+			return addColumnIfNotExists(tx, "json_documents", "raw_data", `
+				ALTER TABLE json_documents ADD COLUMN raw_data LONGBLOB NULL
+			`)
+		},
+	},
+	{
+		Version:     6,
+		Description: "add chunked storage",
+		Statements: []string{`
+			CREATE TABLE IF NOT EXISTS json_document_chunks (
+				document_id VARCHAR(36) NOT NULL,
+				seq INT NOT NULL,
+				data LONGBLOB NOT NULL,
+				PRIMARY KEY (document_id, seq),
+				FOREIGN KEY (document_id) REFERENCES json_documents(id) ON DELETE CASCADE
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+		},
+		Apply: func(tx *sql.Tx) error {
+			return addColumnIfNotExists(tx, "json_documents", "chunk_count", `
+				ALTER TABLE json_documents ADD COLUMN chunk_count INT NOT NULL DEFAULT 0
+			`)
+		},
+	},
+	{
+		Version:     7,
+		Description: "add simhash column",
+		Apply: func(tx *sql.Tx) error {
+			return addColumnIfNotExists(tx, "json_documents", "simhash", `
+				ALTER TABLE json_documents ADD COLUMN simhash BIGINT NULL
+			`)
+		},
+	},
+	{
+		Version:     8,
+		Description: "add raw_hash column",
+		Apply: func(tx *sql.Tx) error {
+			if err := addColumnIfNotExists(tx, "json_documents", "raw_hash", `
+				ALTER TABLE json_documents ADD COLUMN raw_hash VARCHAR(64) NULL
+			`); err != nil {
+				return err
+			}
+			return backfillRawHash(tx, rawHashBackfill{
+				Select: `
+					SELECT ` + backfillColumns + `
+					FROM json_documents
+					WHERE raw_hash IS NULL AND id > ?
+					ORDER BY id
+					LIMIT ?
+				`,
+				SelectChunks: mysqlChunkQueries.SelectChunks,
+				Update:       `UPDATE json_documents SET raw_hash = ?, updated_at = updated_at WHERE id = ?`,
+			})
+		},
+	},
+	{
+		Version:     9,
+		Description: "add size index",
+		Apply: func(tx *sql.Tx) error {
+			return addIndexIfNotExists(tx, "json_documents", "idx_size", `
+				ALTER TABLE json_documents ADD INDEX idx_size (size)
+			`)
+		},
+	},
+	{
+		// 版本2的索引只截取文档前255个字符，几乎无法命中，改为按配置的json_indexes建立路径索引
+		Version:     10,
+		Description: "drop json_data prefix index",
+		Apply: func(tx *sql.Tx) error {
+			return dropIndexIfExists(tx, "json_documents", "idx_json_data")
+		},
+	},
+	{
+		Version:     11,
+		Description: "add access tracking columns",
+		Apply: func(tx *sql.Tx) error {
+			if err := addColumnIfNotExists(tx, "json_documents", "access_count", `
+				ALTER TABLE json_documents ADD COLUMN access_count BIGINT NOT NULL DEFAULT 0
+			`); err != nil {
+				return err
+			}
+			return addColumnIfNotExists(tx, "json_documents", "last_accessed_at", `
+				ALTER TABLE json_documents ADD COLUMN last_accessed_at TIMESTAMP NULL
+			`)
+		},
+	},
+	{
+		Version:     12,
+		Description: "add updated_at index",
+		Apply: func(tx *sql.Tx) error {
+			return addIndexIfNotExists(tx, "json_documents", "idx_updated_at", `
+				ALTER TABLE json_documents ADD INDEX idx_updated_at (updated_at, id)
+			`)
+		},
+	},
+	{
+		// 标签区分大小写，与PostgreSQL一致
+		Version:     13,
+		Description: "create label tables",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS json_labels (
+				label VARCHAR(255) COLLATE utf8mb4_bin PRIMARY KEY,
+				document_id VARCHAR(36),
+				version INT NOT NULL DEFAULT 0,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (document_id) REFERENCES json_documents(id) ON DELETE SET NULL
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+			`CREATE TABLE IF NOT EXISTS json_label_versions (
+				label VARCHAR(255) COLLATE utf8mb4_bin NOT NULL,
+				version INT NOT NULL,
+				document_id VARCHAR(36) NOT NULL,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (label, version),
+				FOREIGN KEY (label) REFERENCES json_labels(label) ON DELETE CASCADE,
+				FOREIGN KEY (document_id) REFERENCES json_documents(id) ON DELETE CASCADE
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+		},
+	},
+	{
+		// 多值索引（MySQL 8.0.17+）供 MEMBER OF 查询使用，标签区分大小写，与PostgreSQL一致
+		Version:     14,
+		Description: "add tags column",
+		Apply: func(tx *sql.Tx) error {
+			if err := addColumnIfNotExists(tx, "json_documents", "tags", `
+				ALTER TABLE json_documents ADD COLUMN tags JSON NOT NULL DEFAULT (JSON_ARRAY())
+			`); err != nil {
+				return err
+			}
+			return addIndexIfNotExists(tx, "json_documents", "idx_tags", fmt.Sprintf(`
+				ALTER TABLE json_documents ADD INDEX idx_tags ((CAST(tags AS CHAR(%d) ARRAY)))
+			`, model.MaxTagLength))
+		},
+	},
+	{
+		// 附件名称区分大小写，与PostgreSQL一致
+		Version:     15,
+		Description: "create attachments table",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS json_attachments (
+				document_id VARCHAR(36) NOT NULL,
+				name VARCHAR(128) COLLATE utf8mb4_bin NOT NULL,
+				content_type VARCHAR(255) NOT NULL,
+				size BIGINT NOT NULL,
+				content_hash VARCHAR(64) NOT NULL,
+				data LONGBLOB NOT NULL,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				PRIMARY KEY (document_id, name),
+				FOREIGN KEY (document_id) REFERENCES json_documents(id) ON DELETE CASCADE
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+		},
+	},
+	{
+		Version:     16,
+		Description: "backfill simhash",
+		Apply: func(tx *sql.Tx) error {
+			return backfillSimHash(tx, simHashBackfill{
+				Select: `
+					SELECT ` + backfillColumns + `
+					FROM json_documents
+					WHERE id > ? AND simhash IS NULL
+					ORDER BY id
+					LIMIT ?
+				`,
+				SelectChunks: mysqlChunkQueries.SelectChunks,
+				Update:       `UPDATE json_documents SET simhash = ?, updated_at = updated_at WHERE id = ?`,
+			})
+		},
+	},
+}
+
+// mysqlIDExists 检查文档ID是否已被占用
+const mysqlIDExists = `SELECT COUNT(*) FROM json_documents WHERE id = ?`
+
+// mysqlDocumentColumns 文档查询列，顺序与scanMySQLDocument一致
+const mysqlDocumentColumns = `id, content_hash, COALESCE(doc_type, ''), json_data, size, created_at, updated_at,
+	metadata, COALESCE(compression, ''), compressed_data, raw_data, chunk_count, tags`
+
+// mysqlInsertDocument 插入未分块的文档，内容已存在时只更新updated_at
+const mysqlInsertDocument = `
+	INSERT INTO json_documents (id, content_hash, doc_type, json_data, size, raw_data, simhash, raw_hash, metadata, tags)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, CAST(? AS JSON), CAST(? AS JSON))
+	ON DUPLICATE KEY UPDATE
+		updated_at = CURRENT_TIMESTAMP
+`
+
+// mysqlSelectWhere 按条件查找一个文档
+func mysqlSelectWhere(condition string) string {
+	return `SELECT ` + mysqlDocumentColumns + ` FROM json_documents WHERE ` + condition + ` LIMIT 1`
+}
+
+// mysqlHotQueries 预编译的热点查询：按ID和哈希读取、去重查找、读取分块、插入
+func mysqlHotQueries(opts Options) []string {
+	dedup, _ := opts.dedupCondition("", "", "", mysqlPlaceholder, 1)
+	return []string{
+		mysqlChunkQueries.SelectDocument,
+		mysqlChunkQueries.SelectChunks,
+		mysqlSelectWhere("content_hash = ?"),
+		mysqlSelectWhere(dedup),
+		mysqlInsertDocument,
+	}
+}
+
+// mysqlChunkQueries MySQL分块存储SQL
+var mysqlChunkQueries = chunkQueries{
+	InsertDocument: `
+		INSERT INTO json_documents (id, content_hash, doc_type, json_data, size, chunk_count, simhash, raw_hash, metadata, tags)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, CAST(? AS JSON), CAST(? AS JSON))
+	`,
+	SelectDocument: `SELECT ` + mysqlDocumentColumns + ` FROM json_documents WHERE id = ?`,
+	InsertChunk:    `INSERT INTO json_document_chunks (document_id, seq, data) VALUES (?, ?, ?)`,
+	SelectChunks:   `SELECT data FROM json_document_chunks WHERE document_id = ? ORDER BY seq`,
+}
+
+// scanMySQLDocument 扫描一行文档记录，解析metadata和标签并还原压缩数据
+func scanMySQLDocument(row rowScanner) (*model.JSONDocument, error) {
+	var doc model.JSONDocument
+	var metadataStr, tagsStr sql.NullString
+	var compressed, raw []byte
+
+	err := row.Scan(
+		&doc.ID, &doc.ContentHash, &doc.DocType, &doc.JSONData, &doc.Size,
+		&doc.CreatedAt, &doc.UpdatedAt, &metadataStr, &doc.Compression, &compressed, &raw, &doc.ChunkCount, &tagsStr,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// 解析metadata
+	if metadataStr.Valid && metadataStr.String != "" {
+		if err := json.Unmarshal([]byte(metadataStr.String), &doc.Metadata); err != nil {
+			log.Error().Err(err).Msg("Failed to unmarshal metadata")
+		}
+	}
+	if tagsStr.Valid && tagsStr.String != "" {
+		if err := json.Unmarshal([]byte(tagsStr.String), &doc.Tags); err != nil {
+			log.Error().Err(err).Msg("Failed to unmarshal tags")
+		}
+	}
 
-	_, err := s.db.Exec(query)
+	if err := restoreJSONData(&doc, compressed, raw); err != nil {
+		return nil, err
+	}
+
+	return &doc, nil
+}
+
+// addColumnIfNotExists 列不存在时才执行ALTER TABLE ADD COLUMN
+func addColumnIfNotExists(tx *sql.Tx, table, column, alterQuery string) error {
+	var count int
+	err := tx.QueryRow(`
+		SELECT COUNT(*)
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE()
+			AND TABLE_NAME = ?
+			AND COLUMN_NAME = ?
+	`, table, column).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to check column %s: %w", column, err)
+	}
+
+	if count > 0 {
+		log.Debug().Str("table", table).Str("column", column).Msg("Column already exists, skipping")
+		return nil
+	}
+
+	_, err = tx.Exec(alterQuery)
 	return err
 }
 
-func (s *MySQLStore) StoreJSON(ctx context.Context, jsonData []byte) (*model.JSONDocument, error) {
+// addIndexIfNotExists 索引不存在时才执行ALTER TABLE ADD INDEX
+// MySQL不支持 ADD INDEX IF NOT EXISTS，重复添加会报错导致启动失败
+func addIndexIfNotExists(tx *sql.Tx, table, index, alterQuery string) error {
+	var count int
+	err := tx.QueryRow(`
+		SELECT COUNT(*)
+		FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = DATABASE()
+			AND TABLE_NAME = ?
+			AND INDEX_NAME = ?
+	`, table, index).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to check index %s: %w", index, err)
+	}
+
+	if count > 0 {
+		log.Debug().Str("table", table).Str("index", index).Msg("Index already exists, skipping")
+		return nil
+	}
+
+	// 并发启动的实例可能同时通过检查，仍需兜底忽略重复索引错误
+	return ignoreDuplicateIndex(tx.Exec(alterQuery))
+}
+
+// ignoreDuplicateIndex 忽略索引已存在错误（1061 Duplicate key name）
+func ignoreDuplicateIndex(_ sql.Result, err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1061 {
+		return nil
+	}
+	return err
+}
+
+func (s *MySQLStore) StoreJSON(ctx context.Context, input model.StoreInput) (*model.JSONDocument, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "StoreJSON")()
+
+	input, err := s.opts.storedInput(input)
+	if err != nil {
+		return nil, err
+	}
+	jsonData := input.JSONData
+
 	// 验证JSON
 	if !json.Valid(jsonData) {
 		return nil, fmt.Errorf("invalid JSON data")
 	}
 
 	// 计算哈希值
-	hash := calculateHash(jsonData)
+	hash, err := s.opts.contentHash(jsonData)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := documentMetadata(input)
+	if err != nil {
+		return nil, err
+	}
+	rawHash := calculateRawHash(jsonData)
 	size := int64(len(jsonData))
 
 	// 检查是否已存在
-	if existing, err := s.GetJSONByHash(ctx, hash); err == nil {
-		return existing, nil
+	if !s.opts.AllowDuplicateContent {
+		if existing, err := s.getJSONByDedupHash(ctx, hash, rawHash, input.DocType); err == nil {
+			if err := clientIDExisting(input, existing); err != nil {
+				return nil, err
+			}
+			existing.Existing = true
+			return existing, nil
+		}
+	}
+
+	if input.ID != "" {
+		if err := checkIDAvailable(ctx, s.db, mysqlIDExists, input.ID); err != nil {
+			return nil, err
+		}
 	}
 
 	// MySQL需要单独检查重复（使用ON DUPLICATE KEY UPDATE）
-	id := uuid.New().String()
-	query := `
-		INSERT INTO json_documents (id, content_hash, json_data, size)
-		VALUES (?, ?, ?, ?)
-		ON DUPLICATE KEY UPDATE
-			updated_at = CURRENT_TIMESTAMP
-	`
+	id := newDocumentID(input)
+	if s.opts.shouldChunk(size) {
+		doc, err := storeChunkedDocument(ctx, s.db, mysqlChunkQueries, scanMySQLDocument,
+			id, hash, input, s.opts.ChunkSize)
+		if err != nil {
+			// 并发写入了相同ID时主键冲突
+			if input.ID != "" {
+				if cerr := checkIDAvailable(ctx, s.db, mysqlIDExists, input.ID); cerr != nil {
+					return nil, cerr
+				}
+			}
+			return nil, fmt.Errorf("failed to store JSON: %w", err)
+		}
+
+		ctxLogger(ctx).Info().
+			Str("id", doc.ID).
+			Str("hash", hash).
+			Int64("size", size).
+			Int("chunks", doc.ChunkCount).
+			Msg("JSON stored in MySQL")
 
-	result, err := s.db.ExecContext(ctx, query, id, hash, jsonData, size)
+		return doc, nil
+	}
+
+	result, err := s.stmts.ExecContext(ctx, mysqlInsertDocument,
+		id, hash, nullString(input.DocType), jsonData, size, s.rawData(jsonData), documentSimHash(jsonData), rawHash, metadata, documentTags(input),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to store JSON: %w", err)
 	}
 
 	// 如果是更新，获取已有ID
 	rowsAffected, _ := result.RowsAffected()
+	if input.ID != "" && rowsAffected != 1 {
+		// 指定ID时命中唯一键，说明ID或内容已被并发写入占用
+		if err := checkIDAvailable(ctx, s.db, mysqlIDExists, input.ID); err != nil {
+			return nil, err
+		}
+		return nil, ErrDuplicateContent
+	}
 	if rowsAffected == 0 {
 		// 重复插入，获取已有记录
-		return s.GetJSONByHash(ctx, hash)
+		existing, err := s.getJSONByDedupHash(ctx, hash, rawHash, input.DocType)
+		if err != nil {
+			return nil, err
+		}
+		existing.Existing = true
+		return existing, nil
 	}
 
 	// 获取新插入的记录
-	doc, err := s.GetJSONByID(ctx, id)
+	doc, err := s.getJSONByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Info().
+	ctxLogger(ctx).Info().
 		Str("id", doc.ID).
 		Str("hash", hash).
 		Int64("size", size).
@@ -124,21 +610,219 @@ func (s *MySQLStore) StoreJSON(ctx context.Context, jsonData []byte) (*model.JSO
 	return doc, nil
 }
 
-func (s *MySQLStore) GetJSONByID(ctx context.Context, id string) (*model.JSONDocument, error) {
-	query := `
-		SELECT id, content_hash, json_data, size, created_at, updated_at, metadata
-		FROM json_documents
+// rawData 开启PreserveRawBytes时返回原始字节，否则写入NULL
+func (s *MySQLStore) rawData(jsonData []byte) []byte {
+	if !s.opts.PreserveRawBytes {
+		return nil
+	}
+	return jsonData
+}
+
+// mysqlUpdateQueries MySQL更新文档SQL
+var mysqlUpdateQueries = updateQueries{
+	LockDocument:  `SELECT COALESCE(doc_type, '') FROM json_documents WHERE id = ? FOR UPDATE`,
+	FindDuplicate: `SELECT id FROM json_documents WHERE id <> ? AND %s LIMIT 1`,
+	Placeholder:   mysqlPlaceholder,
+	DeleteChunks:  `DELETE FROM json_document_chunks WHERE document_id = ?`,
+	UpdateDocument: `
+		UPDATE json_documents
+		SET content_hash = ?, doc_type = COALESCE(?, doc_type), json_data = ?, size = ?,
+			raw_data = ?, chunk_count = ?, simhash = ?, raw_hash = ?,
+			compression = NULL, compressed_data = NULL, updated_at = CURRENT_TIMESTAMP
 		WHERE id = ?
-	`
+	`,
+}
 
-	var doc model.JSONDocument
-	var metadataStr sql.NullString
+// mysqlLabelQueries MySQL标签SQL
+// Ensure不用INSERT IGNORE，避免标签过长等错误被降级为警告
+var mysqlLabelQueries = labelQueries{
+	Ensure:        `INSERT INTO json_labels (label) VALUES (?) ON DUPLICATE KEY UPDATE label = label`,
+	Lock:          `SELECT COALESCE(document_id, ''), version FROM json_labels WHERE label = ? FOR UPDATE`,
+	Update:        `UPDATE json_labels SET document_id = ?, version = ?, updated_at = CURRENT_TIMESTAMP WHERE label = ?`,
+	InsertVersion: `INSERT INTO json_label_versions (label, version, document_id) VALUES (?, ?, ?)`,
+	SelectCurrent: `SELECT document_id, version FROM json_labels WHERE label = ? AND document_id IS NOT NULL`,
+	SelectVersion: `SELECT document_id FROM json_label_versions WHERE label = ? AND version = ?`,
+}
 
-	err := s.db.QueryRowContext(ctx, query, id).Scan(
-		&doc.ID, &doc.ContentHash, &doc.JSONData, &doc.Size,
-		&doc.CreatedAt, &doc.UpdatedAt, &metadataStr,
-	)
+func (s *MySQLStore) StoreByLabel(ctx context.Context, label string, input model.StoreInput) (*model.LabeledDocument, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "StoreByLabel")()
+
+	result, err := storeByLabel(ctx, s.db, mysqlTransactionQueries, mysqlChunkQueries, mysqlLabelQueries,
+		scanMySQLDocument, s.opts, label, input)
+	if err != nil {
+		return nil, err
+	}
+	doc := result.Document
+
+	ctxLogger(ctx).Info().
+		Str("label", label).
+		Int("version", result.Version).
+		Str("id", doc.ID).
+		Bool("changed", result.Changed).
+		Msg("JSON label stored in MySQL")
+
+	return result, nil
+}
+
+func (s *MySQLStore) GetJSONByLabel(ctx context.Context, label string, version int) (*model.JSONDocument, int, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "GetJSONByLabel")()
+
+	id, version, err := resolveLabel(ctx, s.db, mysqlLabelQueries, label, version)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	doc, err := s.GetJSONByID(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	return doc, version, nil
+}
+
+func (s *MySQLStore) DocumentLabels(ctx context.Context) (map[string][]string, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "DocumentLabels")()
+
+	return documentLabels(ctx, s.db)
+}
+
+func (s *MySQLStore) SetLabel(ctx context.Context, label, id string) (*model.LabeledDocument, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "SetLabel")()
+
+	return setLabel(ctx, s.db, mysqlLabelQueries, label, id, s.getJSONByID)
+}
+
+func (s *MySQLStore) RestoreTimestamps(ctx context.Context, id string, createdAt, updatedAt time.Time) error {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "RestoreTimestamps")()
+
+	return restoreTimestamps(ctx, s.db, `UPDATE json_documents SET created_at = ?, updated_at = ? WHERE id = ?`, id, createdAt, updatedAt)
+}
+
+// mysqlAttachmentQueries MySQL附件SQL
+var mysqlAttachmentQueries = attachmentQueries{
+	Upsert: `
+		INSERT INTO json_attachments (document_id, name, content_type, size, content_hash, data)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			content_type = VALUES(content_type),
+			size = VALUES(size),
+			content_hash = VALUES(content_hash),
+			data = VALUES(data)
+	`,
+	SelectInfo: `SELECT content_type, size, content_hash, updated_at FROM json_attachments WHERE document_id = ? AND name = ?`,
+	Select:     `SELECT content_type, size, content_hash, updated_at, data FROM json_attachments WHERE document_id = ? AND name = ?`,
+}
+
+func (s *MySQLStore) StoreAttachment(ctx context.Context, documentID, name, contentType string, data []byte) (*model.Attachment, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "StoreAttachment")()
+
+	attachment, err := storeAttachment(ctx, s.db, mysqlAttachmentQueries, mysqlIDExists, documentID, name, contentType, data)
+	if err != nil {
+		return nil, err
+	}
+
+	ctxLogger(ctx).Info().
+		Str("id", documentID).
+		Str("name", name).
+		Int64("size", attachment.Size).
+		Msg("Attachment stored in MySQL")
+
+	return attachment, nil
+}
+
+func (s *MySQLStore) GetAttachment(ctx context.Context, documentID, name string) (*model.Attachment, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "GetAttachment")()
+
+	return getAttachment(ctx, s.db, mysqlAttachmentQueries, documentID, name)
+}
 
+func (s *MySQLStore) UpdateJSON(ctx context.Context, id string, input model.StoreInput) (*model.JSONDocument, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "UpdateJSON")()
+
+	doc, err := updateDocument(ctx, s.db, mysqlUpdateQueries, mysqlChunkQueries, scanMySQLDocument, s.opts, id, input)
+	if err != nil {
+		return nil, err
+	}
+
+	ctxLogger(ctx).Info().
+		Str("id", doc.ID).
+		Str("hash", doc.ContentHash).
+		Int64("size", doc.Size).
+		Msg("JSON updated in MySQL")
+
+	return doc, nil
+}
+
+// mysqlTransactionQueries MySQL原子批量存储SQL
+var mysqlTransactionQueries = transactionQueries{
+	InsertDocument: `
+		INSERT INTO json_documents (id, content_hash, doc_type, json_data, size, raw_data, simhash, raw_hash, metadata, tags)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, CAST(? AS JSON), CAST(? AS JSON))
+	`,
+	Placeholder: mysqlPlaceholder,
+}
+
+func (s *MySQLStore) StoreJSONTransaction(ctx context.Context, inputs []model.StoreInput) ([]*model.JSONDocument, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "StoreJSONTransaction")()
+
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("no JSON data provided")
+	}
+
+	if len(inputs) > 100 {
+		return nil, fmt.Errorf("batch size exceeds limit of 100")
+	}
+
+	docs, err := storeDocumentsAtomically(ctx, s.db, mysqlTransactionQueries, mysqlChunkQueries,
+		scanMySQLDocument, s.opts, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	ctxLogger(ctx).Info().Int("total", len(docs)).Msg("JSON transaction stored")
+
+	return docs, nil
+}
+
+// mysqlMetadataQueries MySQL合并metadata SQL
+var mysqlMetadataQueries = metadataQueries{
+	Lock: `SELECT metadata FROM json_documents WHERE id = ? FOR UPDATE`,
+	Set:  `UPDATE json_documents SET metadata = CAST(? AS JSON) WHERE id = ?`,
+}
+
+func (s *MySQLStore) UpdateMetadataBatch(ctx context.Context, updates []model.MetadataUpdate) ([]model.MetadataUpdateResult, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "UpdateMetadataBatch")()
+
+	if len(updates) > 100 {
+		return nil, fmt.Errorf("batch size exceeds limit of 100")
+	}
+
+	return mergeMetadataBatch(ctx, s.db, mysqlMetadataQueries, updates)
+}
+
+func (s *MySQLStore) GetJSONByID(ctx context.Context, id string) (*model.JSONDocument, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "GetJSONByID")()
+
+	if !s.opts.TrackAccess {
+		return s.getJSONByID(ctx, id)
+	}
+
+	// MySQL不支持UPDATE ... RETURNING，在同一事务中累加访问计数并读取
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE json_documents
+		SET access_count = access_count + 1, last_accessed_at = CURRENT_TIMESTAMP, updated_at = updated_at
+		WHERE id = ?
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record access: %w", err)
+	}
+
+	doc, err := scanMySQLDocument(tx.QueryRowContext(ctx, mysqlChunkQueries.SelectDocument, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("document not found with id: %s", id)
@@ -146,32 +830,54 @@ func (s *MySQLStore) GetJSONByID(ctx context.Context, id string) (*model.JSONDoc
 		return nil, fmt.Errorf("failed to get JSON: %w", err)
 	}
 
-	// 解析metadata
-	if metadataStr.Valid && metadataStr.String != "" {
-		if err := json.Unmarshal([]byte(metadataStr.String), &doc.Metadata); err != nil {
-			log.Error().Err(err).Msg("Failed to unmarshal metadata")
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if err := loadChunks(ctx, s.db, mysqlChunkQueries.SelectChunks, doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// getJSONByID 按ID读取文档，不计入访问次数，用于存储流程内部回读
+func (s *MySQLStore) getJSONByID(ctx context.Context, id string) (*model.JSONDocument, error) {
+	doc, err := scanMySQLDocument(s.stmts.QueryRowContext(ctx, mysqlChunkQueries.SelectDocument, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("document not found with id: %s", id)
 		}
+		return nil, fmt.Errorf("failed to get JSON: %w", err)
 	}
 
-	return &doc, nil
+	if err := loadChunks(ctx, s.stmts, mysqlChunkQueries.SelectChunks, doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
 }
 
 func (s *MySQLStore) GetJSONByHash(ctx context.Context, hash string) (*model.JSONDocument, error) {
-	query := `
-		SELECT id, content_hash, json_data, size, created_at, updated_at, metadata
-		FROM json_documents
-		WHERE content_hash = ?
-		LIMIT 1
-	`
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "GetJSONByHash")()
 
-	var doc model.JSONDocument
-	var metadataStr sql.NullString
+	return s.getJSONByHashColumn(ctx, "content_hash", hash)
+}
 
-	err := s.db.QueryRowContext(ctx, query, hash).Scan(
-		&doc.ID, &doc.ContentHash, &doc.JSONData, &doc.Size,
-		&doc.CreatedAt, &doc.UpdatedAt, &metadataStr,
-	)
+// getJSONByDedupHash 按去重条件查找文档
+func (s *MySQLStore) getJSONByDedupHash(ctx context.Context, hash, rawHash, docType string) (*model.JSONDocument, error) {
+	condition, args := s.opts.dedupCondition(hash, rawHash, docType, mysqlPlaceholder, 1)
+	return s.getJSONWhere(ctx, s.opts.dedupHash(hash, rawHash), condition, args...)
+}
 
+// getJSONByHashColumn 按指定的哈希列查找文档
+func (s *MySQLStore) getJSONByHashColumn(ctx context.Context, column, hash string) (*model.JSONDocument, error) {
+	return s.getJSONWhere(ctx, hash, column+" = ?", hash)
+}
+
+// getJSONWhere 按条件查找一个文档，hash仅用于错误信息
+func (s *MySQLStore) getJSONWhere(ctx context.Context, hash, condition string, args ...any) (*model.JSONDocument, error) {
+	doc, err := scanMySQLDocument(s.stmts.QueryRowContext(ctx, mysqlSelectWhere(condition), args...))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("document not found with hash: %s", hash)
@@ -179,30 +885,161 @@ func (s *MySQLStore) GetJSONByHash(ctx context.Context, hash string) (*model.JSO
 		return nil, fmt.Errorf("failed to get JSON by hash: %w", err)
 	}
 
-	// 解析metadata
-	if metadataStr.Valid && metadataStr.String != "" {
-		if err := json.Unmarshal([]byte(metadataStr.String), &doc.Metadata); err != nil {
-			log.Error().Err(err).Msg("Failed to unmarshal metadata")
+	if err := loadChunks(ctx, s.stmts, mysqlChunkQueries.SelectChunks, doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// GetStorageInfo 存储大小按实际存储形式计算：分块数据或json_data列，另加原始字节（压缩或未压缩）
+func (s *MySQLStore) GetStorageInfo(ctx context.Context, id string) (*model.StorageInfo, error) {
+	query := `
+		SELECT d.size, COALESCE(d.compression, ''), d.chunk_count, d.raw_data IS NOT NULL OR d.compression IS NOT NULL,
+			CASE
+				WHEN d.chunk_count > 0 THEN (
+					SELECT COALESCE(SUM(LENGTH(c.data)), 0)
+					FROM json_document_chunks c WHERE c.document_id = d.id
+				)
+				ELSE JSON_STORAGE_SIZE(d.json_data)
+			END + COALESCE(LENGTH(d.compressed_data), 0) + COALESCE(LENGTH(d.raw_data), 0),
+			d.access_count, d.last_accessed_at
+		FROM json_documents d
+		WHERE d.id = ?
+	`
+
+	info := &model.StorageInfo{HashAlgorithm: "sha256"}
+	var lastAccessed sql.NullTime
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
+		&info.RawSize, &info.Compression, &info.Chunks, &info.RawBytes, &info.StoredSize,
+		&info.AccessCount, &lastAccessed,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("document not found with id: %s", id)
 		}
+		return nil, fmt.Errorf("failed to get storage info: %w", err)
+	}
+	if lastAccessed.Valid {
+		info.LastAccessedAt = &lastAccessed.Time
 	}
 
-	return &doc, nil
+	return info, nil
+}
+
+// FindNearDuplicates 全表比较SimHash，适用于管理分析而非高频查询
+func (s *MySQLStore) FindNearDuplicates(ctx context.Context, excludeID string, simhash uint64, maxDistance, limit int) ([]model.NearDuplicate, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "FindNearDuplicates")()
+
+	query := `
+		SELECT id, content_hash, COALESCE(doc_type, ''), size, created_at, BIT_COUNT(simhash ^ ?) AS distance
+		FROM json_documents
+		WHERE simhash IS NOT NULL AND id <> ?
+		HAVING distance <= ?
+		ORDER BY distance, created_at DESC
+		LIMIT ?
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, int64(simhash), excludeID, maxDistance, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find near duplicates: %w", err)
+	}
+	defer rows.Close()
+
+	matches := make([]model.NearDuplicate, 0, limit)
+	for rows.Next() {
+		var m model.NearDuplicate
+		if err := rows.Scan(&m.ID, &m.ContentHash, &m.DocType, &m.Size, &m.CreatedAt, &m.Distance); err != nil {
+			return nil, fmt.Errorf("failed to scan near duplicate: %w", err)
+		}
+		matches = append(matches, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return matches, nil
+}
+
+func (s *MySQLStore) FindExistingHashes(ctx context.Context, hashes []string) (map[string]string, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "FindExistingHashes")()
+
+	return findExistingHashes(ctx, s.db, mysqlPlaceholder, hashes)
+}
+
+func (s *MySQLStore) FindHashesByPrefix(ctx context.Context, prefix string, limit int) ([]string, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "FindHashesByPrefix")()
+
+	query := `
+		SELECT DISTINCT content_hash
+		FROM json_documents
+		WHERE content_hash LIKE ?
+		ORDER BY content_hash
+		LIMIT ?
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, prefix+"%", limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find hashes by prefix: %w", err)
+	}
+	defer rows.Close()
+
+	hashes := make([]string, 0, limit)
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, fmt.Errorf("failed to scan hash: %w", err)
+		}
+		hashes = append(hashes, hash)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return hashes, nil
 }
 
 func (s *MySQLStore) HealthCheck(ctx context.Context) error {
 	return s.db.PingContext(ctx)
 }
 
+func (s *MySQLStore) WriteCheck(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 写入一条探测记录后回滚，只读副本或磁盘已满时会失败
+	id := uuid.New().String()
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO json_documents (id, content_hash, json_data, size) VALUES (?, ?, '{}', 2)",
+		id, "healthcheck:"+id,
+	)
+	if err != nil {
+		return fmt.Errorf("write check failed: %w", err)
+	}
+
+	return nil
+}
+
 func (s *MySQLStore) Close() error {
+	if err := s.stmts.Close(); err != nil {
+		log.Warn().Err(err).Msg("Failed to close prepared statements")
+	}
 	return s.db.Close()
 }
 
-func (s *MySQLStore) StoreJSONBatch(ctx context.Context, jsonDataList [][]byte) ([]*model.JSONDocument, error) {
-	if len(jsonDataList) == 0 {
+func (s *MySQLStore) StoreJSONBatch(ctx context.Context, inputs []model.StoreInput) ([]*model.JSONDocument, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "StoreJSONBatch")()
+
+	if len(inputs) == 0 {
 		return nil, fmt.Errorf("no JSON data provided")
 	}
 
-	if len(jsonDataList) > 100 {
+	if len(inputs) > 100 {
 		return nil, fmt.Errorf("batch size exceeds limit of 100")
 	}
 
@@ -213,52 +1050,87 @@ func (s *MySQLStore) StoreJSONBatch(ctx context.Context, jsonDataList [][]byte)
 	}
 	defer tx.Rollback()
 
-	results := make([]*model.JSONDocument, 0, len(jsonDataList))
+	results := make([]*model.JSONDocument, 0, len(inputs))
 
 	// 批量插入
-	for i, jsonData := range jsonDataList {
-		// 验证JSON
+	for i, input := range inputs {
+		input, err := s.opts.storedInput(input)
+		if err != nil {
+			ctxLogger(ctx).Warn().Err(err).Int("index", i).Msg("Control character in batch JSON, skipping")
+			continue
+		}
+		jsonData := input.JSONData
+
+		// 验证JSON，空文档单独记录便于排查
+		if len(bytes.TrimSpace(jsonData)) == 0 {
+			ctxLogger(ctx).Warn().Int("index", i).Msg("Empty JSON in batch, skipping")
+			continue
+		}
 		if !json.Valid(jsonData) {
-			log.Warn().Int("index", i).Msg("Invalid JSON in batch, skipping")
+			ctxLogger(ctx).Warn().Int("index", i).Msg("Invalid JSON in batch, skipping")
 			continue
 		}
 
-		hash := calculateHash(jsonData)
+		hash, err := s.opts.contentHash(jsonData)
+		if err != nil {
+			ctxLogger(ctx).Warn().Err(err).Int("index", i).Msg("Unnormalizable JSON in batch, skipping")
+			continue
+		}
+		metadata, err := documentMetadata(input)
+		if err != nil {
+			ctxLogger(ctx).Warn().Err(err).Int("index", i).Msg("Invalid metadata in batch, skipping")
+			continue
+		}
+		rawHash := calculateRawHash(jsonData)
 		size := int64(len(jsonData))
 
 		// 检查是否已存在
-		var existingID string
-		err := tx.QueryRowContext(ctx,
-			"SELECT id FROM json_documents WHERE content_hash = ?",
-			hash,
-		).Scan(&existingID)
-
-		if err == nil {
-			// 已存在，获取完整记录
-			doc, err := s.GetJSONByID(ctx, existingID)
+		if !s.opts.AllowDuplicateContent {
+			var existingID string
+			condition, args := s.opts.dedupCondition(hash, rawHash, input.DocType, mysqlPlaceholder, 1)
+			err := tx.QueryRowContext(ctx, "SELECT id FROM json_documents WHERE "+condition, args...).Scan(&existingID)
+
 			if err == nil {
-				results = append(results, doc)
-				continue
+				// 已存在，获取完整记录
+				doc, err := s.getJSONByID(ctx, existingID)
+				if err == nil {
+					doc.Existing = true
+					results = append(results, doc)
+					continue
+				}
 			}
 		}
 
 		// 插入新记录
 		id := uuid.New().String()
+		if s.opts.shouldChunk(size) {
+			doc, err := insertChunkedDocument(ctx, tx, mysqlChunkQueries, scanMySQLDocument,
+				id, hash, input, s.opts.ChunkSize)
+			if err != nil {
+				ctxLogger(ctx).Error().Err(err).Int("index", i).Msg("Failed to insert JSON in batch")
+				continue
+			}
+			results = append(results, doc)
+			continue
+		}
+
 		query := `
-			INSERT INTO json_documents (id, content_hash, json_data, size)
-			VALUES (?, ?, ?, ?)
+			INSERT INTO json_documents (id, content_hash, doc_type, json_data, size, raw_data, simhash, raw_hash, metadata, tags)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, CAST(? AS JSON), CAST(? AS JSON))
 		`
 
-		_, err = tx.ExecContext(ctx, query, id, hash, jsonData, size)
+		_, err = tx.ExecContext(ctx, query,
+			id, hash, nullString(input.DocType), jsonData, size, s.rawData(jsonData), documentSimHash(jsonData), rawHash, metadata, documentTags(input),
+		)
 		if err != nil {
-			log.Error().Err(err).Int("index", i).Msg("Failed to insert JSON in batch")
+			ctxLogger(ctx).Error().Err(err).Int("index", i).Msg("Failed to insert JSON in batch")
 			continue
 		}
 
 		// 获取插入的记录
-		doc, err := s.GetJSONByID(ctx, id)
+		doc, err := s.getJSONByID(ctx, id)
 		if err != nil {
-			log.Error().Err(err).Str("id", id).Msg("Failed to get inserted document")
+			ctxLogger(ctx).Error().Err(err).Str("id", id).Msg("Failed to get inserted document")
 			continue
 		}
 
@@ -270,12 +1142,14 @@ func (s *MySQLStore) StoreJSONBatch(ctx context.Context, jsonDataList [][]byte)
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	log.Info().Int("total", len(jsonDataList)).Int("success", len(results)).Msg("JSON batch stored")
+	ctxLogger(ctx).Info().Int("total", len(inputs)).Int("success", len(results)).Msg("JSON batch stored")
 
 	return results, nil
 }
 
 func (s *MySQLStore) GetJSONBatch(ctx context.Context, ids []string) ([]*model.JSONDocument, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "GetJSONBatch")()
+
 	if len(ids) == 0 {
 		return nil, fmt.Errorf("no IDs provided")
 	}
@@ -293,11 +1167,11 @@ func (s *MySQLStore) GetJSONBatch(ctx context.Context, ids []string) ([]*model.J
 	}
 
 	query := fmt.Sprintf(`
-		SELECT id, content_hash, json_data, size, created_at, updated_at, metadata
+		SELECT %s
 		FROM json_documents
 		WHERE id IN (%s)
 		ORDER BY created_at DESC
-	`, strings.Join(placeholders, ","))
+	`, mysqlDocumentColumns, strings.Join(placeholders, ","))
 
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -307,36 +1181,186 @@ func (s *MySQLStore) GetJSONBatch(ctx context.Context, ids []string) ([]*model.J
 
 	documents := make([]*model.JSONDocument, 0, len(ids))
 	for rows.Next() {
-		var doc model.JSONDocument
-		var metadataStr sql.NullString
-
-		err := rows.Scan(
-			&doc.ID, &doc.ContentHash, &doc.JSONData, &doc.Size,
-			&doc.CreatedAt, &doc.UpdatedAt, &metadataStr,
-		)
+		doc, err := scanMySQLDocument(rows)
 		if err != nil {
-			log.Error().Err(err).Msg("Failed to scan row in batch")
+			ctxLogger(ctx).Error().Err(err).Msg("Failed to scan row in batch")
 			continue
 		}
+		documents = append(documents, doc)
+	}
 
-		// 解析metadata
-		if metadataStr.Valid && metadataStr.String != "" {
-			if err := json.Unmarshal([]byte(metadataStr.String), &doc.Metadata); err != nil {
-				log.Error().Err(err).Msg("Failed to unmarshal metadata")
-			}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	// 先释放查询连接，再逐个读取分块
+	rows.Close()
+
+	if err := loadChunks(ctx, s.db, mysqlChunkQueries.SelectChunks, documents...); err != nil {
+		return nil, err
+	}
+
+	return documents, nil
+}
+
+func (s *MySQLStore) LargestDocuments(ctx context.Context, limit int) ([]model.DocumentSummary, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "LargestDocuments")()
+
+	query := `
+		SELECT ` + documentSummaryColumns + `
+		FROM json_documents
+		ORDER BY size DESC, id
+		LIMIT ?
+	`
+
+	return querySummaries(ctx, s.db, query, limit)
+}
+
+// FacetCounts JSON_EXTRACT对JSON null返回null字面量而非SQL NULL，按JSON_TYPE排除，与PostgreSQL一致
+func (s *MySQLStore) FacetCounts(ctx context.Context, metaKey string) (map[string]int64, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "FacetCounts")()
+
+	// 键名加引号作为JSON路径成员，允许包含点号等特殊字符
+	path := "$." + strconv.Quote(metaKey)
+	query := `
+		SELECT JSON_UNQUOTE(JSON_EXTRACT(metadata, ?)) AS value, COUNT(*) AS count
+		FROM json_documents
+		WHERE JSON_TYPE(JSON_EXTRACT(metadata, ?)) <> 'NULL'
+		GROUP BY value
+		ORDER BY count DESC, value
+	`
+
+	return queryFacetCounts(ctx, s.db, query, path, path)
+}
+
+// mysqlCountQuery 参数：类型、类型（为空时统计全部）
+const mysqlCountQuery = `SELECT COUNT(*) FROM json_documents WHERE (? = '' OR doc_type = ?)`
+
+// Explain 对查询模板执行EXPLAIN FORMAT=JSON，只规划不执行
+// 字段键名加引号作为JSON路径成员
+func (s *MySQLStore) Explain(ctx context.Context, template string, params map[string]string) (*model.ExplainResult, error) {
+	if err := validateExplain(template, params); err != nil {
+		return nil, err
+	}
+
+	var query string
+	var args []any
+	switch template {
+	case model.ExplainGetByID:
+		query, args = mysqlChunkQueries.SelectDocument, []any{params["id"]}
+	case model.ExplainGetByHash:
+		query, args = mysqlSelectWhere("content_hash = ?"), []any{params["hash"]}
+	case model.ExplainList:
+		query, args = mysqlListQuery, []any{params["doc_type"], params["doc_type"], params["tag"], params["tag"], explainListLimit, 0}
+	case model.ExplainCount:
+		query, args = mysqlCountQuery, []any{params["doc_type"], params["doc_type"]}
+	case model.ExplainJSONField:
+		query = mysqlSelectWhere("JSON_UNQUOTE(JSON_EXTRACT(json_data, ?)) = ?")
+		args = []any{"$." + strconv.Quote(params["key"]), params["value"]}
+	case model.ExplainMetadataFilter:
+		query = mysqlSelectWhere("JSON_UNQUOTE(JSON_EXTRACT(metadata, ?)) = ?")
+		args = []any{"$." + strconv.Quote(params["key"]), params["value"]}
+	}
+
+	var plan []byte
+	if err := s.db.QueryRowContext(ctx, "EXPLAIN FORMAT=JSON "+query, args...).Scan(&plan); err != nil {
+		return nil, fmt.Errorf("failed to explain query: %w", err)
+	}
+
+	result := explainResult(template, query, plan)
+	if template == model.ExplainJSONField {
+		excluded, err := countUnqueryable(ctx, s.db)
+		if err != nil {
+			return nil, err
+		}
+		result.Excluded = excluded
+	}
+	return result, nil
+}
+
+func (s *MySQLStore) CountJSON(ctx context.Context, docType string, estimate bool) (int64, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "CountJSON")()
+
+	if !estimate {
+		var count int64
+		err := s.db.QueryRowContext(ctx, mysqlCountQuery, docType, docType).Scan(&count)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count JSON: %w", err)
 		}
+		return count, nil
+	}
+
+	// 取优化器基于索引统计给出的扫描行数估算
+	query := `EXPLAIN FORMAT=JSON SELECT 1 FROM json_documents`
+	var args []any
+	if docType != "" {
+		query += ` WHERE doc_type = ?`
+		args = append(args, docType)
+	}
+
+	var plan []byte
+	if err := s.db.QueryRowContext(ctx, query, args...).Scan(&plan); err != nil {
+		return 0, fmt.Errorf("failed to estimate JSON count: %w", err)
+	}
+
+	var explained struct {
+		QueryBlock struct {
+			Table struct {
+				Rows int64 `json:"rows_examined_per_scan"`
+			} `json:"table"`
+		} `json:"query_block"`
+	}
+	if err := json.Unmarshal(plan, &explained); err != nil {
+		return 0, fmt.Errorf("failed to parse query plan: %s", plan)
+	}
+
+	return explained.QueryBlock.Table.Rows, nil
+}
 
-		documents = append(documents, &doc)
+// mysqlListQuery 参数：类型、类型（为空时不过滤）、标签、标签（为空时不过滤）、limit、offset
+const mysqlListQuery = `
+	SELECT ` + mysqlDocumentColumns + `
+	FROM json_documents
+	WHERE (? = '' OR doc_type = ?)
+		AND (? = '' OR ? MEMBER OF(tags))
+	ORDER BY created_at DESC
+	LIMIT ? OFFSET ?
+`
+
+func (s *MySQLStore) ListJSON(ctx context.Context, filter model.ListFilter) ([]*model.JSONDocument, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "ListJSON")()
+
+	rows, err := s.db.QueryContext(ctx, mysqlListQuery, filter.DocType, filter.DocType, filter.Tag, filter.Tag, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list JSON: %w", err)
+	}
+	defer rows.Close()
+
+	documents := make([]*model.JSONDocument, 0, filter.Limit)
+	for rows.Next() {
+		doc, err := scanMySQLDocument(rows)
+		if err != nil {
+			ctxLogger(ctx).Error().Err(err).Msg("Failed to scan row in list")
+			continue
+		}
+		documents = append(documents, doc)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating rows: %w", err)
 	}
+	// 先释放查询连接，再逐个读取分块
+	rows.Close()
+
+	if err := loadChunks(ctx, s.db, mysqlChunkQueries.SelectChunks, documents...); err != nil {
+		return nil, err
+	}
 
 	return documents, nil
 }
 
 func (s *MySQLStore) GetStats(ctx context.Context) (*model.DatabaseStats, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "GetStats")()
+
 	stats := &model.DatabaseStats{}
 
 	// 获取基础统计
@@ -375,7 +1399,7 @@ func (s *MySQLStore) GetStats(ctx context.Context) (*model.DatabaseStats, error)
 
 	rows, err := s.db.QueryContext(ctx, dailyQuery)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to get daily stats")
+		ctxLogger(ctx).Error().Err(err).Msg("Failed to get daily stats")
 	} else {
 		defer rows.Close()
 
@@ -384,7 +1408,7 @@ func (s *MySQLStore) GetStats(ctx context.Context) (*model.DatabaseStats, error)
 			var dc model.DayCount
 			err := rows.Scan(&dc.Date, &dc.Count, &dc.Size)
 			if err != nil {
-				log.Error().Err(err).Msg("Failed to scan daily stats")
+				ctxLogger(ctx).Error().Err(err).Msg("Failed to scan daily stats")
 				continue
 			}
 			dailyCounts = append(dailyCounts, dc)
@@ -392,10 +1416,121 @@ func (s *MySQLStore) GetStats(ctx context.Context) (*model.DatabaseStats, error)
 		stats.DailyCounts = dailyCounts
 	}
 
+	// 获取按类型统计
+	typeQuery := `
+		SELECT 
+			COALESCE(doc_type, '') as doc_type,
+			COUNT(*) as count,
+			SUM(size) as size
+		FROM json_documents
+		GROUP BY doc_type
+		ORDER BY count DESC
+	`
+
+	typeRows, err := s.db.QueryContext(ctx, typeQuery)
+	if err != nil {
+		ctxLogger(ctx).Error().Err(err).Msg("Failed to get type stats")
+	} else {
+		defer typeRows.Close()
+
+		typeCounts := make([]model.TypeCount, 0)
+		for typeRows.Next() {
+			var tc model.TypeCount
+			if err := typeRows.Scan(&tc.Type, &tc.Count, &tc.Size); err != nil {
+				ctxLogger(ctx).Error().Err(err).Msg("Failed to scan type stats")
+				continue
+			}
+			typeCounts = append(typeCounts, tc)
+		}
+		stats.TypeCounts = typeCounts
+	}
+
+	// 获取大小分布直方图
+	histogram, err := querySizeHistogram(ctx, s.db, s.opts.SizeBuckets)
+	if err != nil {
+		ctxLogger(ctx).Error().Err(err).Msg("Failed to get size histogram")
+	} else {
+		stats.SizeHistogram = histogram
+	}
+
+	// 获取年龄分布直方图
+	ages, err := queryAgeHistogram(ctx, s.db, mysqlAgeCondition, s.opts.AgeBuckets)
+	if err != nil {
+		ctxLogger(ctx).Error().Err(err).Msg("Failed to get age histogram")
+	} else {
+		stats.AgeHistogram = ages
+	}
+
+	// 获取存储空间和压缩比
+	if err := queryCompressionStats(ctx, s.db, mysqlCompressQueries.Stats, stats); err != nil {
+		ctxLogger(ctx).Error().Err(err).Msg("Failed to get compression stats")
+	}
+
 	return stats, nil
 }
 
+// mysqlIterateQuery 游标分页查询，参数：游标时间、游标ID、每页行数
+var mysqlIterateQuery = `
+	SELECT ` + mysqlDocumentColumns + `
+	FROM json_documents
+	WHERE (created_at, id) > (?, ?)
+	ORDER BY created_at, id
+	LIMIT ?
+`
+
+func (s *MySQLStore) IterateDocuments(ctx context.Context, since time.Time, fn func(*model.JSONDocument) error) error {
+	return iterateDocuments(ctx, s.db, mysqlIterateQuery, scanMySQLDocument, mysqlChunkQueries.SelectChunks, since, fn)
+}
+
+// mysqlModifiedSinceQuery 按修改时间的游标分页查询，参数：游标时间、游标ID、等待时长（微秒）、行数
+var mysqlModifiedSinceQuery = `
+	SELECT ` + mysqlDocumentColumns + `
+	FROM json_documents
+	WHERE (updated_at, id) > (?, ?)
+		AND updated_at <= NOW(6) - INTERVAL ? MICROSECOND
+	ORDER BY updated_at, id
+	LIMIT ?
+`
+
+func (s *MySQLStore) GetJSONModifiedSince(ctx context.Context, since time.Time, afterID string, limit int) ([]*model.JSONDocument, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "GetJSONModifiedSince")()
+
+	return modifiedSince(ctx, s.db, mysqlModifiedSinceQuery, scanMySQLDocument, mysqlChunkQueries.SelectChunks, since, afterID, s.opts.ChangesLag, limit)
+}
+
+var mysqlCompressQueries = compressQueries{
+	Select: `
+		SELECT id, raw_data FROM json_documents
+		WHERE compression IS NULL AND raw_data IS NOT NULL AND chunk_count = 0 AND size >= ?
+		ORDER BY id
+		LIMIT ?
+	`,
+	// 压缩不算内容修改，保留原updated_at
+	Update: `
+		UPDATE json_documents
+		SET compressed_data = ?, compression = ?, raw_data = NULL, updated_at = updated_at
+		WHERE id = ? AND compression IS NULL AND raw_data IS NOT NULL
+	`,
+	Stats: `
+		SELECT COUNT(compression),
+			COALESCE(SUM(
+				CASE WHEN chunk_count > 0 THEN 0 ELSE JSON_STORAGE_SIZE(json_data) END
+					+ COALESCE(LENGTH(compressed_data), 0) + COALESCE(LENGTH(raw_data), 0)
+			), 0) + (SELECT COALESCE(SUM(LENGTH(data)), 0) FROM json_document_chunks)
+		FROM json_documents
+	`,
+	Remaining: `SELECT COUNT(*) FROM json_documents WHERE compression IS NULL AND raw_data IS NOT NULL AND chunk_count = 0 AND size >= ?`,
+}
+
+func (s *MySQLStore) CompressDocuments(ctx context.Context, minSize int64, batchSize int) (*model.CompressResult, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "CompressDocuments")()
+
+	return compressBatch(ctx, s.db, mysqlCompressQueries, minSize, batchSize)
+}
+
 func (s *MySQLStore) GetMetrics(ctx context.Context) (*model.DatabaseMetrics, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "GetMetrics")()
+
 	metrics := &model.DatabaseMetrics{
 		Timestamp: time.Now(),
 	}
@@ -453,7 +1588,7 @@ func (s *MySQLStore) GetMetrics(ctx context.Context) (*model.DatabaseMetrics, er
 
 	rows, err := s.db.QueryContext(ctx, tableQuery)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to get table metrics")
+		ctxLogger(ctx).Error().Err(err).Msg("Failed to get table metrics")
 	} else {
 		defer rows.Close()
 
@@ -465,7 +1600,7 @@ func (s *MySQLStore) GetMetrics(ctx context.Context) (*model.DatabaseMetrics, er
 
 			err := rows.Scan(&ts.Name, &rowsStr, &dataSize, &indexSize, &totalSize)
 			if err != nil {
-				log.Error().Err(err).Msg("Failed to scan table metrics")
+				ctxLogger(ctx).Error().Err(err).Msg("Failed to scan table metrics")
 				continue
 			}
 
@@ -490,3 +1625,8 @@ func (s *MySQLStore) GetMetrics(ctx context.Context) (*model.DatabaseMetrics, er
 
 	return metrics, nil
 }
+
+// ReplicationStatus MySQL暂不检测复制延迟，总是返回nil
+func (s *MySQLStore) ReplicationStatus(ctx context.Context) (*model.ReplicationStatus, error) {
+	return nil, nil
+}
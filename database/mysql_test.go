@@ -0,0 +1,58 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestAddIndexIfNotExists(t *testing.T) {
+	const alter = "ALTER TABLE json_documents ADD INDEX idx_json_data"
+	tests := []struct {
+		name     string
+		existing int
+		alterErr error
+		wantErr  bool
+	}{
+		{name: "index exists", existing: 1},
+		{name: "index missing", existing: 0},
+		{name: "created concurrently", existing: 0, alterErr: &mysql.MySQLError{Number: 1061, Message: "Duplicate key name"}},
+		{name: "alter fails", existing: 0, alterErr: &mysql.MySQLError{Number: 1064, Message: "syntax error"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			mock.ExpectBegin()
+			mock.ExpectQuery("FROM information_schema.STATISTICS").
+				WithArgs("json_documents", "idx_json_data").
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tt.existing))
+			// 索引已存在时不应再执行ALTER TABLE，否则sqlmock报告未预期的语句
+			if tt.existing == 0 {
+				exec := mock.ExpectExec(alter)
+				if tt.alterErr != nil {
+					exec.WillReturnError(tt.alterErr)
+				} else {
+					exec.WillReturnResult(sqlmock.NewResult(0, 0))
+				}
+			}
+
+			tx, err := db.Begin()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = addIndexIfNotExists(tx, "json_documents", "idx_json_data", alter)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("addIndexIfNotExists() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/leapzhao/json-store/model"
+)
+
+func TestPostgresStoreNormalizeFailure(t *testing.T) {
+	// 数字超出float64范围，能通过JSON校验但无法规范化
+	data := []byte(`{"a": 1e400}`)
+	ctx := context.Background()
+
+	// hash_raw：按原始字节计算哈希，照常存储
+	store, mock := newMockPostgresStore(t, Options{})
+	rawHash := calculateRawHash(data)
+	mock.ExpectQuery("WHERE content_hash").WithArgs(rawHash).WillReturnRows(sqlmock.NewRows(nil))
+	mock.ExpectQuery("INSERT INTO json_documents").
+		WithArgs(sqlmock.AnyArg(), rawHash, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(postgresDocumentRow("00000000-0000-0000-0000-000000000190", rawHash, data))
+	if _, err := store.StoreJSON(ctx, model.StoreInput{JSONData: data}); err != nil {
+		t.Fatalf("hash_raw: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	// reject：不查询数据库，直接返回ErrInvalidDocument
+	store, mock = newMockPostgresStore(t, Options{RejectUnnormalizable: true})
+	if _, err := store.StoreJSON(ctx, model.StoreInput{JSONData: data}); !errors.Is(err, ErrInvalidDocument) {
+		t.Errorf("reject: err = %v, want ErrInvalidDocument", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"time"
+
+	"github.com/leapzhao/json-store/config"
+)
+
+// Options 存储行为选项
+type Options struct {
+	// AllowDuplicateContent 允许重复内容，跳过去重检查并总是插入新记录
+	AllowDuplicateContent bool
+	// DedupByRawBytes 按原始字节哈希（raw_hash）去重，否则按规范化内容哈希（content_hash）
+	DedupByRawBytes bool
+	// RejectUnnormalizable 无法规范化的文档返回ErrInvalidDocument，否则按原始字节计算内容哈希
+	RejectUnnormalizable bool
+	// ControlChars JSON字符串中控制字符的处理方式，取值见config.ControlChars*
+	ControlChars string
+	// DedupPerType 按文档类型分别去重，相同内容在每个类型下各保存一份
+	DedupPerType bool
+	// SizeBuckets 文档大小直方图的桶上界（字节，升序），为空时不统计
+	SizeBuckets []int64
+	// AgeBuckets 文档年龄直方图的桶上界（小时，升序），为空时不统计
+	AgeBuckets []int64
+	// PreserveRawBytes 保存原始请求字节到raw_data列，读取时优先返回
+	PreserveRawBytes bool
+	// ExactNumbers 内容哈希中数字按原始文本规范化，否则经float64转换
+	ExactNumbers bool
+	// StoreNormalized 保存规范化形式而不是请求原始字节
+	StoreNormalized bool
+	// ConnectTimeout 启动时等待数据库可连接的最长时间
+	ConnectTimeout time.Duration
+	// SlowQueryThreshold 慢查询阈值，为0时不记录
+	SlowQueryThreshold time.Duration
+	// ChunkThreshold 超过该大小的文档使用分块存储，为0时关闭
+	ChunkThreshold int64
+	// ChunkSize 每个分块的大小
+	ChunkSize int
+	// CoalesceWrites 合并内容相同的并发写入
+	CoalesceWrites bool
+	// TrackAccess 按ID读取时累加访问计数
+	TrackAccess bool
+	// MaxOpenConns、MaxIdleConns 连接池大小
+	MaxOpenConns int
+	MaxIdleConns int
+	// ConnMaxIdleTime 连接最长空闲时间，超过后关闭
+	ConnMaxIdleTime time.Duration
+	// JSONIndexes 仅MySQL：按JSON路径建立的生成列索引
+	JSONIndexes []config.JSONPathIndex
+	// DSNParams 追加到DSN的驱动参数
+	DSNParams map[string]string
+	// StatementTimeout 服务端语句超时，为0时不设置
+	StatementTimeout time.Duration
+	// ChangesLag 增量同步不返回最近该时长内修改的文档
+	ChangesLag time.Duration
+}
+
+// optionsFromConfig 从配置构建存储选项
+func optionsFromConfig(cfg config.Config) Options {
+	// 精确数字需要原始字节才能原样返回，MySQL的JSON类型会把超出int64的整数转为double
+	exactNumbers := cfg.Database.NumberHandling == config.NumberHandlingExact
+	return Options{
+		AllowDuplicateContent: cfg.Database.AllowDuplicateContent,
+		DedupByRawBytes:       cfg.Database.DedupMode == config.DedupRaw,
+		DedupPerType:          cfg.Database.DedupScope == config.DedupScopePerType,
+		RejectUnnormalizable:  cfg.Database.NormalizeFailure == config.NormalizeFailureReject,
+		ControlChars:          cfg.Database.ControlChars,
+		SizeBuckets:           cfg.Database.SizeHistogramBuckets,
+		AgeBuckets:            cfg.Database.AgeHistogramHours,
+		PreserveRawBytes:      cfg.Database.PreserveRawBytes || exactNumbers,
+		ExactNumbers:          exactNumbers,
+		StoreNormalized:       cfg.Database.StoreNormalized,
+		ConnectTimeout:        time.Duration(cfg.Database.ConnectTimeout) * time.Second,
+		SlowQueryThreshold:    time.Duration(cfg.Database.SlowQueryMs) * time.Millisecond,
+		ChunkThreshold:        cfg.Database.ChunkThreshold,
+		ChunkSize:             cfg.Database.ChunkSize,
+		CoalesceWrites:        cfg.Database.CoalesceWrites,
+		TrackAccess:           cfg.Database.TrackAccess,
+		MaxOpenConns:          cfg.Database.MaxConns,
+		MaxIdleConns:          cfg.Database.IdleConns,
+		ConnMaxIdleTime:       time.Duration(cfg.Database.ConnMaxIdleTime) * time.Second,
+		JSONIndexes:           cfg.Database.JSONIndexes,
+		DSNParams:             cfg.Database.Params,
+		StatementTimeout:      time.Duration(cfg.Database.StatementTimeoutMs) * time.Millisecond,
+		ChangesLag:            time.Duration(cfg.Database.ChangesLagMs) * time.Millisecond,
+	}
+}
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// validateIdleAfter 连接空闲超过该时间后，复用前先做一次轻量检查
+const validateIdleAfter = 30 * time.Second
+
+// configurePool 按配置设置连接池
+// ConnMaxIdleTime 应小于数据库或代理的空闲断开时间，让空闲连接在被静默断开前主动回收
+func configurePool(db *sql.DB, opts Options) {
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+	db.SetMaxIdleConns(opts.MaxIdleConns)
+	db.SetConnMaxLifetime(5 * time.Minute)
+	db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+}
+
+// withSessionInit 包装驱动连接器，新建连接后先执行statements再交给连接池，没有语句时原样返回
+// 用于设置会话级参数，连接池中的每个连接都会执行一次，连接复用时不再执行
+func withSessionInit(connector driver.Connector, statements ...string) driver.Connector {
+	if len(statements) == 0 {
+		return connector
+	}
+	return &sessionInitConnector{Connector: connector, statements: statements}
+}
+
+type sessionInitConnector struct {
+	driver.Connector
+	statements []string
+}
+
+func (c *sessionInitConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	execer, ok := conn.(driver.ExecerContext)
+	if !ok {
+		conn.Close()
+		return nil, fmt.Errorf("driver connection does not support session init statements")
+	}
+	for _, stmt := range c.statements {
+		if _, err := execer.ExecContext(ctx, stmt, nil); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to init session with %q: %w", stmt, err)
+		}
+	}
+	return conn, nil
+}
+
+// validatingConnector 包装驱动连接器，返回的连接在空闲较久后复用前会先ping
+// 用于驱动本身不检查连接存活的情况（lib/pq），避免把已被断开的连接交给查询导致 bad connection
+type validatingConnector struct {
+	driver.Connector
+}
+
+func (c *validatingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vc := &validatingConn{Conn: conn}
+	vc.touch()
+	return vc, nil
+}
+
+// validatingConn 记录连接归还连接池的时间，并转发database/sql使用的可选接口
+type validatingConn struct {
+	driver.Conn
+	lastUsed atomic.Int64
+}
+
+func (c *validatingConn) touch() {
+	c.lastUsed.Store(time.Now().UnixNano())
+}
+
+// ResetSession 连接从连接池取出复用前调用，返回ErrBadConn时database/sql丢弃该连接并换一个
+func (c *validatingConn) ResetSession(ctx context.Context) error {
+	idle := time.Since(time.Unix(0, c.lastUsed.Load()))
+	if idle > validateIdleAfter {
+		if err := c.Ping(ctx); err != nil {
+			return driver.ErrBadConn
+		}
+	}
+
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+// IsValid 连接归还连接池时调用
+func (c *validatingConn) IsValid() bool {
+	c.touch()
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+func (c *validatingConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *validatingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if execer, ok := c.Conn.(driver.ExecerContext); ok {
+		return execer.ExecContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *validatingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if queryer, ok := c.Conn.(driver.QueryerContext); ok {
+		return queryer.QueryContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *validatingConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *validatingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin() //nolint:staticcheck // 驱动不支持BeginTx时的回退
+}
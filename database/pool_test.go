@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeConnector 每次Connect返回一个新的fakeConn并计数，dead为true时已有连接的Ping失败
+type fakeConnector struct {
+	connects atomic.Int32
+	dead     atomic.Bool
+}
+
+func (c *fakeConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	c.connects.Add(1)
+	return &fakeConn{connector: c, generation: c.connects.Load()}, nil
+}
+
+func (c *fakeConnector) Driver() driver.Driver { return nil }
+
+type fakeConn struct {
+	connector  *fakeConnector
+	generation int32
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+// Ping 模拟数据库或代理静默断开了空闲连接：断开前建立的连接都不可用
+func (c *fakeConn) Ping(ctx context.Context) error {
+	if c.connector.dead.Load() && c.generation == 1 {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+func TestConfigurePoolIdleTime(t *testing.T) {
+	connector := &fakeConnector{}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	configurePool(db, Options{MaxOpenConns: 1, MaxIdleConns: 1, ConnMaxIdleTime: 50 * time.Millisecond})
+
+	ctx := context.Background()
+	if err := db.PingContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// 连接池的清理协程至少每秒运行一次
+	deadline := time.Now().Add(3 * time.Second)
+	for db.Stats().MaxIdleTimeClosed == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("idle connection was not closed, stats %+v", db.Stats())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := connector.connects.Load(); got != 2 {
+		t.Errorf("connects = %d, want 2 (idle connection must not be reused)", got)
+	}
+}
+
+func TestValidatingConnectorDropsStaleConnection(t *testing.T) {
+	connector := &fakeConnector{}
+	db := sql.OpenDB(&validatingConnector{Connector: connector})
+	defer db.Close()
+	db.SetMaxIdleConns(1)
+
+	var conn *validatingConn
+	ctx := context.Background()
+	raw, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw.Raw(func(driverConn any) error {
+		conn = driverConn.(*validatingConn)
+		return nil
+	})
+	raw.Close()
+
+	// 空闲未超过validateIdleAfter时不检查，直接复用
+	if err := db.PingContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := connector.connects.Load(); got != 1 {
+		t.Fatalf("connects = %d, want 1", got)
+	}
+
+	// 空闲超过validateIdleAfter且已被断开，复用前检查失败，换一个新连接
+	connector.dead.Store(true)
+	conn.lastUsed.Store(time.Now().Add(-validateIdleAfter - time.Second).UnixNano())
+	if err := db.PingContext(ctx); err != nil {
+		t.Fatalf("query got the stale connection: %v", err)
+	}
+	if got := connector.connects.Load(); got != 2 {
+		t.Errorf("connects = %d, want 2", got)
+	}
+}
@@ -1,145 +1,762 @@
 package database
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/leapzhao/json-store/model"
 
 	"github.com/google/uuid"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/rs/zerolog/log"
 )
 
 type PostgresStore struct {
-	db *sql.DB
+	db    *sql.DB
+	opts  Options
+	stmts *statementCache
 }
 
-func NewPostgresStore(host string, port int, user, password, dbname, sslmode string) (*PostgresStore, error) {
+func NewPostgresStore(host string, port int, user, password, dbname, sslmode string, opts Options) (*PostgresStore, error) {
 	connStr := fmt.Sprintf(
 		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 		host, port, user, password, dbname, sslmode,
 	)
+	connStr = appendPostgresParams(connStr, opts.DSNParams)
 
-	db, err := sql.Open("postgres", connStr)
+	connector, err := pq.NewConnector(connStr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
 	}
+	db := sql.OpenDB(&validatingConnector{Connector: withSessionInit(connector, postgresSessionInit(opts)...)})
 
-	// 测试连接
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping postgres: %w", err)
+	// 测试连接（数据库未就绪时重试）
+	if err := waitForDatabase(db.PingContext, opts.ConnectTimeout, "postgres"); err != nil {
+		db.Close()
+		return nil, err
 	}
 
 	// 设置连接池
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	configurePool(db, opts)
 
-	store := &PostgresStore{db: db}
+	store := &PostgresStore{db: db, opts: opts}
 
 	// 执行迁移
 	if err := store.Migrate(); err != nil {
 		return nil, fmt.Errorf("failed to migrate: %w", err)
 	}
 
+	// 表结构就绪后才能准备语句
+	store.stmts = newStatementCache(db, postgresHotQueries(opts)...)
+
 	log.Info().Msg("PostgreSQL connection established")
 	return store, nil
 }
 
+// postgresSessionInit 新连接上执行的会话设置
+func postgresSessionInit(opts Options) []string {
+	if opts.StatementTimeout <= 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("SET statement_timeout = %d", opts.StatementTimeout.Milliseconds())}
+}
+
 func (s *PostgresStore) Migrate() error {
-	query := `
-	CREATE TABLE IF NOT EXISTS json_documents (
-		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-		content_hash VARCHAR(64) UNIQUE NOT NULL,
-		json_data JSONB NOT NULL,
-		size BIGINT NOT NULL DEFAULT 0,
-		metadata JSONB DEFAULT '{}',
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-	
-	CREATE INDEX IF NOT EXISTS idx_content_hash ON json_documents(content_hash);
-	CREATE INDEX IF NOT EXISTS idx_json_data_gin ON json_documents USING GIN(json_data);
-	CREATE INDEX IF NOT EXISTS idx_created_at ON json_documents(created_at);
-	
-	CREATE OR REPLACE FUNCTION update_updated_at_column()
-	RETURNS TRIGGER AS $$
-	BEGIN
-		NEW.updated_at = CURRENT_TIMESTAMP;
-		RETURN NEW;
-	END;
-	$$ language 'plpgsql';
-	
-	DROP TRIGGER IF EXISTS update_json_documents_updated_at ON json_documents;
-	CREATE TRIGGER update_json_documents_updated_at
-		BEFORE UPDATE ON json_documents
-		FOR EACH ROW
-		EXECUTE FUNCTION update_updated_at_column();
-	`
+	if err := runMigrations(s.db, postgresMigrationDialect, postgresMigrations); err != nil {
+		return err
+	}
+
+	return s.syncDedupConstraints()
+}
+
+// syncDedupConstraints 根据去重设置调整content_hash和raw_hash上的唯一约束
+// 不允许重复内容时只有去重使用的列带唯一约束（per_type时为按类型的唯一索引），允许时都不带
+func (s *PostgresStore) syncDedupConstraints() error {
+	for _, c := range s.opts.dedupConstraints() {
+		sync := s.syncUniqueConstraint
+		if c.perType {
+			sync = s.syncTypeScopedIndex
+		}
+		if err := sync(c.column, c.unique); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncUniqueConstraint 移除或恢复指定列上的唯一约束
+func (s *PostgresStore) syncUniqueConstraint(column string, unique bool) error {
+	constraint := "json_documents_" + column + "_key"
+
+	var exists bool
+	err := s.db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM pg_constraint
+			WHERE conname = $1 AND conrelid = 'json_documents'::regclass
+		)
+	`, constraint).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check %s constraint: %w", column, err)
+	}
+
+	switch {
+	case !unique && exists:
+		if _, err := s.db.Exec(`ALTER TABLE json_documents DROP CONSTRAINT ` + constraint); err != nil {
+			return fmt.Errorf("failed to drop %s unique constraint: %w", column, err)
+		}
+		log.Warn().Str("column", column).Msg("Dropped unique constraint, column no longer used for dedup")
+	case unique && !exists:
+		if _, err := s.db.Exec(`ALTER TABLE json_documents ADD CONSTRAINT ` + constraint + ` UNIQUE (` + column + `)`); err != nil {
+			return fmt.Errorf("failed to restore %s unique constraint (duplicate rows may exist): %w", column, err)
+		}
+		log.Info().Str("column", column).Msg("Restored unique constraint for dedup")
+	}
+
+	return nil
+}
 
-	_, err := s.db.Exec(query)
-	return err
+// syncTypeScopedIndex 移除或建立(doc_type, 指定列)上的唯一索引
+// 约束不支持表达式，使用唯一索引使未指定类型（NULL）的文档之间同样去重
+func (s *PostgresStore) syncTypeScopedIndex(column string, unique bool) error {
+	index := "json_documents_doc_type_" + column + "_key"
+
+	var exists bool
+	err := s.db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM pg_indexes
+			WHERE indexname = $1 AND tablename = 'json_documents'
+		)
+	`, index).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check per-type %s index: %w", column, err)
+	}
+
+	switch {
+	case !unique && exists:
+		if _, err := s.db.Exec(`DROP INDEX ` + index); err != nil {
+			return fmt.Errorf("failed to drop per-type %s unique index: %w", column, err)
+		}
+		log.Warn().Str("column", column).Msg("Dropped per-type unique index, no longer used for dedup")
+	case unique && !exists:
+		if _, err := s.db.Exec(`CREATE UNIQUE INDEX ` + index + ` ON json_documents ((COALESCE(doc_type, '')), ` + column + `)`); err != nil {
+			return fmt.Errorf("failed to create per-type %s unique index (duplicate rows may exist): %w", column, err)
+		}
+		log.Info().Str("column", column).Msg("Created per-type unique index for dedup")
+	}
+
+	return nil
+}
+
+// postgresPlaceholder PostgreSQL按序号编号的参数占位符
+func postgresPlaceholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+// postgresMigrations PostgreSQL迁移步骤，只能追加新版本，不能修改已发布的步骤
+var postgresMigrations = []migration{
+	{
+		Version:     1,
+		Description: "create json_documents table",
+		Statements: []string{`
+			CREATE TABLE IF NOT EXISTS json_documents (
+				id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+				content_hash VARCHAR(64) UNIQUE NOT NULL,
+				json_data JSONB NOT NULL,
+				size BIGINT NOT NULL DEFAULT 0,
+				metadata JSONB DEFAULT '{}',
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_content_hash ON json_documents(content_hash)`,
+			`CREATE INDEX IF NOT EXISTS idx_json_data_gin ON json_documents USING GIN(json_data)`,
+			`CREATE INDEX IF NOT EXISTS idx_created_at ON json_documents(created_at)`,
+		},
+	},
+	{
+		Version:     2,
+		Description: "add updated_at trigger",
+		Statements: []string{`
+			CREATE OR REPLACE FUNCTION update_updated_at_column()
+			RETURNS TRIGGER AS $$
+			BEGIN
+				NEW.updated_at = CURRENT_TIMESTAMP;
+				RETURN NEW;
+			END;
+			$$ language 'plpgsql'`,
+			`DROP TRIGGER IF EXISTS update_json_documents_updated_at ON json_documents`,
+			`CREATE TRIGGER update_json_documents_updated_at
+				BEFORE UPDATE ON json_documents
+				FOR EACH ROW
+				EXECUTE FUNCTION update_updated_at_column()`,
+		},
+	},
+	{
+		Version:     3,
+		Description: "add doc_type column",
+		Statements: []string{
+			`ALTER TABLE json_documents ADD COLUMN IF NOT EXISTS doc_type VARCHAR(64)`,
+			`CREATE INDEX IF NOT EXISTS idx_doc_type ON json_documents(doc_type)`,
+		},
+	},
+	{
+		Version:     4,
+		Description: "add compression columns",
+		Statements: []string{
+			`ALTER TABLE json_documents ADD COLUMN IF NOT EXISTS compression VARCHAR(16)`,
+			`ALTER TABLE json_documents ADD COLUMN IF NOT EXISTS compressed_data BYTEA`,
+		},
+	},
+	{
+		Version:     5,
+		Description: "add raw_data column",
+		Statements: []string{
+			`ALTER TABLE json_documents ADD COLUMN IF NOT EXISTS raw_data BYTEA`,
+		},
+	},
+	{
+		Version:     6,
+		Description: "add chunked storage",
+		Statements: []string{
+			`ALTER TABLE json_documents ADD COLUMN IF NOT EXISTS chunk_count INT NOT NULL DEFAULT 0`,
+			`CREATE TABLE IF NOT EXISTS json_document_chunks (
+				document_id UUID NOT NULL REFERENCES json_documents(id) ON DELETE CASCADE,
+				seq INT NOT NULL,
+				data BYTEA NOT NULL,
+				PRIMARY KEY (document_id, seq)
+			)`,
+		},
+	},
+	{
+		Version:     7,
+		Description: "add simhash column",
+		Statements: []string{
+			`ALTER TABLE json_documents ADD COLUMN IF NOT EXISTS simhash BIGINT`,
+		},
+	},
+	{
+		Version:     8,
+		Description: "add raw_hash column",
+		Statements: []string{
+			`ALTER TABLE json_documents ADD COLUMN IF NOT EXISTS raw_hash VARCHAR(64)`,
+		},
+		// 回填派生列不算修改文档，期间停用updated_at触发器
+		Apply: func(tx *sql.Tx) error {
+			return postgresWithoutUpdatedAtTrigger(tx, func() error {
+				return backfillRawHash(tx, postgresRawHashBackfill)
+			})
+		},
+	},
+	{
+		Version:     9,
+		Description: "add size index",
+		Statements: []string{
+			`CREATE INDEX IF NOT EXISTS idx_size ON json_documents(size)`,
+		},
+	},
+	{
+		Version:     10,
+		Description: "add access tracking columns",
+		Statements: []string{
+			`ALTER TABLE json_documents ADD COLUMN IF NOT EXISTS access_count BIGINT NOT NULL DEFAULT 0`,
+			`ALTER TABLE json_documents ADD COLUMN IF NOT EXISTS last_accessed_at TIMESTAMP`,
+		},
+	},
+	{
+		Version:     11,
+		Description: "add updated_at index",
+		Statements: []string{
+			`CREATE INDEX IF NOT EXISTS idx_updated_at ON json_documents(updated_at, id)`,
+		},
+	},
+	{
+		Version:     12,
+		Description: "create label tables",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS json_labels (
+				label VARCHAR(255) PRIMARY KEY,
+				document_id UUID REFERENCES json_documents(id) ON DELETE SET NULL,
+				version INT NOT NULL DEFAULT 0,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE TABLE IF NOT EXISTS json_label_versions (
+				label VARCHAR(255) NOT NULL REFERENCES json_labels(label) ON DELETE CASCADE,
+				version INT NOT NULL,
+				document_id UUID NOT NULL REFERENCES json_documents(id) ON DELETE CASCADE,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (label, version)
+			)`,
+		},
+	},
+	{
+		Version:     13,
+		Description: "add tags column",
+		Statements: []string{
+			`ALTER TABLE json_documents ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}'`,
+			`CREATE INDEX IF NOT EXISTS idx_tags ON json_documents USING GIN(tags)`,
+		},
+	},
+	{
+		Version:     14,
+		Description: "create attachments table",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS json_attachments (
+				document_id UUID NOT NULL REFERENCES json_documents(id) ON DELETE CASCADE,
+				name VARCHAR(128) NOT NULL,
+				content_type VARCHAR(255) NOT NULL,
+				size BIGINT NOT NULL,
+				content_hash VARCHAR(64) NOT NULL,
+				data BYTEA NOT NULL,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (document_id, name)
+			)`,
+		},
+	},
+	{
+		Version:     15,
+		Description: "keep updated_at on maintenance updates",
+		Statements: []string{
+			// 访问计数、压缩原始字节、派生列回填等维护操作不算修改文档，内容相关的列都不变时保留updated_at，
+			// 避免影响条件请求，与MySQL中这些UPDATE显式保留updated_at一致；
+			// 原始字节按raw_hash比较，压缩时raw_data移入compressed_data，raw_hash不变
+			`CREATE OR REPLACE FUNCTION update_updated_at_column()
+			RETURNS TRIGGER AS $$
+			BEGIN
+				IF NEW.content_hash IS NOT DISTINCT FROM OLD.content_hash
+					AND NEW.raw_hash IS NOT DISTINCT FROM OLD.raw_hash
+					AND NEW.size IS NOT DISTINCT FROM OLD.size
+					AND NEW.doc_type IS NOT DISTINCT FROM OLD.doc_type
+					AND NEW.metadata IS NOT DISTINCT FROM OLD.metadata
+					AND NEW.tags IS NOT DISTINCT FROM OLD.tags THEN
+					RETURN NEW;
+				END IF;
+				NEW.updated_at = CURRENT_TIMESTAMP;
+				RETURN NEW;
+			END;
+			$$ language 'plpgsql'`,
+		},
+	},
+	{
+		// simhash不在触发器比较的内容列中，回填不改变updated_at
+		Version:     16,
+		Description: "backfill simhash",
+		Apply: func(tx *sql.Tx) error {
+			return backfillSimHash(tx, simHashBackfill{
+				Select: `
+					SELECT ` + backfillColumns + `
+					FROM json_documents
+					WHERE id > $1 AND simhash IS NULL
+					ORDER BY id
+					LIMIT $2
+				`,
+				SelectChunks: postgresChunkQueries.SelectChunks,
+				Update:       `UPDATE json_documents SET simhash = $1 WHERE id = $2`,
+			})
+		},
+	},
 }
 
-func (s *PostgresStore) StoreJSON(ctx context.Context, jsonData []byte) (*model.JSONDocument, error) {
+// postgresRawHashBackfill 回填raw_hash为空的文档
+var postgresRawHashBackfill = rawHashBackfill{
+	Select: `
+		SELECT ` + backfillColumns + `
+		FROM json_documents
+		WHERE raw_hash IS NULL AND id > $1
+		ORDER BY id
+		LIMIT $2
+	`,
+	SelectChunks: postgresChunkQueries.SelectChunks,
+	Update:       `UPDATE json_documents SET raw_hash = $1 WHERE id = $2`,
+}
+
+// postgresWithoutUpdatedAtTrigger 在迁移事务中停用updated_at触发器执行fn，用于回填派生列等不算修改文档的更新
+// ALTER TABLE在PostgreSQL中是事务性的，fn失败时随事务回滚，触发器保持启用
+func postgresWithoutUpdatedAtTrigger(tx *sql.Tx, fn func() error) error {
+	if _, err := tx.Exec(`ALTER TABLE json_documents DISABLE TRIGGER update_json_documents_updated_at`); err != nil {
+		return fmt.Errorf("failed to disable updated_at trigger: %w", err)
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`ALTER TABLE json_documents ENABLE TRIGGER update_json_documents_updated_at`); err != nil {
+		return fmt.Errorf("failed to enable updated_at trigger: %w", err)
+	}
+	return nil
+}
+
+// postgresIDExists 检查文档ID是否已被占用
+const postgresIDExists = `SELECT COUNT(*) FROM json_documents WHERE id = $1`
+
+// postgresDocumentColumns 文档查询列，顺序与scanPostgresDocument一致
+const postgresDocumentColumns = `id, content_hash, COALESCE(doc_type, ''), json_data, size, created_at, updated_at,
+	metadata, COALESCE(compression, ''), compressed_data, raw_data, chunk_count, array_to_json(tags)`
+
+// postgresInsertDocument 插入未分块的文档并返回完整记录
+const postgresInsertDocument = `
+	INSERT INTO json_documents (id, content_hash, doc_type, json_data, size, raw_data, simhash, raw_hash, metadata, tags)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9::jsonb, ARRAY(SELECT jsonb_array_elements_text($10::jsonb)))
+	RETURNING ` + postgresDocumentColumns
+
+// postgresGetTracked 读取文档并累加访问计数（track_access）
+const postgresGetTracked = `
+	UPDATE json_documents
+	SET access_count = access_count + 1, last_accessed_at = CURRENT_TIMESTAMP
+	WHERE id = $1
+	RETURNING ` + postgresDocumentColumns
+
+// postgresSelectWhere 按条件查找一个文档
+func postgresSelectWhere(condition string) string {
+	return `SELECT ` + postgresDocumentColumns + ` FROM json_documents WHERE ` + condition + ` LIMIT 1`
+}
+
+// postgresHotQueries 预编译的热点查询：按ID和哈希读取、去重查找、读取分块、插入
+func postgresHotQueries(opts Options) []string {
+	dedup, _ := opts.dedupCondition("", "", "", postgresPlaceholder, 1)
+	queries := []string{
+		postgresChunkQueries.SelectDocument,
+		postgresChunkQueries.SelectChunks,
+		postgresSelectWhere("content_hash = $1"),
+		postgresSelectWhere(dedup),
+		postgresInsertDocument,
+	}
+	if opts.TrackAccess {
+		queries = append(queries, postgresGetTracked)
+	}
+	return queries
+}
+
+// postgresChunkQueries PostgreSQL分块存储SQL
+var postgresChunkQueries = chunkQueries{
+	InsertDocument: `
+		INSERT INTO json_documents (id, content_hash, doc_type, json_data, size, chunk_count, simhash, raw_hash, metadata, tags)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9::jsonb, ARRAY(SELECT jsonb_array_elements_text($10::jsonb)))
+	`,
+	SelectDocument: `SELECT ` + postgresDocumentColumns + ` FROM json_documents WHERE id = $1`,
+	InsertChunk:    `INSERT INTO json_document_chunks (document_id, seq, data) VALUES ($1, $2, $3)`,
+	SelectChunks:   `SELECT data FROM json_document_chunks WHERE document_id = $1 ORDER BY seq`,
+}
+
+// scanPostgresDocument 扫描一行文档记录，解析metadata和标签并还原压缩数据
+func scanPostgresDocument(row rowScanner) (*model.JSONDocument, error) {
+	var doc model.JSONDocument
+	var metadata, compressed, raw, tags []byte
+
+	err := row.Scan(
+		&doc.ID, &doc.ContentHash, &doc.DocType, &doc.JSONData, &doc.Size,
+		&doc.CreatedAt, &doc.UpdatedAt, &metadata, &doc.Compression, &compressed, &raw, &doc.ChunkCount, &tags,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// 解析metadata
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &doc.Metadata); err != nil {
+			log.Error().Err(err).Msg("Failed to unmarshal metadata")
+		}
+	}
+	if len(tags) > 0 {
+		if err := json.Unmarshal(tags, &doc.Tags); err != nil {
+			log.Error().Err(err).Msg("Failed to unmarshal tags")
+		}
+	}
+
+	if err := restoreJSONData(&doc, compressed, raw); err != nil {
+		return nil, err
+	}
+
+	return &doc, nil
+}
+
+func (s *PostgresStore) StoreJSON(ctx context.Context, input model.StoreInput) (*model.JSONDocument, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "StoreJSON")()
+
+	input, err := s.opts.storedInput(input)
+	if err != nil {
+		return nil, err
+	}
+	jsonData := input.JSONData
+
 	// 验证JSON
 	if !json.Valid(jsonData) {
 		return nil, fmt.Errorf("invalid JSON data")
 	}
 
 	// 计算哈希值
-	hash := calculateHash(jsonData)
+	hash, err := s.opts.contentHash(jsonData)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := documentMetadata(input)
+	if err != nil {
+		return nil, err
+	}
+	rawHash := calculateRawHash(jsonData)
 	size := int64(len(jsonData))
 
 	// 检查是否已存在
-	if existing, err := s.GetJSONByHash(ctx, hash); err == nil {
-		return existing, nil
+	if !s.opts.AllowDuplicateContent {
+		if existing, err := s.getJSONByDedupHash(ctx, hash, rawHash, input.DocType); err == nil {
+			if err := clientIDExisting(input, existing); err != nil {
+				return nil, err
+			}
+			existing.Existing = true
+			return existing, nil
+		}
 	}
 
-	// 插入新记录
-	id := uuid.New().String()
-	query := `
-		INSERT INTO json_documents (id, content_hash, json_data, size)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, content_hash, json_data, size, created_at, updated_at
-	`
-
-	var doc model.JSONDocument
-	err := s.db.QueryRowContext(ctx, query, id, hash, jsonData, size).Scan(
-		&doc.ID, &doc.ContentHash, &doc.JSONData, &doc.Size, &doc.CreatedAt, &doc.UpdatedAt,
-	)
+	if input.ID != "" {
+		if err := checkIDAvailable(ctx, s.db, postgresIDExists, input.ID); err != nil {
+			return nil, err
+		}
+	}
 
+	// 插入新记录
+	id := newDocumentID(input)
+	var doc *model.JSONDocument
+	if s.opts.shouldChunk(size) {
+		doc, err = storeChunkedDocument(ctx, s.db, postgresChunkQueries, scanPostgresDocument,
+			id, hash, input, s.opts.ChunkSize)
+	} else {
+		doc, err = scanPostgresDocument(s.stmts.QueryRowContext(ctx, postgresInsertDocument,
+			id, hash, nullString(input.DocType), jsonData, size, s.rawData(jsonData), documentSimHash(jsonData), rawHash, metadata, documentTags(input),
+		))
+	}
 	if err != nil {
+		// 并发写入了相同ID时主键冲突
+		if input.ID != "" {
+			if cerr := checkIDAvailable(ctx, s.db, postgresIDExists, input.ID); cerr != nil {
+				return nil, cerr
+			}
+		}
 		return nil, fmt.Errorf("failed to store JSON: %w", err)
 	}
 
-	log.Info().
+	ctxLogger(ctx).Info().
 		Str("id", doc.ID).
 		Str("hash", hash).
 		Int64("size", size).
 		Msg("JSON stored in PostgreSQL")
 
-	return &doc, nil
+	return doc, nil
+}
+
+// rawData 开启PreserveRawBytes时返回原始字节，否则写入NULL
+func (s *PostgresStore) rawData(jsonData []byte) []byte {
+	if !s.opts.PreserveRawBytes {
+		return nil
+	}
+	return jsonData
+}
+
+// postgresUpdateQueries PostgreSQL更新文档SQL
+var postgresUpdateQueries = updateQueries{
+	LockDocument:  `SELECT COALESCE(doc_type, '') FROM json_documents WHERE id = $1 FOR UPDATE`,
+	FindDuplicate: `SELECT id FROM json_documents WHERE id <> $1 AND %s LIMIT 1`,
+	Placeholder:   postgresPlaceholder,
+	DeleteChunks:  `DELETE FROM json_document_chunks WHERE document_id = $1`,
+	UpdateDocument: `
+		UPDATE json_documents
+		SET content_hash = $1, doc_type = COALESCE($2, doc_type), json_data = $3, size = $4,
+			raw_data = $5, chunk_count = $6, simhash = $7, raw_hash = $8,
+			compression = NULL, compressed_data = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $9
+	`,
+}
+
+func (s *PostgresStore) UpdateJSON(ctx context.Context, id string, input model.StoreInput) (*model.JSONDocument, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "UpdateJSON")()
+
+	doc, err := updateDocument(ctx, s.db, postgresUpdateQueries, postgresChunkQueries, scanPostgresDocument, s.opts, id, input)
+	if err != nil {
+		return nil, err
+	}
+
+	ctxLogger(ctx).Info().
+		Str("id", doc.ID).
+		Str("hash", doc.ContentHash).
+		Int64("size", doc.Size).
+		Msg("JSON updated in PostgreSQL")
+
+	return doc, nil
+}
+
+// postgresLabelQueries PostgreSQL标签SQL
+var postgresLabelQueries = labelQueries{
+	Ensure:        `INSERT INTO json_labels (label) VALUES ($1) ON CONFLICT (label) DO NOTHING`,
+	Lock:          `SELECT COALESCE(document_id::text, ''), version FROM json_labels WHERE label = $1 FOR UPDATE`,
+	Update:        `UPDATE json_labels SET document_id = $1, version = $2, updated_at = CURRENT_TIMESTAMP WHERE label = $3`,
+	InsertVersion: `INSERT INTO json_label_versions (label, version, document_id) VALUES ($1, $2, $3)`,
+	SelectCurrent: `SELECT document_id, version FROM json_labels WHERE label = $1 AND document_id IS NOT NULL`,
+	SelectVersion: `SELECT document_id FROM json_label_versions WHERE label = $1 AND version = $2`,
+}
+
+func (s *PostgresStore) StoreByLabel(ctx context.Context, label string, input model.StoreInput) (*model.LabeledDocument, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "StoreByLabel")()
+
+	result, err := storeByLabel(ctx, s.db, postgresTransactionQueries, postgresChunkQueries, postgresLabelQueries,
+		scanPostgresDocument, s.opts, label, input)
+	if err != nil {
+		return nil, err
+	}
+	doc := result.Document
+
+	ctxLogger(ctx).Info().
+		Str("label", label).
+		Int("version", result.Version).
+		Str("id", doc.ID).
+		Bool("changed", result.Changed).
+		Msg("JSON label stored in PostgreSQL")
+
+	return result, nil
+}
+
+func (s *PostgresStore) GetJSONByLabel(ctx context.Context, label string, version int) (*model.JSONDocument, int, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "GetJSONByLabel")()
+
+	id, version, err := resolveLabel(ctx, s.db, postgresLabelQueries, label, version)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	doc, err := s.GetJSONByID(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	return doc, version, nil
+}
+
+func (s *PostgresStore) DocumentLabels(ctx context.Context) (map[string][]string, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "DocumentLabels")()
+
+	return documentLabels(ctx, s.db)
+}
+
+func (s *PostgresStore) SetLabel(ctx context.Context, label, id string) (*model.LabeledDocument, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "SetLabel")()
+
+	return setLabel(ctx, s.db, postgresLabelQueries, label, id, s.getJSONByID)
+}
+
+func (s *PostgresStore) RestoreTimestamps(ctx context.Context, id string, createdAt, updatedAt time.Time) error {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "RestoreTimestamps")()
+
+	return restoreTimestamps(ctx, s.db, `UPDATE json_documents SET created_at = $1, updated_at = $2 WHERE id = $3`, id, createdAt, updatedAt)
+}
+
+// postgresAttachmentQueries PostgreSQL附件SQL
+var postgresAttachmentQueries = attachmentQueries{
+	Upsert: `
+		INSERT INTO json_attachments (document_id, name, content_type, size, content_hash, data)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (document_id, name) DO UPDATE SET
+			content_type = EXCLUDED.content_type,
+			size = EXCLUDED.size,
+			content_hash = EXCLUDED.content_hash,
+			data = EXCLUDED.data,
+			updated_at = CURRENT_TIMESTAMP
+	`,
+	SelectInfo: `SELECT content_type, size, content_hash, updated_at FROM json_attachments WHERE document_id = $1 AND name = $2`,
+	Select:     `SELECT content_type, size, content_hash, updated_at, data FROM json_attachments WHERE document_id = $1 AND name = $2`,
+}
+
+func (s *PostgresStore) StoreAttachment(ctx context.Context, documentID, name, contentType string, data []byte) (*model.Attachment, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "StoreAttachment")()
+
+	attachment, err := storeAttachment(ctx, s.db, postgresAttachmentQueries, postgresIDExists, documentID, name, contentType, data)
+	if err != nil {
+		return nil, err
+	}
+
+	ctxLogger(ctx).Info().
+		Str("id", documentID).
+		Str("name", name).
+		Int64("size", attachment.Size).
+		Msg("Attachment stored in PostgreSQL")
+
+	return attachment, nil
+}
+
+func (s *PostgresStore) GetAttachment(ctx context.Context, documentID, name string) (*model.Attachment, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "GetAttachment")()
+
+	return getAttachment(ctx, s.db, postgresAttachmentQueries, documentID, name)
+}
+
+// postgresTransactionQueries PostgreSQL原子批量存储SQL
+var postgresTransactionQueries = transactionQueries{
+	InsertDocument: `
+		INSERT INTO json_documents (id, content_hash, doc_type, json_data, size, raw_data, simhash, raw_hash, metadata, tags)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9::jsonb, ARRAY(SELECT jsonb_array_elements_text($10::jsonb)))
+	`,
+	Placeholder: postgresPlaceholder,
+}
+
+func (s *PostgresStore) StoreJSONTransaction(ctx context.Context, inputs []model.StoreInput) ([]*model.JSONDocument, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "StoreJSONTransaction")()
+
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("no JSON data provided")
+	}
+
+	if len(inputs) > 100 {
+		return nil, fmt.Errorf("batch size exceeds limit of 100")
+	}
+
+	docs, err := storeDocumentsAtomically(ctx, s.db, postgresTransactionQueries, postgresChunkQueries,
+		scanPostgresDocument, s.opts, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	ctxLogger(ctx).Info().Int("total", len(docs)).Msg("JSON transaction stored")
+
+	return docs, nil
+}
+
+// postgresMetadataQueries PostgreSQL合并metadata SQL
+var postgresMetadataQueries = metadataQueries{
+	Lock: `SELECT metadata FROM json_documents WHERE id = $1 FOR UPDATE`,
+	Set:  `UPDATE json_documents SET metadata = $1::jsonb WHERE id = $2`,
+}
+
+func (s *PostgresStore) UpdateMetadataBatch(ctx context.Context, updates []model.MetadataUpdate) ([]model.MetadataUpdateResult, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "UpdateMetadataBatch")()
+
+	if len(updates) > 100 {
+		return nil, fmt.Errorf("batch size exceeds limit of 100")
+	}
+
+	return mergeMetadataBatch(ctx, s.db, postgresMetadataQueries, updates)
 }
 
 func (s *PostgresStore) GetJSONByID(ctx context.Context, id string) (*model.JSONDocument, error) {
-	query := `
-		SELECT id, content_hash, json_data, size, created_at, updated_at, metadata
-		FROM json_documents
-		WHERE id = $1
-	`
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "GetJSONByID")()
 
-	var doc model.JSONDocument
-	err := s.db.QueryRowContext(ctx, query, id).Scan(
-		&doc.ID, &doc.ContentHash, &doc.JSONData, &doc.Size,
-		&doc.CreatedAt, &doc.UpdatedAt, &doc.Metadata,
-	)
+	if !s.opts.TrackAccess {
+		return s.getJSONByID(ctx, id)
+	}
+
+	// 读取与累加访问计数在同一条语句中完成
+	return s.getJSONByIDQuery(ctx, postgresGetTracked, id)
+}
 
+// getJSONByID 按ID读取文档，不计入访问次数，用于存储流程内部回读
+func (s *PostgresStore) getJSONByID(ctx context.Context, id string) (*model.JSONDocument, error) {
+	return s.getJSONByIDQuery(ctx, postgresChunkQueries.SelectDocument, id)
+}
+
+// getJSONByIDQuery 执行按ID返回单个文档的查询并读取分块
+func (s *PostgresStore) getJSONByIDQuery(ctx context.Context, query, id string) (*model.JSONDocument, error) {
+	doc, err := scanPostgresDocument(s.stmts.QueryRowContext(ctx, query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("document not found with id: %s", id)
@@ -147,23 +764,33 @@ func (s *PostgresStore) GetJSONByID(ctx context.Context, id string) (*model.JSON
 		return nil, fmt.Errorf("failed to get JSON: %w", err)
 	}
 
-	return &doc, nil
+	if err := loadChunks(ctx, s.stmts, postgresChunkQueries.SelectChunks, doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
 }
 
 func (s *PostgresStore) GetJSONByHash(ctx context.Context, hash string) (*model.JSONDocument, error) {
-	query := `
-		SELECT id, content_hash, json_data, size, created_at, updated_at, metadata
-		FROM json_documents
-		WHERE content_hash = $1
-		LIMIT 1
-	`
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "GetJSONByHash")()
 
-	var doc model.JSONDocument
-	err := s.db.QueryRowContext(ctx, query, hash).Scan(
-		&doc.ID, &doc.ContentHash, &doc.JSONData, &doc.Size,
-		&doc.CreatedAt, &doc.UpdatedAt, &doc.Metadata,
-	)
+	return s.getJSONByHashColumn(ctx, "content_hash", hash)
+}
+
+// getJSONByDedupHash 按去重条件查找文档
+func (s *PostgresStore) getJSONByDedupHash(ctx context.Context, hash, rawHash, docType string) (*model.JSONDocument, error) {
+	condition, args := s.opts.dedupCondition(hash, rawHash, docType, postgresPlaceholder, 1)
+	return s.getJSONWhere(ctx, s.opts.dedupHash(hash, rawHash), condition, args...)
+}
+
+// getJSONByHashColumn 按指定的哈希列查找文档
+func (s *PostgresStore) getJSONByHashColumn(ctx context.Context, column, hash string) (*model.JSONDocument, error) {
+	return s.getJSONWhere(ctx, hash, column+" = $1", hash)
+}
 
+// getJSONWhere 按条件查找一个文档，hash仅用于错误信息
+func (s *PostgresStore) getJSONWhere(ctx context.Context, hash, condition string, args ...any) (*model.JSONDocument, error) {
+	doc, err := scanPostgresDocument(s.stmts.QueryRowContext(ctx, postgresSelectWhere(condition), args...))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("document not found with hash: %s", hash)
@@ -171,23 +798,165 @@ func (s *PostgresStore) GetJSONByHash(ctx context.Context, hash string) (*model.
 		return nil, fmt.Errorf("failed to get JSON by hash: %w", err)
 	}
 
-	return &doc, nil
+	if err := loadChunks(ctx, s.stmts, postgresChunkQueries.SelectChunks, doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// GetStorageInfo 存储大小按实际存储形式计算：分块数据或json_data列，另加原始字节（压缩或未压缩）
+func (s *PostgresStore) GetStorageInfo(ctx context.Context, id string) (*model.StorageInfo, error) {
+	query := `
+		SELECT d.size, COALESCE(d.compression, ''), d.chunk_count, d.raw_data IS NOT NULL OR d.compression IS NOT NULL,
+			CASE
+				WHEN d.chunk_count > 0 THEN (
+					SELECT COALESCE(SUM(OCTET_LENGTH(c.data)), 0)
+					FROM json_document_chunks c WHERE c.document_id = d.id
+				)
+				ELSE pg_column_size(d.json_data)
+			END + COALESCE(OCTET_LENGTH(d.compressed_data), 0) + COALESCE(OCTET_LENGTH(d.raw_data), 0),
+			d.access_count, d.last_accessed_at
+		FROM json_documents d
+		WHERE d.id = $1
+	`
+
+	info := &model.StorageInfo{HashAlgorithm: "sha256"}
+	var lastAccessed sql.NullTime
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
+		&info.RawSize, &info.Compression, &info.Chunks, &info.RawBytes, &info.StoredSize,
+		&info.AccessCount, &lastAccessed,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("document not found with id: %s", id)
+		}
+		return nil, fmt.Errorf("failed to get storage info: %w", err)
+	}
+	if lastAccessed.Valid {
+		info.LastAccessedAt = &lastAccessed.Time
+	}
+
+	return info, nil
+}
+
+// FindNearDuplicates 全表比较SimHash，适用于管理分析而非高频查询
+func (s *PostgresStore) FindNearDuplicates(ctx context.Context, excludeID string, simhash uint64, maxDistance, limit int) ([]model.NearDuplicate, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "FindNearDuplicates")()
+
+	query := `
+		SELECT id, content_hash, COALESCE(doc_type, ''), size, created_at, distance
+		FROM (
+			SELECT id, content_hash, doc_type, size, created_at,
+				LENGTH(REPLACE(((simhash # $1)::bit(64))::text, '0', '')) AS distance
+			FROM json_documents
+			WHERE simhash IS NOT NULL AND id <> $2
+		) candidates
+		WHERE distance <= $3
+		ORDER BY distance, created_at DESC
+		LIMIT $4
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, int64(simhash), excludeID, maxDistance, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find near duplicates: %w", err)
+	}
+	defer rows.Close()
+
+	matches := make([]model.NearDuplicate, 0, limit)
+	for rows.Next() {
+		var m model.NearDuplicate
+		if err := rows.Scan(&m.ID, &m.ContentHash, &m.DocType, &m.Size, &m.CreatedAt, &m.Distance); err != nil {
+			return nil, fmt.Errorf("failed to scan near duplicate: %w", err)
+		}
+		matches = append(matches, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return matches, nil
+}
+
+func (s *PostgresStore) FindExistingHashes(ctx context.Context, hashes []string) (map[string]string, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "FindExistingHashes")()
+
+	return findExistingHashes(ctx, s.db, postgresPlaceholder, hashes)
+}
+
+func (s *PostgresStore) FindHashesByPrefix(ctx context.Context, prefix string, limit int) ([]string, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "FindHashesByPrefix")()
+
+	query := `
+		SELECT DISTINCT content_hash
+		FROM json_documents
+		WHERE content_hash LIKE $1
+		ORDER BY content_hash
+		LIMIT $2
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, prefix+"%", limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find hashes by prefix: %w", err)
+	}
+	defer rows.Close()
+
+	hashes := make([]string, 0, limit)
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, fmt.Errorf("failed to scan hash: %w", err)
+		}
+		hashes = append(hashes, hash)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return hashes, nil
 }
 
 func (s *PostgresStore) HealthCheck(ctx context.Context) error {
 	return s.db.PingContext(ctx)
 }
 
+func (s *PostgresStore) WriteCheck(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 写入一条探测记录后回滚，只读副本或磁盘已满时会失败
+	id := uuid.New().String()
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO json_documents (id, content_hash, json_data, size) VALUES ($1, $2, '{}', 2)",
+		id, "healthcheck:"+id,
+	)
+	if err != nil {
+		return fmt.Errorf("write check failed: %w", err)
+	}
+
+	return nil
+}
+
 func (s *PostgresStore) Close() error {
+	if err := s.stmts.Close(); err != nil {
+		log.Warn().Err(err).Msg("Failed to close prepared statements")
+	}
 	return s.db.Close()
 }
 
-func (s *PostgresStore) StoreJSONBatch(ctx context.Context, jsonDataList [][]byte) ([]*model.JSONDocument, error) {
-	if len(jsonDataList) == 0 {
+func (s *PostgresStore) StoreJSONBatch(ctx context.Context, inputs []model.StoreInput) ([]*model.JSONDocument, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "StoreJSONBatch")()
+
+	if len(inputs) == 0 {
 		return nil, fmt.Errorf("no JSON data provided")
 	}
 
-	if len(jsonDataList) > 100 {
+	if len(inputs) > 100 {
 		return nil, fmt.Errorf("batch size exceeds limit of 100")
 	}
 
@@ -198,56 +967,80 @@ func (s *PostgresStore) StoreJSONBatch(ctx context.Context, jsonDataList [][]byt
 	}
 	defer tx.Rollback()
 
-	results := make([]*model.JSONDocument, 0, len(jsonDataList))
+	results := make([]*model.JSONDocument, 0, len(inputs))
 
 	// 批量插入
-	for i, jsonData := range jsonDataList {
-		// 验证JSON
+	for i, input := range inputs {
+		input, err := s.opts.storedInput(input)
+		if err != nil {
+			ctxLogger(ctx).Warn().Err(err).Int("index", i).Msg("Control character in batch JSON, skipping")
+			continue
+		}
+		jsonData := input.JSONData
+
+		// 验证JSON，空文档单独记录便于排查
+		if len(bytes.TrimSpace(jsonData)) == 0 {
+			ctxLogger(ctx).Warn().Int("index", i).Msg("Empty JSON in batch, skipping")
+			continue
+		}
 		if !json.Valid(jsonData) {
-			log.Warn().Int("index", i).Msg("Invalid JSON in batch, skipping")
+			ctxLogger(ctx).Warn().Int("index", i).Msg("Invalid JSON in batch, skipping")
 			continue
 		}
 
-		hash := calculateHash(jsonData)
+		hash, err := s.opts.contentHash(jsonData)
+		if err != nil {
+			ctxLogger(ctx).Warn().Err(err).Int("index", i).Msg("Unnormalizable JSON in batch, skipping")
+			continue
+		}
+		metadata, err := documentMetadata(input)
+		if err != nil {
+			ctxLogger(ctx).Warn().Err(err).Int("index", i).Msg("Invalid metadata in batch, skipping")
+			continue
+		}
+		rawHash := calculateRawHash(jsonData)
 		size := int64(len(jsonData))
 		id := uuid.New().String()
 
 		// 检查是否已存在
-		var existingID string
-		err := tx.QueryRowContext(ctx,
-			"SELECT id FROM json_documents WHERE content_hash = $1",
-			hash,
-		).Scan(&existingID)
-
-		if err == nil {
-			// 已存在，获取完整记录
-			doc, err := s.GetJSONByID(ctx, existingID)
+		if !s.opts.AllowDuplicateContent {
+			var existingID string
+			condition, args := s.opts.dedupCondition(hash, rawHash, input.DocType, postgresPlaceholder, 1)
+			err := tx.QueryRowContext(ctx, "SELECT id FROM json_documents WHERE "+condition, args...).Scan(&existingID)
+
 			if err == nil {
-				results = append(results, doc)
-				continue
+				// 已存在，获取完整记录
+				doc, err := s.getJSONByID(ctx, existingID)
+				if err == nil {
+					doc.Existing = true
+					results = append(results, doc)
+					continue
+				}
 			}
 		}
 
 		// 插入新记录
-		query := `
-			INSERT INTO json_documents (id, content_hash, json_data, size)
-			VALUES ($1, $2, $3, $4)
-			RETURNING id, content_hash, json_data, size, created_at, updated_at
-		`
-
-		var doc model.JSONDocument
-		err = tx.QueryRowContext(ctx, query, id, hash, jsonData, size).Scan(
-			&doc.ID, &doc.ContentHash, &doc.JSONData, &doc.Size,
-			&doc.CreatedAt, &doc.UpdatedAt,
-		)
-
+		var doc *model.JSONDocument
+		if s.opts.shouldChunk(size) {
+			doc, err = insertChunkedDocument(ctx, tx, postgresChunkQueries, scanPostgresDocument,
+				id, hash, input, s.opts.ChunkSize)
+		} else {
+			query := `
+				INSERT INTO json_documents (id, content_hash, doc_type, json_data, size, raw_data, simhash, raw_hash, metadata, tags)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9::jsonb, ARRAY(SELECT jsonb_array_elements_text($10::jsonb)))
+				RETURNING ` + postgresDocumentColumns
+
+			doc, err = scanPostgresDocument(tx.QueryRowContext(ctx, query,
+				id, hash, nullString(input.DocType), jsonData, size, s.rawData(jsonData), documentSimHash(jsonData), rawHash, metadata, documentTags(input),
+			))
+		}
 		if err != nil {
-			log.Error().Err(err).Int("index", i).Msg("Failed to insert JSON in batch")
+			ctxLogger(ctx).Error().Err(err).Int("index", i).Msg("Failed to insert JSON in batch")
 			// 继续处理其他记录
 			continue
 		}
 
-		results = append(results, &doc)
+		results = append(results, doc)
 	}
 
 	// 提交事务
@@ -255,12 +1048,14 @@ func (s *PostgresStore) StoreJSONBatch(ctx context.Context, jsonDataList [][]byt
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	log.Info().Int("total", len(jsonDataList)).Int("success", len(results)).Msg("JSON batch stored")
+	ctxLogger(ctx).Info().Int("total", len(inputs)).Int("success", len(results)).Msg("JSON batch stored")
 
 	return results, nil
 }
 
 func (s *PostgresStore) GetJSONBatch(ctx context.Context, ids []string) ([]*model.JSONDocument, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "GetJSONBatch")()
+
 	if len(ids) == 0 {
 		return nil, fmt.Errorf("no IDs provided")
 	}
@@ -278,11 +1073,11 @@ func (s *PostgresStore) GetJSONBatch(ctx context.Context, ids []string) ([]*mode
 	}
 
 	query := fmt.Sprintf(`
-		SELECT id, content_hash, json_data, size, created_at, updated_at, metadata
+		SELECT %s
 		FROM json_documents
 		WHERE id IN (%s)
 		ORDER BY created_at DESC
-	`, strings.Join(placeholders, ","))
+	`, postgresDocumentColumns, strings.Join(placeholders, ","))
 
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -292,26 +1087,139 @@ func (s *PostgresStore) GetJSONBatch(ctx context.Context, ids []string) ([]*mode
 
 	documents := make([]*model.JSONDocument, 0, len(ids))
 	for rows.Next() {
-		var doc model.JSONDocument
-		err := rows.Scan(
-			&doc.ID, &doc.ContentHash, &doc.JSONData, &doc.Size,
-			&doc.CreatedAt, &doc.UpdatedAt, &doc.Metadata,
-		)
+		doc, err := scanPostgresDocument(rows)
 		if err != nil {
-			log.Error().Err(err).Msg("Failed to scan row in batch")
+			ctxLogger(ctx).Error().Err(err).Msg("Failed to scan row in batch")
 			continue
 		}
-		documents = append(documents, &doc)
+		documents = append(documents, doc)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating rows: %w", err)
 	}
+	// 先释放查询连接，再逐个读取分块
+	rows.Close()
+
+	if err := loadChunks(ctx, s.db, postgresChunkQueries.SelectChunks, documents...); err != nil {
+		return nil, err
+	}
+
+	return documents, nil
+}
+
+func (s *PostgresStore) LargestDocuments(ctx context.Context, limit int) ([]model.DocumentSummary, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "LargestDocuments")()
+
+	query := `
+		SELECT ` + documentSummaryColumns + `
+		FROM json_documents
+		ORDER BY size DESC, id
+		LIMIT $1
+	`
+
+	return querySummaries(ctx, s.db, query, limit)
+}
+
+// FacetCounts ->> 对JSON null返回SQL NULL，不计入
+func (s *PostgresStore) FacetCounts(ctx context.Context, metaKey string) (map[string]int64, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "FacetCounts")()
+
+	query := `
+		SELECT metadata->>$1::text AS value, COUNT(*) AS count
+		FROM json_documents
+		WHERE metadata->>$1::text IS NOT NULL
+		GROUP BY value
+		ORDER BY count DESC, value
+	`
+
+	return queryFacetCounts(ctx, s.db, query, metaKey)
+}
+
+// postgresCountQuery 参数：类型（为空时统计全部）
+const postgresCountQuery = `SELECT COUNT(*) FROM json_documents WHERE ($1::text = '' OR doc_type = $1)`
+
+func (s *PostgresStore) CountJSON(ctx context.Context, docType string, estimate bool) (int64, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "CountJSON")()
+
+	if !estimate {
+		var count int64
+		err := s.db.QueryRowContext(ctx, postgresCountQuery, docType).Scan(&count)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count JSON: %w", err)
+		}
+		return count, nil
+	}
+
+	// 取规划器基于pg_class/pg_stats给出的行数估算；EXPLAIN不能使用参数，类型值按字面量转义
+	query := `EXPLAIN (FORMAT JSON) SELECT 1 FROM json_documents`
+	if docType != "" {
+		query += ` WHERE doc_type = ` + pq.QuoteLiteral(docType)
+	}
+
+	var plan []byte
+	if err := s.db.QueryRowContext(ctx, query).Scan(&plan); err != nil {
+		return 0, fmt.Errorf("failed to estimate JSON count: %w", err)
+	}
+
+	var explained []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal(plan, &explained); err != nil || len(explained) == 0 {
+		return 0, fmt.Errorf("failed to parse query plan: %s", plan)
+	}
+
+	return int64(explained[0].Plan.Rows), nil
+}
+
+// postgresListQuery 参数：类型（为空时不过滤）、limit、offset、标签（为空时不过滤）
+// 标签条件写成数组包含，才能使用tags列的GIN索引
+const postgresListQuery = `
+	SELECT ` + postgresDocumentColumns + `
+	FROM json_documents
+	WHERE ($1::text = '' OR doc_type = $1)
+		AND ($4::text = '' OR tags @> ARRAY[$4::text])
+	ORDER BY created_at DESC
+	LIMIT $2 OFFSET $3
+`
+
+func (s *PostgresStore) ListJSON(ctx context.Context, filter model.ListFilter) ([]*model.JSONDocument, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "ListJSON")()
+
+	rows, err := s.db.QueryContext(ctx, postgresListQuery, filter.DocType, filter.Limit, filter.Offset, filter.Tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list JSON: %w", err)
+	}
+	defer rows.Close()
+
+	documents := make([]*model.JSONDocument, 0, filter.Limit)
+	for rows.Next() {
+		doc, err := scanPostgresDocument(rows)
+		if err != nil {
+			ctxLogger(ctx).Error().Err(err).Msg("Failed to scan row in list")
+			continue
+		}
+		documents = append(documents, doc)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	// 先释放查询连接，再逐个读取分块
+	rows.Close()
+
+	if err := loadChunks(ctx, s.db, postgresChunkQueries.SelectChunks, documents...); err != nil {
+		return nil, err
+	}
 
 	return documents, nil
 }
 
 func (s *PostgresStore) GetStats(ctx context.Context) (*model.DatabaseStats, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "GetStats")()
+
 	stats := &model.DatabaseStats{}
 
 	// 获取基础统计
@@ -350,7 +1258,7 @@ func (s *PostgresStore) GetStats(ctx context.Context) (*model.DatabaseStats, err
 
 	rows, err := s.db.QueryContext(ctx, dailyQuery)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to get daily stats")
+		ctxLogger(ctx).Error().Err(err).Msg("Failed to get daily stats")
 	} else {
 		defer rows.Close()
 
@@ -359,7 +1267,7 @@ func (s *PostgresStore) GetStats(ctx context.Context) (*model.DatabaseStats, err
 			var dc model.DayCount
 			err := rows.Scan(&dc.Date, &dc.Count, &dc.Size)
 			if err != nil {
-				log.Error().Err(err).Msg("Failed to scan daily stats")
+				ctxLogger(ctx).Error().Err(err).Msg("Failed to scan daily stats")
 				continue
 			}
 			dailyCounts = append(dailyCounts, dc)
@@ -367,10 +1275,169 @@ func (s *PostgresStore) GetStats(ctx context.Context) (*model.DatabaseStats, err
 		stats.DailyCounts = dailyCounts
 	}
 
+	// 获取按类型统计
+	typeQuery := `
+		SELECT 
+			COALESCE(doc_type, '') as doc_type,
+			COUNT(*) as count,
+			SUM(size) as size
+		FROM json_documents
+		GROUP BY doc_type
+		ORDER BY count DESC
+	`
+
+	typeRows, err := s.db.QueryContext(ctx, typeQuery)
+	if err != nil {
+		ctxLogger(ctx).Error().Err(err).Msg("Failed to get type stats")
+	} else {
+		defer typeRows.Close()
+
+		typeCounts := make([]model.TypeCount, 0)
+		for typeRows.Next() {
+			var tc model.TypeCount
+			if err := typeRows.Scan(&tc.Type, &tc.Count, &tc.Size); err != nil {
+				ctxLogger(ctx).Error().Err(err).Msg("Failed to scan type stats")
+				continue
+			}
+			typeCounts = append(typeCounts, tc)
+		}
+		stats.TypeCounts = typeCounts
+	}
+
+	// 获取大小分布直方图
+	histogram, err := querySizeHistogram(ctx, s.db, s.opts.SizeBuckets)
+	if err != nil {
+		ctxLogger(ctx).Error().Err(err).Msg("Failed to get size histogram")
+	} else {
+		stats.SizeHistogram = histogram
+	}
+
+	// 获取年龄分布直方图
+	ages, err := queryAgeHistogram(ctx, s.db, postgresAgeCondition, s.opts.AgeBuckets)
+	if err != nil {
+		ctxLogger(ctx).Error().Err(err).Msg("Failed to get age histogram")
+	} else {
+		stats.AgeHistogram = ages
+	}
+
+	// 获取存储空间和压缩比
+	if err := queryCompressionStats(ctx, s.db, postgresCompressQueries.Stats, stats); err != nil {
+		ctxLogger(ctx).Error().Err(err).Msg("Failed to get compression stats")
+	}
+
 	return stats, nil
 }
 
+// postgresIterateQuery 游标分页查询，参数：游标时间、游标ID、每页行数
+var postgresIterateQuery = `
+	SELECT ` + postgresDocumentColumns + `
+	FROM json_documents
+	WHERE (created_at, id) > ($1, $2)
+	ORDER BY created_at, id
+	LIMIT $3
+`
+
+func (s *PostgresStore) IterateDocuments(ctx context.Context, since time.Time, fn func(*model.JSONDocument) error) error {
+	return iterateDocuments(ctx, s.db, postgresIterateQuery, scanPostgresDocument, postgresChunkQueries.SelectChunks, since, fn)
+}
+
+// postgresModifiedSinceQuery 按修改时间的游标分页查询，参数：游标时间、游标ID、等待时长（微秒）、行数
+var postgresModifiedSinceQuery = `
+	SELECT ` + postgresDocumentColumns + `
+	FROM json_documents
+	WHERE (updated_at, id) > ($1, $2)
+		AND updated_at <= clock_timestamp() - $3::float8 * INTERVAL '1 microsecond'
+	ORDER BY updated_at, id
+	LIMIT $4
+`
+
+// postgresPlaceholderPattern 匹配 $1、$2 等参数占位符
+var postgresPlaceholderPattern = regexp.MustCompile(`\$[0-9]+`)
+
+// Explain 对查询模板执行EXPLAIN (FORMAT JSON)，只规划不执行
+// EXPLAIN不能使用参数，参数按字面量转义后代入模板中的占位符
+func (s *PostgresStore) Explain(ctx context.Context, template string, params map[string]string) (*model.ExplainResult, error) {
+	if err := validateExplain(template, params); err != nil {
+		return nil, err
+	}
+
+	var query string
+	var args []string
+	switch template {
+	case model.ExplainGetByID:
+		query, args = postgresChunkQueries.SelectDocument, []string{params["id"]}
+	case model.ExplainGetByHash:
+		query, args = postgresSelectWhere("content_hash = $1"), []string{params["hash"]}
+	case model.ExplainList:
+		query, args = postgresListQuery, []string{params["doc_type"], strconv.Itoa(explainListLimit), "0", params["tag"]}
+	case model.ExplainCount:
+		query, args = postgresCountQuery, []string{params["doc_type"]}
+	case model.ExplainJSONField:
+		query, args = postgresSelectWhere("json_data->>$1 = $2"), []string{params["key"], params["value"]}
+	case model.ExplainMetadataFilter:
+		query, args = postgresSelectWhere("metadata->>$1 = $2"), []string{params["key"], params["value"]}
+	}
+
+	// 一次替换全部占位符，已代入的值中出现的 $N 不会被再次替换
+	query = postgresPlaceholderPattern.ReplaceAllStringFunc(query, func(p string) string {
+		n, _ := strconv.Atoi(p[1:])
+		return pq.QuoteLiteral(args[n-1])
+	})
+
+	var plan []byte
+	if err := s.db.QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) "+query).Scan(&plan); err != nil {
+		return nil, fmt.Errorf("failed to explain query: %w", err)
+	}
+
+	result := explainResult(template, query, plan)
+	if template == model.ExplainJSONField {
+		excluded, err := countUnqueryable(ctx, s.db)
+		if err != nil {
+			return nil, err
+		}
+		result.Excluded = excluded
+	}
+	return result, nil
+}
+
+func (s *PostgresStore) GetJSONModifiedSince(ctx context.Context, since time.Time, afterID string, limit int) ([]*model.JSONDocument, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "GetJSONModifiedSince")()
+
+	return modifiedSince(ctx, s.db, postgresModifiedSinceQuery, scanPostgresDocument, postgresChunkQueries.SelectChunks, since, afterID, s.opts.ChangesLag, limit)
+}
+
+var postgresCompressQueries = compressQueries{
+	Select: `
+		SELECT id, raw_data FROM json_documents
+		WHERE compression IS NULL AND raw_data IS NOT NULL AND chunk_count = 0 AND size >= $1
+		ORDER BY id
+		LIMIT $2
+	`,
+	Update: `
+		UPDATE json_documents
+		SET compressed_data = $1, compression = $2, raw_data = NULL
+		WHERE id = $3 AND compression IS NULL AND raw_data IS NOT NULL
+	`,
+	Stats: `
+		SELECT COUNT(compression),
+			COALESCE(SUM(
+				CASE WHEN chunk_count > 0 THEN 0 ELSE pg_column_size(json_data) END
+					+ COALESCE(OCTET_LENGTH(compressed_data), 0) + COALESCE(OCTET_LENGTH(raw_data), 0)
+			), 0) + (SELECT COALESCE(SUM(OCTET_LENGTH(data)), 0) FROM json_document_chunks)
+		FROM json_documents
+	`,
+	Remaining: `SELECT COUNT(*) FROM json_documents WHERE compression IS NULL AND raw_data IS NOT NULL AND chunk_count = 0 AND size >= $1`,
+}
+
+func (s *PostgresStore) CompressDocuments(ctx context.Context, minSize int64, batchSize int) (*model.CompressResult, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "CompressDocuments")()
+
+	return compressBatch(ctx, s.db, postgresCompressQueries, minSize, batchSize)
+}
+
 func (s *PostgresStore) GetMetrics(ctx context.Context) (*model.DatabaseMetrics, error) {
+	defer trackQuery(ctx, s.opts.SlowQueryThreshold, "GetMetrics")()
+
 	metrics := &model.DatabaseMetrics{
 		Timestamp: time.Now(),
 	}
@@ -390,7 +1457,7 @@ func (s *PostgresStore) GetMetrics(ctx context.Context) (*model.DatabaseMetrics,
 	)
 
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to get connection metrics")
+		ctxLogger(ctx).Error().Err(err).Msg("Failed to get connection metrics")
 	}
 
 	// 获取缓存命中率
@@ -421,7 +1488,7 @@ func (s *PostgresStore) GetMetrics(ctx context.Context) (*model.DatabaseMetrics,
 
 	rows, err := s.db.QueryContext(ctx, tableQuery)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to get table metrics")
+		ctxLogger(ctx).Error().Err(err).Msg("Failed to get table metrics")
 	} else {
 		defer rows.Close()
 
@@ -430,7 +1497,7 @@ func (s *PostgresStore) GetMetrics(ctx context.Context) (*model.DatabaseMetrics,
 			var ts model.TableStats
 			err := rows.Scan(&ts.Name, &ts.Rows, &ts.Size, &ts.IndexSize, &ts.TotalSize)
 			if err != nil {
-				log.Error().Err(err).Msg("Failed to scan table metrics")
+				ctxLogger(ctx).Error().Err(err).Msg("Failed to scan table metrics")
 				continue
 			}
 			tables = append(tables, ts)
@@ -438,5 +1505,41 @@ func (s *PostgresStore) GetMetrics(ctx context.Context) (*model.DatabaseMetrics,
 		metrics.Tables = tables
 	}
 
+	replication, err := s.ReplicationStatus(ctx)
+	if err != nil {
+		ctxLogger(ctx).Error().Err(err).Msg("Failed to get replication status")
+	}
+	metrics.Replication = replication
+
 	return metrics, nil
 }
+
+// ReplicationStatus 备库上按接收与回放位置的差值计算延迟，主库返回nil
+// 只从归档恢复、没有流复制时pg_last_wal_receive_lsn为NULL，延迟按0计
+func (s *PostgresStore) ReplicationStatus(ctx context.Context) (*model.ReplicationStatus, error) {
+	query := `
+		SELECT
+			pg_is_in_recovery(),
+			COALESCE(pg_wal_lsn_diff(pg_last_wal_receive_lsn(), pg_last_wal_replay_lsn()), 0)::bigint,
+			pg_last_xact_replay_timestamp()
+	`
+
+	var (
+		inRecovery bool
+		status     model.ReplicationStatus
+		replayedAt sql.NullTime
+	)
+	if err := s.db.QueryRowContext(ctx, query).Scan(&inRecovery, &status.LagBytes, &replayedAt); err != nil {
+		return nil, fmt.Errorf("failed to query replication status: %w", err)
+	}
+	if !inRecovery {
+		return nil, nil
+	}
+
+	// 接收位置可能因WAL段回收短暂落后于回放位置
+	status.LagBytes = max(status.LagBytes, 0)
+	if replayedAt.Valid {
+		status.ReplayedAt = &replayedAt.Time
+	}
+	return &status, nil
+}
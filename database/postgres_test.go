@@ -0,0 +1,226 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/model"
+)
+
+// newMockPostgresStore 使用sqlmock的PostgresStore，不执行迁移也不预编译语句
+func newMockPostgresStore(t *testing.T, opts Options) (*PostgresStore, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return &PostgresStore{db: db, opts: opts, stmts: newStatementCache(db)}, mock
+}
+
+// postgresDocumentRow postgresDocumentColumns对应的一行未压缩、未分块的文档
+func postgresDocumentRow(id, hash string, data []byte) *sqlmock.Rows {
+	now := time.Now()
+	return sqlmock.NewRows([]string{
+		"id", "content_hash", "doc_type", "json_data", "size", "created_at", "updated_at",
+		"metadata", "compression", "compressed_data", "raw_data", "chunk_count", "tags",
+	}).AddRow(id, hash, "", data, int64(len(data)), now, now, nil, "", nil, nil, 0, nil)
+}
+
+// recordingMatcher 按正则匹配期望，并按顺序记录实际执行的SQL，用于断言语句本身而非mock返回的数据
+type recordingMatcher struct {
+	issued []string
+}
+
+func (m *recordingMatcher) Match(expectedSQL, actualSQL string) error {
+	if err := sqlmock.QueryMatcherRegexp.Match(expectedSQL, actualSQL); err != nil {
+		return err
+	}
+	m.issued = append(m.issued, actualSQL)
+	return nil
+}
+
+// count 返回已执行的SQL中包含fragment的条数
+func (m *recordingMatcher) count(fragment string) int {
+	n := 0
+	for _, q := range m.issued {
+		if strings.Contains(q, fragment) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestPostgresStoreIdenticalContent(t *testing.T) {
+	data := []byte(`{"a":1}`)
+	dedup, _ := Options{}.dedupCondition("", "", "", postgresPlaceholder, 1)
+	dedupLookup := postgresSelectWhere(dedup)
+	tests := []struct {
+		name            string
+		allowDuplicates bool
+		wantLookups     int
+		wantInserts     int
+	}{
+		// 默认先按content_hash查找，命中时不再插入
+		{name: "dedup", allowDuplicates: false, wantLookups: 2, wantInserts: 1},
+		// 允许重复内容时不查找，每次都插入
+		{name: "allow duplicates", allowDuplicates: true, wantLookups: 0, wantInserts: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matcher := &recordingMatcher{}
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(matcher))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			store := &PostgresStore{db: db, opts: Options{AllowDuplicateContent: tt.allowDuplicates}, stmts: newStatementCache(db)}
+
+			// 期望只决定mock返回什么，断言以实际执行的SQL为准
+			row := func() *sqlmock.Rows { return postgresDocumentRow("00000000-0000-0000-0000-000000000001", "h", data) }
+			if tt.allowDuplicates {
+				mock.ExpectQuery("INSERT INTO json_documents").WillReturnRows(row())
+				mock.ExpectQuery("INSERT INTO json_documents").WillReturnRows(row())
+			} else {
+				mock.ExpectQuery("WHERE content_hash").WillReturnRows(sqlmock.NewRows(nil))
+				mock.ExpectQuery("INSERT INTO json_documents").WillReturnRows(row())
+				mock.ExpectQuery("WHERE content_hash").WillReturnRows(row())
+			}
+
+			ctx := context.Background()
+			if _, err := store.StoreJSON(ctx, model.StoreInput{JSONData: data}); err != nil {
+				t.Fatal(err)
+			}
+			second, err := store.StoreJSON(ctx, model.StoreInput{JSONData: data})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := matcher.count(dedupLookup); got != tt.wantLookups {
+				t.Errorf("dedup lookups = %d, want %d; issued %q", got, tt.wantLookups, matcher.issued)
+			}
+			if got := matcher.count("INSERT INTO json_documents"); got != tt.wantInserts {
+				t.Errorf("inserts = %d, want %d; issued %q", got, tt.wantInserts, matcher.issued)
+			}
+			if second.Existing == tt.allowDuplicates {
+				t.Errorf("second.Existing = %v, want %v", second.Existing, !tt.allowDuplicates)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestDedupConstraints(t *testing.T) {
+	// 允许重复内容时content_hash和raw_hash都不带唯一约束
+	for _, allow := range []bool{false, true} {
+		unique := 0
+		for _, c := range (Options{AllowDuplicateContent: allow}).dedupConstraints() {
+			if c.unique {
+				unique++
+			}
+		}
+		want := 1
+		if allow {
+			want = 0
+		}
+		if unique != want {
+			t.Errorf("AllowDuplicateContent=%v: %d unique constraints, want %d", allow, unique, want)
+		}
+	}
+}
+
+func TestPostgresStoreDocType(t *testing.T) {
+	store, mock := newMockPostgresStore(t, Options{AllowDuplicateContent: true})
+	ctx := context.Background()
+
+	// 类型写入doc_type列，未设置类型时写入NULL
+	for _, docType := range []string{"invoice", "order"} {
+		data := []byte(`{"type":"` + docType + `"}`)
+		mock.ExpectQuery("INSERT INTO json_documents").
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), docType, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+				sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(postgresDocumentRow("00000000-0000-0000-0000-00000000000"+docType[:1], "h", data))
+		if _, err := store.StoreJSON(ctx, model.StoreInput{JSONData: data, DocType: docType}); err != nil {
+			t.Fatalf("store %s: %v", docType, err)
+		}
+	}
+
+	// 按类型过滤时类型作为第一个参数传给列表查询
+	for _, docType := range []string{"invoice", "order"} {
+		mock.ExpectQuery("doc_type = \\$1").
+			WithArgs(docType, 20, 0, "").
+			WillReturnRows(postgresDocumentRow("id", "h", []byte(`{}`)))
+		documents, err := store.ListJSON(ctx, model.ListFilter{DocType: docType, Limit: 20})
+		if err != nil {
+			t.Fatalf("list %s: %v", docType, err)
+		}
+		if len(documents) != 1 {
+			t.Errorf("list %s returned %d documents, want 1", docType, len(documents))
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPostgresStoreNumberHandling(t *testing.T) {
+	data := []byte(`{"id": 9223372036854775807}`)
+	tests := []struct {
+		mode     string
+		wantHash string
+		wantRaw  []byte
+	}{
+		// 默认与已有文档的哈希一致，不保存原始字节
+		{mode: config.NumberHandlingFloat64, wantHash: calculateRawHash([]byte(`{"id":9223372036854776000}`)), wantRaw: []byte(nil)},
+		{mode: config.NumberHandlingExact, wantHash: calculateRawHash([]byte(`{"id":9223372036854775807}`)), wantRaw: data},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			var cfg config.Config
+			cfg.Database.NumberHandling = tt.mode
+			store, mock := newMockPostgresStore(t, optionsFromConfig(cfg))
+			ctx := context.Background()
+			const id = "00000000-0000-0000-0000-0000000000bb"
+
+			// 数据库的JSON类型可能改写大整数，这里用double的形式模拟
+			lossy := []byte(`{"id": 9.223372036854776e18}`)
+			row := func(raw []byte) *sqlmock.Rows {
+				now := time.Now()
+				return sqlmock.NewRows([]string{
+					"id", "content_hash", "doc_type", "json_data", "size", "created_at", "updated_at",
+					"metadata", "compression", "compressed_data", "raw_data", "chunk_count", "tags",
+				}).AddRow(id, tt.wantHash, "", lossy, int64(len(data)), now, now, nil, "", nil, raw, 0, nil)
+			}
+
+			mock.ExpectQuery("WHERE content_hash").WithArgs(tt.wantHash).WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectQuery("INSERT INTO json_documents").
+				WithArgs(sqlmock.AnyArg(), tt.wantHash, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), tt.wantRaw,
+					sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+				WillReturnRows(row(nil))
+			if _, err := store.StoreJSON(ctx, model.StoreInput{JSONData: data}); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(tt.wantRaw) > 0 {
+				mock.ExpectQuery("FROM json_documents WHERE id = \\$1").WithArgs(id).WillReturnRows(row(data))
+				doc, err := store.GetJSONByID(ctx, id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(doc.JSONData) != string(data) {
+					t.Errorf("GetJSONByID = %s, want %s", doc.JSONData, data)
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
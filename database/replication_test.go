@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPostgresStoreReplicationStatus(t *testing.T) {
+	replayed := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		inRecovery bool
+		lag        int64
+		replayedAt any
+		wantNil    bool
+		wantLag    int64
+	}{
+		{name: "primary", inRecovery: false, wantNil: true},
+		{name: "standby", inRecovery: true, lag: 65536, replayedAt: replayed, wantLag: 65536},
+		// 接收位置短暂落后于回放位置时按0计
+		{name: "receive behind replay", inRecovery: true, lag: -128, wantLag: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, mock := newMockPostgresStore(t, Options{})
+			mock.ExpectQuery(`pg_is_in_recovery\(\),\s+COALESCE\(pg_wal_lsn_diff\(pg_last_wal_receive_lsn\(\), pg_last_wal_replay_lsn\(\)\), 0\)`).
+				WillReturnRows(sqlmock.NewRows([]string{"in_recovery", "lag", "replayed_at"}).AddRow(tt.inRecovery, tt.lag, tt.replayedAt))
+
+			status, err := store.ReplicationStatus(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantNil {
+				if status != nil {
+					t.Errorf("status = %+v, want nil on a primary", status)
+				}
+				return
+			}
+			if status == nil || status.LagBytes != tt.wantLag {
+				t.Fatalf("status = %+v, want lag %d", status, tt.wantLag)
+			}
+			if tt.replayedAt != nil && (status.ReplayedAt == nil || !status.ReplayedAt.Equal(replayed)) {
+				t.Errorf("replayed_at = %v, want %v", status.ReplayedAt, replayed)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
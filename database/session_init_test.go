@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// timeoutServer 模拟按会话执行statement_timeout的数据库：SET设置本会话的超时，
+// "SELECT pg_sleep(ms)" 运行超过超时时间时中止并返回与PostgreSQL相同的错误
+type timeoutServer struct {
+	sessions int
+	failInit bool
+}
+
+func (s *timeoutServer) Connect(ctx context.Context) (driver.Conn, error) {
+	s.sessions++
+	return &timeoutSession{server: s}, nil
+}
+
+func (s *timeoutServer) Driver() driver.Driver { return nil }
+
+type timeoutSession struct {
+	server  *timeoutServer
+	timeout time.Duration
+}
+
+func (c *timeoutSession) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+func (c *timeoutSession) Close() error              { return nil }
+func (c *timeoutSession) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+func (c *timeoutSession) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if c.server.failInit {
+		return nil, errors.New("permission denied to set parameter")
+	}
+	var ms int64
+	if _, err := fmt.Sscanf(query, "SET statement_timeout = %d", &ms); err != nil {
+		return nil, fmt.Errorf("unexpected statement %q", query)
+	}
+	c.timeout = time.Duration(ms) * time.Millisecond
+	return driver.ResultNoRows, nil
+}
+
+func (c *timeoutSession) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	var ms int64
+	if _, err := fmt.Sscanf(query, "SELECT pg_sleep(%d)", &ms); err != nil {
+		return nil, fmt.Errorf("unexpected query %q", query)
+	}
+	if c.timeout > 0 && time.Duration(ms)*time.Millisecond > c.timeout {
+		return nil, errors.New("pq: canceling statement due to statement timeout")
+	}
+	return &emptyRows{}, nil
+}
+
+type emptyRows struct{}
+
+func (emptyRows) Columns() []string              { return []string{"pg_sleep"} }
+func (emptyRows) Close() error                   { return nil }
+func (emptyRows) Next(dest []driver.Value) error { return io.EOF }
+
+func TestStatementTimeoutSessionInit(t *testing.T) {
+	server := &timeoutServer{}
+	opts := Options{StatementTimeout: 100 * time.Millisecond}
+	db := sql.OpenDB(withSessionInit(server, postgresSessionInit(opts)...))
+	defer db.Close()
+	ctx := context.Background()
+
+	// 超时由数据库在每个连接上执行，与请求的context无关
+	conns := make([]*sql.Conn, 2)
+	for i := range conns {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		conns[i] = conn
+
+		rows, err := conn.QueryContext(ctx, "SELECT pg_sleep(1000)")
+		if err == nil {
+			rows.Close()
+			t.Fatalf("connection %d: slow query was not aborted", i)
+		}
+		if !strings.Contains(err.Error(), "statement timeout") {
+			t.Errorf("connection %d: error = %v, want statement timeout", i, err)
+		}
+
+		rows, err = conn.QueryContext(ctx, "SELECT pg_sleep(10)")
+		if err != nil {
+			t.Errorf("connection %d: fast query failed: %v", i, err)
+		} else {
+			rows.Close()
+		}
+	}
+	if server.sessions != 2 {
+		t.Errorf("%d sessions, want 2", server.sessions)
+	}
+}
+
+func TestSessionInit(t *testing.T) {
+	// 未设置超时时不包装连接器，也不执行任何语句
+	server := &timeoutServer{}
+	if got := withSessionInit(server, postgresSessionInit(Options{})...); got != driver.Connector(server) {
+		t.Errorf("withSessionInit without statements = %T, want the original connector", got)
+	}
+	if got := mysqlSessionInit(Options{StatementTimeout: 1500 * time.Millisecond}); len(got) != 1 || got[0] != "SET SESSION max_execution_time = 1500" {
+		t.Errorf("mysqlSessionInit() = %q", got)
+	}
+
+	// 初始化失败时关闭连接并返回错误，不把未设置超时的连接交给连接池
+	server = &timeoutServer{failInit: true}
+	connector := withSessionInit(server, postgresSessionInit(Options{StatementTimeout: time.Second})...)
+	conn, err := connector.Connect(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "SET statement_timeout = 1000") {
+		t.Errorf("Connect() = %v, %v; want init error", conn, err)
+	}
+}
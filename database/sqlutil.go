@@ -0,0 +1,33 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/leapzhao/json-store/utils"
+)
+
+// queryer *sql.DB和*sql.Tx共有的查询方法
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// nullString 空字符串写入为NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// documentSimHash 计算文档SimHash用于相似文档查询，无法计算时写入NULL
+// 按有符号BIGINT存储，比较时只关心位模式
+func documentSimHash(data []byte) sql.NullInt64 {
+	hash, err := utils.SimHash(data)
+	if err != nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(hash), Valid: true}
+}
+
+// rowScanner 兼容 *sql.Row 和 *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
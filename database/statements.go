@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// statementCache 热点查询的预编译语句，按SQL文本索引
+// 首次使用时一次性准备，之后各请求共用；database/sql在每个连接上按需重新准备
+// 准备失败的语句（如经过事务级连接池代理时）回退为直接执行，不影响功能
+type statementCache struct {
+	db      *sql.DB
+	queries []string
+
+	once  sync.Once
+	mu    sync.RWMutex
+	stmts map[string]*sql.Stmt
+}
+
+func newStatementCache(db *sql.DB, queries ...string) *statementCache {
+	return &statementCache{db: db, queries: queries}
+}
+
+func (c *statementCache) prepare() {
+	stmts := make(map[string]*sql.Stmt, len(c.queries))
+	for _, query := range c.queries {
+		if _, ok := stmts[query]; ok {
+			continue
+		}
+		stmt, err := c.db.PrepareContext(context.Background(), query)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to prepare statement, falling back to ad hoc queries")
+			continue
+		}
+		stmts[query] = stmt
+	}
+
+	c.mu.Lock()
+	c.stmts = stmts
+	c.mu.Unlock()
+}
+
+// stmt 返回query的预编译语句，不在缓存中或已关闭时返回nil
+func (c *statementCache) stmt(query string) *sql.Stmt {
+	c.once.Do(c.prepare)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.stmts[query]
+}
+
+func (c *statementCache) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	if stmt := c.stmt(query); stmt != nil {
+		return stmt.QueryRowContext(ctx, args...)
+	}
+	return c.db.QueryRowContext(ctx, query, args...)
+}
+
+func (c *statementCache) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if stmt := c.stmt(query); stmt != nil {
+		return stmt.QueryContext(ctx, args...)
+	}
+	return c.db.QueryContext(ctx, query, args...)
+}
+
+func (c *statementCache) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if stmt := c.stmt(query); stmt != nil {
+		return stmt.ExecContext(ctx, args...)
+	}
+	return c.db.ExecContext(ctx, query, args...)
+}
+
+// Close 关闭全部预编译语句，之后的查询直接执行；未使用过时不再准备
+func (c *statementCache) Close() error {
+	c.once.Do(func() {})
+
+	c.mu.Lock()
+	stmts := c.stmts
+	c.stmts = nil
+	c.mu.Unlock()
+
+	var errs []error
+	for _, stmt := range stmts {
+		if err := stmt.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
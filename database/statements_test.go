@@ -0,0 +1,180 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// parseServer 统计语句解析和关闭次数的数据库：连接不支持直接执行，
+// database/sql对未预编译的查询每次都先Prepare再执行，与服务端每次解析SQL对应
+type parseServer struct {
+	parses atomic.Int64
+	closes atomic.Int64
+}
+
+func (s *parseServer) Connect(ctx context.Context) (driver.Conn, error) {
+	return &parseConn{server: s}, nil
+}
+
+func (s *parseServer) Driver() driver.Driver { return nil }
+
+type parseConn struct {
+	server *parseServer
+}
+
+func (c *parseConn) Prepare(query string) (driver.Stmt, error) {
+	c.server.parses.Add(1)
+	return &parsedStmt{server: c.server}, nil
+}
+
+func (c *parseConn) Close() error              { return nil }
+func (c *parseConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+type parsedStmt struct {
+	server *parseServer
+}
+
+func (s *parsedStmt) Close() error {
+	s.server.closes.Add(1)
+	return nil
+}
+
+func (s *parsedStmt) NumInput() int { return -1 }
+
+func (s *parsedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+
+func (s *parsedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &documentRows{}, nil
+}
+
+// documentRows 返回一行postgresDocumentColumns格式的文档
+type documentRows struct {
+	done bool
+}
+
+func (r *documentRows) Columns() []string {
+	return []string{
+		"id", "content_hash", "doc_type", "json_data", "size", "created_at", "updated_at",
+		"metadata", "compression", "compressed_data", "raw_data", "chunk_count", "tags",
+	}
+}
+
+func (r *documentRows) Close() error { return nil }
+
+func (r *documentRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	now := time.Now()
+	values := []driver.Value{
+		"00000000-0000-0000-0000-0000000000e1", "h", "", []byte(`{"a":1}`), int64(7), now, now,
+		nil, "", nil, nil, int64(0), nil,
+	}
+	copy(dest, values)
+	return nil
+}
+
+// newParseCountingStore 使用parseServer的PostgresStore，prepared为false时不预编译任何语句
+func newParseCountingStore(prepared bool) (*PostgresStore, *parseServer) {
+	server := &parseServer{}
+	db := sql.OpenDB(server)
+	store := &PostgresStore{db: db, stmts: newStatementCache(db)}
+	if prepared {
+		store.stmts = newStatementCache(db, postgresHotQueries(Options{})...)
+	}
+	return store, server
+}
+
+func TestStatementCacheReuseAndClose(t *testing.T) {
+	store, server := newParseCountingStore(true)
+	ctx := context.Background()
+	distinct := make(map[string]bool)
+	for _, query := range postgresHotQueries(Options{}) {
+		distinct[query] = true
+	}
+
+	// 首次使用时准备全部热点语句，之后的读取不再解析
+	for i := 0; i < 3; i++ {
+		if _, err := store.GetJSONByID(ctx, "00000000-0000-0000-0000-0000000000e1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := server.parses.Load(); got != int64(len(distinct)) {
+		t.Errorf("%d parses after 3 reads, want %d (one per hot query)", got, len(distinct))
+	}
+	if got := server.closes.Load(); got != 0 {
+		t.Errorf("%d statements closed before Close, want 0", got)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if parses, closes := server.parses.Load(), server.closes.Load(); closes != parses {
+		t.Errorf("Close closed %d of %d prepared statements", closes, parses)
+	}
+}
+
+func TestStatementCacheAdHoc(t *testing.T) {
+	// 未预编译的查询每次都解析，执行后即关闭
+	store, server := newParseCountingStore(false)
+	defer store.Close()
+	for i := 0; i < 3; i++ {
+		if _, err := store.GetJSONByID(context.Background(), "00000000-0000-0000-0000-0000000000e1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if parses, closes := server.parses.Load(), server.closes.Load(); parses != 3 || closes != 3 {
+		t.Errorf("parses = %d, closes = %d, want 3 and 3", parses, closes)
+	}
+}
+
+func BenchmarkPostgresGetJSONByID(b *testing.B) {
+	for _, bc := range []struct {
+		name     string
+		prepared bool
+	}{
+		{name: "ad hoc", prepared: false},
+		{name: "prepared", prepared: true},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			store, server := newParseCountingStore(bc.prepared)
+			defer store.Close()
+			ctx := context.Background()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := store.GetJSONByID(ctx, "00000000-0000-0000-0000-0000000000e1"); err != nil {
+					b.Fatal(err)
+				}
+			}
+			// 每次请求的服务端解析次数，真实数据库中对应每次的解析和计划开销
+			b.ReportMetric(float64(server.parses.Load())/float64(b.N), "parses/op")
+		})
+	}
+}
+
+func TestMySQLStoreCloseStatements(t *testing.T) {
+	server := &parseServer{}
+	db := sql.OpenDB(server)
+	store := &MySQLStore{db: db, stmts: newStatementCache(db, mysqlHotQueries(Options{})...)}
+
+	if _, err := store.GetJSONByID(context.Background(), "00000000-0000-0000-0000-0000000000e2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if parses, closes := server.parses.Load(), server.closes.Load(); parses == 0 || closes != parses {
+		t.Errorf("Close closed %d of %d prepared statements", closes, parses)
+	}
+}
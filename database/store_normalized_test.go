@@ -0,0 +1,58 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/leapzhao/json-store/model"
+)
+
+func TestPostgresStoreNormalized(t *testing.T) {
+	compact := []byte(`{"a":1,"b":[true,null]}`)
+	formatted := []byte("{\n  \"b\": [ true, null ],\n  \"a\": 1\n}")
+	tests := []struct {
+		name            string
+		storeNormalized bool
+		want            [][]byte
+	}{
+		// 格式不同的相同内容写入相同的字节和大小
+		{name: "normalized", storeNormalized: true, want: [][]byte{compact, compact}},
+		// 默认按请求原样保存
+		{name: "as sent", storeNormalized: false, want: [][]byte{compact, formatted}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 允许重复内容，两次写入都执行INSERT，便于比较写入的参数
+			store, mock := newMockPostgresStore(t, Options{StoreNormalized: tt.storeNormalized, AllowDuplicateContent: true})
+			for i, data := range tt.want {
+				mock.ExpectQuery("INSERT INTO json_documents").
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), data, int64(len(data)), sqlmock.AnyArg(),
+						sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnRows(postgresDocumentRow(fmt.Sprintf("00000000-0000-0000-0000-%012d", i+1), "h", data))
+			}
+
+			for _, data := range [][]byte{compact, formatted} {
+				if _, err := store.StoreJSON(context.Background(), model.StoreInput{JSONData: data}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestStoredInputUnnormalizable(t *testing.T) {
+	// 无法规范化的文档原样交给后续校验，不因开启StoreNormalized而改变
+	invalid := []byte(`{"a":`)
+	input, err := Options{StoreNormalized: true}.storedInput(model.StoreInput{JSONData: invalid})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(input.JSONData) != string(invalid) {
+		t.Errorf("storedInput() = %s, want %s unchanged", input.JSONData, invalid)
+	}
+}
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/leapzhao/json-store/model"
+)
+
+// documentSummaryColumns 文档摘要查询列，顺序与querySummaries一致，不含文档内容
+const documentSummaryColumns = `id, content_hash, COALESCE(doc_type, ''), size, created_at, metadata`
+
+// querySummaries 查询文档摘要列表
+func querySummaries(ctx context.Context, db *sql.DB, query string, args ...any) ([]model.DocumentSummary, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query document summaries: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := make([]model.DocumentSummary, 0)
+	for rows.Next() {
+		var summary model.DocumentSummary
+		var metadata []byte
+		if err := rows.Scan(
+			&summary.ID, &summary.ContentHash, &summary.DocType, &summary.Size, &summary.CreatedAt, &metadata,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan document summary: %w", err)
+		}
+
+		if len(metadata) > 0 {
+			if err := json.Unmarshal(metadata, &summary.Metadata); err != nil {
+				ctxLogger(ctx).Error().Err(err).Str("id", summary.ID).Msg("Failed to unmarshal metadata")
+			}
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating document summaries: %w", err)
+	}
+
+	return summaries, nil
+}
@@ -0,0 +1,40 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPostgresStoreLargestDocuments(t *testing.T) {
+	store, mock := newMockPostgresStore(t, Options{})
+	now := time.Now()
+
+	// 数据库按size降序返回前limit个，摘要不含文档内容
+	mock.ExpectQuery("ORDER BY size DESC, id\\s+LIMIT \\$1").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "content_hash", "doc_type", "size", "created_at", "metadata"}).
+			AddRow("big", "h1", "report", int64(1<<20), now, []byte(`{"owner":"ops"}`)).
+			AddRow("medium", "h2", "", int64(4096), now, nil))
+
+	summaries, err := store.LargestDocuments(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summaries) != 2 {
+		t.Fatalf("got %d summaries, want 2", len(summaries))
+	}
+	if summaries[0].ID != "big" || summaries[1].ID != "medium" || summaries[0].Size < summaries[1].Size {
+		t.Errorf("summaries = %+v, want big then medium", summaries)
+	}
+	if summaries[0].Metadata["owner"] != "ops" || summaries[0].DocType != "report" {
+		t.Errorf("first summary = %+v, want metadata owner=ops and type report", summaries[0])
+	}
+	if summaries[1].Metadata != nil {
+		t.Errorf("second summary metadata = %v, want none", summaries[1].Metadata)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,94 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/leapzhao/json-store/model"
+)
+
+func TestDocumentTags(t *testing.T) {
+	tests := []struct {
+		tags []string
+		want string
+	}{
+		{tags: nil, want: `[]`},
+		// 去重并排序，相同标签集合写入相同的值
+		{tags: []string{"urgent", "billing", "urgent"}, want: `["billing","urgent"]`},
+	}
+	for _, tt := range tests {
+		if got := documentTags(model.StoreInput{Tags: tt.tags}); got != tt.want {
+			t.Errorf("documentTags(%q) = %s, want %s", tt.tags, got, tt.want)
+		}
+	}
+}
+
+// taggedDocumentRow 带标签的一行文档，tags为数据库返回的JSON数组
+func taggedDocumentRow(id string, tags string) *sqlmock.Rows {
+	now := time.Now()
+	data := []byte(`{"invoice":1}`)
+	return sqlmock.NewRows([]string{
+		"id", "content_hash", "doc_type", "json_data", "size", "created_at", "updated_at",
+		"metadata", "compression", "compressed_data", "raw_data", "chunk_count", "tags",
+	}).AddRow(id, "h", "", data, int64(len(data)), now, now, nil, "", nil, nil, 0, []byte(tags))
+}
+
+func TestPostgresStoreTags(t *testing.T) {
+	store, mock := newMockPostgresStore(t, Options{AllowDuplicateContent: true})
+	ctx := context.Background()
+	const id = "00000000-0000-0000-0000-0000000000a7"
+
+	// 标签作为第10个参数以JSON数组写入
+	mock.ExpectQuery("INSERT INTO json_documents").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), `["billing","urgent"]`).
+		WillReturnRows(taggedDocumentRow(id, `["billing","urgent"]`))
+	doc, err := store.StoreJSON(ctx, model.StoreInput{JSONData: []byte(`{"invoice":1}`), Tags: []string{"urgent", "billing"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Tags) != 2 || doc.Tags[0] != "billing" {
+		t.Errorf("stored tags = %q, want [billing urgent]", doc.Tags)
+	}
+
+	// 按标签过滤时使用可走GIN索引的包含条件
+	mock.ExpectQuery("tags @> ARRAY\\[\\$4::text\\]").
+		WithArgs("", 20, 0, "urgent").
+		WillReturnRows(taggedDocumentRow(id, `["billing","urgent"]`))
+	documents, err := store.ListJSON(ctx, model.ListFilter{Tag: "urgent", Limit: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(documents) != 1 || documents[0].ID != id {
+		t.Errorf("ListJSON(tag=urgent) = %d documents, want %s", len(documents), id)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMySQLStoreListByTag(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	store := &MySQLStore{db: db}
+
+	// 类型和标签各出现两次（空值判断和过滤条件）
+	mock.ExpectQuery("MEMBER OF").
+		WithArgs("invoice", "invoice", "urgent", "urgent", 10, 0).
+		WillReturnRows(taggedDocumentRow("00000000-0000-0000-0000-0000000000a8", `["urgent"]`))
+	documents, err := store.ListJSON(context.Background(), model.ListFilter{DocType: "invoice", Tag: "urgent", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(documents) != 1 || len(documents[0].Tags) != 1 || documents[0].Tags[0] != "urgent" {
+		t.Errorf("ListJSON(tag=urgent) = %+v", documents)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
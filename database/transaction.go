@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/leapzhao/json-store/model"
+)
+
+// transactionQueries 原子批量存储使用的SQL，按数据库方言提供
+type transactionQueries struct {
+	// InsertDocument 参数：ID、哈希、类型、内容、大小、原始字节、SimHash、原始字节哈希、metadata、标签（JSON数组）
+	InsertDocument string
+	// Placeholder 按参数序号生成占位符
+	Placeholder func(int) string
+}
+
+// storeDocumentsAtomically 在一个事务中存储全部文档，任意一个无效或写入失败则全部回滚
+// 与StoreJSONBatch不同，不会跳过失败的文档；命中去重的文档返回已有记录
+// 所有读取都在事务内进行，同一请求中重复的内容能找到前面刚插入的文档
+func storeDocumentsAtomically(
+	ctx context.Context,
+	db *sql.DB,
+	queries transactionQueries,
+	chunks chunkQueries,
+	scan func(rowScanner) (*model.JSONDocument, error),
+	opts Options,
+	inputs []model.StoreInput,
+) ([]*model.JSONDocument, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	results := make([]*model.JSONDocument, 0, len(inputs))
+	for i, input := range inputs {
+		doc, err := storeDocumentTx(ctx, tx, queries, chunks, scan, opts, input)
+		if err != nil {
+			return nil, fmt.Errorf("document at index %d: %w", i, err)
+		}
+		results = append(results, doc)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return results, nil
+}
+
+// storeDocumentTx 在事务tx中存储一个文档，命中去重时返回已有记录
+func storeDocumentTx(
+	ctx context.Context,
+	tx *sql.Tx,
+	queries transactionQueries,
+	chunks chunkQueries,
+	scan func(rowScanner) (*model.JSONDocument, error),
+	opts Options,
+	input model.StoreInput,
+) (*model.JSONDocument, error) {
+	input, err := opts.storedInput(input)
+	if err != nil {
+		return nil, err
+	}
+	data := input.JSONData
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("invalid JSON data")
+	}
+
+	hash, err := opts.contentHash(data)
+	if err != nil {
+		return nil, err
+	}
+	rawHash := calculateRawHash(data)
+	size := int64(len(data))
+
+	if !opts.AllowDuplicateContent {
+		var existingID string
+		condition, args := opts.dedupCondition(hash, rawHash, input.DocType, queries.Placeholder, 1)
+		err := tx.QueryRowContext(ctx, "SELECT id FROM json_documents WHERE "+condition+" LIMIT 1", args...).Scan(&existingID)
+		if err == nil {
+			doc, err := scan(tx.QueryRowContext(ctx, chunks.SelectDocument, existingID))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read existing document: %w", err)
+			}
+			if err := loadChunks(ctx, tx, chunks.SelectChunks, doc); err != nil {
+				return nil, err
+			}
+			doc.Existing = true
+			return doc, nil
+		}
+		if err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to check duplicate content: %w", err)
+		}
+	}
+
+	id := uuid.New().String()
+	if opts.shouldChunk(size) {
+		return insertChunkedDocument(ctx, tx, chunks, scan, id, hash, input, opts.ChunkSize)
+	}
+
+	var raw []byte
+	if opts.PreserveRawBytes {
+		raw = data
+	}
+	metadata, err := documentMetadata(input)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, queries.InsertDocument,
+		id, hash, nullString(input.DocType), data, size, raw, documentSimHash(data), rawHash, metadata, documentTags(input),
+	); err != nil {
+		return nil, fmt.Errorf("failed to insert: %w", err)
+	}
+
+	doc, err := scan(tx.QueryRowContext(ctx, chunks.SelectDocument, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inserted document: %w", err)
+	}
+	return doc, nil
+}
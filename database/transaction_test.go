@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/leapzhao/json-store/model"
+)
+
+func TestPostgresStoreJSONTransaction(t *testing.T) {
+	valid := []byte(`{"n":1}`)
+	expectInsert := func(mock sqlmock.Sqlmock, id string, data []byte) {
+		mock.ExpectQuery("SELECT id FROM json_documents WHERE content_hash = \\$1").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectExec("INSERT INTO json_documents").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("FROM json_documents WHERE id = \\$1").WillReturnRows(postgresDocumentRow(id, "h", data))
+	}
+
+	t.Run("all stored", func(t *testing.T) {
+		store, mock := newMockPostgresStore(t, Options{})
+		mock.ExpectBegin()
+		expectInsert(mock, "00000000-0000-0000-0000-000000000001", valid)
+		expectInsert(mock, "00000000-0000-0000-0000-000000000002", []byte(`{"n":2}`))
+		mock.ExpectCommit()
+
+		docs, err := store.StoreJSONTransaction(context.Background(), []model.StoreInput{
+			{JSONData: valid}, {JSONData: []byte(`{"n":2}`)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(docs) != 2 {
+			t.Errorf("got %d documents, want 2", len(docs))
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("invalid document rolls back", func(t *testing.T) {
+		store, mock := newMockPostgresStore(t, Options{})
+		// 第一个文档已在事务中插入，第二个无效时整个事务回滚，不提交
+		mock.ExpectBegin()
+		expectInsert(mock, "00000000-0000-0000-0000-000000000001", valid)
+		mock.ExpectRollback()
+
+		docs, err := store.StoreJSONTransaction(context.Background(), []model.StoreInput{
+			{JSONData: valid}, {JSONData: []byte(`{"n":`)},
+		})
+		if err == nil {
+			t.Fatalf("StoreJSONTransaction returned %d documents, want error", len(docs))
+		}
+		if !strings.Contains(err.Error(), "index 1") {
+			t.Errorf("error %q does not name the failing index", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leapzhao/json-store/model"
+)
+
+// ErrDocumentNotFound 要更新的文档不存在
+var ErrDocumentNotFound = errors.New("document not found")
+
+// ErrDuplicateContent 新内容已由其他文档保存（未开启allow_duplicate_content时内容哈希唯一）
+var ErrDuplicateContent = errors.New("content already stored by another document")
+
+// updateQueries 更新文档使用的SQL，按数据库方言提供
+type updateQueries struct {
+	// LockDocument 参数：ID，锁定要更新的行并返回当前类型（NULL为空字符串）
+	LockDocument string
+	// FindDuplicate 参数：ID、去重条件的参数，返回内容相同的其他文档ID，%s为去重条件
+	FindDuplicate string
+	// Placeholder 按参数序号生成占位符
+	Placeholder func(int) string
+	// DeleteChunks 参数：文档ID
+	DeleteChunks string
+	// UpdateDocument 参数：哈希、类型、内容、大小、原始字节、分块数、SimHash、原始字节哈希、ID
+	// 类型为NULL时保留原值，同时清除压缩数据
+	UpdateDocument string
+}
+
+// updateDocument 在事务中替换文档内容，ID和创建时间保持不变
+// 新内容按当前的分块阈值重新决定存储方式，旧的分块和压缩数据一并清除
+func updateDocument(
+	ctx context.Context,
+	db *sql.DB,
+	queries updateQueries,
+	chunks chunkQueries,
+	scan func(rowScanner) (*model.JSONDocument, error),
+	opts Options,
+	id string,
+	input model.StoreInput,
+) (*model.JSONDocument, error) {
+	// 文档ID都是UUID，提前拦截避免PostgreSQL报类型错误
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrDocumentNotFound
+	}
+
+	input, err := opts.storedInput(input)
+	if err != nil {
+		return nil, err
+	}
+	data := input.JSONData
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("invalid JSON data")
+	}
+
+	hash, err := opts.contentHash(data)
+	if err != nil {
+		return nil, err
+	}
+	rawHash := calculateRawHash(data)
+	size := int64(len(data))
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var currentType string
+	if err := tx.QueryRowContext(ctx, queries.LockDocument, id).Scan(&currentType); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrDocumentNotFound
+		}
+		return nil, fmt.Errorf("failed to lock document: %w", err)
+	}
+
+	if !opts.AllowDuplicateContent {
+		// 未指定新类型时保留原类型，按更新后的类型查找重复
+		docType := input.DocType
+		if docType == "" {
+			docType = currentType
+		}
+
+		var existingID string
+		condition, args := opts.dedupCondition(hash, rawHash, docType, queries.Placeholder, 2)
+		err := tx.QueryRowContext(ctx, fmt.Sprintf(queries.FindDuplicate, condition),
+			append([]any{id}, args...)...,
+		).Scan(&existingID)
+		if err == nil {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateContent, existingID)
+		}
+		if err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to check duplicate content: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, queries.DeleteChunks, id); err != nil {
+		return nil, fmt.Errorf("failed to delete chunks: %w", err)
+	}
+
+	stored, raw, count := data, []byte(nil), 0
+	if opts.shouldChunk(size) {
+		stored = []byte(chunkedPlaceholder)
+		count = chunkCount(len(data), opts.ChunkSize)
+	} else if opts.PreserveRawBytes {
+		raw = data
+	}
+
+	if _, err := tx.ExecContext(ctx, queries.UpdateDocument,
+		hash, nullString(input.DocType), stored, size, raw, count, documentSimHash(data), rawHash, id,
+	); err != nil {
+		return nil, fmt.Errorf("failed to update document: %w", err)
+	}
+
+	if count > 0 {
+		if err := insertChunks(ctx, tx, chunks.InsertChunk, id, data, opts.ChunkSize); err != nil {
+			return nil, err
+		}
+	}
+
+	doc, err := scan(tx.QueryRowContext(ctx, chunks.SelectDocument, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read updated document: %w", err)
+	}
+	doc.JSONData = data
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return doc, nil
+}
+
+// restoreTimestamps 设置文档的创建和修改时间，query参数：created_at、updated_at、ID
+// 只修改时间戳，PostgreSQL的updated_at触发器保留给定的值
+func restoreTimestamps(ctx context.Context, db *sql.DB, query, id string, createdAt, updatedAt time.Time) error {
+	if _, err := db.ExecContext(ctx, query, createdAt, updatedAt, id); err != nil {
+		return fmt.Errorf("failed to restore timestamps: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/leapzhao/json-store/model"
+)
+
+func TestPostgresStoreUpdateJSON(t *testing.T) {
+	store, mock := newMockPostgresStore(t, Options{})
+	ctx := context.Background()
+	const id = "00000000-0000-0000-0000-0000000000cc"
+	data := []byte(`{"v":2}`)
+
+	// 锁定、查重、清除旧分块、更新、读回，全部在一个事务中
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(postgresUpdateQueries.LockDocument)).WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"doc_type"}).AddRow("config"))
+	mock.ExpectQuery("SELECT id FROM json_documents WHERE id <> \\$1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectExec(regexp.QuoteMeta(postgresUpdateQueries.DeleteChunks)).WithArgs(id).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE json_documents").
+		WithArgs(sqlmock.AnyArg(), nil, data, int64(len(data)), []byte(nil), 0, sqlmock.AnyArg(), sqlmock.AnyArg(), id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("FROM json_documents WHERE id = \\$1").WithArgs(id).
+		WillReturnRows(postgresDocumentRow(id, "h", data))
+	mock.ExpectCommit()
+
+	doc, err := store.UpdateJSON(ctx, id, model.StoreInput{JSONData: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.ID != id || string(doc.JSONData) != string(data) {
+		t.Errorf("updated document = %s %s, want %s %s", doc.ID, doc.JSONData, id, data)
+	}
+
+	// 文档不存在时回滚并返回ErrDocumentNotFound
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(postgresUpdateQueries.LockDocument)).WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"doc_type"}))
+	mock.ExpectRollback()
+	if _, err := store.UpdateJSON(ctx, id, model.StoreInput{JSONData: data}); !errors.Is(err, ErrDocumentNotFound) {
+		t.Errorf("missing document: err = %v, want ErrDocumentNotFound", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
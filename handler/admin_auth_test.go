@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+)
+
+// 管理任务在处理参数和访问存储前检查管理员认证，store为nil，被调用时会panic
+func TestAdminJobsRequireAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var cfg config.Config
+	cfg.Security.AdminUsername = "admin"
+	cfg.Security.AdminPassword = "secret"
+	h := NewJSONHandler(nil, cfg)
+	router := gin.New()
+	router.POST("/api/admin/maintenance/compress", h.CompressDocuments)
+	router.POST("/api/admin/backup/export", h.ExportBackup)
+	router.POST("/api/admin/backup/import", h.ImportBackup)
+
+	paths := []string{
+		"/api/admin/maintenance/compress",
+		"/api/admin/backup/export",
+		"/api/admin/backup/import?key=backup.ndjson.gz",
+	}
+	for _, path := range paths {
+		for _, pass := range []string{"", "wrong"} {
+			req := httptest.NewRequest(http.MethodPost, path, nil)
+			if pass != "" {
+				req.SetBasicAuth("admin", pass)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("%s password %q: status = %d, want %d: %s", path, pass, w.Code, http.StatusUnauthorized, w.Body)
+			}
+		}
+	}
+}
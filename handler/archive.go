@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/model"
+	"github.com/leapzhao/json-store/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// archiveManifest ZIP归档中的manifest.json，记录请求的ID中哪些已写入、哪些未找到
+type archiveManifest struct {
+	Requested int       `json:"requested"`
+	Included  []string  `json:"included"`
+	Missing   []string  `json:"missing"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GetJSONArchive 以ZIP格式下载多个文档，每个文档一个<id>.json条目，末尾附manifest.json
+// 文档逐个读取并直接写入响应，不在内存中缓存整个归档；找不到的ID跳过并记录在manifest中
+func (h *JSONHandler) GetJSONArchive(c *gin.Context) {
+	ids := utils.ParseCommaSeparatedIDs(c.Query("ids"))
+	if len(ids) == 0 {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "MISSING_IDS",
+			Message: "At least one ID is required",
+		})
+		return
+	}
+
+	if len(ids) > 100 {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "TOO_MANY_IDS",
+			Message: "Maximum 100 IDs allowed per request",
+		})
+		return
+	}
+
+	// 先写出头部，之后的内容不经响应信封等中间件缓冲
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", `attachment; filename="json-documents.zip"`)
+	c.Status(http.StatusOK)
+	c.Writer.WriteHeaderNow()
+
+	manifest := archiveManifest{
+		Requested: len(ids),
+		Included:  []string{},
+		Missing:   []string{},
+		CreatedAt: time.Now().UTC(),
+	}
+
+	zw := zip.NewWriter(c.Writer)
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		doc, err := h.store.GetJSONByID(c.Request.Context(), id)
+		if err != nil {
+			log.Debug().Err(err).Str("id", id).Msg("Document not added to archive")
+			manifest.Missing = append(manifest.Missing, id)
+			continue
+		}
+
+		entry, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     doc.ID + ".json",
+			Method:   zip.Deflate,
+			Modified: doc.UpdatedAt,
+		})
+		if err == nil {
+			_, err = entry.Write(doc.JSONData)
+		}
+		if err != nil {
+			// 头部已经写出，只能中断连接，客户端会得到不完整的归档
+			log.Error().Err(err).Str("id", id).Msg("Failed to write archive entry")
+			c.Abort()
+			return
+		}
+		manifest.Included = append(manifest.Included, doc.ID)
+	}
+
+	data, _ := json.MarshalIndent(manifest, "", "  ")
+	entry, err := zw.Create("manifest.json")
+	if err == nil {
+		_, err = entry.Write(data)
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to finish archive")
+		c.Abort()
+	}
+}
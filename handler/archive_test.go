@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+)
+
+func TestGetJSONArchive(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const (
+		a       = "00000000-0000-0000-0000-0000000000a1"
+		b       = "00000000-0000-0000-0000-0000000000b2"
+		missing = "00000000-0000-0000-0000-0000000000ff"
+	)
+	store := &rawBytesStore{docs: map[string][]byte{
+		a: []byte(`{"name":"a"}`),
+		b: []byte(`[1,2,3]`),
+	}}
+	router := gin.New()
+	router.GET("/api/v1/json/archive", NewJSONHandler(store, config.Config{}).GetJSONArchive)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/json/archive?ids="+strings.Join([]string{a, missing, b, a}, ","), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/zip" {
+		t.Errorf("Content-Type = %q, want application/zip", ct)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := make(map[string]string)
+	var names []string
+	for _, f := range archive.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[f.Name] = string(data)
+		names = append(names, f.Name)
+	}
+
+	// 重复的ID只写入一次，manifest在最后
+	wantNames := []string{a + ".json", b + ".json", "manifest.json"}
+	if strings.Join(names, ",") != strings.Join(wantNames, ",") {
+		t.Fatalf("entries = %v, want %v", names, wantNames)
+	}
+	if entries[a+".json"] != `{"name":"a"}` || entries[b+".json"] != `[1,2,3]` {
+		t.Errorf("document entries = %v", entries)
+	}
+
+	var manifest archiveManifest
+	if err := json.Unmarshal([]byte(entries["manifest.json"]), &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Requested != 4 || strings.Join(manifest.Included, ",") != a+","+b || strings.Join(manifest.Missing, ",") != missing {
+		t.Errorf("manifest = %+v", manifest)
+	}
+}
+
+func TestGetJSONArchiveTooManyIDs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/v1/json/archive", NewJSONHandler(&rawBytesStore{docs: map[string][]byte{}}, config.Config{}).GetJSONArchive)
+
+	ids := make([]string, 101)
+	for i := range ids {
+		ids[i] = "00000000-0000-0000-0000-000000000001"
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/json/archive?ids="+strings.Join(ids, ","), nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+	"github.com/rs/zerolog/log"
+)
+
+// attachmentNamePattern 附件名称：字母数字开头，可含 . _ -，最长128个字符，如 photo.png
+var attachmentNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+// defaultAttachmentType 请求未指定Content-Type时附件的类型
+const defaultAttachmentType = "application/octet-stream"
+
+// requireAttachmentName 名称格式无效时写出400并返回false
+func requireAttachmentName(c *gin.Context, name string) bool {
+	if attachmentNamePattern.MatchString(name) {
+		return true
+	}
+	c.JSON(http.StatusBadRequest, model.ErrorResponse{
+		Error:   "INVALID_NAME",
+		Message: "attachment name must start with a letter or digit, contain only letters, digits and . _ -, and be at most 128 characters",
+	})
+	return false
+}
+
+// StoreAttachment 保存文档的二进制附件：POST /json/:id/attachments?name=photo.png
+// 请求体就是附件内容，Content-Type原样保存并在读取时返回；同名附件已存在时覆盖
+func (h *JSONHandler) StoreAttachment(c *gin.Context) {
+	id := c.Param("id")
+	if !requireDocumentID(c, id) {
+		return
+	}
+	name := c.Query("name")
+	if !requireAttachmentName(c, name) {
+		return
+	}
+
+	contentType := c.GetHeader("Content-Type")
+	if contentType == "" {
+		contentType = defaultAttachmentType
+	}
+	if _, _, err := mime.ParseMediaType(contentType); err != nil || len(contentType) > 255 {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "INVALID_CONTENT_TYPE",
+			Message: "Content-Type must be a valid media type of at most 255 characters",
+		})
+		return
+	}
+
+	limit := h.config.Server.AttachmentMaxBytes
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, limit))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, model.ErrorResponse{
+				Error:   "REQUEST_TOO_LARGE",
+				Message: fmt.Sprintf("Attachment exceeds %d bytes", limit),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "INVALID_REQUEST",
+			Message: "Failed to read request body",
+		})
+		return
+	}
+	if len(data) == 0 {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "EMPTY_ATTACHMENT",
+			Message: "Request body must not be empty",
+		})
+		return
+	}
+
+	start := time.Now()
+	attachment, err := h.store.StoreAttachment(c.Request.Context(), id, name, contentType, data)
+	if err != nil {
+		if errors.Is(err, database.ErrDocumentNotFound) {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{
+				Error:   "NOT_FOUND",
+				Message: "Document not found",
+			})
+			return
+		}
+		log.Error().Err(err).Str("id", id).Str("name", name).Msg("Failed to store attachment")
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Error:   "STORAGE_ERROR",
+			Message: "Failed to store attachment",
+		})
+		return
+	}
+
+	log.Info().
+		Str("id", id).
+		Str("name", name).
+		Int64("size", attachment.Size).
+		Dur("duration", time.Since(start)).
+		Msg("Attachment stored")
+
+	c.Header("Location", "/api/v1/json/"+id+"/attachments/"+name)
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// GetAttachment 返回附件内容：GET /json/:id/attachments/:name
+// ETag为内容哈希，支持Range和条件请求；以下载方式返回，避免浏览器直接渲染上传的HTML等内容
+func (h *JSONHandler) GetAttachment(c *gin.Context) {
+	id := c.Param("id")
+	if !requireDocumentID(c, id) {
+		return
+	}
+	name := c.Param("name")
+	if !requireAttachmentName(c, name) {
+		return
+	}
+
+	attachment, err := h.store.GetAttachment(c.Request.Context(), id, name)
+	if err != nil {
+		if errors.Is(err, database.ErrAttachmentNotFound) {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{
+				Error:   "NOT_FOUND",
+				Message: fmt.Sprintf("Attachment %s not found", name),
+			})
+			return
+		}
+		log.Error().Err(err).Str("id", id).Str("name", name).Msg("Failed to get attachment")
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Error:   "QUERY_ERROR",
+			Message: "Failed to get attachment",
+		})
+		return
+	}
+
+	c.Header("Content-Type", attachment.ContentType)
+	c.Header("ETag", `"`+attachment.ContentHash+`"`)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	http.ServeContent(c.Writer, c.Request, "", attachment.UpdatedAt, bytes.NewReader(attachment.Data))
+}
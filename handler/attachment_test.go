@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// attachmentBlobStore 在内存中按文档ID和名称保存附件，只认识documents中的文档
+type attachmentBlobStore struct {
+	database.JSONStore
+	documents   map[string]bool
+	attachments map[string]*model.Attachment
+}
+
+func (s *attachmentBlobStore) StoreAttachment(ctx context.Context, documentID, name, contentType string, data []byte) (*model.Attachment, error) {
+	if !s.documents[documentID] {
+		return nil, database.ErrDocumentNotFound
+	}
+	sum := sha256.Sum256(data)
+	attachment := &model.Attachment{
+		DocumentID:  documentID,
+		Name:        name,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		ContentHash: hex.EncodeToString(sum[:]),
+		UpdatedAt:   time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC),
+		Data:        data,
+	}
+	s.attachments[documentID+"/"+name] = attachment
+	return attachment, nil
+}
+
+func (s *attachmentBlobStore) GetAttachment(ctx context.Context, documentID, name string) (*model.Attachment, error) {
+	attachment, ok := s.attachments[documentID+"/"+name]
+	if !ok {
+		return nil, database.ErrAttachmentNotFound
+	}
+	return attachment, nil
+}
+
+func TestAttachments(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const id = "00000000-0000-0000-0000-0000000000b2"
+	var cfg config.Config
+	cfg.Server.AttachmentMaxBytes = 16
+	store := &attachmentBlobStore{documents: map[string]bool{id: true}, attachments: map[string]*model.Attachment{}}
+	handler := NewJSONHandler(store, cfg)
+	router := gin.New()
+	router.POST("/api/v1/json/:id/attachments", handler.StoreAttachment)
+	router.GET("/api/v1/json/:id/attachments/:name", handler.GetAttachment)
+
+	upload := func(docID, name, contentType string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/json/"+docID+"/attachments?name="+name, bytes.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	data := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff}
+
+	w := upload(id, "photo.png", "image/png", data)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("upload: status = %d: %s", w.Code, w.Body)
+	}
+	var stored model.Attachment
+	if err := json.Unmarshal(w.Body.Bytes(), &stored); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	if stored.ContentHash != hex.EncodeToString(sum[:]) || stored.Size != int64(len(data)) {
+		t.Errorf("upload response = %+v", stored)
+	}
+	if got := w.Header().Get("Location"); got != "/api/v1/json/"+id+"/attachments/photo.png" {
+		t.Errorf("Location = %q", got)
+	}
+
+	// 读取时原样返回字节和Content-Type，以下载方式返回
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/json/"+id+"/attachments/photo.png", nil))
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), data) {
+		t.Fatalf("get: status = %d, body %q, want 200 and %q", w.Code, w.Body.Bytes(), data)
+	}
+	if got := w.Header().Get("Content-Type"); got != "image/png" {
+		t.Errorf("Content-Type = %q, want image/png", got)
+	}
+	if got := w.Header().Get("Content-Disposition"); got != `attachment; filename=photo.png` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+
+	// ETag为内容哈希，匹配时返回304
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/json/"+id+"/attachments/photo.png", nil)
+	req.Header.Set("If-None-Match", `"`+stored.ContentHash+`"`)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match: status = %d, want 304", w.Code)
+	}
+
+	errorCases := []struct {
+		name       string
+		w          *httptest.ResponseRecorder
+		wantStatus int
+	}{
+		{name: "unknown document", w: upload("00000000-0000-0000-0000-0000000000b3", "photo.png", "", data), wantStatus: http.StatusNotFound},
+		{name: "invalid name", w: upload(id, ".hidden", "", data), wantStatus: http.StatusBadRequest},
+		{name: "empty body", w: upload(id, "empty.bin", "", nil), wantStatus: http.StatusBadRequest},
+		{name: "too large", w: upload(id, "big.bin", "", make([]byte, 17)), wantStatus: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range errorCases {
+		if tt.w.Code != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tt.name, tt.w.Code, tt.wantStatus)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/json/"+id+"/attachments/missing.bin", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("missing attachment: status = %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+)
+
+func TestStoreJSONAutoMetadata(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var cfg config.Config
+	cfg.Environment = config.EnvProduct
+	cfg.Server.AutoMetadata.Fields = []string{
+		config.AutoMetadataSourceIP, config.AutoMetadataRequestID, config.AutoMetadataUserAgent,
+		config.AutoMetadataEnvironment, config.AutoMetadataIngestedAt,
+	}
+	cfg.Server.AutoMetadata.Reserved = []string{config.AutoMetadataSourceIP}
+	store := &lastInputStore{}
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("request_id", "req-192") })
+	router.POST("/api/v1/json", NewJSONHandler(store, cfg).StoreJSON)
+
+	// 客户端可以覆盖user_agent，但source_ip是保留字段
+	body := `{"json_data": {"a": 1}, "metadata": {"owner": "team-a", "user_agent": "custom", "source_ip": "10.0.0.1"}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/json", bytes.NewBufferString(body))
+	req.RemoteAddr = "192.0.2.7:4000"
+	req.Header.Set("User-Agent", "curl/8.0")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+
+	metadata := store.last.Metadata
+	want := map[string]any{
+		"owner":       "team-a",
+		"user_agent":  "custom",
+		"source_ip":   "192.0.2.7",
+		"request_id":  "req-192",
+		"environment": string(config.EnvProduct),
+	}
+	for key, value := range want {
+		if metadata[key] != value {
+			t.Errorf("metadata[%s] = %v, want %v", key, metadata[key], value)
+		}
+	}
+	ingested, _ := metadata["ingested_at"].(string)
+	if _, err := time.Parse(time.RFC3339Nano, ingested); err != nil {
+		t.Errorf("ingested_at = %q, want an RFC 3339 timestamp", metadata["ingested_at"])
+	}
+}
+
+func TestStoreJSONWithoutAutoMetadata(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &lastInputStore{}
+	router := gin.New()
+	router.POST("/api/v1/json", NewJSONHandler(store, config.Config{}).StoreJSON)
+
+	// 未配置时只写入客户端提供的metadata
+	body := `{"json_data": {"a": 1}, "metadata": {"owner": "team-a"}}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/json", bytes.NewBufferString(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	if len(store.last.Metadata) != 1 || store.last.Metadata["owner"] != "team-a" {
+		t.Errorf("metadata = %v, want only the client metadata", store.last.Metadata)
+	}
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"context"
+	"sync"
+
+	"github.com/leapzhao/json-store/model"
+)
+
+// batchGetChunkSize 单次数据库查询的最大ID数
+const batchGetChunkSize = 100
+
+// getDocumentsChunked 按每batchGetChunkSize个ID拆分查询，最多同时执行batch_get_concurrency个
+// 只返回找到的文档，顺序由调用方按ID重排；任一查询失败时取消其余查询并返回该错误
+func (h *JSONHandler) getDocumentsChunked(ctx context.Context, ids []string) ([]*model.JSONDocument, error) {
+	if len(ids) <= batchGetChunkSize {
+		return h.store.GetJSONBatch(ctx, ids)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	chunks := (len(ids) + batchGetChunkSize - 1) / batchGetChunkSize
+	results := make([][]*model.JSONDocument, chunks)
+	sem := make(chan struct{}, h.config.Server.BatchGetConcurrency)
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	for i := 0; i < chunks; i++ {
+		end := min((i+1)*batchGetChunkSize, len(ids))
+		chunk := ids[i*batchGetChunkSize : end]
+
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			<-sem
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, chunk []string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			documents, err := h.store.GetJSONBatch(ctx, chunk)
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			results[i] = documents
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	// 客户端断开等外部取消时，未执行的分块不能当作未找到
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	documents := make([]*model.JSONDocument, 0, len(ids))
+	for _, chunk := range results {
+		documents = append(documents, chunk...)
+	}
+	return documents, nil
+}
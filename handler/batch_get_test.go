@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// batchStore 只实现GetJSONBatch的存储，记录每次查询的ID数和最大并发数
+type batchStore struct {
+	database.JSONStore
+	failOn string
+
+	mu      sync.Mutex
+	sizes   []int
+	active  atomic.Int32
+	maxSeen atomic.Int32
+}
+
+func (s *batchStore) GetJSONBatch(ctx context.Context, ids []string) ([]*model.JSONDocument, error) {
+	n := s.active.Add(1)
+	defer s.active.Add(-1)
+	for {
+		seen := s.maxSeen.Load()
+		if n <= seen || s.maxSeen.CompareAndSwap(seen, n) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+
+	s.mu.Lock()
+	s.sizes = append(s.sizes, len(ids))
+	s.mu.Unlock()
+
+	documents := make([]*model.JSONDocument, 0, len(ids))
+	for _, id := range ids {
+		if id == s.failOn {
+			return nil, errors.New("query failed")
+		}
+		// 奇数编号的文档不存在
+		var n int
+		fmt.Sscanf(id, "id-%d", &n)
+		if n%2 == 0 {
+			documents = append(documents, &model.JSONDocument{ID: id})
+		}
+	}
+	return documents, nil
+}
+
+func batchIDs(n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("id-%d", i)
+	}
+	return ids
+}
+
+func TestGetDocumentsChunked(t *testing.T) {
+	tests := []struct {
+		name      string
+		ids       int
+		failOn    string
+		wantSizes []int
+		wantDocs  int
+		wantErr   bool
+	}{
+		{name: "single query", ids: 100, wantSizes: []int{100}, wantDocs: 50},
+		{name: "split", ids: 250, wantSizes: []int{50, 100, 100}, wantDocs: 125},
+		{name: "many chunks", ids: 1000, wantSizes: []int{100, 100, 100, 100, 100, 100, 100, 100, 100, 100}, wantDocs: 500},
+		{name: "chunk fails", ids: 1000, failOn: "id-420", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg config.Config
+			cfg.Server.BatchGetConcurrency = 3
+			store := &batchStore{failOn: tt.failOn}
+			h := NewJSONHandler(store, cfg)
+
+			documents, err := h.getDocumentsChunked(context.Background(), batchIDs(tt.ids))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("getDocumentsChunked succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("getDocumentsChunked error: %v", err)
+			}
+
+			if len(documents) != tt.wantDocs {
+				t.Errorf("got %d documents, want %d", len(documents), tt.wantDocs)
+			}
+			sort.Ints(store.sizes)
+			if fmt.Sprint(store.sizes) != fmt.Sprint(tt.wantSizes) {
+				t.Errorf("query sizes = %v, want %v", store.sizes, tt.wantSizes)
+			}
+			if n := store.maxSeen.Load(); n > 3 {
+				t.Errorf("%d concurrent queries, want at most 3", n)
+			}
+		})
+	}
+}
+
+func TestGetDocumentsChunkedCancelled(t *testing.T) {
+	var cfg config.Config
+	cfg.Server.BatchGetConcurrency = 1
+	h := NewJSONHandler(&batchStore{}, cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// 外部取消时未执行的分块不能当作未找到而返回部分结果
+	if _, err := h.getDocumentsChunked(ctx, batchIDs(300)); !errors.Is(err, context.Canceled) {
+		t.Errorf("getDocumentsChunked error = %v, want context.Canceled", err)
+	}
+}
+
+// newestFirstStore 像数据库查询一样按created_at倒序返回找到的文档，与请求的顺序无关
+type newestFirstStore struct {
+	database.JSONStore
+	docs []string
+}
+
+func (s newestFirstStore) GetJSONBatch(ctx context.Context, ids []string) ([]*model.JSONDocument, error) {
+	var documents []*model.JSONDocument
+	for i := len(s.docs) - 1; i >= 0; i-- {
+		for _, id := range ids {
+			if id == s.docs[i] {
+				documents = append(documents, &model.JSONDocument{ID: id})
+			}
+		}
+	}
+	return documents, nil
+}
+
+func TestGetJSONBatchOrder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const (
+		a       = "00000000-0000-0000-0000-00000000000a"
+		b       = "00000000-0000-0000-0000-00000000000b"
+		c       = "00000000-0000-0000-0000-00000000000c"
+		missing = "00000000-0000-0000-0000-0000000000ff"
+	)
+	var cfg config.Config
+	cfg.Server.BatchGetMaxIDs = 100
+	cfg.Server.BatchGetConcurrency = 1
+	router := gin.New()
+	router.GET("/api/v1/json/batch", NewJSONHandler(newestFirstStore{docs: []string{a, b, c}}, cfg).GetJSONBatch)
+
+	requested := []string{b, missing, a, c}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/json/batch?ids="+strings.Join(requested, ","), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var resp model.GetBatchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	// 结果与请求按位置对应，未找到的位置为null
+	if len(resp.Documents) != len(requested) {
+		t.Fatalf("got %d documents, want %d", len(resp.Documents), len(requested))
+	}
+	for i, id := range requested {
+		doc := resp.Documents[i]
+		if id == missing {
+			if doc != nil {
+				t.Errorf("documents[%d] = %s, want null", i, doc.ID)
+			}
+			continue
+		}
+		if doc == nil || doc.ID != id {
+			t.Errorf("documents[%d] = %v, want %s", i, doc, id)
+		}
+	}
+	if resp.SuccessCount != 3 || resp.FailureCount != 1 {
+		t.Errorf("success %d failure %d, want 3 and 1", resp.SuccessCount, resp.FailureCount)
+	}
+	if len(resp.Failures) != 1 || resp.Failures[0].Index != 1 {
+		t.Errorf("failures = %+v, want one at index 1", resp.Failures)
+	}
+}
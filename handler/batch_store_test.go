@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// batchInsertStore 批量存储全部成功，记录收到的文档
+type batchInsertStore struct {
+	database.JSONStore
+	received [][]byte
+}
+
+func (s *batchInsertStore) StoreJSONBatch(ctx context.Context, inputs []model.StoreInput) ([]*model.JSONDocument, error) {
+	docs := make([]*model.JSONDocument, 0, len(inputs))
+	for i, input := range inputs {
+		s.received = append(s.received, input.JSONData)
+		docs = append(docs, &model.JSONDocument{ID: fmt.Sprintf("00000000-0000-0000-0000-%012d", i+1), JSONData: input.JSONData})
+	}
+	return docs, nil
+}
+
+func TestStoreJSONBatchEmptyData(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// 下标1缺少json_data，下标3为null
+	body := `{"documents": [{"json_data": {"a": 1}}, {"type": "x"}, {"json_data": [2]}, {"json_data": null}]}`
+
+	t.Run("reject", func(t *testing.T) {
+		var cfg config.Config
+		cfg.Server.BatchEmptyData = config.BatchEmptyReject
+		store := &batchInsertStore{}
+		router := gin.New()
+		router.POST("/api/v1/json/batch", NewJSONHandler(store, cfg).StoreJSONBatch)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/json/batch", bytes.NewBufferString(body)))
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "index 1") {
+			t.Errorf("status = %d, want 400 naming index 1: %s", w.Code, w.Body)
+		}
+		if len(store.received) != 0 {
+			t.Errorf("stored %d documents, want none", len(store.received))
+		}
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		var cfg config.Config
+		cfg.Server.BatchEmptyData = config.BatchEmptySkip
+		store := &batchInsertStore{}
+		router := gin.New()
+		router.POST("/api/v1/json/batch", NewJSONHandler(store, cfg).StoreJSONBatch)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/json/batch", bytes.NewBufferString(body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", w.Code, w.Body)
+		}
+		var resp model.StoreBatchResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.TotalCount != 4 || resp.SuccessCount != 2 || resp.FailureCount != 2 {
+			t.Errorf("total %d success %d failure %d, want 4, 2, 2", resp.TotalCount, resp.SuccessCount, resp.FailureCount)
+		}
+		// 每个空文档单独列出下标和原因
+		var indexes []int
+		for _, failure := range resp.Failures {
+			if failure.Error != "EMPTY_JSON_DATA" {
+				t.Errorf("failure %+v, want EMPTY_JSON_DATA", failure)
+			}
+			indexes = append(indexes, failure.Index)
+		}
+		if fmt.Sprint(indexes) != "[1 3]" {
+			t.Errorf("failure indexes = %v, want [1 3]", indexes)
+		}
+		if len(store.received) != 2 {
+			t.Errorf("stored %d documents, want 2", len(store.received))
+		}
+	})
+}
+
+func TestIsEmptyDocument(t *testing.T) {
+	for _, data := range []string{"", "  ", "\n\t", "null", " null "} {
+		if !isEmptyDocument(json.RawMessage(data)) {
+			t.Errorf("isEmptyDocument(%q) = false, want true", data)
+		}
+	}
+	for _, data := range []string{`""`, "{}", "[]", "0", "false"} {
+		if isEmptyDocument(json.RawMessage(data)) {
+			t.Errorf("isEmptyDocument(%q) = true, want false", data)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// stdJSONBinding 与binding.JSON相同但总是使用encoding/json解析
+// gin的binding.JSON随jsoniter/go_json构建标签切换实现，对json.RawMessage的检查不同，会改变接受的文档
+type stdJSONBinding struct{}
+
+func (stdJSONBinding) Name() string {
+	return "json"
+}
+
+func (stdJSONBinding) Bind(req *http.Request, obj any) error {
+	if req == nil || req.Body == nil {
+		return errors.New("invalid request")
+	}
+	if err := json.NewDecoder(req.Body).Decode(obj); err != nil {
+		return err
+	}
+	if binding.Validator == nil {
+		return nil
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// bindJSON 替代c.ShouldBindJSON，请求体的合法性不随构建标签变化
+func bindJSON(c *gin.Context, obj any) error {
+	return c.ShouldBindWith(obj, stdJSONBinding{})
+}
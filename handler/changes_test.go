@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// changeLogStore 内存中的修改记录，按数据库的规则返回已提交、且早于now-lag的修改
+type changeLogStore struct {
+	database.JSONStore
+	now  time.Time
+	lag  time.Duration
+	docs map[string]*changedDoc
+}
+
+// changedDoc updatedAt在写入开始时确定，committed之前对其他连接不可见
+type changedDoc struct {
+	updatedAt time.Time
+	committed bool
+}
+
+func (s *changeLogStore) write(id string, updatedAt time.Time, committed bool) {
+	s.docs[id] = &changedDoc{updatedAt: updatedAt, committed: committed}
+}
+
+func (s *changeLogStore) GetJSONModifiedSince(ctx context.Context, since time.Time, afterID string, limit int) ([]*model.JSONDocument, error) {
+	var page []*model.JSONDocument
+	for id, d := range s.docs {
+		after := d.updatedAt.After(since) || (d.updatedAt.Equal(since) && id > afterID)
+		if d.committed && after && !d.updatedAt.After(s.now.Add(-s.lag)) {
+			page = append(page, &model.JSONDocument{ID: id, JSONData: []byte(`{}`), UpdatedAt: d.updatedAt})
+		}
+	}
+	sort.Slice(page, func(i, j int) bool {
+		if !page[i].UpdatedAt.Equal(page[j].UpdatedAt) {
+			return page[i].UpdatedAt.Before(page[j].UpdatedAt)
+		}
+		return page[i].ID < page[j].ID
+	})
+	if len(page) > limit {
+		page = page[:limit]
+	}
+	return page, nil
+}
+
+// changesClient 按返回的游标轮询changes接口，记录收到的文档ID
+type changesClient struct {
+	t       *testing.T
+	router  *gin.Engine
+	since   time.Time
+	afterID string
+}
+
+func (c *changesClient) poll() []string {
+	c.t.Helper()
+	query := url.Values{"limit": {"100"}}
+	if !c.since.IsZero() {
+		query.Set("since", c.since.Format(time.RFC3339Nano))
+	}
+	if c.afterID != "" {
+		query.Set("after_id", c.afterID)
+	}
+	w := httptest.NewRecorder()
+	c.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/json/changes?"+query.Encode(), nil))
+	if w.Code != http.StatusOK {
+		c.t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var resp model.ChangesResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		c.t.Fatal(err)
+	}
+	c.since, c.afterID = resp.NextSince, resp.NextAfterID
+	ids := make([]string, 0, len(resp.Documents))
+	for _, doc := range resp.Documents {
+		ids = append(ids, doc.ID)
+	}
+	return ids
+}
+
+func TestGetJSONChanges(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const (
+		a    = "00000000-0000-0000-0000-00000000000a"
+		b    = "00000000-0000-0000-0000-00000000000b"
+		late = "00000000-0000-0000-0000-00000000000c"
+	)
+	t0 := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	newClient := func(lag time.Duration) (*changeLogStore, *changesClient) {
+		store := &changeLogStore{lag: lag, docs: make(map[string]*changedDoc)}
+		router := gin.New()
+		router.GET("/api/v1/json/changes", NewJSONHandler(store, config.Config{}).GetJSONChanges)
+		return store, &changesClient{t: t, router: router}
+	}
+	equal := func(got, want []string) bool {
+		if len(got) != len(want) {
+			return false
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	t.Run("new and updated", func(t *testing.T) {
+		store, client := newClient(0)
+		store.write(a, t0, true)
+		store.write(b, t0, true)
+		store.now = t0.Add(time.Second)
+		if got := client.poll(); !equal(got, []string{a, b}) {
+			t.Fatalf("first poll = %v, want [%s %s]", got, a, b)
+		}
+		// 没有新的修改时游标不变
+		if got := client.poll(); len(got) != 0 {
+			t.Fatalf("idle poll = %v, want none", got)
+		}
+		// 修改过的文档以新的updated_at再次出现
+		store.write(a, t0.Add(2*time.Second), true)
+		store.now = t0.Add(3 * time.Second)
+		if got := client.poll(); !equal(got, []string{a}) {
+			t.Errorf("poll after update = %v, want [%s]", got, a)
+		}
+	})
+
+	// late的写入先开始、后提交：updated_at早于b，但b可见时late还不可见
+	lateCommit := func(lag time.Duration) []string {
+		store, client := newClient(lag)
+		store.write(late, t0, false)
+		store.write(b, t0.Add(time.Second), true)
+		store.now = t0.Add(2 * time.Second)
+		seen := client.poll()
+
+		store.docs[late].committed = true
+		store.now = t0.Add(10 * time.Second)
+		return append(seen, client.poll()...)
+	}
+	t.Run("late commit without lag", func(t *testing.T) {
+		// 说明保留窗口的必要性：游标已越过late，它永远不会返回
+		if got := lateCommit(0); !equal(got, []string{b}) {
+			t.Errorf("changes = %v, want [%s] (late commit skipped)", got, b)
+		}
+	})
+	t.Run("late commit held back", func(t *testing.T) {
+		if got := lateCommit(5 * time.Second); !equal(got, []string{late, b}) {
+			t.Errorf("changes = %v, want [%s %s]", got, late, b)
+		}
+	})
+}
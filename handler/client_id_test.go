@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// takenIDStore 模拟已有一个ID为taken、内容为takenData的文档
+type takenIDStore struct {
+	database.JSONStore
+	taken     string
+	takenData string
+}
+
+func (s *takenIDStore) StoreJSON(ctx context.Context, input model.StoreInput) (*model.JSONDocument, error) {
+	if string(input.JSONData) == s.takenData && input.ID != s.taken {
+		return nil, fmt.Errorf("%w: %s", database.ErrDuplicateContent, s.taken)
+	}
+	if input.ID == s.taken {
+		return nil, database.ErrIDConflict
+	}
+	return &model.JSONDocument{ID: input.ID, JSONData: input.JSONData}, nil
+}
+
+func TestStoreJSONClientID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const taken = "00000000-0000-0000-0000-000000000180"
+	const free = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	tests := []struct {
+		name      string
+		body      string
+		wantCode  int
+		wantError string
+	}{
+		{name: "custom id", body: `{"id":"` + free + `","json_data":{"a":1}}`, wantCode: http.StatusOK},
+		{name: "id collision", body: `{"id":"` + taken + `","json_data":{"a":2}}`, wantCode: http.StatusConflict, wantError: "ID_CONFLICT"},
+		// 内容已由其他ID保存时不能再以新ID保存
+		{name: "content under other id", body: `{"id":"` + free + `","json_data":{"taken":true}}`, wantCode: http.StatusConflict, wantError: "DUPLICATE_CONTENT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &takenIDStore{taken: taken, takenData: `{"taken":true}`}
+			router := gin.New()
+			router.POST("/api/v1/json", NewJSONHandler(store, config.Config{}).StoreJSON)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/json", bytes.NewBufferString(tt.body)))
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantCode, w.Body)
+			}
+			if tt.wantError != "" && !strings.Contains(w.Body.String(), tt.wantError) {
+				t.Errorf("body = %s, want error %s", w.Body, tt.wantError)
+			}
+			if tt.wantError == "" && !strings.Contains(w.Body.String(), free) {
+				t.Errorf("body = %s, want id %s", w.Body, free)
+			}
+		})
+	}
+}
+
+func TestStoreJSONBatchRejectsClientID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/v1/json/batch", NewJSONHandler(&takenIDStore{}, config.Config{}).StoreJSONBatch)
+
+	body := `{"documents":[{"id":"6ba7b810-9dad-11d1-80b4-00c04fd430c8","json_data":{"a":1}}]}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/json/batch", bytes.NewBufferString(body)))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "ID_NOT_SUPPORTED") {
+		t.Errorf("status = %d body = %s, want 400 ID_NOT_SUPPORTED", w.Code, w.Body)
+	}
+}
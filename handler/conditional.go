@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checkNotModified 设置Last-Modified，并按If-Modified-Since判断文档自该时间后是否未修改
+// HTTP日期只精确到秒，比较前截断updated_at；无法解析的If-Modified-Since按未携带处理
+func checkNotModified(c *gin.Context, updatedAt time.Time) bool {
+	if updatedAt.IsZero() {
+		return false
+	}
+
+	lastModified := updatedAt.UTC().Truncate(time.Second)
+	c.Header("Last-Modified", lastModified.Format(http.TimeFormat))
+
+	header := c.GetHeader("If-Modified-Since")
+	if header == "" {
+		return false
+	}
+
+	since, err := http.ParseTime(header)
+	if err != nil {
+		return false
+	}
+
+	return !lastModified.After(since)
+}
+
+// writeDocument 写出文档，文档未修改时返回不带响应体的304
+func (h *JSONHandler) writeDocument(c *gin.Context, updatedAt time.Time, doc any) {
+	if checkNotModified(c, updatedAt) {
+		c.AbortWithStatus(http.StatusNotModified)
+		return
+	}
+
+	h.renderJSON(c, http.StatusOK, doc)
+}
+
+// renderJSON 写出包含文档内容的响应，server.escape_html为false时不转义 <、>、&
+func (h *JSONHandler) renderJSON(c *gin.Context, code int, obj any) {
+	if h.config.Server.EscapeHTML {
+		c.JSON(code, obj)
+		return
+	}
+	c.PureJSON(code, obj)
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// timestampedStore 返回一个带固定修改时间的文档
+type timestampedStore struct {
+	database.JSONStore
+	updatedAt time.Time
+}
+
+func (s timestampedStore) GetJSONByID(ctx context.Context, id string) (*model.JSONDocument, error) {
+	return &model.JSONDocument{ID: id, JSONData: []byte(`{"v":1}`), CreatedAt: s.updatedAt, UpdatedAt: s.updatedAt}, nil
+}
+
+func TestGetJSONIfModifiedSince(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// 数据库时间带亚秒部分，HTTP日期只到秒
+	updatedAt := time.Date(2026, 3, 1, 12, 30, 15, 750_000_000, time.UTC)
+	router := gin.New()
+	router.GET("/api/v1/json/:id", NewJSONHandler(timestampedStore{updatedAt: updatedAt}, config.Config{}).GetJSON)
+
+	lastModified := "Sun, 01 Mar 2026 12:30:15 GMT"
+	tests := []struct {
+		name            string
+		ifModifiedSince string
+		wantStatus      int
+	}{
+		{name: "no header", wantStatus: http.StatusOK},
+		{name: "stale", ifModifiedSince: "Sun, 01 Mar 2026 12:30:14 GMT", wantStatus: http.StatusOK},
+		// 与Last-Modified相同的秒数视为未修改，不能因亚秒部分而返回200
+		{name: "same second", ifModifiedSince: lastModified, wantStatus: http.StatusNotModified},
+		{name: "fresh", ifModifiedSince: "Mon, 02 Mar 2026 00:00:00 GMT", wantStatus: http.StatusNotModified},
+		{name: "unparseable", ifModifiedSince: "yesterday", wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/json/00000000-0000-0000-0000-000000000005", nil)
+			if tt.ifModifiedSince != "" {
+				req.Header.Set("If-Modified-Since", tt.ifModifiedSince)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Last-Modified"); got != lastModified {
+				t.Errorf("Last-Modified = %q, want %q", got, lastModified)
+			}
+			if tt.wantStatus == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("304 response has body %q", w.Body.String())
+			}
+		})
+	}
+}
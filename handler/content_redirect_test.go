@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+)
+
+func TestRedirectByContentHash(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	hash := strings.Repeat("cd", 32)
+	const target = "/api/v1/json/00000000-0000-0000-0000-000000000004"
+
+	// 只有按规范化内容全局去重时哈希与ID一一对应，才能永久重定向
+	unique := func(cfg *config.Config) {
+		cfg.Database.DedupMode = config.DedupNormalized
+		cfg.Database.DedupScope = config.DedupScopeGlobal
+	}
+	tests := []struct {
+		name         string
+		hash         string
+		configure    func(*config.Config)
+		wantStatus   int
+		wantLocation string
+	}{
+		{name: "unique hash", hash: hash, configure: unique, wantStatus: http.StatusMovedPermanently, wantLocation: target},
+		{name: "duplicates allowed", hash: hash, configure: func(cfg *config.Config) {
+			unique(cfg)
+			cfg.Database.AllowDuplicateContent = true
+		}, wantStatus: http.StatusFound, wantLocation: target},
+		{name: "per type scope", hash: hash, configure: func(cfg *config.Config) {
+			unique(cfg)
+			cfg.Database.DedupScope = config.DedupScopePerType
+		}, wantStatus: http.StatusFound, wantLocation: target},
+		{name: "unknown hash", hash: strings.Repeat("ef", 32), configure: unique, wantStatus: http.StatusNotFound},
+		{name: "malformed hash", hash: "not-a-hash", configure: unique, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg config.Config
+			tt.configure(&cfg)
+			router := gin.New()
+			router.GET("/api/v1/json/content/:hash", NewJSONHandler(&hashLookupStore{hash: hash}, cfg).RedirectByContentHash)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/json/content/"+tt.hash, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if got := w.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", got, tt.wantLocation)
+			}
+		})
+	}
+}
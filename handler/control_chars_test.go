@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+	"github.com/leapzhao/json-store/utils"
+)
+
+// nullRejectingStore 按reject_null策略拒绝字符串中含U+0000的文档
+type nullRejectingStore struct {
+	database.JSONStore
+}
+
+func (s nullRejectingStore) check(data []byte) error {
+	if err := utils.FindControlChar(data, true); err != nil {
+		return fmt.Errorf("%w: %v", database.ErrControlCharacter, err)
+	}
+	return nil
+}
+
+func (s nullRejectingStore) StoreJSON(ctx context.Context, input model.StoreInput) (*model.JSONDocument, error) {
+	if err := s.check(input.JSONData); err != nil {
+		return nil, err
+	}
+	return &model.JSONDocument{ID: "00000000-0000-0000-0000-0000000000c8", JSONData: input.JSONData}, nil
+}
+
+func (s nullRejectingStore) StoreJSONTransaction(ctx context.Context, inputs []model.StoreInput) ([]*model.JSONDocument, error) {
+	for i, input := range inputs {
+		if err := s.check(input.JSONData); err != nil {
+			return nil, fmt.Errorf("document at index %d: %w", i, err)
+		}
+	}
+	return nil, fmt.Errorf("unexpected transaction")
+}
+
+func TestStoreControlCharacter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := NewJSONHandler(nullRejectingStore{}, config.Config{})
+	router := gin.New()
+	router.POST("/api/v1/json", handler.StoreJSON)
+	router.POST("/api/v1/json/transaction", handler.StoreJSONTransaction)
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{name: "clean document", path: "/api/v1/json", body: `{"json_data":{"name":"ab"}}`, wantStatus: http.StatusOK},
+		// 不同数据库都返回明确的400，而不是驱动错误
+		{name: "null byte", path: "/api/v1/json", body: `{"json_data":{"name":"a\u0000b"}}`, wantStatus: http.StatusBadRequest},
+		{name: "null byte in transaction", path: "/api/v1/json/transaction",
+			body: `{"documents":[{"json_data":{"n":1}},{"json_data":{"\u0000":2}}]}`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusBadRequest {
+				return
+			}
+			var resp model.ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Error != "CONTROL_CHARACTER" || !strings.Contains(resp.Message, "U+0000") {
+				t.Errorf("error = %s %q, want CONTROL_CHARACTER naming U+0000", resp.Error, resp.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// slowCountStore 精确计数阻塞到请求结束，估算立即返回，模拟大表上的COUNT(*)
+type slowCountStore struct {
+	database.JSONStore
+	exactCalls int
+}
+
+func (s *slowCountStore) ListJSON(ctx context.Context, filter model.ListFilter) ([]*model.JSONDocument, error) {
+	return []*model.JSONDocument{{ID: "00000000-0000-0000-0000-000000000178", JSONData: []byte(`{}`)}}, nil
+}
+
+func (s *slowCountStore) CountJSON(ctx context.Context, docType string, estimate bool) (int64, error) {
+	if estimate {
+		return 1_000_000, nil
+	}
+	s.exactCalls++
+	<-ctx.Done()
+	return 0, ctx.Err()
+}
+
+func TestListJSONEstimatedTotal(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &slowCountStore{}
+	h := NewJSONHandler(store, config.Config{})
+	router := gin.New()
+	router.GET("/api/v1/json/list", h.ListJSON)
+	router.GET("/api/v1/json/count", h.CountJSON)
+
+	get := func(path string, timeout time.Duration) *httptest.ResponseRecorder {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil).WithContext(ctx))
+		return w
+	}
+
+	// 估算路径不等待精确计数，立即返回并标记为近似值
+	start := time.Now()
+	w := get("/api/v1/json/list?total=estimated", 5*time.Second)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("list with estimated total took %v", elapsed)
+	}
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var resp model.ListResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Total == nil || *resp.Total != 1_000_000 || !resp.TotalApproximate {
+		t.Errorf("total = %v approximate = %v, want 1000000 approximate", resp.Total, resp.TotalApproximate)
+	}
+	if store.exactCalls != 0 {
+		t.Errorf("list issued %d exact counts, want 0", store.exactCalls)
+	}
+
+	// 不请求总数时不返回total
+	w = get("/api/v1/json/list", 5*time.Second)
+	var plain model.ListResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &plain); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || plain.Total != nil {
+		t.Errorf("status = %d total = %v, want 200 without total", w.Code, plain.Total)
+	}
+
+	if w := get("/api/v1/json/list?total=exact", 5*time.Second); w.Code != http.StatusBadRequest {
+		t.Errorf("total=exact status = %d, want 400", w.Code)
+	}
+
+	// 精确计数只通过/json/count执行
+	if w := get("/api/v1/json/count", 10*time.Millisecond); w.Code != http.StatusInternalServerError || store.exactCalls != 1 {
+		t.Errorf("count status = %d exact calls = %d, want 500 after one exact count", w.Code, store.exactCalls)
+	}
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// storageInfoStore 返回一个固定的压缩文档及其存储信息
+type storageInfoStore struct {
+	database.JSONStore
+}
+
+func (storageInfoStore) GetJSONByID(ctx context.Context, id string) (*model.JSONDocument, error) {
+	return &model.JSONDocument{ID: id, JSONData: []byte(`{"a":1}`)}, nil
+}
+
+func (storageInfoStore) GetStorageInfo(ctx context.Context, id string) (*model.StorageInfo, error) {
+	return &model.StorageInfo{RawSize: 7, StoredSize: 5, Compression: "gzip", HashAlgorithm: "sha256"}, nil
+}
+
+func TestGetJSONDebug(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var cfg config.Config
+	cfg.Security.AdminUsername = "admin"
+	cfg.Security.AdminPassword = "secret"
+	router := gin.New()
+	router.GET("/api/v1/json/:id", NewJSONHandler(storageInfoStore{}, cfg).GetJSON)
+
+	const id = "00000000-0000-0000-0000-000000000001"
+	tests := []struct {
+		name        string
+		query       string
+		user, pass  string
+		wantStatus  int
+		wantStorage bool
+	}{
+		{name: "debug with admin", query: "?debug=true", user: "admin", pass: "secret", wantStatus: http.StatusOK, wantStorage: true},
+		{name: "debug without auth", query: "?debug=true", wantStatus: http.StatusUnauthorized},
+		{name: "debug with wrong password", query: "?debug=true", user: "admin", pass: "wrong", wantStatus: http.StatusUnauthorized},
+		// 不带debug时管理员也只拿到普通文档
+		{name: "admin without debug", user: "admin", pass: "secret", wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/json/"+id+tt.query, nil)
+			if tt.user != "" {
+				req.SetBasicAuth(tt.user, tt.pass)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var body map[string]json.RawMessage
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			_, hasStorage := body["storage"]
+			if hasStorage != tt.wantStorage {
+				t.Fatalf("storage present = %v, want %v: %s", hasStorage, tt.wantStorage, w.Body)
+			}
+			if !tt.wantStorage {
+				return
+			}
+			var resp model.DebugDocumentResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Document == nil || resp.Document.ID != id {
+				t.Errorf("document = %+v, want id %s", resp.Document, id)
+			}
+			if resp.Storage.RawSize != 7 || resp.Storage.StoredSize != 5 || resp.Storage.Compression != "gzip" || resp.Storage.HashAlgorithm != "sha256" {
+				t.Errorf("storage = %+v", resp.Storage)
+			}
+		})
+	}
+}
+
+// readCountingStore 记录GetJSONByID的调用次数，文档总是不存在
+type readCountingStore struct {
+	database.JSONStore
+	reads int
+}
+
+func (s *readCountingStore) GetJSONByID(ctx context.Context, id string) (*model.JSONDocument, error) {
+	s.reads++
+	return nil, database.ErrDocumentNotFound
+}
+
+// 未认证的debug请求在访问存储前被拒绝，不能借404探测文档是否存在
+func TestGetJSONDebugAuthBeforeRead(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var cfg config.Config
+	cfg.Security.AdminUsername = "admin"
+	cfg.Security.AdminPassword = "secret"
+	store := &readCountingStore{}
+	router := gin.New()
+	router.GET("/api/v1/json/:id", NewJSONHandler(store, cfg).GetJSON)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/json/00000000-0000-0000-0000-000000000002?debug=true", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusUnauthorized, w.Body)
+	}
+	if store.reads != 0 {
+		t.Errorf("GetJSONByID called %d times before authentication", store.reads)
+	}
+}
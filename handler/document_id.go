@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/leapzhao/json-store/model"
+)
+
+// documentIDLength 文档ID为带连字符的标准UUID
+const documentIDLength = 36
+
+// isDocumentID 检查是否为带连字符的标准UUID，大小写均可，与PostgreSQL的UUID类型一致
+// 存储时ID统一转为小写（normalizeDocumentID），与生成的ID格式一致
+func isDocumentID(id string) bool {
+	// uuid.Parse还接受urn:uuid:前缀、花括号等形式，先按长度排除
+	if len(id) != documentIDLength {
+		return false
+	}
+	_, err := uuid.Parse(id)
+	return err == nil
+}
+
+// normalizeDocumentID 返回ID的小写形式，调用前应已通过isDocumentID检查
+func normalizeDocumentID(id string) string {
+	return strings.ToLower(id)
+}
+
+// requireDocumentID 路径中的ID不是标准UUID时返回400，避免无意义的查询和PostgreSQL的类型转换错误
+func requireDocumentID(c *gin.Context, id string) bool {
+	if isDocumentID(id) {
+		return true
+	}
+
+	c.JSON(http.StatusBadRequest, model.ErrorResponse{
+		Error:   "INVALID_ID",
+		Message: "Document ID must be a UUID",
+	})
+	return false
+}
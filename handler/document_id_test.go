@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// queriedIDsStore 记录传到存储层的ID，用于断言格式错误的ID不会触发查询
+type queriedIDsStore struct {
+	database.JSONStore
+	queried []string
+}
+
+func (s *queriedIDsStore) GetJSONByID(ctx context.Context, id string) (*model.JSONDocument, error) {
+	s.queried = append(s.queried, id)
+	return &model.JSONDocument{ID: id, JSONData: []byte(`{}`)}, nil
+}
+
+func (s *queriedIDsStore) GetJSONBatch(ctx context.Context, ids []string) ([]*model.JSONDocument, error) {
+	s.queried = append(s.queried, ids...)
+	documents := make([]*model.JSONDocument, len(ids))
+	for i, id := range ids {
+		documents[i] = &model.JSONDocument{ID: id, JSONData: []byte(`{}`)}
+	}
+	return documents, nil
+}
+
+func (s *queriedIDsStore) UpdateJSON(ctx context.Context, id string, input model.StoreInput) (*model.JSONDocument, error) {
+	s.queried = append(s.queried, id)
+	return &model.JSONDocument{ID: id, JSONData: input.JSONData}, nil
+}
+
+func TestDocumentIDValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const valid = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	tests := []struct {
+		name     string
+		id       string
+		wantCode int
+		// wantQueried 传到存储层的ID，为空表示不应查询
+		wantQueried string
+	}{
+		{name: "valid UUID", id: valid, wantCode: http.StatusOK, wantQueried: valid},
+		{name: "uppercase UUID", id: strings.ToUpper(valid), wantCode: http.StatusOK, wantQueried: strings.ToUpper(valid)},
+		{name: "not a UUID", id: "not-a-uuid", wantCode: http.StatusBadRequest},
+		{name: "braced UUID", id: "{" + valid + "}", wantCode: http.StatusBadRequest},
+		{name: "overly long", id: strings.Repeat("a", 4096), wantCode: http.StatusBadRequest},
+	}
+
+	var cfg config.Config
+	cfg.Server.BatchGetMaxIDs = 10
+	cfg.Server.BatchGetConcurrency = 1
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &queriedIDsStore{}
+			h := NewJSONHandler(store, cfg)
+			router := gin.New()
+			router.GET("/api/v1/json/:id", h.GetJSON)
+			router.PUT("/api/v1/json/:id/raw", h.UpdateJSONRaw)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/json/"+tt.id, nil))
+			if w.Code != tt.wantCode {
+				t.Errorf("GET status = %d, want %d: %s", w.Code, tt.wantCode, w.Body)
+			}
+
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/json/"+tt.id+"/raw", bytes.NewBufferString(`{"a":1}`))
+			req.Header.Set("Content-Type", "application/json")
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantCode {
+				t.Errorf("PUT raw status = %d, want %d: %s", w.Code, tt.wantCode, w.Body)
+			}
+
+			var want []string
+			if tt.wantQueried != "" {
+				want = []string{tt.wantQueried, tt.wantQueried}
+			}
+			if strings.Join(store.queried, ",") != strings.Join(want, ",") {
+				t.Errorf("queried %q, want %q", store.queried, want)
+			}
+		})
+	}
+}
+
+func TestGetJSONBatchDocumentIDs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const valid = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	var cfg config.Config
+	cfg.Server.BatchGetMaxIDs = 10
+	cfg.Server.BatchGetConcurrency = 1
+
+	get := func(store *queriedIDsStore, ids string) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.GET("/api/v1/json/batch", NewJSONHandler(store, cfg).GetJSONBatch)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/json/batch?ids="+ids, nil))
+		return w
+	}
+
+	// 任一ID格式错误时不查询，返回400并指出位置
+	for _, bad := range []string{"not-a-uuid", strings.Repeat("a", 4096)} {
+		store := &queriedIDsStore{}
+		w := get(store, valid+","+bad)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "ids[1]") {
+			t.Errorf("ids %.40q: status = %d, body %s; want 400 naming ids[1]", bad, w.Code, w.Body)
+		}
+		if len(store.queried) != 0 {
+			t.Errorf("ids %.40q: queried %q, want no query", bad, store.queried)
+		}
+	}
+
+	// 大写ID转为小写后查询，与存储中的ID一致
+	store := &queriedIDsStore{}
+	w := get(store, strings.ToUpper(valid))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	if len(store.queried) != 1 || store.queried[0] != valid {
+		t.Errorf("queried %q, want [%s]", store.queried, valid)
+	}
+	if !strings.Contains(w.Body.String(), valid) {
+		t.Errorf("response %s does not contain %s", w.Body, valid)
+	}
+}
+
+func TestStoreJSONSuppliedID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const valid = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	tests := []struct {
+		id       string
+		wantCode int
+		wantID   string
+	}{
+		// 指定的ID转为小写后存储，与生成的ID格式一致
+		{id: strings.ToUpper(valid), wantCode: http.StatusOK, wantID: valid},
+		{id: "custom-id", wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		store := &lastInputStore{}
+		router := gin.New()
+		router.POST("/api/v1/json", NewJSONHandler(store, config.Config{}).StoreJSON)
+
+		body := `{"id":"` + tt.id + `","json_data":{"a":1}}`
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/json", bytes.NewBufferString(body)))
+		if w.Code != tt.wantCode {
+			t.Errorf("id %s: status = %d, want %d: %s", tt.id, w.Code, tt.wantCode, w.Body)
+			continue
+		}
+		if tt.wantID != "" && (store.last == nil || store.last.ID != tt.wantID) {
+			t.Errorf("id %s: stored input %+v, want id %s", tt.id, store.last, tt.wantID)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/middleware"
+)
+
+func TestEscapeHTML(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const id = "00000000-0000-0000-0000-000000000183"
+	store := fixedBodyStore{body: []byte(`{"html":"<b>a&b</b>"}`)}
+	// 文档接口的json_data是字节数组，按base64输出，不涉及HTML转义；normalized默认返回哈希字节，只有展示形式受escape_html控制
+	paths := []string{
+		"/api/v1/json/" + id + "/normalized?display=true",
+		"/api/v1/json/" + id + "/flatten",
+	}
+
+	for _, escape := range []bool{true, false} {
+		var cfg config.Config
+		cfg.Server.EscapeHTML = escape
+		h := NewJSONHandler(store, cfg)
+		router := gin.New()
+		router.GET("/api/v1/json/:id/normalized", h.GetJSONNormalized)
+		router.GET("/api/v1/json/:id/flatten", h.FlattenJSON)
+		// 信封中间件不能改变内层响应的转义方式
+		enveloped := gin.New()
+		enveloped.Use(middleware.ResponseEnvelope())
+		enveloped.GET("/api/v1/json/:id/flatten", h.FlattenJSON)
+
+		want, notWant := `\u003cb\u003ea\u0026b`, `<b>a&b`
+		if !escape {
+			want, notWant = notWant, want
+		}
+		check := func(engine *gin.Engine, path string) {
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("escape_html=%v %s: status = %d: %s", escape, path, w.Code, w.Body)
+			}
+			body := w.Body.String()
+			if !strings.Contains(body, want) || strings.Contains(body, notWant) {
+				t.Errorf("escape_html=%v %s: body = %s, want %s", escape, path, body, want)
+			}
+		}
+		for _, path := range paths {
+			check(router, path)
+		}
+		check(enveloped, paths[1]+"?envelope=true")
+	}
+}
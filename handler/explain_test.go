@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// planningStore 只为metadata_filter模板返回执行计划
+type planningStore struct {
+	database.JSONStore
+}
+
+func (planningStore) Explain(ctx context.Context, template string, params map[string]string) (*model.ExplainResult, error) {
+	if template != model.ExplainMetadataFilter {
+		return nil, fmt.Errorf("%w: unknown template %q", database.ErrInvalidExplain, template)
+	}
+	return &model.ExplainResult{
+		Template: template,
+		Query:    "SELECT id FROM json_documents WHERE metadata->>'" + params["key"] + "' = '" + params["value"] + "'",
+		Plan:     json.RawMessage(`[{"Plan":{"Node Type":"Index Scan"}}]`),
+	}, nil
+}
+
+func TestExplain(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var cfg config.Config
+	cfg.Security.AdminUsername = "admin"
+	cfg.Security.AdminPassword = "secret"
+	router := gin.New()
+	router.POST("/api/admin/explain", NewJSONHandler(planningStore{}, cfg).Explain)
+
+	explain := func(body string, auth bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/explain", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if auth {
+			req.SetBasicAuth("admin", "secret")
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	metadataFilter := `{"template":"metadata_filter","params":{"key":"env","value":"prod"}}`
+	if w := explain(metadataFilter, false); w.Code != http.StatusUnauthorized {
+		t.Errorf("without auth: status = %d, want 401", w.Code)
+	}
+	if w := explain(`{"template":"raw_sql","params":{"sql":"DROP TABLE json_documents"}}`, true); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown template: status = %d, want 400", w.Code)
+	}
+
+	w := explain(metadataFilter, true)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var result model.ExplainResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Template != model.ExplainMetadataFilter || !bytes.Contains(result.Plan, []byte("Index Scan")) {
+		t.Errorf("result = %+v, want the metadata_filter plan", result)
+	}
+}
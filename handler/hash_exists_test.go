@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// knownHashStore 按内容哈希返回已存储文档的ID，记录查询次数
+type knownHashStore struct {
+	database.JSONStore
+	ids     map[string]string
+	lookups int
+}
+
+func (s *knownHashStore) FindExistingHashes(ctx context.Context, hashes []string) (map[string]string, error) {
+	s.lookups++
+	existing := make(map[string]string)
+	for _, hash := range hashes {
+		if id, ok := s.ids[hash]; ok {
+			existing[hash] = id
+		}
+	}
+	return existing, nil
+}
+
+func TestBatchExists(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	first := strings.Repeat("1", 64)
+	second := strings.Repeat("2", 64)
+	unknown := strings.Repeat("f", 64)
+	store := &knownHashStore{ids: map[string]string{
+		first:  "00000000-0000-0000-0000-0000000000a4",
+		second: "00000000-0000-0000-0000-0000000000a5",
+	}}
+	router := gin.New()
+	router.POST("/json/batch/exists", NewJSONHandler(store, config.Config{}).BatchExists)
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/json/batch/exists", strings.NewReader(body)))
+		return w
+	}
+
+	// 结果按请求顺序返回，已存在和新内容混合
+	w := post(`{"hashes":["` + unknown + `","` + second + `","` + first + `"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var resp model.HashExistsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	want := []model.HashExists{
+		{Hash: unknown},
+		{Hash: second, Exists: true, ID: "00000000-0000-0000-0000-0000000000a5"},
+		{Hash: first, Exists: true, ID: "00000000-0000-0000-0000-0000000000a4"},
+	}
+	if len(resp.Results) != len(want) || resp.ExistingCount != 2 {
+		t.Fatalf("response = %+v, want 3 results and existing_count 2", resp)
+	}
+	for i := range want {
+		if resp.Results[i] != want[i] {
+			t.Errorf("results[%d] = %+v, want %+v", i, resp.Results[i], want[i])
+		}
+	}
+
+	tooMany := `{"hashes":["` + strings.TrimSuffix(strings.Repeat(first+`","`, model.MaxExistsHashes+1), `","`) + `"]}`
+	for _, tt := range []struct {
+		name      string
+		body      string
+		wantError string
+	}{
+		{name: "empty list", body: `{"hashes":[]}`, wantError: "MISSING_HASHES"},
+		{name: "malformed hash", body: `{"hashes":["` + strings.ToUpper(unknown) + `"]}`, wantError: "INVALID_HASH"},
+		{name: "too many", body: tooMany, wantError: "TOO_MANY_HASHES"},
+		{name: "invalid body", body: `{"hashes":`, wantError: "INVALID_REQUEST"},
+	} {
+		w := post(tt.body)
+		var errResp model.ErrorResponse
+		json.Unmarshal(w.Body.Bytes(), &errResp)
+		if w.Code != http.StatusBadRequest || errResp.Error != tt.wantError {
+			t.Errorf("%s: status %d error %q, want 400 %s", tt.name, w.Code, errResp.Error, tt.wantError)
+		}
+	}
+	if store.lookups != 1 {
+		t.Errorf("store queried %d times, want 1 (rejected requests never reach the store)", store.lookups)
+	}
+}
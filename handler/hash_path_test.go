@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// hashLookupStore 按完整哈希查找单个文档，并统计查询次数
+type hashLookupStore struct {
+	database.JSONStore
+	hash    string
+	lookups int
+}
+
+func (s *hashLookupStore) GetJSONByHash(ctx context.Context, hash string) (*model.JSONDocument, error) {
+	s.lookups++
+	if hash != s.hash {
+		return nil, database.ErrDocumentNotFound
+	}
+	return &model.JSONDocument{ID: "00000000-0000-0000-0000-000000000004", ContentHash: hash, JSONData: []byte(`{"k":"v"}`)}, nil
+}
+
+func TestGetJSONByHashPath(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	hash := strings.Repeat("ab", 32)
+	tests := []struct {
+		name        string
+		hash        string
+		wantStatus  int
+		wantLookups int
+	}{
+		{name: "valid", hash: hash, wantStatus: http.StatusOK, wantLookups: 1},
+		{name: "unknown", hash: strings.Repeat("0", 64), wantStatus: http.StatusNotFound, wantLookups: 1},
+		// 格式不对时不查询数据库
+		{name: "not hex", hash: strings.Repeat("zz", 32), wantStatus: http.StatusBadRequest},
+		{name: "uppercase", hash: strings.ToUpper(hash), wantStatus: http.StatusBadRequest},
+		{name: "too short", hash: hash[:63], wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &hashLookupStore{hash: hash}
+			router := gin.New()
+			router.GET("/api/v1/json/by-hash/:hash", NewJSONHandler(store, config.Config{}).GetJSONByHashPath)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/json/by-hash/"+tt.hash, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if store.lookups != tt.wantLookups {
+				t.Errorf("lookups = %d, want %d", store.lookups, tt.wantLookups)
+			}
+			if tt.wantStatus == http.StatusBadRequest && !strings.Contains(w.Body.String(), "INVALID_HASH") {
+				t.Errorf("body = %s, want INVALID_HASH", w.Body)
+			}
+		})
+	}
+}
+
+func TestGetJSONByHashQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	hash := strings.Repeat("cd", 32)
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+	}{
+		{name: "valid", query: hash, wantStatus: http.StatusOK},
+		{name: "uppercase", query: strings.ToUpper(hash), wantStatus: http.StatusBadRequest},
+		{name: "wrong length", query: hash + "00", wantStatus: http.StatusBadRequest},
+		{name: "non-hex", query: strings.Repeat("g", 64), wantStatus: http.StatusBadRequest},
+		{name: "missing", query: "", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &hashLookupStore{hash: hash}
+			router := gin.New()
+			router.GET("/api/v1/json/hash", NewJSONHandler(store, config.Config{}).GetJSONByHash)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/json/hash?hash="+tt.query, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			// 格式错误时不访问存储
+			if tt.wantStatus == http.StatusBadRequest && store.lookups != 0 {
+				t.Errorf("store queried %d times for a malformed hash", store.lookups)
+			}
+		})
+	}
+}
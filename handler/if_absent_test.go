@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// dedupStore 按内容去重，内容已存在时返回已有文档并标记Existing
+type dedupStore struct {
+	database.JSONStore
+	ids map[string]string
+}
+
+func (s *dedupStore) StoreJSON(ctx context.Context, input model.StoreInput) (*model.JSONDocument, error) {
+	if id, ok := s.ids[string(input.JSONData)]; ok {
+		return &model.JSONDocument{ID: id, JSONData: input.JSONData, Existing: true}, nil
+	}
+	id := uuid.NewString()
+	s.ids[string(input.JSONData)] = id
+	return &model.JSONDocument{ID: id, JSONData: input.JSONData}, nil
+}
+
+func TestStoreJSONIfAbsent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/v1/json", NewJSONHandler(&dedupStore{ids: map[string]string{}}, config.Config{}).StoreJSON)
+
+	store := func(query, body string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/json"+query, bytes.NewBufferString(body))
+		for name, value := range header {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	body := `{"json_data": {"order": 1}}`
+	if w := store("?if_absent=true", body, nil); w.Code != http.StatusCreated {
+		t.Fatalf("new content: status = %d, want 201: %s", w.Code, w.Body)
+	}
+	w := store("?if_absent=true", body, nil)
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "ALREADY_EXISTS") {
+		t.Errorf("existing content: status = %d, want 409 ALREADY_EXISTS: %s", w.Code, w.Body)
+	}
+	if w := store("", body, map[string]string{"If-None-Match": "*"}); w.Code != http.StatusConflict {
+		t.Errorf("existing content with If-None-Match: status = %d, want 409", w.Code)
+	}
+	// 不带标志时仍返回已有文档
+	if w := store("", body, nil); w.Code != http.StatusOK {
+		t.Errorf("existing content without flag: status = %d, want 200", w.Code)
+	}
+	if w := store("", `{"json_data": {"order": 2}}`, map[string]string{"If-None-Match": "*"}); w.Code != http.StatusCreated {
+		t.Errorf("new content with If-None-Match: status = %d, want 201", w.Code)
+	}
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// lastInputStore 记录最近一次StoreJSON收到的输入
+type lastInputStore struct {
+	database.JSONStore
+	last *model.StoreInput
+}
+
+func (s *lastInputStore) StoreJSON(ctx context.Context, input model.StoreInput) (*model.JSONDocument, error) {
+	s.last = &input
+	return &model.JSONDocument{ID: "00000000-0000-0000-0000-000000000003", JSONData: input.JSONData}, nil
+}
+
+func TestStoreJSONInlineData(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantData   string
+	}{
+		{name: "object", body: `{"json_data": {"a": [1, 2], "b": "x"}}`, wantStatus: http.StatusOK, wantData: `{"a": [1, 2], "b": "x"}`},
+		{name: "array", body: `{"json_data": [true, null]}`, wantStatus: http.StatusOK, wantData: `[true, null]`},
+		// 字符串是合法的JSON文档，按字符串本身存储，不再当作base64解码
+		{name: "string not decoded", body: `{"json_data": "eyJhIjoxfQ=="}`, wantStatus: http.StatusOK, wantData: `"eyJhIjoxfQ=="`},
+		{name: "missing", body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "null", body: `{"json_data": null}`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &lastInputStore{}
+			router := gin.New()
+			router.POST("/api/v1/json", NewJSONHandler(store, config.Config{}).StoreJSON)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/json", bytes.NewBufferString(tt.body)))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if store.last != nil {
+					t.Errorf("store called with %s", store.last.JSONData)
+				}
+				return
+			}
+			if store.last == nil || string(store.last.JSONData) != tt.wantData {
+				t.Errorf("stored %v, want %s", store.last, tt.wantData)
+			}
+		})
+	}
+}
@@ -1,45 +1,92 @@
 package handler
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/leapzhao/json-store/backup"
+	"github.com/leapzhao/json-store/config"
 	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/middleware"
 	"github.com/leapzhao/json-store/model"
+	"github.com/leapzhao/json-store/search"
+	"github.com/leapzhao/json-store/utils"
 	"net/http"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 )
 
 type JSONHandler struct {
 	store      database.JSONStore
+	config     config.Config
 	appVersion string
 	buildTime  string
 	gitCommit  string
 	startTime  time.Time
+	// liveness 额外的存活检查（如请求看门狗），返回错误时/health报告不健康
+	liveness func() error
+	// appMetrics 应用层计数器，为nil时不统计
+	appMetrics *middleware.AppMetrics
+	// searchClient 搜索索引客户端，与索引同步共用，未开启搜索时为nil
+	searchClient *search.Client
+	// metadataLimits 写入前校验metadata
+	metadataLimits metadataLimits
+	// draining 进入排空状态后/ready返回503，已有请求照常处理
+	draining atomic.Bool
+	// maintenanceWindow 重型维护任务允许执行的时间段
+	maintenanceWindow maintenanceWindow
+	// endpoints GET /api/v1 列出的接口，路由注册完成后设置
+	endpoints []model.Endpoint
+	// adminAccounts 管理员账号，与管理接口的BasicAuth共用
+	adminAccounts gin.Accounts
 }
 
-func NewJSONHandler(store database.JSONStore) *JSONHandler {
-	return &JSONHandler{
+func NewJSONHandler(store database.JSONStore, cfg config.Config) *JSONHandler {
+	h := &JSONHandler{
 		store:      store,
+		config:     cfg,
 		appVersion: "1.0.0",
 		buildTime:  time.Now().Format(time.RFC3339),
 		gitCommit:  "unknown",
 		startTime:  time.Now(),
 	}
+	h.adminAccounts = middleware.AdminAccounts(cfg.Security.AdminUsername, cfg.Security.AdminPassword)
+	h.metadataLimits = newMetadataLimits(cfg)
+	h.maintenanceWindow = newMaintenanceWindow(cfg)
+	return h
+}
+
+// SetLivenessCheck 设置额外的存活检查
+func (h *JSONHandler) SetLivenessCheck(check func() error) {
+	h.liveness = check
+}
+
+// SetAppMetrics 设置应用层计数器
+func (h *JSONHandler) SetAppMetrics(metrics *middleware.AppMetrics) {
+	h.appMetrics = metrics
+}
+
+// SetSearchClient 设置搜索索引客户端
+func (h *JSONHandler) SetSearchClient(client *search.Client) {
+	h.searchClient = client
 }
 
 // StoreJSON 存储JSON
 func (h *JSONHandler) StoreJSON(c *gin.Context) {
 	var req model.StoreRequest
 
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, model.ErrorResponse{
 			Error:   "INVALID_REQUEST",
 			Message: "Invalid request body",
@@ -47,6 +94,18 @@ func (h *JSONHandler) StoreJSON(c *gin.Context) {
 		return
 	}
 
+	// 文档ID在存储中是UUID类型（PostgreSQL的id列及引用它的分块、标签、附件表），不支持自定义的ID格式
+	if req.ID != "" {
+		if !isDocumentID(req.ID) {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{
+				Error:   "INVALID_ID",
+				Message: "id must be a UUID; custom ID formats are not supported",
+			})
+			return
+		}
+		req.ID = normalizeDocumentID(req.ID)
+	}
+
 	// 验证JSON数据
 	validate := validator.New()
 	if err := validate.Struct(req); err != nil {
@@ -57,23 +116,109 @@ func (h *JSONHandler) StoreJSON(c *gin.Context) {
 		return
 	}
 
-	// 存储JSON
+	if err := validateDocumentData(req.JSONData); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "VALIDATION_ERROR",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := utils.ValidateMaxElements(req.JSONData, h.config.Server.MaxElements); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "TOO_MANY_ELEMENTS",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := h.metadataLimits.validate(req.Metadata); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "INVALID_METADATA",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := validateTags(req.Tags); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "INVALID_TAGS",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.storeDocument(c, model.StoreInput{
+		JSONData: req.JSONData,
+		DocType:  req.Type,
+		ID:       req.ID,
+		Metadata: req.Metadata,
+		Tags:     req.Tags,
+	})
+}
+
+// StoreJSONRaw 存储JSON，请求体就是JSON文档本身，不需要StoreRequest包装
+// 类型通过?type=指定
+func (h *JSONHandler) StoreJSONRaw(c *gin.Context) {
+	data, docType, ok := h.readRawDocument(c)
+	if !ok {
+		return
+	}
+
+	h.storeDocument(c, model.StoreInput{
+		JSONData: data,
+		DocType:  docType,
+	})
+}
+
+// storeDocument 存储单个文档并写出StoreResponse
+// 带 ?if_absent=true 或 If-None-Match: * 时只允许新建：新建返回201，内容已存在返回409
+func (h *JSONHandler) storeDocument(c *gin.Context, input model.StoreInput) {
+	ifAbsent := c.Query("if_absent") == "true" || c.GetHeader("If-None-Match") == "*"
+	input.Metadata = h.mergeAutoMetadata(h.autoMetadata(c), input.Metadata)
+
 	start := time.Now()
-	doc, err := h.store.StoreJSON(c.Request.Context(), req.JSONData)
+	doc, err := h.store.StoreJSON(c.Request.Context(), input)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to store JSON")
-		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
-			Error:   "STORAGE_ERROR",
-			Message: "Failed to store JSON document",
-		})
+		switch {
+		case errors.Is(err, database.ErrIDConflict):
+			c.JSON(http.StatusConflict, model.ErrorResponse{
+				Error:   "ID_CONFLICT",
+				Message: fmt.Sprintf("Document ID %s is already in use", input.ID),
+			})
+		case errors.Is(err, database.ErrDuplicateContent):
+			c.JSON(http.StatusConflict, model.ErrorResponse{
+				Error:   "DUPLICATE_CONTENT",
+				Message: err.Error(),
+			})
+		case errors.Is(err, database.ErrControlCharacter):
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{
+				Error:   "CONTROL_CHARACTER",
+				Message: err.Error(),
+			})
+		case errors.Is(err, database.ErrInvalidDocument):
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{
+				Error:   "INVALID_JSON",
+				Message: err.Error(),
+			})
+		default:
+			log.Error().Err(err).Msg("Failed to store JSON")
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+				Error:   "STORAGE_ERROR",
+				Message: "Failed to store JSON document",
+			})
+		}
 		return
 	}
 
 	// 检查是否是新建
-	isNew := time.Since(doc.CreatedAt) < time.Second
+	isNew := !doc.Existing
+	h.appMetrics.RecordStore(time.Since(start), isNew)
+	recordQuotaUsage(c, []*model.JSONDocument{doc})
 
 	response := model.StoreResponse{
 		ID:        doc.ID,
+		ShortHash: h.shortHash(doc.ContentHash),
 		IsNew:     isNew,
 		CreatedAt: doc.CreatedAt,
 		Message:   getStorageMessage(isNew),
@@ -85,14 +230,37 @@ func (h *JSONHandler) StoreJSON(c *gin.Context) {
 		Dur("duration", time.Since(start)).
 		Msg("JSON stored successfully")
 
+	if ifAbsent {
+		if !isNew {
+			c.JSON(http.StatusConflict, model.ErrorResponse{
+				Error:   "ALREADY_EXISTS",
+				Message: fmt.Sprintf("Document with the same content already exists: %s", doc.ID),
+			})
+			return
+		}
+		c.JSON(http.StatusCreated, response)
+		return
+	}
+
 	c.JSON(http.StatusOK, response)
 }
 
+// recordQuotaUsage 按新建的文档数计入每日配额，命中去重的文档不计
+func recordQuotaUsage(c *gin.Context, docs []*model.JSONDocument) {
+	created := 0
+	for _, doc := range docs {
+		if !doc.Existing {
+			created++
+		}
+	}
+	c.Set(middleware.QuotaUsageKey, created)
+}
+
 // StoreJSONBatch 批量存储JSON
 func (h *JSONHandler) StoreJSONBatch(c *gin.Context) {
 	var req model.StoreBatchRequest
 
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, model.ErrorResponse{
 			Error:   "INVALID_REQUEST",
 			Message: "Invalid request body",
@@ -112,29 +280,25 @@ func (h *JSONHandler) StoreJSONBatch(c *gin.Context) {
 
 	// 提取JSON数据
 	start := time.Now()
-	jsonDataList := make([][]byte, 0, len(req.Documents))
+	skipEmpty := h.config.Server.BatchEmptyData == config.BatchEmptySkip
+	inputs, failures, ok := h.batchInputs(c, req.Documents, skipEmpty)
+	if !ok {
+		return
+	}
 
-	for i, docReq := range req.Documents {
-		// 验证每个文档的JSON
-		if !json.Valid(docReq.JSONData) {
-			c.JSON(http.StatusBadRequest, model.ErrorResponse{
-				Error:   "INVALID_JSON",
-				Message: fmt.Sprintf("Document at index %d contains invalid JSON", i),
+	// 批量存储（全部为空时无需访问数据库）
+	var results []*model.JSONDocument
+	if len(inputs) > 0 {
+		var err error
+		results, err = h.store.StoreJSONBatch(c.Request.Context(), inputs)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to store JSON batch")
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+				Error:   "BATCH_STORAGE_ERROR",
+				Message: "Failed to store JSON documents in batch",
 			})
 			return
 		}
-		jsonDataList = append(jsonDataList, docReq.JSONData)
-	}
-
-	// 批量存储
-	results, err := h.store.StoreJSONBatch(c.Request.Context(), jsonDataList)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to store JSON batch")
-		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
-			Error:   "BATCH_STORAGE_ERROR",
-			Message: "Failed to store JSON documents in batch",
-		})
-		return
 	}
 
 	// 构建响应
@@ -145,26 +309,29 @@ func (h *JSONHandler) StoreJSONBatch(c *gin.Context) {
 		Duration:     time.Since(start),
 		Results:      make([]model.StoreResponse, 0, len(results)),
 	}
+	recordQuotaUsage(c, results)
 
 	for _, doc := range results {
-		isNew := time.Since(doc.CreatedAt) < time.Second
+		isNew := !doc.Existing
+		// 批量写入按文档平均耗时计入
+		h.appMetrics.RecordStore(response.Duration/time.Duration(len(results)), isNew)
 		response.Results = append(response.Results, model.StoreResponse{
 			ID:        doc.ID,
+			ShortHash: h.shortHash(doc.ContentHash),
 			IsNew:     isNew,
 			CreatedAt: doc.CreatedAt,
 			Message:   getStorageMessage(isNew),
 		})
 	}
 
-	// 如果有失败，添加失败信息
-	if response.FailureCount > 0 {
-		response.Failures = []model.BatchFailure{
-			{
-				Index:   response.SuccessCount,
-				Error:   "PROCESSING_ERROR",
-				Message: "Some documents failed to process",
-			},
-		}
+	// 如果有失败，添加失败信息：空文档逐条列出，存储层跳过的文档无法对应到下标，合并为一条
+	response.Failures = failures
+	if len(inputs) > len(results) {
+		response.Failures = append(response.Failures, model.BatchFailure{
+			Index:   response.SuccessCount,
+			Error:   "PROCESSING_ERROR",
+			Message: "Some documents failed to process",
+		})
 	}
 
 	log.Info().
@@ -177,68 +344,236 @@ func (h *JSONHandler) StoreJSONBatch(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-// GetJSON 根据ID获取JSON
-func (h *JSONHandler) GetJSON(c *gin.Context) {
-	id := c.Param("id")
-	if id == "" {
+// batchInputs 逐个校验批量请求中的文档并转换为存储参数，校验失败时写出400并返回false
+// skipEmpty为true时空文档不使整个请求失败，而是记为该下标的EMPTY_JSON_DATA失败并跳过
+func (h *JSONHandler) batchInputs(c *gin.Context, documents []model.StoreRequest, skipEmpty bool) ([]model.StoreInput, []model.BatchFailure, bool) {
+	inputs := make([]model.StoreInput, 0, len(documents))
+	var failures []model.BatchFailure
+	auto := h.autoMetadata(c)
+
+	for i, docReq := range documents {
+		if docReq.ID != "" {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{
+				Error:   "ID_NOT_SUPPORTED",
+				Message: fmt.Sprintf("Document at index %d: id is only supported when storing a single document", i),
+			})
+			return nil, nil, false
+		}
+		if skipEmpty && isEmptyDocument(docReq.JSONData) {
+			failures = append(failures, model.BatchFailure{
+				Index:   i,
+				Error:   "EMPTY_JSON_DATA",
+				Message: "json_data is missing, empty or null",
+			})
+			continue
+		}
+		// 验证每个文档的JSON
+		if err := validateDocumentData(docReq.JSONData); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{
+				Error:   "INVALID_JSON",
+				Message: fmt.Sprintf("Document at index %d: %v", i, err),
+			})
+			return nil, nil, false
+		}
+		if err := utils.ValidateMaxElements(docReq.JSONData, h.config.Server.MaxElements); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{
+				Error:   "TOO_MANY_ELEMENTS",
+				Message: fmt.Sprintf("Document at index %d: %v", i, err),
+			})
+			return nil, nil, false
+		}
+		if err := h.metadataLimits.validate(docReq.Metadata); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{
+				Error:   "INVALID_METADATA",
+				Message: fmt.Sprintf("Document at index %d: %v", i, err),
+			})
+			return nil, nil, false
+		}
+		if err := validateTags(docReq.Tags); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{
+				Error:   "INVALID_TAGS",
+				Message: fmt.Sprintf("Document at index %d: %v", i, err),
+			})
+			return nil, nil, false
+		}
+		inputs = append(inputs, model.StoreInput{
+			JSONData: docReq.JSONData,
+			DocType:  docReq.Type,
+			Metadata: h.mergeAutoMetadata(auto, docReq.Metadata),
+			Tags:     docReq.Tags,
+		})
+	}
+
+	return inputs, failures, true
+}
+
+// StoreJSONTransaction 原子存储多个文档：全部成功返回所有ID，任意一个失败则全部回滚
+// 与StoreJSONBatch不同，不会跳过失败的文档
+func (h *JSONHandler) StoreJSONTransaction(c *gin.Context) {
+	var req model.StoreBatchRequest
+
+	if err := bindJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, model.ErrorResponse{
-			Error:   "MISSING_ID",
-			Message: "Document ID is required",
+			Error:   "INVALID_REQUEST",
+			Message: "Invalid request body",
 		})
 		return
 	}
 
-	doc, err := h.store.GetJSONByID(c.Request.Context(), id)
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "VALIDATION_ERROR",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	start := time.Now()
+	// 事务要求全部成功，空文档总是使整个请求失败
+	inputs, _, ok := h.batchInputs(c, req.Documents, false)
+	if !ok {
+		return
+	}
+
+	docs, err := h.store.StoreJSONTransaction(c.Request.Context(), inputs)
+	if errors.Is(err, database.ErrControlCharacter) {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "CONTROL_CHARACTER",
+			Message: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, database.ErrInvalidDocument) {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "INVALID_JSON",
+			Message: err.Error(),
+		})
+		return
+	}
 	if err != nil {
-		log.Error().Err(err).Str("id", id).Msg("Failed to get JSON")
-		c.JSON(http.StatusNotFound, model.ErrorResponse{
-			Error:   "NOT_FOUND",
-			Message: "Document not found",
+		log.Error().Err(err).Msg("Failed to store JSON transaction")
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Error:   "TRANSACTION_FAILED",
+			Message: "Transaction rolled back, no documents were stored",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, doc)
+	duration := time.Since(start)
+	response := model.StoreTransactionResponse{
+		Count:    len(docs),
+		IDs:      make([]string, 0, len(docs)),
+		Duration: duration,
+	}
+	for _, doc := range docs {
+		h.appMetrics.RecordStore(duration/time.Duration(len(docs)), !doc.Existing)
+		response.IDs = append(response.IDs, doc.ID)
+	}
+	recordQuotaUsage(c, docs)
+
+	c.JSON(http.StatusCreated, response)
 }
 
-// GetJSONBatch 批量获取JSON
-func (h *JSONHandler) GetJSONBatch(c *gin.Context) {
-	var req model.GetBatchRequest
+// UpdateJSONRaw 用请求体替换文档内容
+// 请求体就是JSON文档本身，不需要StoreRequest包装，类型通过?type=指定，不指定时保留原类型
+func (h *JSONHandler) UpdateJSONRaw(c *gin.Context) {
+	id := c.Param("id")
+	if !requireDocumentID(c, id) {
+		return
+	}
 
-	// 尝试从URL参数获取
-	idsParam := c.Query("ids")
-	if idsParam != "" {
-		// 从逗号分隔的字符串解析ID
-		ids := strings.Split(idsParam, ",")
-		if len(ids) > 0 {
-			req.IDs = ids
-		}
+	data, docType, ok := h.readRawDocument(c)
+	if !ok {
+		return
 	}
 
-	// 如果没有URL参数，尝试从请求体获取
-	if len(req.IDs) == 0 {
-		if err := c.ShouldBindJSON(&req); err != nil {
+	doc, err := h.store.UpdateJSON(c.Request.Context(), id, model.StoreInput{
+		JSONData: data,
+		DocType:  docType,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrDocumentNotFound):
+			c.JSON(http.StatusNotFound, model.ErrorResponse{
+				Error:   "NOT_FOUND",
+				Message: "Document not found",
+			})
+		case errors.Is(err, database.ErrDuplicateContent):
+			c.JSON(http.StatusConflict, model.ErrorResponse{
+				Error:   "DUPLICATE_CONTENT",
+				Message: err.Error(),
+			})
+		case errors.Is(err, database.ErrControlCharacter):
 			c.JSON(http.StatusBadRequest, model.ErrorResponse{
-				Error:   "INVALID_REQUEST",
-				Message: "Invalid request body or missing IDs",
+				Error:   "CONTROL_CHARACTER",
+				Message: err.Error(),
+			})
+		case errors.Is(err, database.ErrInvalidDocument):
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{
+				Error:   "INVALID_JSON",
+				Message: err.Error(),
+			})
+		default:
+			log.Error().Err(err).Str("id", id).Msg("Failed to update JSON")
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+				Error:   "STORAGE_ERROR",
+				Message: "Failed to update JSON document",
 			})
-			return
 		}
+		return
 	}
 
-	// 验证请求
-	if len(req.IDs) == 0 {
+	c.JSON(http.StatusOK, model.StoreResponse{
+		ID:        doc.ID,
+		ShortHash: h.shortHash(doc.ContentHash),
+		IsNew:     false,
+		CreatedAt: doc.CreatedAt,
+		Message:   "JSON document updated successfully",
+	})
+}
+
+// readRawDocument 读取并校验作为文档本身的请求体，失败时已写出错误响应
+// Content-Type和JSON格式由ValidateJSON中间件检查
+func (h *JSONHandler) readRawDocument(c *gin.Context) ([]byte, string, bool) {
+	data, err := c.GetRawData()
+	if err != nil || len(data) == 0 {
 		c.JSON(http.StatusBadRequest, model.ErrorResponse{
-			Error:   "MISSING_IDS",
-			Message: "At least one ID is required",
+			Error:   "INVALID_REQUEST",
+			Message: "Request body must be a JSON document",
 		})
-		return
+		return nil, "", false
 	}
 
-	if len(req.IDs) > 100 {
+	docType := c.Query("type")
+	if len(docType) > 64 {
 		c.JSON(http.StatusBadRequest, model.ErrorResponse{
-			Error:   "TOO_MANY_IDS",
-			Message: "Maximum 100 IDs allowed per request",
+			Error:   "VALIDATION_ERROR",
+			Message: "type must be at most 64 characters",
+		})
+		return nil, "", false
+	}
+
+	if err := utils.ValidateMaxElements(data, h.config.Server.MaxElements); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "TOO_MANY_ELEMENTS",
+			Message: err.Error(),
+		})
+		return nil, "", false
+	}
+
+	return data, docType, true
+}
+
+// UpdateMetadataBatch 批量合并文档metadata，逐个报告结果
+// 顶层键覆盖已有值，值为null时删除该键，嵌套对象整体替换
+func (h *JSONHandler) UpdateMetadataBatch(c *gin.Context) {
+	var req model.MetadataBatchRequest
+
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "INVALID_REQUEST",
+			Message: "Invalid request body",
 		})
 		return
 	}
@@ -252,80 +587,1042 @@ func (h *JSONHandler) GetJSONBatch(c *gin.Context) {
 		return
 	}
 
-	// 批量获取
-	start := time.Now()
-	documents, err := h.store.GetJSONBatch(c.Request.Context(), req.IDs)
+	for i, update := range req.Updates {
+		if err := h.metadataLimits.validate(update.Metadata); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{
+				Error:   "INVALID_METADATA",
+				Message: fmt.Sprintf("Update at index %d: %v", i, err),
+			})
+			return
+		}
+	}
+
+	results, err := h.store.UpdateMetadataBatch(c.Request.Context(), req.Updates)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to get JSON batch")
+		log.Error().Err(err).Msg("Failed to update metadata batch")
 		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
-			Error:   "BATCH_GET_ERROR",
-			Message: "Failed to get JSON documents in batch",
+			Error:   "BATCH_UPDATE_ERROR",
+			Message: "Failed to update metadata in batch",
 		})
 		return
 	}
 
-	// 构建响应
-	response := model.GetBatchResponse{
-		SuccessCount: len(documents),
-		FailureCount: len(req.IDs) - len(documents),
-		Documents:    make([]model.JSONDocument, 0, len(documents)),
-	}
-
-	for _, doc := range documents {
-		response.Documents = append(response.Documents, *doc)
-	}
-
-	// 如果有失败，识别哪些ID没找到
-	if response.FailureCount > 0 {
-		foundIDs := make(map[string]bool)
-		for _, doc := range documents {
-			foundIDs[doc.ID] = true
-		}
-
-		for i, id := range req.IDs {
-			if !foundIDs[id] {
-				response.Failures = append(response.Failures, model.BatchFailure{
-					Index:   i,
-					Error:   "NOT_FOUND",
-					Message: fmt.Sprintf("Document with ID %s not found", id),
-				})
-			}
+	response := model.MetadataBatchResponse{Results: results}
+	for _, result := range results {
+		if result.Success {
+			response.SuccessCount++
+		} else {
+			response.FailureCount++
 		}
 	}
 
-	log.Info().
-		Int("requested", len(req.IDs)).
-		Int("found", response.SuccessCount).
-		Dur("duration", time.Since(start)).
-		Msg("JSON batch retrieved successfully")
-
 	c.JSON(http.StatusOK, response)
 }
 
-// GetJSONByHash 根据哈希值获取JSON
-func (h *JSONHandler) GetJSONByHash(c *gin.Context) {
-	hash := c.Query("hash")
-	if hash == "" {
+// GetJSON 根据ID获取JSON
+func (h *JSONHandler) GetJSON(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
 		c.JSON(http.StatusBadRequest, model.ErrorResponse{
-			Error:   "MISSING_HASH",
-			Message: "Hash parameter is required",
+			Error:   "MISSING_ID",
+			Message: "Document ID is required",
 		})
 		return
 	}
 
-	doc, err := h.store.GetJSONByHash(c.Request.Context(), hash)
+	if !requireDocumentID(c, id) {
+		return
+	}
+
+	// debug=true 时附带存储诊断信息，涉及内部实现，需要管理员认证；在读取文档前检查，未认证时不访问存储
+	debug := c.Query("debug") == "true"
+	if debug && !h.requireAdmin(c) {
+		return
+	}
+
+	doc, err := h.store.GetJSONByID(c.Request.Context(), id)
 	if err != nil {
+		log.Error().Err(err).Str("id", id).Msg("Failed to get JSON")
 		c.JSON(http.StatusNotFound, model.ErrorResponse{
 			Error:   "NOT_FOUND",
-			Message: "Document not found with the provided hash",
+			Message: "Document not found",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, doc)
-}
+	if !h.checkResponseSize(c, int64(len(doc.JSONData))) {
+		return
+	}
 
-// HealthCheck 健康检查
+	doc.ShortHash = h.shortHash(doc.ContentHash)
+
+	// resolve=true 时展开文档中的 {"$ref": "<id>"} 引用
+	if c.Query("resolve") == "true" {
+		resolved, err := h.resolveRefs(c.Request.Context(), doc)
+		if err != nil {
+			h.writeResolveError(c, id, err)
+			return
+		}
+		// 展开后的内容可能远大于原文档
+		if !h.checkResponseSize(c, int64(len(resolved))) {
+			return
+		}
+		doc.JSONData = resolved
+	}
+
+	if debug {
+		h.writeDebugDocument(c, doc)
+		return
+	}
+
+	// 展开引用后的内容还取决于被引用的文档，不做条件请求判断
+	if c.Query("resolve") == "true" {
+		h.renderJSON(c, http.StatusOK, doc)
+		return
+	}
+
+	h.writeDocument(c, doc.UpdatedAt, doc)
+}
+
+// writeDebugDocument 返回文档及其存储诊断信息，调用方已完成管理员认证
+func (h *JSONHandler) writeDebugDocument(c *gin.Context, doc *model.JSONDocument) {
+	info, err := h.store.GetStorageInfo(c.Request.Context(), doc.ID)
+	if err != nil {
+		log.Error().Err(err).Str("id", doc.ID).Msg("Failed to get storage info")
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Error:   "STORAGE_INFO_ERROR",
+			Message: "Failed to get document storage info",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.DebugDocumentResponse{
+		Document: doc,
+		Storage:  info,
+	})
+}
+
+// GetJSONRaw 返回文档的原始内容，不带JSONDocument包装
+// 支持Range请求（206 Partial Content、多段multipart/byteranges、不可满足时416），用于大文档的分段下载和断点续传
+// 同时按updated_at处理If-Modified-Since和If-Range
+func (h *JSONHandler) GetJSONRaw(c *gin.Context) {
+	id := c.Param("id")
+	if !requireDocumentID(c, id) {
+		return
+	}
+
+	doc, err := h.store.GetJSONByID(c.Request.Context(), id)
+	if err != nil {
+		log.Error().Err(err).Str("id", id).Msg("Failed to get JSON")
+		c.JSON(http.StatusNotFound, model.ErrorResponse{
+			Error:   "NOT_FOUND",
+			Message: "Document not found",
+		})
+		return
+	}
+
+	// 分段请求不受整体响应大小限制
+	if c.GetHeader("Range") == "" && !h.checkResponseSize(c, int64(len(doc.JSONData))) {
+		return
+	}
+
+	c.Header("Content-Type", "application/json")
+	http.ServeContent(c.Writer, c.Request, "", doc.UpdatedAt, bytes.NewReader(doc.JSONData))
+}
+
+// FlattenJSON 返回文档平铺后的“路径 -> 标量值”映射，用于配置比对和索引
+// 分隔符通过?separator=指定，默认为"."
+func (h *JSONHandler) FlattenJSON(c *gin.Context) {
+	id := c.Param("id")
+	if !requireDocumentID(c, id) {
+		return
+	}
+
+	separator := c.DefaultQuery("separator", ".")
+	if separator == "" || len(separator) > 8 {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "INVALID_SEPARATOR",
+			Message: "separator must be between 1 and 8 bytes",
+		})
+		return
+	}
+
+	doc, err := h.store.GetJSONByID(c.Request.Context(), id)
+	if err != nil {
+		log.Error().Err(err).Str("id", id).Msg("Failed to get JSON")
+		c.JSON(http.StatusNotFound, model.ErrorResponse{
+			Error:   "NOT_FOUND",
+			Message: "Document not found",
+		})
+		return
+	}
+
+	if !h.checkResponseSize(c, int64(len(doc.JSONData))) {
+		return
+	}
+
+	paths, err := utils.FlattenJSON(doc.JSONData, separator)
+	if err != nil {
+		log.Error().Err(err).Str("id", id).Msg("Failed to flatten stored JSON")
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Error:   "FLATTEN_FAILED",
+			Message: "Stored document could not be flattened",
+		})
+		return
+	}
+
+	h.renderJSON(c, http.StatusOK, model.FlattenResponse{
+		ID:        doc.ID,
+		Separator: separator,
+		Count:     len(paths),
+		Paths:     paths,
+	})
+}
+
+// GetJSONPointer 返回文档中RFC 6901 JSON Pointer指向的值：/json/:id/pointer?p=/a/b/0
+// 值按原始字节返回；p为空字符串时返回整个文档，路径不存在时返回404
+func (h *JSONHandler) GetJSONPointer(c *gin.Context) {
+	id := c.Param("id")
+	if !requireDocumentID(c, id) {
+		return
+	}
+
+	pointer, ok := c.GetQuery("p")
+	if !ok {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "INVALID_POINTER",
+			Message: "query parameter p is required",
+		})
+		return
+	}
+	if _, err := utils.ParsePointer(pointer); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "INVALID_POINTER",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	doc, err := h.store.GetJSONByID(c.Request.Context(), id)
+	if err != nil {
+		log.Error().Err(err).Str("id", id).Msg("Failed to get JSON")
+		c.JSON(http.StatusNotFound, model.ErrorResponse{
+			Error:   "NOT_FOUND",
+			Message: "Document not found",
+		})
+		return
+	}
+
+	value, err := utils.EvaluatePointer(doc.JSONData, pointer)
+	if err != nil {
+		if errors.Is(err, utils.ErrPointerNotFound) {
+			c.JSON(http.StatusNotFound, model.ErrorResponse{
+				Error:   "POINTER_NOT_FOUND",
+				Message: err.Error(),
+			})
+			return
+		}
+		log.Error().Err(err).Str("id", id).Msg("Failed to evaluate JSON pointer")
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Error:   "POINTER_FAILED",
+			Message: "Stored document could not be evaluated",
+		})
+		return
+	}
+
+	if !h.checkResponseSize(c, int64(len(value))) {
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json", value)
+}
+
+// GetJSONNormalized 返回计算内容哈希时使用的字节（与database.number_handling一致），用于排查去重不一致
+// display=true 时改为返回输出用的规范化形式：数字保留原文，按escape_html决定是否转义，与内容哈希无关
+func (h *JSONHandler) GetJSONNormalized(c *gin.Context) {
+	id := c.Param("id")
+	if !requireDocumentID(c, id) {
+		return
+	}
+
+	doc, err := h.store.GetJSONByID(c.Request.Context(), id)
+	if err != nil {
+		log.Error().Err(err).Str("id", id).Msg("Failed to get JSON")
+		c.JSON(http.StatusNotFound, model.ErrorResponse{
+			Error:   "NOT_FOUND",
+			Message: "Document not found",
+		})
+		return
+	}
+
+	if !h.checkResponseSize(c, int64(len(doc.JSONData))) {
+		return
+	}
+
+	var normalized []byte
+	if c.Query("display") == "true" {
+		c.Header("X-Normalized-Form", "display")
+		normalize := utils.NormalizeJSON
+		if !h.config.Server.EscapeHTML {
+			normalize = utils.NormalizeJSONNoEscape
+		}
+		normalized, err = normalize(doc.JSONData)
+	} else {
+		c.Header("X-Normalized-Form", "hash")
+		normalized, err = utils.NormalizeForHash(doc.JSONData, h.config.Database.NumberHandling == config.NumberHandlingExact)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("id", id).Msg("Failed to normalize stored JSON")
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Error:   "NORMALIZE_FAILED",
+			Message: "Stored document could not be normalized",
+		})
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json", normalized)
+}
+
+// GetJSONBatch 批量获取JSON
+func (h *JSONHandler) GetJSONBatch(c *gin.Context) {
+	var req model.GetBatchRequest
+
+	ndjson, ok := ndjsonRequested(c)
+	if !ok {
+		return
+	}
+
+	// 尝试从URL参数获取
+	idsParam := c.Query("ids")
+	if idsParam != "" {
+		// 从逗号分隔的字符串解析ID
+		ids := strings.Split(idsParam, ",")
+		if len(ids) > 0 {
+			req.IDs = ids
+		}
+	}
+
+	// 如果没有URL参数，尝试从请求体获取
+	if len(req.IDs) == 0 {
+		if err := bindJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{
+				Error:   "INVALID_REQUEST",
+				Message: "Invalid request body or missing IDs",
+			})
+			return
+		}
+	}
+
+	// 验证请求
+	if len(req.IDs) == 0 {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "MISSING_IDS",
+			Message: "At least one ID is required",
+		})
+		return
+	}
+
+	if len(req.IDs) > h.config.Server.BatchGetMaxIDs {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "TOO_MANY_IDS",
+			Message: fmt.Sprintf("Maximum %d IDs allowed per request", h.config.Server.BatchGetMaxIDs),
+		})
+		return
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "VALIDATION_ERROR",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// 任一ID不是UUID时整个请求返回400，与单个读取一致；转为小写以便与返回文档的ID对应
+	for i, id := range req.IDs {
+		if !isDocumentID(id) {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{
+				Error:   "INVALID_ID",
+				Message: fmt.Sprintf("ids[%d] must be a UUID", i),
+			})
+			return
+		}
+		req.IDs[i] = normalizeDocumentID(id)
+	}
+
+	// 批量获取
+	start := time.Now()
+	documents, err := h.getDocumentsChunked(c.Request.Context(), req.IDs)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get JSON batch")
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Error:   "BATCH_GET_ERROR",
+			Message: "Failed to get JSON documents in batch",
+		})
+		return
+	}
+
+	// 构建响应：按请求的ID顺序排列，未找到的位置留null并记录失败
+	byID := make(map[string]*model.JSONDocument, len(documents))
+	for _, doc := range documents {
+		doc.ShortHash = h.shortHash(doc.ContentHash)
+		byID[doc.ID] = doc
+	}
+
+	// ndjson：按请求顺序只输出找到的文档，未找到的数量在X-Failure-Count中
+	if ndjson {
+		ordered := make([]*model.JSONDocument, 0, len(documents))
+		for _, id := range req.IDs {
+			if doc, ok := byID[id]; ok {
+				ordered = append(ordered, doc)
+			}
+		}
+		c.Header("X-Failure-Count", strconv.Itoa(len(req.IDs)-len(ordered)))
+		h.writeNDJSON(c, ordered)
+		return
+	}
+
+	response := model.GetBatchResponse{
+		Documents: make([]*model.JSONDocument, len(req.IDs)),
+	}
+
+	for i, id := range req.IDs {
+		doc, ok := byID[id]
+		if !ok {
+			response.FailureCount++
+			response.Failures = append(response.Failures, model.BatchFailure{
+				Index:   i,
+				Error:   "NOT_FOUND",
+				Message: fmt.Sprintf("Document with ID %s not found", id),
+			})
+			continue
+		}
+		response.Documents[i] = doc
+		response.SuccessCount++
+	}
+
+	log.Info().
+		Int("requested", len(req.IDs)).
+		Int("found", response.SuccessCount).
+		Dur("duration", time.Since(start)).
+		Msg("JSON batch retrieved successfully")
+
+	h.renderJSON(c, http.StatusOK, response)
+}
+
+// BatchExists 批量检查内容哈希是否已存储，客户端上传前据此跳过已有内容
+// 哈希与响应中的content_hash相同（规范化后的SHA-256），结果与请求按位置一一对应
+func (h *JSONHandler) BatchExists(c *gin.Context) {
+	var req model.HashExistsRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "INVALID_REQUEST",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	if len(req.Hashes) == 0 {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "MISSING_HASHES",
+			Message: "At least one hash is required",
+		})
+		return
+	}
+	if len(req.Hashes) > model.MaxExistsHashes {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "TOO_MANY_HASHES",
+			Message: fmt.Sprintf("Maximum %d hashes allowed per request", model.MaxExistsHashes),
+		})
+		return
+	}
+	for i, hash := range req.Hashes {
+		if !isContentHash(hash) {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{
+				Error:   "INVALID_HASH",
+				Message: fmt.Sprintf("Hash at index %d must be %d lowercase hex characters", i, contentHashLength),
+			})
+			return
+		}
+	}
+
+	existing, err := h.store.FindExistingHashes(c.Request.Context(), req.Hashes)
+	if err != nil {
+		log.Error().Err(err).Int("count", len(req.Hashes)).Msg("Failed to check hashes")
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Error:   "QUERY_ERROR",
+			Message: "Failed to check hashes",
+		})
+		return
+	}
+
+	response := model.HashExistsResponse{
+		Results: make([]model.HashExists, len(req.Hashes)),
+	}
+	for i, hash := range req.Hashes {
+		id, ok := existing[hash]
+		response.Results[i] = model.HashExists{Hash: hash, Exists: ok, ID: id}
+		if ok {
+			response.ExistingCount++
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// QueryJSON 根据查询参数获取JSON：hash精确查找，short_hash前缀查找，type、tag按类型和标签列出
+func (h *JSONHandler) QueryJSON(c *gin.Context) {
+	if c.Query("hash") == "" && c.Query("short_hash") != "" {
+		h.GetJSONByShortHash(c)
+		return
+	}
+
+	if c.Query("hash") == "" && (c.Query("type") != "" || c.Query("tag") != "") {
+		h.ListJSON(c)
+		return
+	}
+
+	h.GetJSONByHash(c)
+}
+
+// ListJSON 按类型列出JSON，?tag= 只列出带有该标签的文档
+func (h *JSONHandler) ListJSON(c *gin.Context) {
+	ndjson, ok := ndjsonRequested(c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "INVALID_LIMIT",
+			Message: "Limit must be between 1 and 100",
+		})
+		return
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "INVALID_OFFSET",
+			Message: "Offset must be a non-negative integer",
+		})
+		return
+	}
+
+	// total=estimated 时附带估算的总数，精确总数通过 /json/count 单独获取，避免大表COUNT(*)拖慢列表
+	total := c.Query("total")
+	if total != "" && total != "estimated" {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "INVALID_TOTAL",
+			Message: "total must be 'estimated', use /json/count for exact counts",
+		})
+		return
+	}
+
+	tag := c.Query("tag")
+	if tag != "" {
+		if err := validateTag(tag); err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{
+				Error:   "INVALID_TAG",
+				Message: err.Error(),
+			})
+			return
+		}
+		// 估算基于类型的查询计划，不含标签条件
+		if total != "" {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{
+				Error:   "INVALID_TOTAL",
+				Message: "total is not supported together with tag",
+			})
+			return
+		}
+	}
+
+	filter := model.ListFilter{
+		DocType: c.Query("type"),
+		Tag:     tag,
+		Limit:   limit,
+		Offset:  offset,
+	}
+
+	documents, err := h.store.ListJSON(c.Request.Context(), filter)
+	if err != nil {
+		log.Error().Err(err).Str("type", filter.DocType).Str("tag", filter.Tag).Msg("Failed to list JSON")
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Error:   "LIST_ERROR",
+			Message: "Failed to list JSON documents",
+		})
+		return
+	}
+
+	if ndjson {
+		if total != "" {
+			if estimated, err := h.store.CountJSON(c.Request.Context(), filter.DocType, true); err == nil {
+				c.Header("X-Total-Estimated", strconv.FormatInt(estimated, 10))
+			} else {
+				log.Warn().Err(err).Str("type", filter.DocType).Msg("Failed to estimate JSON count")
+			}
+		}
+		h.writeNDJSON(c, documents)
+		return
+	}
+
+	response := model.ListResponse{
+		Documents: make([]model.JSONDocument, 0, len(documents)),
+		Count:     len(documents),
+		Limit:     limit,
+		Offset:    offset,
+	}
+
+	for _, doc := range documents {
+		doc.ShortHash = h.shortHash(doc.ContentHash)
+		response.Documents = append(response.Documents, *doc)
+	}
+
+	// 估算失败不影响列表本身，只是不返回总数
+	if total != "" {
+		estimated, err := h.store.CountJSON(c.Request.Context(), filter.DocType, true)
+		if err != nil {
+			log.Warn().Err(err).Str("type", filter.DocType).Msg("Failed to estimate JSON count")
+		} else {
+			response.Total = &estimated
+			response.TotalApproximate = true
+		}
+	}
+
+	h.renderJSON(c, http.StatusOK, response)
+}
+
+// CountJSON 返回符合条件的文档精确数量，?type= 按类型过滤；大表上可能较慢
+func (h *JSONHandler) CountJSON(c *gin.Context) {
+	docType := c.Query("type")
+
+	count, err := h.store.CountJSON(c.Request.Context(), docType, false)
+	if err != nil {
+		log.Error().Err(err).Str("type", docType).Msg("Failed to count JSON")
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Error:   "COUNT_ERROR",
+			Message: "Failed to count JSON documents",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.CountResponse{
+		DocType: docType,
+		Count:   count,
+	})
+}
+
+// GetJSONChanges 增量同步：按修改时间升序返回since（含）之后新建或修改的文档
+// 客户端每次以响应中的next_since、next_after_id作为下次请求的since、after_id，has_more为true时立即继续
+// 文档不支持删除，结果中没有删除标记；最近database.changes_lag_ms内修改的文档留到之后的请求返回
+func (h *JSONHandler) GetJSONChanges(c *gin.Context) {
+	ndjson, ok := ndjsonRequested(c)
+	if !ok {
+		return
+	}
+
+	var since time.Time
+	if value := c.Query("since"); value != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{
+				Error:   "INVALID_SINCE",
+				Message: "since must be an RFC 3339 timestamp",
+			})
+			return
+		}
+		since = parsed
+	}
+
+	afterID := c.Query("after_id")
+	if afterID != "" {
+		if _, err := uuid.Parse(afterID); err != nil || len(afterID) != documentIDLength {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{
+				Error:   "INVALID_AFTER_ID",
+				Message: "after_id must be a document ID returned as next_after_id",
+			})
+			return
+		}
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "INVALID_LIMIT",
+			Message: "Limit must be between 1 and 100",
+		})
+		return
+	}
+
+	documents, err := h.store.GetJSONModifiedSince(c.Request.Context(), since, afterID, limit)
+	if err != nil {
+		log.Error().Err(err).Time("since", since).Msg("Failed to get JSON changes")
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Error:   "CHANGES_ERROR",
+			Message: "Failed to get changed JSON documents",
+		})
+		return
+	}
+
+	// ndjson：游标放在响应头中
+	if ndjson {
+		nextSince, nextAfterID := since, afterID
+		if len(documents) > 0 {
+			last := documents[len(documents)-1]
+			nextSince, nextAfterID = last.UpdatedAt, last.ID
+		}
+		c.Header("X-Next-Since", nextSince.Format(time.RFC3339Nano))
+		c.Header("X-Next-After-ID", nextAfterID)
+		c.Header("X-Has-More", strconv.FormatBool(len(documents) == limit))
+		h.writeNDJSON(c, documents)
+		return
+	}
+
+	response := model.ChangesResponse{
+		Documents:   make([]model.JSONDocument, 0, len(documents)),
+		Count:       len(documents),
+		NextSince:   since,
+		NextAfterID: afterID,
+		HasMore:     len(documents) == limit,
+	}
+	for _, doc := range documents {
+		doc.ShortHash = h.shortHash(doc.ContentHash)
+		response.Documents = append(response.Documents, *doc)
+	}
+	if len(documents) > 0 {
+		last := documents[len(documents)-1]
+		response.NextSince, response.NextAfterID = last.UpdatedAt, last.ID
+	}
+
+	h.renderJSON(c, http.StatusOK, response)
+}
+
+// GetJSONByHash 根据哈希值获取JSON
+func (h *JSONHandler) GetJSONByHash(c *gin.Context) {
+	hash := c.Query("hash")
+	if hash == "" {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "MISSING_HASH",
+			Message: "Hash parameter is required",
+		})
+		return
+	}
+
+	h.writeDocumentByHash(c, hash)
+}
+
+// GetJSONByHashPath 根据路径中的哈希值获取JSON：/json/by-hash/:hash
+func (h *JSONHandler) GetJSONByHashPath(c *gin.Context) {
+	h.writeDocumentByHash(c, c.Param("hash"))
+}
+
+// RedirectByContentHash 按内容哈希查找文档并重定向到以ID为准的规范地址：/json/content/:hash
+// 按规范化内容全局去重时哈希与ID一一对应，返回301；否则同一哈希可能对应多个ID，返回302
+func (h *JSONHandler) RedirectByContentHash(c *gin.Context) {
+	hash := c.Param("hash")
+	if !isContentHash(hash) {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "INVALID_HASH",
+			Message: fmt.Sprintf("Hash must be %d lowercase hex characters", contentHashLength),
+		})
+		return
+	}
+
+	doc, err := h.store.GetJSONByHash(c.Request.Context(), hash)
+	if err != nil {
+		c.JSON(http.StatusNotFound, model.ErrorResponse{
+			Error:   "NOT_FOUND",
+			Message: "Document not found with the provided hash",
+		})
+		return
+	}
+
+	code := http.StatusFound
+	dbCfg := h.config.Database
+	if !dbCfg.AllowDuplicateContent && dbCfg.DedupMode == config.DedupNormalized && dbCfg.DedupScope == config.DedupScopeGlobal {
+		code = http.StatusMovedPermanently
+	}
+	c.Redirect(code, "/api/v1/json/"+doc.ID)
+}
+
+// writeDocumentByHash 查询哈希对应的文档并写出响应
+// 格式不正确的哈希直接返回400，不查询数据库
+func (h *JSONHandler) writeDocumentByHash(c *gin.Context, hash string) {
+	if !isContentHash(hash) {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "INVALID_HASH",
+			Message: fmt.Sprintf("Hash must be %d lowercase hex characters", contentHashLength),
+		})
+		return
+	}
+
+	doc, err := h.store.GetJSONByHash(c.Request.Context(), hash)
+	if err != nil {
+		c.JSON(http.StatusNotFound, model.ErrorResponse{
+			Error:   "NOT_FOUND",
+			Message: "Document not found with the provided hash",
+		})
+		return
+	}
+
+	if !h.checkResponseSize(c, int64(len(doc.JSONData))) {
+		return
+	}
+
+	doc.ShortHash = h.shortHash(doc.ContentHash)
+	h.writeDocument(c, doc.UpdatedAt, doc)
+}
+
+// GetJSONByShortHash 根据短哈希（内容哈希前缀）获取JSON
+// 前缀匹配到多个不同哈希时返回409，需要使用完整哈希查询
+func (h *JSONHandler) GetJSONByShortHash(c *gin.Context) {
+	if h.config.Server.ShortHashLength == 0 {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "SHORT_HASH_DISABLED",
+			Message: "Short hash lookups are not enabled",
+		})
+		return
+	}
+
+	shortHash := strings.ToLower(c.Query("short_hash"))
+	if len(shortHash) < h.config.Server.ShortHashLength || len(shortHash) > 64 || !isHex(shortHash) {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "INVALID_SHORT_HASH",
+			Message: fmt.Sprintf("short_hash must be at least %d hex characters", h.config.Server.ShortHashLength),
+		})
+		return
+	}
+
+	hashes, err := h.store.FindHashesByPrefix(c.Request.Context(), shortHash, 2)
+	if err != nil {
+		log.Error().Err(err).Str("short_hash", shortHash).Msg("Failed to find hashes by prefix")
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Error:   "QUERY_ERROR",
+			Message: "Failed to look up short hash",
+		})
+		return
+	}
+
+	if len(hashes) == 0 {
+		c.JSON(http.StatusNotFound, model.ErrorResponse{
+			Error:   "NOT_FOUND",
+			Message: "Document not found with the provided short hash",
+		})
+		return
+	}
+
+	if len(hashes) > 1 {
+		c.JSON(http.StatusConflict, model.ErrorResponse{
+			Error:   "AMBIGUOUS_SHORT_HASH",
+			Message: "Multiple documents match the provided short hash, use the full hash",
+		})
+		return
+	}
+
+	doc, err := h.store.GetJSONByHash(c.Request.Context(), hashes[0])
+	if err != nil {
+		c.JSON(http.StatusNotFound, model.ErrorResponse{
+			Error:   "NOT_FOUND",
+			Message: "Document not found with the provided short hash",
+		})
+		return
+	}
+
+	if !h.checkResponseSize(c, int64(len(doc.JSONData))) {
+		return
+	}
+
+	doc.ShortHash = h.shortHash(doc.ContentHash)
+	h.renderJSON(c, http.StatusOK, doc)
+}
+
+// checkResponseSize 文档超过max_response_bytes时写出413并返回false
+// 按内容字节数判断，响应中的JSON编码会更大；超限文档可通过原始内容接口分段获取
+func (h *JSONHandler) checkResponseSize(c *gin.Context, size int64) bool {
+	limit := h.config.Server.MaxResponseBytes
+	if limit <= 0 || size <= limit {
+		return true
+	}
+
+	c.JSON(http.StatusRequestEntityTooLarge, model.ErrorResponse{
+		Error: "RESPONSE_TOO_LARGE",
+		Message: fmt.Sprintf("Document is %d bytes, exceeding the response limit of %d bytes; "+
+			"fetch it in parts with a Range header on GET /api/v1/json/:id/raw", size, limit),
+	})
+	return false
+}
+
+// shortHash 按配置截取内容哈希前缀，未开启时返回空字符串
+func (h *JSONHandler) shortHash(hash string) string {
+	n := h.config.Server.ShortHashLength
+	if n <= 0 || n >= len(hash) {
+		return ""
+	}
+	return hash[:n]
+}
+
+// contentHashLength 内容哈希（SHA-256）的十六进制长度
+const contentHashLength = 64
+
+// isContentHash 检查字符串是否为完整的内容哈希
+func isContentHash(s string) bool {
+	return len(s) == contentHashLength && isHex(s)
+}
+
+// isHex 检查字符串是否只包含小写十六进制字符
+func isHex(s string) bool {
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// LargestDocuments 列出最大的文档（不含内容），用于清理存储
+func (h *JSONHandler) LargestDocuments(c *gin.Context) {
+	if !h.requireAdmin(c) {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "INVALID_LIMIT",
+			Message: "Limit must be between 1 and 100",
+		})
+		return
+	}
+
+	documents, err := h.store.LargestDocuments(c.Request.Context(), limit)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list largest documents")
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Error:   "QUERY_ERROR",
+			Message: "Failed to list largest documents",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.LargestDocumentsResponse{
+		Limit:     limit,
+		Documents: documents,
+	})
+}
+
+// Explain 返回查询模板的执行计划，用于确认查询是否命中索引
+// 只接受预定义模板和参数，不执行任意SQL；EXPLAIN只规划不执行查询
+func (h *JSONHandler) Explain(c *gin.Context) {
+	if !h.requireAdmin(c) {
+		return
+	}
+
+	var req model.ExplainRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "INVALID_REQUEST",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	result, err := h.store.Explain(c.Request.Context(), req.Template, req.Params)
+	if err != nil {
+		if errors.Is(err, database.ErrInvalidExplain) {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{
+				Error:   "INVALID_TEMPLATE",
+				Message: err.Error(),
+			})
+			return
+		}
+		log.Error().Err(err).Str("template", req.Template).Msg("Failed to explain query")
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Error:   "QUERY_ERROR",
+			Message: "Failed to explain query",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// FacetCounts 按metadata顶层键的取值统计文档数，如 ?key=env
+func (h *JSONHandler) FacetCounts(c *gin.Context) {
+	key := c.Query("key")
+	if key == "" || len(key) > 128 {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "INVALID_KEY",
+			Message: "key parameter is required and must be at most 128 characters",
+		})
+		return
+	}
+
+	counts, err := h.store.FacetCounts(c.Request.Context(), key)
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Failed to count facets")
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Error:   "FACETS_ERROR",
+			Message: "Failed to count facets",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.FacetCountsResponse{
+		Key:    key,
+		Facets: counts,
+	})
+}
+
+// SearchJSON 全文搜索文档内容，查询转发到配置的搜索索引，只返回匹配的文档ID
+// 索引异步同步，刚写入的文档可能短时间内搜不到
+func (h *JSONHandler) SearchJSON(c *gin.Context) {
+	if h.searchClient == nil {
+		c.JSON(http.StatusNotImplemented, model.ErrorResponse{
+			Error:   "SEARCH_DISABLED",
+			Message: "Search is not enabled (search.enabled / SEARCH_ENABLED)",
+		})
+		return
+	}
+
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "MISSING_QUERY",
+			Message: "Query parameter q is required",
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "INVALID_LIMIT",
+			Message: "limit must be an integer between 1 and 100",
+		})
+		return
+	}
+
+	ids, err := h.searchClient.Search(c.Request.Context(), query, limit)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to query search index")
+		c.JSON(http.StatusBadGateway, model.ErrorResponse{
+			Error:   "SEARCH_UNAVAILABLE",
+			Message: "Search index is unavailable",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.SearchResponse{
+		Query: query,
+		Count: len(ids),
+		IDs:   ids,
+	})
+}
+
+// HealthCheck 健康检查
 func (h *JSONHandler) HealthCheck(c *gin.Context) {
 	status := "healthy"
 
@@ -339,6 +1636,13 @@ func (h *JSONHandler) HealthCheck(c *gin.Context) {
 		dbStatus = true
 	}
 
+	if h.liveness != nil {
+		if err := h.liveness(); err != nil {
+			status = "unhealthy"
+			log.Error().Err(err).Msg("Liveness check failed")
+		}
+	}
+
 	response := model.HealthResponse{
 		Status:    status,
 		Timestamp: time.Now(),
@@ -375,6 +1679,38 @@ func (h *JSONHandler) ReadyCheck(c *gin.Context) {
 			Name:   "database",
 			Status: "ok",
 		})
+
+		// 深度检查：验证数据库可写
+		if h.config.Server.DeepReadyCheck {
+			if err := h.store.WriteCheck(ctx); err != nil {
+				ready = false
+				checks = append(checks, model.HealthCheck{
+					Name:   "database_write",
+					Status: "failed",
+					Error:  err.Error(),
+				})
+			} else {
+				checks = append(checks, model.HealthCheck{
+					Name:   "database_write",
+					Status: "ok",
+				})
+			}
+		}
+
+		// 备库延迟过大时读到的数据过旧，按阈值摘除
+		if check, ok := h.replicationCheck(ctx); check != nil {
+			ready = ready && ok
+			checks = append(checks, *check)
+		}
+	}
+
+	// 排空中：让负载均衡器摘除实例，等待之后的SIGTERM优雅退出
+	if h.draining.Load() {
+		ready = false
+		checks = append(checks, model.HealthCheck{
+			Name:   "drain",
+			Status: "draining",
+		})
 	}
 
 	response := model.ReadyResponse{
@@ -391,6 +1727,52 @@ func (h *JSONHandler) ReadyCheck(c *gin.Context) {
 	c.JSON(statusCode, response)
 }
 
+// replicationCheck 连接的是备库时返回复制检查项，延迟超过max_replication_lag_bytes时ok为false
+// 查询失败只报告不摘除，数据库本身的可用性已由database检查项覆盖
+func (h *JSONHandler) replicationCheck(ctx context.Context) (*model.HealthCheck, bool) {
+	status, err := h.store.ReplicationStatus(ctx)
+	if err != nil {
+		return &model.HealthCheck{
+			Name:   "replication",
+			Status: "unknown",
+			Error:  err.Error(),
+		}, true
+	}
+	if status == nil {
+		return nil, true
+	}
+
+	check := &model.HealthCheck{
+		Name:     "replication",
+		Status:   "ok",
+		LagBytes: &status.LagBytes,
+	}
+	threshold := h.config.Database.MaxReplicationLagBytes
+	if threshold > 0 && status.LagBytes > threshold {
+		check.Status = "degraded"
+		check.Error = fmt.Sprintf("replication lag %d bytes exceeds %d", status.LagBytes, threshold)
+		return check, false
+	}
+	return check, true
+}
+
+// Drain 进入排空状态，用于滚动发布前让负载均衡器停止转发新流量
+// 只影响/ready，/health保持不变；状态不可撤销，需重启进程恢复
+func (h *JSONHandler) Drain(c *gin.Context) {
+	if !h.requireAdmin(c) {
+		return
+	}
+
+	if h.draining.CompareAndSwap(false, true) {
+		log.Warn().Str("client_ip", c.ClientIP()).Msg("Server draining, readiness now reports not ready")
+	}
+
+	c.JSON(http.StatusAccepted, model.DrainResponse{
+		Draining: true,
+		Message:  "Readiness will report 503 until the process exits",
+	})
+}
+
 // Version 版本信息
 func (h *JSONHandler) Version(c *gin.Context) {
 	response := model.VersionResponse{
@@ -399,6 +1781,7 @@ func (h *JSONHandler) Version(c *gin.Context) {
 		GitCommit:   h.gitCommit,
 		Environment: GetEnvironment(),
 		GoVersion:   runtime.Version(),
+		JSONCodec:   utils.JSONCodec,
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -407,22 +1790,7 @@ func (h *JSONHandler) Version(c *gin.Context) {
 // Metrics 获取性能指标
 func (h *JSONHandler) Metrics(c *gin.Context) {
 	// 检查认证
-	user, password, hasAuth := c.Request.BasicAuth()
-	if hasAuth {
-		if user != "admin" || password != "secret" {
-			c.Header("WWW-Authenticate", `Basic realm="Restricted"`)
-			c.JSON(http.StatusUnauthorized, model.ErrorResponse{
-				Error:   "UNAUTHORIZED",
-				Message: "Authentication required",
-			})
-			return
-		}
-	} else {
-		c.Header("WWW-Authenticate", `Basic realm="Restricted"`)
-		c.JSON(http.StatusUnauthorized, model.ErrorResponse{
-			Error:   "UNAUTHORIZED",
-			Message: "Authentication required",
-		})
+	if !h.requireAdmin(c) {
 		return
 	}
 
@@ -443,25 +1811,31 @@ func (h *JSONHandler) Metrics(c *gin.Context) {
 	c.JSON(http.StatusOK, metrics)
 }
 
+// AppMetrics 获取应用层指标
+func (h *JSONHandler) AppMetrics(c *gin.Context) {
+	if !h.requireAdmin(c) {
+		return
+	}
+
+	if h.appMetrics == nil {
+		c.JSON(http.StatusNotFound, model.ErrorResponse{
+			Error:   "METRICS_DISABLED",
+			Message: "Application metrics are not enabled",
+		})
+		return
+	}
+
+	snapshot := h.appMetrics.Snapshot()
+	if stats, ok := database.DedupStatsOf(h.store); ok {
+		snapshot.DedupHitRatio = stats.HitRatio
+	}
+	c.JSON(http.StatusOK, snapshot)
+}
+
 // Stats 获取统计信息
 func (h *JSONHandler) Stats(c *gin.Context) {
 	// 检查认证
-	user, password, hasAuth := c.Request.BasicAuth()
-	if hasAuth {
-		if user != "admin" || password != "secret" {
-			c.Header("WWW-Authenticate", `Basic realm="Restricted"`)
-			c.JSON(http.StatusUnauthorized, model.ErrorResponse{
-				Error:   "UNAUTHORIZED",
-				Message: "Authentication required",
-			})
-			return
-		}
-	} else {
-		c.Header("WWW-Authenticate", `Basic realm="Restricted"`)
-		c.JSON(http.StatusUnauthorized, model.ErrorResponse{
-			Error:   "UNAUTHORIZED",
-			Message: "Authentication required",
-		})
+	if !h.requireAdmin(c) {
 		return
 	}
 
@@ -479,6 +1853,252 @@ func (h *JSONHandler) Stats(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
+// CompressDocuments 压缩存量大文档保存的原始字节（管理任务，可重复执行以继续），json_data不变
+func (h *JSONHandler) CompressDocuments(c *gin.Context) {
+	if !h.requireAdmin(c) {
+		return
+	}
+
+	if !h.requireMaintenanceWindow(c) {
+		return
+	}
+
+	minSize, err := strconv.ParseInt(c.DefaultQuery("min_size", "65536"), 10, 64)
+	if err != nil || minSize < 0 {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "INVALID_MIN_SIZE",
+			Message: "min_size must be a non-negative integer",
+		})
+		return
+	}
+
+	batchSize, err := strconv.Atoi(c.DefaultQuery("batch_size", "100"))
+	if err != nil || batchSize < 1 || batchSize > 1000 {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "INVALID_BATCH_SIZE",
+			Message: "batch_size must be between 1 and 1000",
+		})
+		return
+	}
+
+	maxBatches, err := strconv.Atoi(c.DefaultQuery("max_batches", "10"))
+	if err != nil || maxBatches < 1 {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "INVALID_MAX_BATCHES",
+			Message: "max_batches must be a positive integer",
+		})
+		return
+	}
+
+	start := time.Now()
+	total := &model.CompressResult{}
+
+	for total.Batches < maxBatches {
+		result, err := h.store.CompressDocuments(c.Request.Context(), minSize, batchSize)
+		if err != nil {
+			log.Error().Err(err).Int("batch", total.Batches+1).Msg("Failed to compress documents")
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+				Error:   "COMPRESS_ERROR",
+				Message: fmt.Sprintf("Compression stopped after %d batches, re-run to resume", total.Batches),
+			})
+			return
+		}
+
+		total.Batches++
+		total.Compressed += result.Compressed
+		total.Failed += result.Failed
+		total.BytesBefore += result.BytesBefore
+		total.BytesAfter += result.BytesAfter
+		total.Remaining = result.Remaining
+		total.Done = result.Done
+
+		// 没有可压缩的行，或本批全部失败时停止，避免重复处理同一批
+		if result.Done || result.Compressed == 0 {
+			break
+		}
+	}
+	total.Duration = time.Since(start)
+
+	c.JSON(http.StatusOK, total)
+}
+
+// ExportBackup 将文档导出为压缩NDJSON并上传到配置的S3存储桶
+// since（RFC3339）可选，只导出该时间之后创建的文档
+func (h *JSONHandler) ExportBackup(c *gin.Context) {
+	if !h.requireAdmin(c) {
+		return
+	}
+
+	var since time.Time
+	if value := c.Query("since"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{
+				Error:   "INVALID_SINCE",
+				Message: "since must be an RFC3339 timestamp",
+			})
+			return
+		}
+		since = parsed
+	}
+
+	client, err := backup.NewS3Client(c.Request.Context(), h.config)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create S3 client")
+		c.JSON(http.StatusServiceUnavailable, model.ErrorResponse{
+			Error:   "BACKUP_NOT_CONFIGURED",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	result, err := backup.Export(c.Request.Context(), h.store, client,
+		h.config.Backup.S3.Bucket, h.config.Backup.S3.Prefix, since)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to export backup")
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Error:   "EXPORT_ERROR",
+			Message: "Failed to export backup",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ImportBackup 从S3读取ExportBackup生成的备份对象并导入
+// 写入按内容去重，中断后可用相同key重新执行
+func (h *JSONHandler) ImportBackup(c *gin.Context) {
+	if !h.requireAdmin(c) {
+		return
+	}
+
+	key := c.Query("key")
+	if key == "" {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "MISSING_KEY",
+			Message: "Backup object key is required",
+		})
+		return
+	}
+
+	client, err := backup.NewS3Client(c.Request.Context(), h.config)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create S3 client")
+		c.JSON(http.StatusServiceUnavailable, model.ErrorResponse{
+			Error:   "BACKUP_NOT_CONFIGURED",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	result, err := backup.Import(c.Request.Context(), h.store, client, h.config.Backup.S3.Bucket, key)
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Failed to import backup")
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Error:   "IMPORT_ERROR",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// FindNearDuplicates 查找与指定文档结构和内容相近的其他文档（SimHash汉明距离）
+func (h *JSONHandler) FindNearDuplicates(c *gin.Context) {
+	id := c.Param("id")
+	if !requireDocumentID(c, id) {
+		return
+	}
+
+	maxDistance, err := strconv.Atoi(c.DefaultQuery("max_distance", "3"))
+	if err != nil || maxDistance < 0 || maxDistance > 32 {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "INVALID_MAX_DISTANCE",
+			Message: "max_distance must be between 0 and 32",
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "INVALID_LIMIT",
+			Message: "Limit must be between 1 and 100",
+		})
+		return
+	}
+
+	doc, err := h.store.GetJSONByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, model.ErrorResponse{
+			Error:   "NOT_FOUND",
+			Message: "Document not found",
+		})
+		return
+	}
+
+	// 按当前内容计算，功能上线前写入的源文档同样可以查询
+	simhash, err := utils.SimHash(doc.JSONData)
+	if err != nil {
+		log.Error().Err(err).Str("id", id).Msg("Failed to compute simhash")
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Error:   "SIMHASH_ERROR",
+			Message: "Failed to compute document similarity hash",
+		})
+		return
+	}
+
+	matches, err := h.store.FindNearDuplicates(c.Request.Context(), id, simhash, maxDistance, limit)
+	if err != nil {
+		log.Error().Err(err).Str("id", id).Msg("Failed to find near duplicates")
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Error:   "QUERY_ERROR",
+			Message: "Failed to find near-duplicate documents",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NearDuplicatesResponse{
+		ID:          id,
+		MaxDistance: maxDistance,
+		Matches:     matches,
+	})
+}
+
+// requireAdmin 检查管理员基本认证，失败时写入401响应并返回false
+func (h *JSONHandler) requireAdmin(c *gin.Context) bool {
+	if middleware.IsAdmin(c, h.adminAccounts) {
+		return true
+	}
+
+	c.Header("WWW-Authenticate", `Basic realm="Restricted"`)
+	c.JSON(http.StatusUnauthorized, model.ErrorResponse{
+		Error:   "UNAUTHORIZED",
+		Message: "Authentication required",
+	})
+	return false
+}
+
+// validateDocumentData 检查json_data非空且是合法JSON，null视为缺失
+func validateDocumentData(data json.RawMessage) error {
+	if isEmptyDocument(data) {
+		return fmt.Errorf("json_data is required")
+	}
+	trimmed := bytes.TrimSpace(data)
+	if !utils.ValidateJSON(trimmed) {
+		return fmt.Errorf("json_data must be valid JSON")
+	}
+	return nil
+}
+
+// isEmptyDocument json_data缺失、只有空白或为null时视为空文档
+func isEmptyDocument(data json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(data)
+	return len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null"))
+}
+
 func getStorageMessage(isNew bool) string {
 	if isNew {
 		return "JSON document stored successfully"
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+	"github.com/rs/zerolog/log"
+)
+
+// labelPattern 标签格式：字母数字开头，可含 . _ - : /，最长255个字符，如 config/prod/service-a
+var labelPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:/-]{0,254}$`)
+
+// labelParam 读取路由中的标签（通配参数带前导斜杠），格式无效时写出400并返回ok=false
+func labelParam(c *gin.Context) (string, bool) {
+	label := strings.TrimPrefix(c.Param("label"), "/")
+	if !labelPattern.MatchString(label) {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "INVALID_LABEL",
+			Message: "label must start with a letter or digit, contain only letters, digits and . _ - : /, and be at most 255 characters",
+		})
+		return "", false
+	}
+	return label, true
+}
+
+// StoreByLabel 用请求体更新标签指向的内容：/json/by-label/*label
+// 请求体就是JSON文档本身，类型通过?type=指定；标签首次写入返回201，内容未变时版本号不变
+func (h *JSONHandler) StoreByLabel(c *gin.Context) {
+	label, ok := labelParam(c)
+	if !ok {
+		return
+	}
+
+	data, docType, ok := h.readRawDocument(c)
+	if !ok {
+		return
+	}
+
+	start := time.Now()
+	result, err := h.store.StoreByLabel(c.Request.Context(), label, model.StoreInput{
+		JSONData: data,
+		DocType:  docType,
+		Metadata: h.autoMetadata(c),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrControlCharacter):
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{
+				Error:   "CONTROL_CHARACTER",
+				Message: err.Error(),
+			})
+		case errors.Is(err, database.ErrInvalidDocument):
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{
+				Error:   "INVALID_JSON",
+				Message: err.Error(),
+			})
+		default:
+			log.Error().Err(err).Str("label", label).Msg("Failed to store JSON by label")
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+				Error:   "STORAGE_ERROR",
+				Message: "Failed to store JSON document",
+			})
+		}
+		return
+	}
+
+	doc := result.Document
+	isNew := !doc.Existing
+	h.appMetrics.RecordStore(time.Since(start), isNew)
+	recordQuotaUsage(c, []*model.JSONDocument{doc})
+
+	log.Info().
+		Str("label", label).
+		Int("version", result.Version).
+		Str("id", doc.ID).
+		Bool("changed", result.Changed).
+		Dur("duration", time.Since(start)).
+		Msg("JSON stored by label")
+
+	status := http.StatusOK
+	if result.Changed && result.Version == 1 {
+		status = http.StatusCreated
+	}
+	c.Header("Location", "/api/v1/json/by-label/"+label)
+	c.JSON(status, model.LabelResponse{
+		Label:     label,
+		Version:   result.Version,
+		Changed:   result.Changed,
+		ID:        doc.ID,
+		ShortHash: h.shortHash(doc.ContentHash),
+		IsNew:     isNew,
+		CreatedAt: doc.CreatedAt,
+	})
+}
+
+// GetJSONByLabel 获取标签当前指向的文档：/json/by-label/*label，?version=N 获取历史版本
+// 响应头X-Label-Version为返回的版本号
+func (h *JSONHandler) GetJSONByLabel(c *gin.Context) {
+	label, ok := labelParam(c)
+	if !ok {
+		return
+	}
+
+	version := 0
+	if v := c.Query("version"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{
+				Error:   "INVALID_VERSION",
+				Message: "version must be a positive integer",
+			})
+			return
+		}
+		version = n
+	}
+
+	doc, current, err := h.store.GetJSONByLabel(c.Request.Context(), label, version)
+	if err != nil {
+		if errors.Is(err, database.ErrLabelNotFound) {
+			message := fmt.Sprintf("Label %s not found", label)
+			if version > 0 {
+				message = fmt.Sprintf("Label %s has no version %d", label, version)
+			}
+			c.JSON(http.StatusNotFound, model.ErrorResponse{
+				Error:   "NOT_FOUND",
+				Message: message,
+			})
+			return
+		}
+		log.Error().Err(err).Str("label", label).Msg("Failed to get JSON by label")
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Error:   "QUERY_ERROR",
+			Message: "Failed to get JSON document",
+		})
+		return
+	}
+
+	if !h.checkResponseSize(c, int64(len(doc.JSONData))) {
+		return
+	}
+
+	doc.ShortHash = h.shortHash(doc.ContentHash)
+	c.Header("X-Label-Version", strconv.Itoa(current))
+	// 标签可能改指向更早修改的文档，不能按文档的updated_at做条件请求判断
+	h.renderJSON(c, http.StatusOK, doc)
+}
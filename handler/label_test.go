@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// versionedLabelStore 标签指向最近一次写入的内容，内容变化时版本号加1
+type versionedLabelStore struct {
+	database.JSONStore
+	contents map[string]string
+	versions map[string]int
+}
+
+func (s *versionedLabelStore) StoreByLabel(ctx context.Context, label string, input model.StoreInput) (*model.LabeledDocument, error) {
+	if !json.Valid(input.JSONData) {
+		return nil, fmt.Errorf("%w: unexpected end of JSON input", database.ErrInvalidDocument)
+	}
+	doc := &model.JSONDocument{ID: "00000000-0000-0000-0000-0000000000d1", JSONData: input.JSONData}
+	result := &model.LabeledDocument{Label: label, Version: s.versions[label], Document: doc}
+	if s.contents[label] == string(input.JSONData) {
+		doc.Existing = true
+		return result, nil
+	}
+	s.contents[label] = string(input.JSONData)
+	s.versions[label]++
+	result.Version = s.versions[label]
+	result.Changed = true
+	return result, nil
+}
+
+func TestStoreByLabel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &versionedLabelStore{contents: map[string]string{}, versions: map[string]int{}}
+	router := gin.New()
+	router.PUT("/json/by-label/*label", NewJSONHandler(store, config.Config{}).StoreByLabel)
+
+	tests := []struct {
+		name        string
+		label       string
+		body        string
+		wantStatus  int
+		wantVersion int
+		wantChanged bool
+	}{
+		// 首次写入创建标签
+		{name: "create", label: "config/prod", body: `{"replicas":2}`, wantStatus: http.StatusCreated, wantVersion: 1, wantChanged: true},
+		// 内容未变时版本号不变
+		{name: "unchanged", label: "config/prod", body: `{"replicas":2}`, wantStatus: http.StatusOK, wantVersion: 1},
+		{name: "overwrite", label: "config/prod", body: `{"replicas":3}`, wantStatus: http.StatusOK, wantVersion: 2, wantChanged: true},
+		{name: "invalid label", label: ".hidden", body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "rejected document", label: "config/prod", body: `{"replicas":`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, "/json/by-label/"+tt.label, strings.NewReader(tt.body))
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus >= http.StatusBadRequest {
+				return
+			}
+			var resp model.LabelResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Version != tt.wantVersion || resp.Changed != tt.wantChanged {
+				t.Errorf("version %d changed %v, want version %d changed %v", resp.Version, resp.Changed, tt.wantVersion, tt.wantChanged)
+			}
+			if got := w.Header().Get("Location"); got != "/api/v1/json/by-label/"+tt.label {
+				t.Errorf("Location = %q", got)
+			}
+		})
+	}
+}
+
+// labelHistoryStore 按标签和版本号记录文档ID，version为0时返回最新版本
+type labelHistoryStore struct {
+	database.JSONStore
+	history map[string][]string
+}
+
+func (s *labelHistoryStore) GetJSONByLabel(ctx context.Context, label string, version int) (*model.JSONDocument, int, error) {
+	versions := s.history[label]
+	if version == 0 {
+		version = len(versions)
+	}
+	if version < 1 || version > len(versions) {
+		return nil, 0, database.ErrLabelNotFound
+	}
+	return &model.JSONDocument{ID: versions[version-1], JSONData: []byte(`{}`)}, version, nil
+}
+
+func TestGetJSONByLabel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &labelHistoryStore{history: map[string][]string{
+		"config/prod": {"00000000-0000-0000-0000-0000000000d2", "00000000-0000-0000-0000-0000000000d3"},
+	}}
+	router := gin.New()
+	router.GET("/json/by-label/*label", NewJSONHandler(store, config.Config{}).GetJSONByLabel)
+
+	tests := []struct {
+		name        string
+		path        string
+		wantStatus  int
+		wantVersion string
+		wantID      string
+	}{
+		{name: "current", path: "/json/by-label/config/prod", wantStatus: http.StatusOK, wantVersion: "2", wantID: "00000000-0000-0000-0000-0000000000d3"},
+		{name: "historical", path: "/json/by-label/config/prod?version=1", wantStatus: http.StatusOK, wantVersion: "1", wantID: "00000000-0000-0000-0000-0000000000d2"},
+		{name: "unknown label", path: "/json/by-label/config/dev", wantStatus: http.StatusNotFound},
+		{name: "unknown version", path: "/json/by-label/config/prod?version=3", wantStatus: http.StatusNotFound},
+		{name: "invalid version", path: "/json/by-label/config/prod?version=0", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if got := w.Header().Get("X-Label-Version"); got != tt.wantVersion {
+				t.Errorf("X-Label-Version = %q, want %q", got, tt.wantVersion)
+			}
+			var doc model.JSONDocument
+			if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+				t.Fatal(err)
+			}
+			if doc.ID != tt.wantID {
+				t.Errorf("id = %s, want %s", doc.ID, tt.wantID)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// sizedStore 保存各文档的大小，按大小降序返回前limit个
+type sizedStore struct {
+	database.JSONStore
+	sizes map[string]int64
+}
+
+func (s sizedStore) LargestDocuments(ctx context.Context, limit int) ([]model.DocumentSummary, error) {
+	summaries := make([]model.DocumentSummary, 0, len(s.sizes))
+	for id, size := range s.sizes {
+		summaries = append(summaries, model.DocumentSummary{ID: id, Size: size})
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Size > summaries[j].Size })
+	if len(summaries) > limit {
+		summaries = summaries[:limit]
+	}
+	return summaries, nil
+}
+
+func TestLargestDocuments(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var cfg config.Config
+	cfg.Security.AdminUsername = "admin"
+	cfg.Security.AdminPassword = "secret"
+	store := sizedStore{sizes: map[string]int64{"a": 10, "b": 5000, "c": 300, "d": 70000}}
+	router := gin.New()
+	router.GET("/api/admin/json/largest", NewJSONHandler(store, cfg).LargestDocuments)
+
+	get := func(query string, auth bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/json/largest"+query, nil)
+		if auth {
+			req.SetBasicAuth("admin", "secret")
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get("?limit=2", false); w.Code != http.StatusUnauthorized {
+		t.Errorf("without auth: status = %d, want 401", w.Code)
+	}
+	for _, limit := range []string{"0", "101", "x"} {
+		if w := get("?limit="+limit, true); w.Code != http.StatusBadRequest {
+			t.Errorf("limit=%s: status = %d, want 400", limit, w.Code)
+		}
+	}
+
+	w := get("?limit=3", true)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var resp model.LargestDocumentsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, doc := range resp.Documents {
+		ids = append(ids, doc.ID)
+	}
+	if resp.Limit != 3 || len(ids) != 3 || ids[0] != "d" || ids[1] != "b" || ids[2] != "c" {
+		t.Errorf("limit %d, documents %v, want 3 and [d b c]", resp.Limit, ids)
+	}
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/model"
+)
+
+// maintenanceWindow 每天允许执行重型维护任务的时间段
+type maintenanceWindow struct {
+	enabled bool
+	// start、end 自零点起的分钟数，end小于start表示跨越零点
+	start, end int
+	loc        *time.Location
+	// desc 用于错误提示，如 "01:00-05:00 Asia/Shanghai"
+	desc string
+}
+
+// newMaintenanceWindow 从配置构建维护时间段，配置已在加载时校验
+func newMaintenanceWindow(cfg config.Config) maintenanceWindow {
+	window := cfg.Maintenance.Window
+	if window.Start == "" && window.End == "" {
+		return maintenanceWindow{}
+	}
+
+	start, _ := time.Parse("15:04", window.Start)
+	end, _ := time.Parse("15:04", window.End)
+	loc, err := time.LoadLocation(window.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	return maintenanceWindow{
+		enabled: true,
+		start:   start.Hour()*60 + start.Minute(),
+		end:     end.Hour()*60 + end.Minute(),
+		loc:     loc,
+		desc:    fmt.Sprintf("%s-%s %s", window.Start, window.End, loc),
+	}
+}
+
+// contains 判断t是否在维护时间段内，未配置时总是返回true
+func (w maintenanceWindow) contains(t time.Time) bool {
+	if !w.enabled {
+		return true
+	}
+
+	t = t.In(w.loc)
+	minute := t.Hour()*60 + t.Minute()
+	if w.start < w.end {
+		return minute >= w.start && minute < w.end
+	}
+	return minute >= w.start || minute < w.end
+}
+
+// nextStart 返回t之后最近一次时间段开始的时刻
+func (w maintenanceWindow) nextStart(t time.Time) time.Time {
+	t = t.In(w.loc)
+	next := time.Date(t.Year(), t.Month(), t.Day(), w.start/60, w.start%60, 0, 0, w.loc)
+	if !next.After(t) {
+		next = time.Date(t.Year(), t.Month(), t.Day()+1, w.start/60, w.start%60, 0, 0, w.loc)
+	}
+	return next
+}
+
+// requireMaintenanceWindow 不在维护时间段内时返回503，Retry-After为距下次开始的秒数
+func (h *JSONHandler) requireMaintenanceWindow(c *gin.Context) bool {
+	now := time.Now()
+	if h.maintenanceWindow.contains(now) {
+		return true
+	}
+
+	next := h.maintenanceWindow.nextStart(now)
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(next.Sub(now).Seconds()))))
+	c.JSON(http.StatusServiceUnavailable, model.ErrorResponse{
+		Error:   "OUTSIDE_MAINTENANCE_WINDOW",
+		Message: fmt.Sprintf("Maintenance jobs only run during %s, next window starts at %s", h.maintenanceWindow.desc, next.Format(time.RFC3339)),
+	})
+	return false
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// countingStore 只统计写入次数，用于确认超限的文档没有到达存储层
+type countingStore struct {
+	database.JSONStore
+	stored int
+}
+
+func (s *countingStore) StoreJSON(ctx context.Context, input model.StoreInput) (*model.JSONDocument, error) {
+	s.stored++
+	return &model.JSONDocument{ID: "00000000-0000-0000-0000-000000000001", JSONData: input.JSONData}, nil
+}
+
+func TestStoreJSONMaxElements(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var cfg config.Config
+	cfg.Server.MaxElements = 4
+	store := &countingStore{}
+	router := gin.New()
+	router.POST("/api/v1/json", NewJSONHandler(store, cfg).StoreJSON)
+
+	tests := []struct {
+		body       string
+		wantStatus int
+	}{
+		{body: `{"json_data": [1,2,3]}`, wantStatus: http.StatusOK},
+		{body: `{"json_data": [1,2,3,4]}`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/json", bytes.NewBufferString(tt.body)))
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d: %s", tt.body, w.Code, tt.wantStatus, w.Body)
+		}
+		if tt.wantStatus == http.StatusBadRequest && !strings.Contains(w.Body.String(), "TOO_MANY_ELEMENTS") {
+			t.Errorf("%s: body = %s, want TOO_MANY_ELEMENTS", tt.body, w.Body)
+		}
+	}
+	if store.stored != 1 {
+		t.Errorf("stored %d documents, want 1", store.stored)
+	}
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+)
+
+// metadataLimits metadata的键数、大小和键名限制，避免把metadata当作不计入文档大小的存储空间
+type metadataLimits struct {
+	maxKeys    int
+	maxBytes   int
+	disallowed []*regexp.Regexp
+}
+
+// newMetadataLimits 从配置构建限制，正则已在加载配置时校验
+func newMetadataLimits(cfg config.Config) metadataLimits {
+	limits := metadataLimits{
+		maxKeys:  cfg.Server.MetadataMaxKeys,
+		maxBytes: cfg.Server.MetadataMaxBytes,
+	}
+	for _, pattern := range cfg.Server.MetadataDisallowedKeys {
+		if re, err := regexp.Compile(pattern); err == nil {
+			limits.disallowed = append(limits.disallowed, re)
+		}
+	}
+	return limits
+}
+
+// validate 检查metadata是否超出限制，返回具体的违规项
+func (l metadataLimits) validate(metadata map[string]any) error {
+	if len(metadata) == 0 {
+		return nil
+	}
+
+	if l.maxKeys > 0 && len(metadata) > l.maxKeys {
+		return fmt.Errorf("metadata has %d keys, exceeds the maximum of %d", len(metadata), l.maxKeys)
+	}
+
+	for key := range metadata {
+		for _, re := range l.disallowed {
+			if re.MatchString(key) {
+				return fmt.Errorf("metadata key %q matches disallowed pattern %q", key, re.String())
+			}
+		}
+	}
+
+	if l.maxBytes > 0 {
+		data, err := json.Marshal(metadata)
+		if err != nil {
+			return fmt.Errorf("metadata cannot be serialized: %v", err)
+		}
+		if len(data) > l.maxBytes {
+			return fmt.Errorf("metadata is %d bytes, exceeds the maximum of %d", len(data), l.maxBytes)
+		}
+	}
+
+	return nil
+}
+
+// autoMetadata 按server.auto_metadata生成本次请求的来源信息，未配置时返回nil
+// 同一请求中的多个文档使用同一份结果，ingested_at相同
+func (h *JSONHandler) autoMetadata(c *gin.Context) map[string]any {
+	fields := h.config.Server.AutoMetadata.Fields
+	if len(fields) == 0 {
+		return nil
+	}
+
+	auto := make(map[string]any, len(fields))
+	for _, field := range fields {
+		switch field {
+		case config.AutoMetadataSourceIP:
+			auto[field] = c.ClientIP()
+		case config.AutoMetadataRequestID:
+			auto[field] = c.GetString("request_id")
+		case config.AutoMetadataUserAgent:
+			auto[field] = c.Request.UserAgent()
+		case config.AutoMetadataEnvironment:
+			auto[field] = string(h.config.Environment)
+		case config.AutoMetadataIngestedAt:
+			auto[field] = time.Now().UTC().Format(time.RFC3339Nano)
+		}
+	}
+	return auto
+}
+
+// mergeAutoMetadata 合并自动来源信息和客户端metadata，返回新的map，不修改参数
+// 客户端提供的同名键优先，server.auto_metadata.reserved中的字段总是使用自动生成的值
+func (h *JSONHandler) mergeAutoMetadata(auto, metadata map[string]any) map[string]any {
+	if len(auto) == 0 {
+		return metadata
+	}
+
+	merged := make(map[string]any, len(auto)+len(metadata))
+	for key, value := range auto {
+		merged[key] = value
+	}
+	for key, value := range metadata {
+		if _, ok := auto[key]; ok && slices.Contains(h.config.Server.AutoMetadata.Reserved, key) {
+			continue
+		}
+		merged[key] = value
+	}
+	return merged
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+)
+
+func TestMetadataLimits(t *testing.T) {
+	var cfg config.Config
+	cfg.Server.MetadataMaxKeys = 2
+	cfg.Server.MetadataMaxBytes = 32
+	cfg.Server.MetadataDisallowedKeys = []string{`^\$`, `^_`}
+	limits := newMetadataLimits(cfg)
+
+	tests := []struct {
+		name     string
+		metadata map[string]any
+		wantErr  string
+	}{
+		{name: "empty", metadata: nil},
+		{name: "within limits", metadata: map[string]any{"env": "prod", "team": "a"}},
+		{name: "too many keys", metadata: map[string]any{"a": 1, "b": 2, "c": 3}, wantErr: "3 keys, exceeds the maximum of 2"},
+		{name: "too large", metadata: map[string]any{"blob": strings.Repeat("x", 40)}, wantErr: "exceeds the maximum of 32"},
+		{name: "dollar key", metadata: map[string]any{"$where": "x"}, wantErr: `"$where" matches disallowed pattern`},
+		{name: "underscore key", metadata: map[string]any{"_internal": true}, wantErr: `"_internal" matches disallowed pattern`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := limits.validate(tt.metadata)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validate() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// 默认不限制
+	if err := newMetadataLimits(config.Config{}).validate(map[string]any{"blob": strings.Repeat("x", 1<<20)}); err != nil {
+		t.Errorf("default limits rejected metadata: %v", err)
+	}
+}
+
+func TestStoreJSONInvalidMetadata(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var cfg config.Config
+	cfg.Server.MetadataMaxKeys = 1
+	store := &countingStore{}
+	router := gin.New()
+	router.POST("/api/v1/json", NewJSONHandler(store, cfg).StoreJSON)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/json",
+		bytes.NewBufferString(`{"json_data": {}, "metadata": {"a": 1, "b": 2}}`)))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "INVALID_METADATA") {
+		t.Errorf("status = %d, want 400 INVALID_METADATA: %s", w.Code, w.Body)
+	}
+	if store.stored != 0 {
+		t.Errorf("stored %d documents, want 0", store.stored)
+	}
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/model"
+	"github.com/rs/zerolog/log"
+)
+
+// ndjsonContentType JSON Lines响应的Content-Type，响应信封不包装该类型
+const ndjsonContentType = "application/x-ndjson"
+
+// ndjsonRequested 解析 ?format=，ndjson时返回true；取值无效时写出400并返回ok=false
+func ndjsonRequested(c *gin.Context) (ndjson bool, ok bool) {
+	switch c.Query("format") {
+	case "", "json":
+		return false, true
+	case "ndjson":
+		return true, true
+	}
+
+	c.JSON(http.StatusBadRequest, model.ErrorResponse{
+		Error:   "INVALID_FORMAT",
+		Message: "format must be 'json' or 'ndjson'",
+	})
+	return false, false
+}
+
+// writeNDJSON 每行写出一个文档并立即刷新，不构建响应数组；分页等元数据需在调用前通过响应头设置
+// 头部已写出后编码失败无法再返回错误码，只记录日志并中止，客户端会读到不完整的最后一行
+func (h *JSONHandler) writeNDJSON(c *gin.Context, documents []*model.JSONDocument) {
+	c.Header("Content-Type", ndjsonContentType)
+	c.Status(http.StatusOK)
+	c.Writer.WriteHeaderNow()
+
+	encoder := json.NewEncoder(c.Writer)
+	encoder.SetEscapeHTML(h.config.Server.EscapeHTML)
+	for _, doc := range documents {
+		doc.ShortHash = h.shortHash(doc.ContentHash)
+		if err := encoder.Encode(doc); err != nil {
+			log.Error().Err(err).Str("id", doc.ID).Msg("Failed to write NDJSON document")
+			return
+		}
+		c.Writer.Flush()
+	}
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// listedStore 按固定顺序保存的一组文档，列表和批量读取都从中返回
+type listedStore struct {
+	database.JSONStore
+	documents []*model.JSONDocument
+}
+
+func (s *listedStore) ListJSON(ctx context.Context, filter model.ListFilter) ([]*model.JSONDocument, error) {
+	return s.documents[:min(filter.Limit, len(s.documents))], nil
+}
+
+func (s *listedStore) CountJSON(ctx context.Context, docType string, estimate bool) (int64, error) {
+	return int64(len(s.documents)), nil
+}
+
+func (s *listedStore) GetJSONBatch(ctx context.Context, ids []string) ([]*model.JSONDocument, error) {
+	var found []*model.JSONDocument
+	for _, doc := range s.documents {
+		for _, id := range ids {
+			if doc.ID == id {
+				found = append(found, doc)
+			}
+		}
+	}
+	return found, nil
+}
+
+// readNDJSON 逐行解析响应，每行必须是一个完整的文档
+func readNDJSON(t *testing.T, w *httptest.ResponseRecorder) []model.JSONDocument {
+	t.Helper()
+	if got := w.Header().Get("Content-Type"); got != ndjsonContentType {
+		t.Errorf("Content-Type = %q, want %q", got, ndjsonContentType)
+	}
+	var documents []model.JSONDocument
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var doc model.JSONDocument
+		if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil {
+			t.Fatalf("line %d %q: %v", len(documents)+1, scanner.Text(), err)
+		}
+		documents = append(documents, doc)
+	}
+	return documents
+}
+
+func TestNDJSONResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ids := []string{
+		"00000000-0000-0000-0000-000000000181",
+		"00000000-0000-0000-0000-000000000182",
+		"00000000-0000-0000-0000-000000000183",
+	}
+	store := &listedStore{}
+	for i, id := range ids {
+		store.documents = append(store.documents, &model.JSONDocument{ID: id, JSONData: []byte(fmt.Sprintf(`{"n":%d}`, i))})
+	}
+	var cfg config.Config
+	cfg.Server.BatchGetMaxIDs = 10
+	cfg.Server.BatchGetConcurrency = 1
+	h := NewJSONHandler(store, cfg)
+	router := gin.New()
+	router.GET("/api/v1/json/list", h.ListJSON)
+	router.GET("/api/v1/json/batch", h.GetJSONBatch)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d: %s", path, w.Code, w.Body)
+		}
+		return w
+	}
+	docIDs := func(documents []model.JSONDocument) string {
+		var got []string
+		for _, doc := range documents {
+			got = append(got, doc.ID)
+		}
+		return strings.Join(got, ",")
+	}
+
+	// 列表：每行一个文档，总数在响应头中
+	w := get("/api/v1/json/list?format=ndjson&limit=2&total=estimated")
+	if got, want := docIDs(readNDJSON(t, w)), strings.Join(ids[:2], ","); got != want {
+		t.Errorf("list lines = %s, want %s", got, want)
+	}
+	if got := w.Header().Get("X-Total-Estimated"); got != "3" {
+		t.Errorf("X-Total-Estimated = %q, want 3", got)
+	}
+
+	// 批量读取：按请求顺序只输出找到的文档，未找到的数量在响应头中
+	missing := "00000000-0000-0000-0000-000000000999"
+	w = get("/api/v1/json/batch?format=ndjson&ids=" + ids[2] + "," + missing + "," + ids[0])
+	if got, want := docIDs(readNDJSON(t, w)), ids[2]+","+ids[0]; got != want {
+		t.Errorf("batch lines = %s, want %s", got, want)
+	}
+	if got := w.Header().Get("X-Failure-Count"); got != "1" {
+		t.Errorf("X-Failure-Count = %q, want 1", got)
+	}
+
+	// 默认仍是JSON对象
+	w = get("/api/v1/json/list")
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Errorf("default Content-Type = %q, want application/json", ct)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/json/list?format=csv", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("format=csv status = %d, want 400", w.Code)
+	}
+}
+
+func TestGetJSONChangesNDJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const id = "00000000-0000-0000-0000-000000000188"
+	updated := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	store := &changeLogStore{now: updated.Add(time.Hour), docs: map[string]*changedDoc{}}
+	store.write(id, updated, true)
+	router := gin.New()
+	router.GET("/api/v1/json/changes", NewJSONHandler(store, config.Config{}).GetJSONChanges)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/json/changes?format=ndjson&limit=1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	if documents := readNDJSON(t, w); len(documents) != 1 || documents[0].ID != id {
+		t.Errorf("lines = %+v, want %s", documents, id)
+	}
+	// 游标在响应头中
+	if got := w.Header().Get("X-Next-Since"); got != updated.Format(time.RFC3339Nano) {
+		t.Errorf("X-Next-Since = %q, want %s", got, updated.Format(time.RFC3339Nano))
+	}
+	if got := w.Header().Get("X-Next-After-ID"); got != id {
+		t.Errorf("X-Next-After-ID = %q, want %s", got, id)
+	}
+	if got := w.Header().Get("X-Has-More"); got != "true" {
+		t.Errorf("X-Has-More = %q, want true for a full page", got)
+	}
+}
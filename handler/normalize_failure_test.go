@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// unnormalizableStore 按normalize_failure=reject处理：所有写入都因无法规范化被拒绝
+type unnormalizableStore struct {
+	database.JSONStore
+}
+
+func (unnormalizableStore) StoreJSON(ctx context.Context, input model.StoreInput) (*model.JSONDocument, error) {
+	return nil, fmt.Errorf("%w: number out of range", database.ErrInvalidDocument)
+}
+
+func (unnormalizableStore) UpdateJSON(ctx context.Context, id string, input model.StoreInput) (*model.JSONDocument, error) {
+	return nil, fmt.Errorf("%w: number out of range", database.ErrInvalidDocument)
+}
+
+func TestStoreJSONNormalizeFailure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewJSONHandler(unnormalizableStore{}, config.Config{})
+	router := gin.New()
+	router.POST("/api/v1/json", h.StoreJSON)
+	router.PUT("/api/v1/json/:id/raw", h.UpdateJSONRaw)
+
+	requests := []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/api/v1/json", bytes.NewBufferString(`{"json_data":{"a":1e400}}`)),
+		httptest.NewRequest(http.MethodPut, "/api/v1/json/00000000-0000-0000-0000-000000000190/raw", bytes.NewBufferString(`{"a":1e400}`)),
+	}
+	// 存储层拒绝时返回400而不是500
+	for _, req := range requests {
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "INVALID_JSON") {
+			t.Errorf("%s %s: status = %d body = %s, want 400 INVALID_JSON", req.Method, req.URL.Path, w.Code, w.Body)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+	"github.com/leapzhao/json-store/utils"
+)
+
+// storedBytesStore 按ID返回固定的存储内容
+type storedBytesStore struct {
+	database.JSONStore
+	data map[string]string
+}
+
+func (s *storedBytesStore) GetJSONByID(ctx context.Context, id string) (*model.JSONDocument, error) {
+	data, ok := s.data[id]
+	if !ok {
+		return nil, database.ErrDocumentNotFound
+	}
+	return &model.JSONDocument{ID: id, JSONData: []byte(data)}, nil
+}
+
+func TestGetJSONNormalized(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const (
+		messy   = "00000000-0000-0000-0000-000000000001"
+		broken  = "00000000-0000-0000-0000-000000000002"
+		missing = "00000000-0000-0000-0000-000000000003"
+		numbers = "00000000-0000-0000-0000-000000000004"
+	)
+	store := &storedBytesStore{data: map[string]string{
+		messy:   "{ \"b\" : [ 1, 2 ],\n\t\"a\": {\"z\": null, \"y\": \"<x>\"} }",
+		broken:  `{"a":`,
+		numbers: `{"f":1.0,"big":9007199254740993}`,
+	}}
+
+	tests := []struct {
+		name       string
+		id         string
+		query      string
+		escapeHTML bool
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "hash form escapes html", id: messy, wantStatus: http.StatusOK, wantBody: `{"a":{"y":"\u003cx\u003e","z":null},"b":[1,2]}`},
+		{name: "hash form ignores escape_html", id: messy, escapeHTML: true, wantStatus: http.StatusOK, wantBody: `{"a":{"y":"\u003cx\u003e","z":null},"b":[1,2]}`},
+		{name: "hash form converts numbers", id: numbers, wantStatus: http.StatusOK, wantBody: `{"big":9007199254740992,"f":1}`},
+		{name: "display form", id: messy, query: "?display=true", wantStatus: http.StatusOK, wantBody: `{"a":{"y":"<x>","z":null},"b":[1,2]}`},
+		{name: "display form escape html", id: messy, query: "?display=true", escapeHTML: true, wantStatus: http.StatusOK, wantBody: `{"a":{"y":"\u003cx\u003e","z":null},"b":[1,2]}`},
+		{name: "display form keeps numbers", id: numbers, query: "?display=true", wantStatus: http.StatusOK, wantBody: `{"big":9007199254740993,"f":1.0}`},
+		{name: "cannot normalize", id: broken, wantStatus: http.StatusInternalServerError},
+		{name: "not found", id: missing, wantStatus: http.StatusNotFound},
+		{name: "invalid id", id: "not-a-uuid", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg config.Config
+			cfg.Server.EscapeHTML = tt.escapeHTML
+			h := NewJSONHandler(store, cfg)
+			router := gin.New()
+			router.GET("/api/v1/json/:id/normalized", h.GetJSONNormalized)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/json/"+tt.id+"/normalized"+tt.query, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantBody == "" {
+				return
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("body = %s, want %s", got, tt.wantBody)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+		})
+	}
+}
+
+// 默认返回的字节的sha256必须等于存储时计算的内容哈希
+func TestGetJSONNormalizedMatchesHash(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const id = "00000000-0000-0000-0000-000000000001"
+	docs := []string{
+		`{"f":1.0}`,
+		`{"big":9007199254740993}`,
+		`{"s":"<a&b>","n":[1.0,9007199254740993,1e2]}`,
+	}
+	for _, mode := range []string{config.NumberHandlingFloat64, config.NumberHandlingExact} {
+		for _, data := range docs {
+			t.Run(mode+" "+data, func(t *testing.T) {
+				var cfg config.Config
+				cfg.Database.NumberHandling = mode
+				h := NewJSONHandler(&storedBytesStore{data: map[string]string{id: data}}, cfg)
+				router := gin.New()
+				router.GET("/api/v1/json/:id/normalized", h.GetJSONNormalized)
+
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/json/"+id+"/normalized", nil))
+				if w.Code != http.StatusOK {
+					t.Fatalf("status = %d: %s", w.Code, w.Body)
+				}
+				want, err := utils.CalculateHash([]byte(data), mode == config.NumberHandlingExact)
+				if err != nil {
+					t.Fatalf("CalculateHash: %v", err)
+				}
+				sum := sha256.Sum256(w.Body.Bytes())
+				if got := hex.EncodeToString(sum[:]); got != want {
+					t.Errorf("sha256(body) = %s, want %s (body %s)", got, want, w.Body)
+				}
+				if form := w.Header().Get("X-Normalized-Form"); form != "hash" {
+					t.Errorf("X-Normalized-Form = %q, want hash", form)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// rawBytesStore 原样保存写入的字节，相当于开启number_handling: exact后存储层从raw_data返回的内容
+type rawBytesStore struct {
+	database.JSONStore
+	docs map[string][]byte
+}
+
+func (s *rawBytesStore) StoreJSON(ctx context.Context, input model.StoreInput) (*model.JSONDocument, error) {
+	id := uuid.NewString()
+	s.docs[id] = append([]byte(nil), input.JSONData...)
+	return &model.JSONDocument{ID: id, JSONData: input.JSONData}, nil
+}
+
+func (s *rawBytesStore) GetJSONByID(ctx context.Context, id string) (*model.JSONDocument, error) {
+	data, ok := s.docs[id]
+	if !ok {
+		return nil, database.ErrDocumentNotFound
+	}
+	return &model.JSONDocument{ID: id, JSONData: data}, nil
+}
+
+func TestStoreAndGetLargeInteger(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewJSONHandler(&rawBytesStore{docs: map[string][]byte{}}, config.Config{})
+	router := gin.New()
+	router.POST("/api/v1/json", h.StoreJSON)
+	router.GET("/api/v1/json/:id", h.GetJSON)
+	router.GET("/api/v1/json/:id/raw", h.GetJSONRaw)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/json",
+		bytes.NewBufferString(`{"json_data": {"id": 9223372036854775807}}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("store status = %d: %s", w.Code, w.Body)
+	}
+	var stored model.StoreResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &stored); err != nil {
+		t.Fatal(err)
+	}
+
+	// json_data按[]byte编码为base64，/raw返回内容本身，两者都必须是写入时的整数文本
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/json/"+stored.ID, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("get status = %d: %s", w.Code, w.Body)
+	}
+	var doc model.JSONDocument
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	assertExactID(t, "GET /json/:id", doc.JSONData)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/json/"+stored.ID+"/raw", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("get raw status = %d: %s", w.Code, w.Body)
+	}
+	assertExactID(t, "GET /json/:id/raw", w.Body.Bytes())
+}
+
+// assertExactID 按文本比较id，经float64转换会变成9223372036854775808
+func assertExactID(t *testing.T, endpoint string, data []byte) {
+	t.Helper()
+	var doc struct {
+		ID json.Number `json:"id"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		t.Fatalf("%s: %v", endpoint, err)
+	}
+	if doc.ID != "9223372036854775807" {
+		t.Errorf("%s: id = %s, want 9223372036854775807", endpoint, doc.ID)
+	}
+}
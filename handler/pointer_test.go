@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// pointerDocumentStore 按ID保存文档内容，不存在的ID返回ErrDocumentNotFound
+type pointerDocumentStore struct {
+	database.JSONStore
+	docs map[string]string
+}
+
+func (s pointerDocumentStore) GetJSONByID(ctx context.Context, id string) (*model.JSONDocument, error) {
+	data, ok := s.docs[id]
+	if !ok {
+		return nil, database.ErrDocumentNotFound
+	}
+	return &model.JSONDocument{ID: id, JSONData: []byte(data)}, nil
+}
+
+func TestGetJSONPointer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const id = "00000000-0000-0000-0000-0000000000f1"
+	store := pointerDocumentStore{docs: map[string]string{
+		id: `{"order":{"items":[{"sku":"A-1","qty":2}],"a/b":{"m~n":true}}}`,
+	}}
+	router := gin.New()
+	router.GET("/api/v1/json/:id/pointer", NewJSONHandler(store, config.Config{}).GetJSONPointer)
+
+	tests := []struct {
+		name       string
+		id         string
+		pointer    *string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "object", id: id, pointer: pointerParam("/order/items/0"), wantStatus: http.StatusOK, wantBody: `{"sku":"A-1","qty":2}`},
+		{name: "array element", id: id, pointer: pointerParam("/order/items/0/qty"), wantStatus: http.StatusOK, wantBody: `2`},
+		{name: "escaped keys", id: id, pointer: pointerParam("/order/a~1b/m~0n"), wantStatus: http.StatusOK, wantBody: `true`},
+		{name: "absent", id: id, pointer: pointerParam("/order/items/1"), wantStatus: http.StatusNotFound},
+		{name: "malformed", id: id, pointer: pointerParam("order"), wantStatus: http.StatusBadRequest},
+		{name: "missing parameter", id: id, wantStatus: http.StatusBadRequest},
+		{name: "unknown document", id: "00000000-0000-0000-0000-0000000000f2", pointer: pointerParam("/order"), wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := "/api/v1/json/" + tt.id + "/pointer"
+			if tt.pointer != nil {
+				target += "?p=" + url.QueryEscape(*tt.pointer)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %s, want %s", w.Body, tt.wantBody)
+			}
+		})
+	}
+}
+
+// pointerParam 返回p参数的值，nil表示不带该参数
+func pointerParam(p string) *string { return &p }
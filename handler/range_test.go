@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/middleware"
+	"github.com/leapzhao/json-store/model"
+)
+
+// fixedBodyStore 对任意ID返回同一份内容
+type fixedBodyStore struct {
+	database.JSONStore
+	body []byte
+}
+
+func (s fixedBodyStore) GetJSONByID(ctx context.Context, id string) (*model.JSONDocument, error) {
+	return &model.JSONDocument{ID: id, JSONData: s.body, UpdatedAt: time.Now()}, nil
+}
+
+func TestGetJSONRawRange(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	body := `{"data":"0123456789abcdefghij"}`
+	router := gin.New()
+	// 缩进中间件不能改写分段响应
+	router.Use(middleware.PrettyJSON())
+	router.GET("/api/v1/json/:id/raw", NewJSONHandler(fixedBodyStore{body: []byte(body)}, config.Config{}).GetJSONRaw)
+
+	tests := []struct {
+		name             string
+		rangeHeader      string
+		wantStatus       int
+		wantBody         string
+		wantContentRange string
+	}{
+		{name: "full", wantStatus: http.StatusOK, wantBody: body},
+		{name: "single range", rangeHeader: "bytes=0-7", wantStatus: http.StatusPartialContent,
+			wantBody: `{"data":`, wantContentRange: "bytes 0-7/31"},
+		{name: "suffix range", rangeHeader: "bytes=-3", wantStatus: http.StatusPartialContent,
+			wantBody: `j"}`, wantContentRange: "bytes 28-30/31"},
+		{name: "unsatisfiable", rangeHeader: "bytes=100-200", wantStatus: http.StatusRequestedRangeNotSatisfiable,
+			wantContentRange: "bytes */31"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/json/00000000-0000-0000-0000-000000000006/raw?pretty=true", nil)
+			if tt.rangeHeader != "" {
+				req.Header.Set("Range", tt.rangeHeader)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+			if got := w.Header().Get("Content-Range"); got != tt.wantContentRange {
+				t.Errorf("Content-Range = %q, want %q", got, tt.wantContentRange)
+			}
+		})
+	}
+
+	// 多段请求返回multipart/byteranges
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/json/00000000-0000-0000-0000-000000000006/raw", nil)
+	req.Header.Set("Range", "bytes=0-1,5-6")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusPartialContent || !strings.HasPrefix(w.Header().Get("Content-Type"), "multipart/byteranges") {
+		t.Errorf("multiple ranges: status = %d, Content-Type = %q", w.Code, w.Header().Get("Content-Type"))
+	}
+}
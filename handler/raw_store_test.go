@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/middleware"
+	"github.com/leapzhao/json-store/model"
+)
+
+func TestStoreJSONRaw(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewJSONHandler(&rawBytesStore{docs: map[string][]byte{}}, config.Config{})
+	router := gin.New()
+	router.POST("/api/v1/json/raw", middleware.ValidateJSON([]string{"application/json"}), h.StoreJSONRaw)
+	router.GET("/api/v1/json/:id/raw", h.GetJSONRaw)
+
+	// 请求体直接是文档，不包装在json_data中
+	body := `{"hello":"world"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/json/raw", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("store status = %d: %s", w.Code, w.Body)
+	}
+	var stored model.StoreResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored.ID == "" {
+		t.Fatalf("response has no id: %s", w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/json/"+stored.ID+"/raw", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("raw get status = %d: %s", w.Code, w.Body)
+	}
+	if w.Body.String() != body {
+		t.Errorf("raw get = %s, want %s", w.Body, body)
+	}
+
+	// 包装形式的请求体按原样存储，不会被解包
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/json/raw", bytes.NewBufferString(`{"json_data":{"a":1}}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if err := json.Unmarshal(w.Body.Bytes(), &stored); err != nil {
+		t.Fatal(err)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/json/"+stored.ID+"/raw", nil))
+	if w.Body.String() != `{"json_data":{"a":1}}` {
+		t.Errorf("wrapped body stored as %s", w.Body)
+	}
+}
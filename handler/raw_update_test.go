@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/middleware"
+	"github.com/leapzhao/json-store/model"
+)
+
+// replacingStore 只有一个文档，UpdateJSON替换其内容
+type replacingStore struct {
+	database.JSONStore
+	id      string
+	current model.StoreInput
+}
+
+func (s *replacingStore) UpdateJSON(ctx context.Context, id string, input model.StoreInput) (*model.JSONDocument, error) {
+	if id != s.id {
+		return nil, database.ErrDocumentNotFound
+	}
+	s.current = input
+	return &model.JSONDocument{ID: id, JSONData: input.JSONData, DocType: input.DocType}, nil
+}
+
+func TestUpdateJSONRaw(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const id = "00000000-0000-0000-0000-000000000002"
+	store := &replacingStore{id: id}
+	router := gin.New()
+	router.Use(middleware.ValidateJSON([]string{"application/json"}))
+	router.PUT("/api/v1/json/:id/raw", NewJSONHandler(store, config.Config{}).UpdateJSONRaw)
+
+	put := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 请求体就是文档本身，不需要json_data包装
+	body := `{"name": "raw", "items": [1, 2]}`
+	w := put("/api/v1/json/"+id+"/raw?type=config", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var resp model.StoreResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.ID != id || resp.IsNew {
+		t.Errorf("response = %+v, want id %s and is_new false", resp, id)
+	}
+	if string(store.current.JSONData) != body {
+		t.Errorf("stored %s, want %s", store.current.JSONData, body)
+	}
+	if store.current.DocType != "config" {
+		t.Errorf("doc type = %q, want config", store.current.DocType)
+	}
+
+	if w := put("/api/v1/json/"+id+"/raw", `{"broken":`); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid JSON: status = %d, want 400", w.Code)
+	}
+	if w := put("/api/v1/json/00000000-0000-0000-0000-000000000009/raw", `{}`); w.Code != http.StatusNotFound {
+		t.Errorf("unknown id: status = %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// readOnlyStore 连接正常但写入失败的存储，模拟只读备库或磁盘已满
+type readOnlyStore struct {
+	database.JSONStore
+	writeChecks int
+}
+
+func (s *readOnlyStore) HealthCheck(ctx context.Context) error { return nil }
+
+func (s *readOnlyStore) WriteCheck(ctx context.Context) error {
+	s.writeChecks++
+	return errors.New("cannot execute INSERT in a read-only transaction")
+}
+
+func (s *readOnlyStore) ReplicationStatus(ctx context.Context) (*model.ReplicationStatus, error) {
+	return nil, nil
+}
+
+func TestReadyCheckDeep(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name       string
+		deep       bool
+		wantStatus int
+		wantChecks map[string]string
+	}{
+		{name: "ping only", deep: false, wantStatus: http.StatusOK, wantChecks: map[string]string{"database": "ok"}},
+		{name: "deep", deep: true, wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"database": "ok", "database_write": "failed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg config.Config
+			cfg.Server.DeepReadyCheck = tt.deep
+			store := &readOnlyStore{}
+			h := NewJSONHandler(store, cfg)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/ready", nil)
+			h.ReadyCheck(c)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			var response model.ReadyResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			checks := make(map[string]string)
+			for _, check := range response.Checks {
+				checks[check.Name] = check.Status
+			}
+			if len(checks) != len(tt.wantChecks) {
+				t.Errorf("checks = %v, want %v", checks, tt.wantChecks)
+			}
+			for name, status := range tt.wantChecks {
+				if checks[name] != status {
+					t.Errorf("check %s = %q, want %q", name, checks[name], status)
+				}
+			}
+			// 未开启时不产生写入
+			if !tt.deep && store.writeChecks != 0 {
+				t.Errorf("%d write checks without deep_ready_check", store.writeChecks)
+			}
+		})
+	}
+}
+
+func TestDrain(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var cfg config.Config
+	cfg.Security.AdminUsername = "admin"
+	cfg.Security.AdminPassword = "secret"
+	h := NewJSONHandler(&readOnlyStore{}, cfg)
+	router := gin.New()
+	router.GET("/health", h.HealthCheck)
+	router.GET("/ready", h.ReadyCheck)
+	router.POST("/api/admin/drain", h.Drain)
+
+	request := func(method, path string, auth bool) int {
+		req := httptest.NewRequest(method, path, nil)
+		if auth {
+			req.SetBasicAuth("admin", "secret")
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := request(http.MethodGet, "/ready", false); code != http.StatusOK {
+		t.Fatalf("ready before drain = %d, want 200", code)
+	}
+	if code := request(http.MethodPost, "/api/admin/drain", false); code != http.StatusUnauthorized {
+		t.Errorf("drain without auth = %d, want 401", code)
+	}
+	if code := request(http.MethodGet, "/ready", false); code != http.StatusOK {
+		t.Errorf("ready after rejected drain = %d, want 200", code)
+	}
+
+	if code := request(http.MethodPost, "/api/admin/drain", true); code != http.StatusAccepted {
+		t.Fatalf("drain = %d, want 202", code)
+	}
+	// 排空后只有就绪检查失败，存活检查和已有请求不受影响
+	if code := request(http.MethodGet, "/ready", false); code != http.StatusServiceUnavailable {
+		t.Errorf("ready after drain = %d, want 503", code)
+	}
+	if code := request(http.MethodGet, "/health", false); code != http.StatusOK {
+		t.Errorf("health after drain = %d, want 200", code)
+	}
+	// 重复调用保持排空状态
+	if code := request(http.MethodPost, "/api/admin/drain", true); code != http.StatusAccepted {
+		t.Errorf("second drain = %d, want 202", code)
+	}
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// standbyStore 连接正常，复制状态由status和err决定；status为nil表示主库
+type standbyStore struct {
+	database.JSONStore
+	status *model.ReplicationStatus
+	err    error
+}
+
+func (s standbyStore) HealthCheck(ctx context.Context) error { return nil }
+
+func (s standbyStore) ReplicationStatus(ctx context.Context) (*model.ReplicationStatus, error) {
+	return s.status, s.err
+}
+
+func TestReadyCheckReplication(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name       string
+		store      standbyStore
+		threshold  int64
+		wantStatus int
+		// wantCheck replication检查项的状态，为空表示没有该检查项
+		wantCheck string
+		wantLag   int64
+	}{
+		{name: "primary", wantStatus: http.StatusOK},
+		{name: "within threshold", store: standbyStore{status: &model.ReplicationStatus{LagBytes: 1024}}, threshold: 4096,
+			wantStatus: http.StatusOK, wantCheck: "ok", wantLag: 1024},
+		{name: "over threshold", store: standbyStore{status: &model.ReplicationStatus{LagBytes: 8192}}, threshold: 4096,
+			wantStatus: http.StatusServiceUnavailable, wantCheck: "degraded", wantLag: 8192},
+		// 未设置阈值时只报告延迟
+		{name: "no threshold", store: standbyStore{status: &model.ReplicationStatus{LagBytes: 1 << 30}},
+			wantStatus: http.StatusOK, wantCheck: "ok", wantLag: 1 << 30},
+		// 查询失败只报告，数据库可用性由database检查项判断
+		{name: "query failed", store: standbyStore{err: errors.New("permission denied")}, threshold: 4096,
+			wantStatus: http.StatusOK, wantCheck: "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg config.Config
+			cfg.Database.MaxReplicationLagBytes = tt.threshold
+			h := NewJSONHandler(tt.store, cfg)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/ready", nil)
+			h.ReadyCheck(c)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			var response model.ReadyResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			var check *model.HealthCheck
+			for i := range response.Checks {
+				if response.Checks[i].Name == "replication" {
+					check = &response.Checks[i]
+				}
+			}
+			if tt.wantCheck == "" {
+				if check != nil {
+					t.Errorf("replication check = %+v, want none on a primary", check)
+				}
+				return
+			}
+			if check == nil || check.Status != tt.wantCheck {
+				t.Fatalf("replication check = %+v, want status %s", check, tt.wantCheck)
+			}
+			if tt.wantLag > 0 && (check.LagBytes == nil || *check.LagBytes != tt.wantLag) {
+				t.Errorf("lag_bytes = %v, want %d", check.LagBytes, tt.wantLag)
+			}
+		})
+	}
+}
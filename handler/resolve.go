@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/model"
+	"github.com/leapzhao/json-store/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// maxRefDepth 引用展开的最大嵌套层数
+const maxRefDepth = 16
+
+// 一次展开的总量上限，被引用的文档每内联一次都计入；
+// 层数限制挡不住少量文档互相多次引用（如每层引用下一层两次）造成的指数级展开
+const (
+	// maxRefCount 最多内联的引用次数
+	maxRefCount = 10000
+	// maxRefBytes 内联文档的累计字节数上限，配置了max_response_bytes时改用配置值
+	maxRefBytes = 64 << 20
+)
+
+var (
+	errRefCycle    = errors.New("cyclic $ref")
+	errRefDepth    = errors.New("$ref nesting too deep")
+	errRefNotFound = errors.New("$ref target not found")
+	errRefTooLarge = errors.New("$ref expansion too large")
+)
+
+// refResolver 展开文档中的 {"$ref": "<id>"} 节点
+// 同一次展开中被多次引用的文档只读取一次
+type refResolver struct {
+	ctx   context.Context
+	h     *JSONHandler
+	cache map[string]refTarget
+	// refs、bytes 已内联的引用次数和累计字节数，maxBytes为字节数上限
+	refs     int
+	bytes    int64
+	maxBytes int64
+}
+
+// refTarget 已读取的被引用文档，size为其JSON字节数
+type refTarget struct {
+	value any
+	size  int64
+}
+
+// resolveRefs 递归展开文档中的引用，返回展开后的JSON
+// 引用节点是只有一个字符串类型 "$ref" 键的对象；出现环、超过maxRefDepth层、
+// 展开总量超过上限或引用的文档不存在时返回错误
+func (h *JSONHandler) resolveRefs(ctx context.Context, doc *model.JSONDocument) ([]byte, error) {
+	r := &refResolver{ctx: ctx, h: h, cache: make(map[string]refTarget), maxBytes: maxRefBytes}
+	if limit := h.config.Server.MaxResponseBytes; limit > 0 {
+		r.maxBytes = limit
+	}
+
+	value, err := decodeDocument(doc.JSONData)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved, err := r.resolve(value, []string{doc.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	return utils.MarshalJSON(resolved)
+}
+
+// resolve 展开value中的引用，chain为当前展开路径上的文档ID，用于检测环
+func (r *refResolver) resolve(value any, chain []string) (any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		if id, ok := refID(v); ok {
+			return r.resolveRef(id, chain)
+		}
+		for key, child := range v {
+			resolved, err := r.resolve(child, chain)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = resolved
+		}
+		return v, nil
+	case []any:
+		for i, child := range v {
+			resolved, err := r.resolve(child, chain)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = resolved
+		}
+		return v, nil
+	default:
+		return v, nil
+	}
+}
+
+// resolveRef 读取被引用的文档并继续展开其中的引用
+func (r *refResolver) resolveRef(id string, chain []string) (any, error) {
+	for _, seen := range chain {
+		if seen == id {
+			return nil, fmt.Errorf("%w: %s -> %s", errRefCycle, chain[len(chain)-1], id)
+		}
+	}
+	if len(chain) > maxRefDepth {
+		return nil, fmt.Errorf("%w: more than %d levels", errRefDepth, maxRefDepth)
+	}
+
+	value, err := r.load(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.resolve(value, append(chain[:len(chain):len(chain)], id))
+}
+
+// load 读取并解码被引用的文档，每次返回独立的副本，避免展开时互相修改
+// 拷贝前先计入展开总量，超过上限时立即返回errRefTooLarge
+func (r *refResolver) load(id string) (any, error) {
+	cached, ok := r.cache[id]
+	if !ok {
+		doc, err := r.h.store.GetJSONByID(r.ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errRefNotFound, id)
+		}
+		value, err := decodeDocument(doc.JSONData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode referenced document %s: %w", id, err)
+		}
+		cached = refTarget{value: value, size: int64(len(doc.JSONData))}
+		r.cache[id] = cached
+	}
+
+	r.refs++
+	r.bytes += cached.size
+	if r.refs > maxRefCount {
+		return nil, fmt.Errorf("%w: more than %d references", errRefTooLarge, maxRefCount)
+	}
+	if r.bytes > r.maxBytes {
+		return nil, fmt.Errorf("%w: referenced documents exceed %d bytes", errRefTooLarge, r.maxBytes)
+	}
+
+	return copyValue(cached.value), nil
+}
+
+// refID 判断对象是否为引用节点
+func refID(obj map[string]any) (string, bool) {
+	if len(obj) != 1 {
+		return "", false
+	}
+	id, ok := obj["$ref"].(string)
+	return id, ok
+}
+
+// decodeDocument 解码文档，数字保留原始文本
+func decodeDocument(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// copyValue 深拷贝解码后的JSON值
+func copyValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(v))
+		for key, child := range v {
+			copied[key] = copyValue(child)
+		}
+		return copied
+	case []any:
+		copied := make([]any, len(v))
+		for i, child := range v {
+			copied[i] = copyValue(child)
+		}
+		return copied
+	default:
+		return v
+	}
+}
+
+// writeResolveError 输出引用展开失败的响应
+func (h *JSONHandler) writeResolveError(c *gin.Context, id string, err error) {
+	status, code := http.StatusUnprocessableEntity, "REF_RESOLVE_FAILED"
+	switch {
+	case errors.Is(err, errRefTooLarge):
+		status, code = http.StatusRequestEntityTooLarge, "REF_TOO_LARGE"
+	case errors.Is(err, errRefCycle):
+		code = "REF_CYCLE"
+	case errors.Is(err, errRefDepth):
+		code = "REF_DEPTH_EXCEEDED"
+	case errors.Is(err, errRefNotFound):
+		code = "REF_NOT_FOUND"
+	default:
+		log.Error().Err(err).Str("id", id).Msg("Failed to resolve references")
+	}
+
+	c.JSON(status, model.ErrorResponse{
+		Error:   code,
+		Message: err.Error(),
+	})
+}
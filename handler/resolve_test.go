@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// refStore 只实现GetJSONByID的存储，调用其他方法会panic
+type refStore struct {
+	database.JSONStore
+	docs map[string]string
+}
+
+func (s *refStore) GetJSONByID(ctx context.Context, id string) (*model.JSONDocument, error) {
+	data, ok := s.docs[id]
+	if !ok {
+		return nil, database.ErrDocumentNotFound
+	}
+	return &model.JSONDocument{ID: id, JSONData: []byte(data)}, nil
+}
+
+// fanOutDocs 生成levels层文档，每层引用下一层两次，完全展开共2^levels个叶子
+func fanOutDocs(levels int) map[string]string {
+	docs := map[string]string{fmt.Sprintf("d%d", levels): `{"leaf":true}`}
+	for i := levels - 1; i >= 0; i-- {
+		next := fmt.Sprintf("d%d", i+1)
+		docs[fmt.Sprintf("d%d", i)] = fmt.Sprintf(`[{"$ref":%q},{"$ref":%q}]`, next, next)
+	}
+	return docs
+}
+
+// chainDocs 生成长度为n的引用链，最后一个文档不含引用
+func chainDocs(n int) map[string]string {
+	docs := map[string]string{fmt.Sprintf("c%d", n): `"end"`}
+	for i := 0; i < n; i++ {
+		docs[fmt.Sprintf("c%d", i)] = fmt.Sprintf(`{"next":{"$ref":"c%d"}}`, i+1)
+	}
+	return docs
+}
+
+func TestResolveRefs(t *testing.T) {
+	tests := []struct {
+		name     string
+		docs     map[string]string
+		root     string
+		maxBytes int64
+		want     string
+		wantErr  error
+	}{
+		{
+			name: "simple",
+			docs: map[string]string{"a": `{"b":{"$ref":"b"}}`, "b": `{"x":1}`},
+			root: "a",
+			want: `{"b":{"x":1}}`,
+		},
+		{
+			name: "nested",
+			docs: map[string]string{"a": `[{"$ref":"b"}]`, "b": `{"c":{"$ref":"c"}}`, "c": `12345678901234567890`},
+			root: "a",
+			want: `[{"c":12345678901234567890}]`,
+		},
+		{
+			name: "repeated reference is copied",
+			docs: map[string]string{"a": `[{"$ref":"b"},{"$ref":"b"}]`, "b": `{"x":[1]}`},
+			root: "a",
+			want: `[{"x":[1]},{"x":[1]}]`,
+		},
+		{
+			name: "not a reference",
+			docs: map[string]string{"a": `{"$ref":"b","other":1}`},
+			root: "a",
+			want: `{"$ref":"b","other":1}`,
+		},
+		{
+			name:    "cycle",
+			docs:    map[string]string{"a": `{"$ref":"b"}`, "b": `{"c":{"$ref":"a"}}`},
+			root:    "a",
+			wantErr: errRefCycle,
+		},
+		{
+			name:    "missing",
+			docs:    map[string]string{"a": `{"$ref":"b"}`},
+			root:    "a",
+			wantErr: errRefNotFound,
+		},
+		{
+			name: "depth limit",
+			docs: chainDocs(maxRefDepth),
+			root: "c0",
+			want: strings.Repeat(`{"next":`, maxRefDepth) + `"end"` + strings.Repeat(`}`, maxRefDepth),
+		},
+		{
+			name:    "depth exceeded",
+			docs:    chainDocs(maxRefDepth + 1),
+			root:    "c0",
+			wantErr: errRefDepth,
+		},
+		{
+			name:    "fan-out exceeds reference count",
+			docs:    fanOutDocs(maxRefDepth),
+			root:    "d0",
+			wantErr: errRefTooLarge,
+		},
+		{
+			name:     "fan-out exceeds byte limit",
+			docs:     fanOutDocs(8),
+			root:     "d0",
+			maxBytes: 1024,
+			wantErr:  errRefTooLarge,
+		},
+		{
+			name:     "fan-out within byte limit",
+			docs:     fanOutDocs(2),
+			root:     "d0",
+			maxBytes: 1024,
+			want:     `[[{"leaf":true},{"leaf":true}],[{"leaf":true},{"leaf":true}]]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg config.Config
+			cfg.Server.MaxResponseBytes = tt.maxBytes
+			h := NewJSONHandler(&refStore{docs: tt.docs}, cfg)
+
+			root := &model.JSONDocument{ID: tt.root, JSONData: []byte(tt.docs[tt.root])}
+			got, err := h.resolveRefs(context.Background(), root)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("resolveRefs error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveRefs error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("resolveRefs = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
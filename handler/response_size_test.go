@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/model"
+)
+
+func TestGetJSONMaxResponseBytes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var cfg config.Config
+	cfg.Server.MaxResponseBytes = 64
+	h := NewJSONHandler(&rawBytesStore{docs: map[string][]byte{}}, cfg)
+	router := gin.New()
+	router.POST("/api/v1/json", h.StoreJSON)
+	router.GET("/api/v1/json/:id", h.GetJSON)
+	router.GET("/api/v1/json/:id/raw", h.GetJSONRaw)
+
+	store := func(doc string) string {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/json",
+			bytes.NewBufferString(`{"json_data": `+doc+`}`)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("store status = %d: %s", w.Code, w.Body)
+		}
+		var resp model.StoreResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.ID
+	}
+	get := func(path, rangeHeader string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 写入不受响应大小限制
+	large := store(`{"items":"` + strings.Repeat("x", 100) + `"}`)
+	small := store(`{"a":1}`)
+
+	w := get("/api/v1/json/"+large, "")
+	if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), "Range") {
+		t.Errorf("large GET: status = %d, want 413 with a Range hint: %s", w.Code, w.Body)
+	}
+	if w := get("/api/v1/json/"+large+"/raw", ""); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("large raw GET: status = %d, want 413", w.Code)
+	}
+	// 按范围分段读取不受限制
+	if w := get("/api/v1/json/"+large+"/raw", "bytes=0-31"); w.Code != http.StatusPartialContent || w.Body.Len() != 32 {
+		t.Errorf("large range GET: status = %d, %d bytes, want 206 and 32 bytes", w.Code, w.Body.Len())
+	}
+	if w := get("/api/v1/json/"+small, ""); w.Code != http.StatusOK {
+		t.Errorf("small GET: status = %d, want 200", w.Code)
+	}
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/model"
+)
+
+// serviceName 服务信息中返回的服务名称
+const serviceName = "json-store"
+
+// SetEndpoints 设置 GET /api/v1 列出的接口
+func (h *JSONHandler) SetEndpoints(endpoints []model.Endpoint) {
+	h.endpoints = endpoints
+}
+
+// ServiceInfo 服务信息：名称、版本、环境和常用入口，方便初次接入时确认服务地址
+func (h *JSONHandler) ServiceInfo(c *gin.Context) {
+	c.JSON(http.StatusOK, model.ServiceInfoResponse{
+		Name:        serviceName,
+		Version:     h.appVersion,
+		Environment: GetEnvironment(),
+		Links: map[string]string{
+			"health":  "/health",
+			"ready":   "/ready",
+			"version": "/version",
+			"api":     "/api/v1",
+		},
+	})
+}
+
+// ListEndpoints 列出/api/v1下的全部接口
+func (h *JSONHandler) ListEndpoints(c *gin.Context) {
+	endpoints := h.endpoints
+	if endpoints == nil {
+		endpoints = []model.Endpoint{}
+	}
+	c.JSON(http.StatusOK, model.EndpointsResponse{Endpoints: endpoints})
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// hashPrefixStore 按前缀在固定的内容哈希中查找
+type hashPrefixStore struct {
+	database.JSONStore
+	hashes []string
+}
+
+func (s *hashPrefixStore) FindHashesByPrefix(ctx context.Context, prefix string, limit int) ([]string, error) {
+	var found []string
+	for _, hash := range s.hashes {
+		if strings.HasPrefix(hash, prefix) && len(found) < limit {
+			found = append(found, hash)
+		}
+	}
+	return found, nil
+}
+
+func (s *hashPrefixStore) GetJSONByHash(ctx context.Context, hash string) (*model.JSONDocument, error) {
+	for i, h := range s.hashes {
+		if h == hash {
+			return &model.JSONDocument{ID: string(rune('a' + i)), ContentHash: hash, JSONData: []byte(`{}`)}, nil
+		}
+	}
+	return nil, database.ErrDocumentNotFound
+}
+
+func TestGetJSONByShortHash(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	unique := "1234567890abcdef" + strings.Repeat("0", 48)
+	// 前16位相同、之后不同的两个哈希模拟短哈希碰撞
+	collidingA := "fedcba0987654321" + strings.Repeat("a", 48)
+	collidingB := "fedcba0987654321" + strings.Repeat("b", 48)
+	store := &hashPrefixStore{hashes: []string{unique, collidingA, collidingB}}
+
+	tests := []struct {
+		name       string
+		length     int
+		query      string
+		wantStatus int
+		wantHash   string
+	}{
+		{name: "unique", length: 16, query: unique[:16], wantStatus: http.StatusOK, wantHash: unique},
+		{name: "uppercase", length: 16, query: strings.ToUpper(unique[:16]), wantStatus: http.StatusOK, wantHash: unique},
+		{name: "collision", length: 16, query: collidingA[:16], wantStatus: http.StatusConflict},
+		{name: "disambiguated", length: 16, query: collidingB[:17], wantStatus: http.StatusOK, wantHash: collidingB},
+		{name: "not found", length: 16, query: strings.Repeat("9", 16), wantStatus: http.StatusNotFound},
+		{name: "too short", length: 16, query: unique[:8], wantStatus: http.StatusBadRequest},
+		{name: "not hex", length: 16, query: strings.Repeat("z", 16), wantStatus: http.StatusBadRequest},
+		{name: "disabled", length: 0, query: unique[:16], wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg config.Config
+			cfg.Server.ShortHashLength = tt.length
+			h := NewJSONHandler(store, cfg)
+			router := gin.New()
+			router.GET("/api/v1/json", h.QueryJSON)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/json?short_hash="+tt.query, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantHash == "" {
+				return
+			}
+			var doc model.JSONDocument
+			if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+				t.Fatal(err)
+			}
+			if doc.ContentHash != tt.wantHash || doc.ShortHash != tt.wantHash[:tt.length] {
+				t.Errorf("got hash %s short %s, want %s", doc.ContentHash, doc.ShortHash, tt.wantHash)
+			}
+		})
+	}
+}
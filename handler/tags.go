@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/leapzhao/json-store/model"
+)
+
+// validateTags 检查标签数量和格式：不能为空、不能含首尾空白和控制字符，长度不超过MaxTagLength个字符
+func validateTags(tags []string) error {
+	if len(tags) > model.MaxTags {
+		return fmt.Errorf("%d tags exceeds the maximum of %d", len(tags), model.MaxTags)
+	}
+	for _, tag := range tags {
+		if err := validateTag(tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateTag(tag string) error {
+	if tag == "" || strings.TrimSpace(tag) != tag {
+		return fmt.Errorf("tag %q must be non-empty without leading or trailing whitespace", tag)
+	}
+	if n := utf8.RuneCountInString(tag); n > model.MaxTagLength || !utf8.ValidString(tag) {
+		return fmt.Errorf("tag %q must be valid UTF-8 of at most %d characters", tag, model.MaxTagLength)
+	}
+	if strings.IndexFunc(tag, unicode.IsControl) >= 0 {
+		return fmt.Errorf("tag %q must not contain control characters", tag)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// taggedStore 保存文档及其标签，列表按filter.Tag过滤
+type taggedStore struct {
+	database.JSONStore
+	documents []*model.JSONDocument
+}
+
+func (s *taggedStore) StoreJSON(ctx context.Context, input model.StoreInput) (*model.JSONDocument, error) {
+	doc := &model.JSONDocument{
+		ID:       fmt.Sprintf("00000000-0000-0000-0000-%012d", len(s.documents)+1),
+		JSONData: input.JSONData,
+		Tags:     input.Tags,
+	}
+	s.documents = append(s.documents, doc)
+	return doc, nil
+}
+
+func (s *taggedStore) ListJSON(ctx context.Context, filter model.ListFilter) ([]*model.JSONDocument, error) {
+	var documents []*model.JSONDocument
+	for _, doc := range s.documents {
+		if filter.Tag == "" || slices.Contains(doc.Tags, filter.Tag) {
+			documents = append(documents, doc)
+		}
+	}
+	return documents, nil
+}
+
+func TestDocumentTags(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &taggedStore{}
+	handler := NewJSONHandler(store, config.Config{})
+	router := gin.New()
+	router.POST("/api/v1/json", handler.StoreJSON)
+	router.GET("/api/v1/json", handler.ListJSON)
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, body := range []string{
+		`{"json_data":{"n":1},"tags":["billing","urgent"]}`,
+		`{"json_data":{"n":2},"tags":["billing"]}`,
+		`{"json_data":{"n":3}}`,
+	} {
+		if w := serve(http.MethodPost, "/api/v1/json", body); w.Code != http.StatusOK {
+			t.Fatalf("store %s: status = %d: %s", body, w.Code, w.Body)
+		}
+	}
+
+	tests := []struct {
+		tag     string
+		wantIDs []string
+	}{
+		{tag: "urgent", wantIDs: []string{"00000000-0000-0000-0000-000000000001"}},
+		{tag: "billing", wantIDs: []string{"00000000-0000-0000-0000-000000000001", "00000000-0000-0000-0000-000000000002"}},
+		{tag: "missing"},
+	}
+	for _, tt := range tests {
+		w := serve(http.MethodGet, "/api/v1/json?tag="+tt.tag, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("tag=%s: status = %d: %s", tt.tag, w.Code, w.Body)
+		}
+		var resp model.ListResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, doc := range resp.Documents {
+			ids = append(ids, doc.ID)
+		}
+		if !slices.Equal(ids, tt.wantIDs) {
+			t.Errorf("tag=%s: ids = %v, want %v", tt.tag, ids, tt.wantIDs)
+		}
+	}
+}
+
+func TestDocumentTagsValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := NewJSONHandler(&taggedStore{}, config.Config{})
+	router := gin.New()
+	router.POST("/api/v1/json", handler.StoreJSON)
+	router.GET("/api/v1/json", handler.ListJSON)
+
+	tooMany := make([]string, model.MaxTags+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("t%d", i)
+	}
+	tooManyJSON, _ := json.Marshal(tooMany)
+
+	tests := []struct {
+		name      string
+		method    string
+		target    string
+		body      string
+		wantError string
+	}{
+		{name: "empty tag", method: http.MethodPost, target: "/api/v1/json", body: `{"json_data":{},"tags":[""]}`, wantError: "INVALID_TAGS"},
+		{name: "surrounding whitespace", method: http.MethodPost, target: "/api/v1/json", body: `{"json_data":{},"tags":[" a"]}`, wantError: "INVALID_TAGS"},
+		{name: "control character", method: http.MethodPost, target: "/api/v1/json", body: `{"json_data":{},"tags":["a\tb"]}`, wantError: "INVALID_TAGS"},
+		{name: "too many", method: http.MethodPost, target: "/api/v1/json", body: `{"json_data":{},"tags":` + string(tooManyJSON) + `}`, wantError: "INVALID_TAGS"},
+		{name: "tag too long", method: http.MethodGet, target: "/api/v1/json?tag=" + strings.Repeat("x", model.MaxTagLength+1), wantError: "INVALID_TAG"},
+		// 估算总数不含标签条件
+		{name: "estimated total", method: http.MethodGet, target: "/api/v1/json?tag=a&total=estimated", wantError: "INVALID_TOTAL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400; body %s", w.Code, w.Body)
+			}
+			var resp model.ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Error != tt.wantError {
+				t.Errorf("error = %s, want %s", resp.Error, tt.wantError)
+			}
+		})
+	}
+}
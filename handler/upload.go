@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+	"github.com/leapzhao/json-store/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// uploadMaxFiles 单次上传最多的文件数，与JSON批量存储的上限一致
+const uploadMaxFiles = 100
+
+// uploadedFile multipart请求中的一个文件，超过单文件上限时不保留内容
+type uploadedFile struct {
+	name     string
+	data     []byte
+	tooLarge bool
+}
+
+// UploadJSON 以multipart/form-data上传多个文件，每个文件作为一个JSON文档存储，类型通过?type=指定
+// 结果按原始文件名索引；单个文件无效、超限或存储失败只记为该文件失败，不影响其他文件
+func (h *JSONHandler) UploadJSON(c *gin.Context) {
+	docType := c.Query("type")
+	if len(docType) > 64 {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "VALIDATION_ERROR",
+			Message: "type must be at most 64 characters",
+		})
+		return
+	}
+
+	files, ok := h.readUploadedFiles(c)
+	if !ok {
+		return
+	}
+
+	start := time.Now()
+	response := model.UploadResponse{
+		Results: make(map[string]model.UploadFileResult, len(files)),
+	}
+	fail := func(name, code, message string) {
+		response.Results[name] = model.UploadFileResult{Error: code, Message: message}
+		response.FailureCount++
+	}
+
+	var stored []*model.JSONDocument
+	auto := h.autoMetadata(c)
+	for _, file := range files {
+		if file.tooLarge {
+			fail(file.name, "FILE_TOO_LARGE", fmt.Sprintf("File exceeds %d bytes", h.config.Server.UploadMaxFileBytes))
+			continue
+		}
+		if err := validateDocumentData(file.data); err != nil {
+			fail(file.name, "INVALID_JSON", "File must contain a valid JSON document")
+			continue
+		}
+		if err := utils.ValidateMaxElements(file.data, h.config.Server.MaxElements); err != nil {
+			fail(file.name, "TOO_MANY_ELEMENTS", err.Error())
+			continue
+		}
+
+		storeStart := time.Now()
+		doc, err := h.store.StoreJSON(c.Request.Context(), model.StoreInput{
+			JSONData: file.data,
+			DocType:  docType,
+			Metadata: auto,
+		})
+		if err != nil {
+			if errors.Is(err, database.ErrControlCharacter) {
+				fail(file.name, "CONTROL_CHARACTER", err.Error())
+				continue
+			}
+			if errors.Is(err, database.ErrInvalidDocument) {
+				fail(file.name, "INVALID_JSON", err.Error())
+				continue
+			}
+			log.Error().Err(err).Str("file", file.name).Msg("Failed to store uploaded JSON")
+			fail(file.name, "STORAGE_ERROR", "Failed to store JSON document")
+			continue
+		}
+
+		isNew := !doc.Existing
+		h.appMetrics.RecordStore(time.Since(storeStart), isNew)
+		stored = append(stored, doc)
+		response.Results[file.name] = model.UploadFileResult{
+			ID:        doc.ID,
+			ShortHash: h.shortHash(doc.ContentHash),
+			IsNew:     isNew,
+			Message:   getStorageMessage(isNew),
+		}
+		response.SuccessCount++
+	}
+	recordQuotaUsage(c, stored)
+	response.Duration = time.Since(start)
+
+	log.Info().
+		Int("files", len(files)).
+		Int("success", response.SuccessCount).
+		Int("failed", response.FailureCount).
+		Dur("duration", response.Duration).
+		Msg("JSON files uploaded")
+
+	c.JSON(http.StatusOK, response)
+}
+
+// readUploadedFiles 读取请求中的全部文件部分，普通表单字段忽略；先读完再存储，
+// 这样文件名重复、文件过多或请求体超限时不会留下部分已存储的文档。失败时已写出错误响应
+func (h *JSONHandler) readUploadedFiles(c *gin.Context) ([]uploadedFile, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.config.Server.UploadMaxTotalBytes)
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, model.ErrorResponse{
+			Error:   "INVALID_CONTENT_TYPE",
+			Message: "Content-Type must be multipart/form-data",
+		})
+		return nil, false
+	}
+
+	// readError 区分请求体超限（413）和格式错误（400）
+	readError := func(err error) {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, model.ErrorResponse{
+				Error:   "REQUEST_TOO_LARGE",
+				Message: fmt.Sprintf("Upload exceeds %d bytes", maxErr.Limit),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "INVALID_MULTIPART",
+			Message: "Failed to read multipart body",
+		})
+	}
+
+	var files []uploadedFile
+	seen := make(map[string]bool)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			readError(err)
+			return nil, false
+		}
+
+		name := part.FileName()
+		if name == "" {
+			part.Close()
+			continue
+		}
+		if seen[name] {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{
+				Error:   "DUPLICATE_FILENAME",
+				Message: fmt.Sprintf("File name %q appears more than once", name),
+			})
+			return nil, false
+		}
+		seen[name] = true
+		if len(files) == uploadMaxFiles {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{
+				Error:   "TOO_MANY_FILES",
+				Message: fmt.Sprintf("Maximum %d files allowed per upload", uploadMaxFiles),
+			})
+			return nil, false
+		}
+
+		// 多读一个字节判断是否超过单文件上限，剩余内容由NextPart跳过
+		limit := h.config.Server.UploadMaxFileBytes
+		data, err := io.ReadAll(io.LimitReader(part, limit+1))
+		part.Close()
+		if err != nil {
+			readError(err)
+			return nil, false
+		}
+
+		file := uploadedFile{name: name, data: data}
+		if int64(len(data)) > limit {
+			file = uploadedFile{name: name, tooLarge: true}
+		}
+		files = append(files, file)
+	}
+
+	if len(files) == 0 {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Error:   "NO_FILES",
+			Message: "At least one file part is required",
+		})
+		return nil, false
+	}
+
+	return files, true
+}
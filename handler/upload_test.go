@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// contentAddressedStore 按内容去重：相同内容返回同一ID并标记为已存在
+type contentAddressedStore struct {
+	database.JSONStore
+	ids map[string]string
+}
+
+func (s *contentAddressedStore) StoreJSON(ctx context.Context, input model.StoreInput) (*model.JSONDocument, error) {
+	if id, ok := s.ids[string(input.JSONData)]; ok {
+		return &model.JSONDocument{ID: id, JSONData: input.JSONData, Existing: true}, nil
+	}
+	id := fmt.Sprintf("00000000-0000-0000-0000-%012d", len(s.ids)+1)
+	s.ids[string(input.JSONData)] = id
+	return &model.JSONDocument{ID: id, JSONData: input.JSONData}, nil
+}
+
+// uploadFile multipart请求中的一个部分，name为空时是普通表单字段
+type uploadFile struct {
+	name    string
+	content string
+}
+
+func TestUploadJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var cfg config.Config
+	cfg.Server.UploadMaxFileBytes = 64
+	cfg.Server.UploadMaxTotalBytes = 4096
+
+	upload := func(files ...uploadFile) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		for _, f := range files {
+			if f.name == "" {
+				writer.WriteField("comment", f.content)
+				continue
+			}
+			part, err := writer.CreateFormFile("files", f.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			part.Write([]byte(f.content))
+		}
+		writer.Close()
+
+		router := gin.New()
+		router.POST("/api/v1/json/upload", NewJSONHandler(&contentAddressedStore{ids: map[string]string{}}, cfg).UploadJSON)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/json/upload", &body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("per file results", func(t *testing.T) {
+		w := upload(
+			uploadFile{name: "a.json", content: `{"a": 1}`},
+			uploadFile{name: "broken.json", content: `{"a": `},
+			uploadFile{content: "form fields are ignored"},
+			uploadFile{name: "big.json", content: `{"data": "` + strings.Repeat("x", 100) + `"}`},
+			uploadFile{name: "copy.json", content: `{"a": 1}`},
+		)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", w.Code, w.Body)
+		}
+		var resp model.UploadResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.SuccessCount != 2 || resp.FailureCount != 2 || len(resp.Results) != 4 {
+			t.Errorf("success = %d failure = %d results = %d, want 2, 2 and 4", resp.SuccessCount, resp.FailureCount, len(resp.Results))
+		}
+
+		want := map[string]model.UploadFileResult{
+			"a.json":      {ID: "00000000-0000-0000-0000-000000000001", IsNew: true},
+			"broken.json": {Error: "INVALID_JSON"},
+			"big.json":    {Error: "FILE_TOO_LARGE"},
+			// 内容相同的文件命中去重，返回同一ID
+			"copy.json": {ID: "00000000-0000-0000-0000-000000000001", IsNew: false},
+		}
+		for name, w := range want {
+			got, ok := resp.Results[name]
+			if !ok {
+				t.Errorf("no result for %s", name)
+				continue
+			}
+			if got.ID != w.ID || got.IsNew != w.IsNew || got.Error != w.Error {
+				t.Errorf("%s: result = %+v, want %+v", name, got, w)
+			}
+		}
+	})
+
+	// 以下错误拒绝整个请求，不存储任何文件
+	t.Run("duplicate filename", func(t *testing.T) {
+		w := upload(uploadFile{name: "a.json", content: `{}`}, uploadFile{name: "a.json", content: `[]`})
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "DUPLICATE_FILENAME") {
+			t.Errorf("status = %d body = %s, want 400 DUPLICATE_FILENAME", w.Code, w.Body)
+		}
+	})
+	t.Run("total too large", func(t *testing.T) {
+		var files []uploadFile
+		for i := 0; i < 100; i++ {
+			files = append(files, uploadFile{name: fmt.Sprintf("%d.json", i), content: `{"n": "` + strings.Repeat("y", 40) + `"}`})
+		}
+		w := upload(files...)
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("status = %d body = %s, want 413", w.Code, w.Body)
+		}
+	})
+	t.Run("no files", func(t *testing.T) {
+		w := upload(uploadFile{content: "only a field"})
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "NO_FILES") {
+			t.Errorf("status = %d body = %s, want 400 NO_FILES", w.Code, w.Body)
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"context"
 	"fmt"
 	"github.com/leapzhao/json-store/config"
 	"io"
@@ -98,3 +99,16 @@ func createLogFile(path string, env config.Environment) io.Writer {
 func WithContext(requestID string) zerolog.Logger {
 	return globalLogger.With().Str("request_id", requestID).Logger()
 }
+
+type requestIDKey struct{}
+
+// ContextWithRequestID 将请求ID放入context，供下游（如存储层）日志使用
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext 从context中获取请求ID，不存在时返回空字符串
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConcurrencyLimit 全局在途请求数限制，不区分客户端，用于防止突发流量在构建响应时耗尽内存
+// 最多limit个请求同时处理，其余最多等待wait，超时后返回503并附带Retry-After
+func ConcurrencyLimit(limit int, wait time.Duration) gin.HandlerFunc {
+	slots := make(chan struct{}, limit)
+	retryAfter := strconv.Itoa(int(math.Max(1, math.Ceil(wait.Seconds()))))
+
+	return func(c *gin.Context) {
+		select {
+		case slots <- struct{}{}:
+		default:
+			// 没有空闲槽位，短暂等待其他请求完成
+			timer := time.NewTimer(wait)
+			select {
+			case slots <- struct{}{}:
+				timer.Stop()
+			case <-timer.C:
+				c.Header("Retry-After", retryAfter)
+				c.JSON(http.StatusServiceUnavailable, gin.H{
+					"error":   "SERVER_BUSY",
+					"message": "Too many concurrent requests, please retry later",
+				})
+				c.Abort()
+				return
+			case <-c.Request.Context().Done():
+				timer.Stop()
+				c.AbortWithStatus(http.StatusServiceUnavailable)
+				return
+			}
+		}
+
+		defer func() { <-slots }()
+		c.Next()
+	}
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// blockingRouter 请求进入处理函数后通知entered，直到release关闭才返回
+func blockingRouter(limit int, wait time.Duration) (router *gin.Engine, entered chan struct{}, release chan struct{}) {
+	gin.SetMode(gin.TestMode)
+	entered = make(chan struct{}, 16)
+	release = make(chan struct{})
+	router = gin.New()
+	router.Use(ConcurrencyLimit(limit, wait))
+	router.GET("/api/v1/json", func(c *gin.Context) {
+		entered <- struct{}{}
+		<-release
+		c.Status(http.StatusOK)
+	})
+	return router, entered, release
+}
+
+func serveAsync(router *gin.Engine) <-chan *httptest.ResponseRecorder {
+	done := make(chan *httptest.ResponseRecorder, 1)
+	go func() {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/json", nil))
+		done <- w
+	}()
+	return done
+}
+
+func TestConcurrencyLimitRejectsBeyondCap(t *testing.T) {
+	router, entered, release := blockingRouter(2, 20*time.Millisecond)
+
+	// 两个请求占满槽位
+	inFlight := []<-chan *httptest.ResponseRecorder{serveAsync(router), serveAsync(router)}
+	for range inFlight {
+		<-entered
+	}
+
+	// 超出上限的请求等待后返回503
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/json", nil))
+		if w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("request %d beyond cap: status = %d, want 503", i, w.Code)
+		}
+		if got := w.Header().Get("Retry-After"); got != "1" {
+			t.Errorf("Retry-After = %q, want 1", got)
+		}
+	}
+
+	close(release)
+	for i, done := range inFlight {
+		if w := <-done; w.Code != http.StatusOK {
+			t.Errorf("in-flight request %d: status = %d, want 200", i, w.Code)
+		}
+	}
+
+	// 槽位释放后新请求正常处理
+	next := serveAsync(router)
+	<-entered
+	if w := <-next; w.Code != http.StatusOK {
+		t.Errorf("after release: status = %d, want 200", w.Code)
+	}
+}
+
+func TestConcurrencyLimitQueues(t *testing.T) {
+	router, entered, release := blockingRouter(1, 5*time.Second)
+
+	first := serveAsync(router)
+	<-entered
+	// 第二个请求在队列中等待，第一个完成后获得槽位
+	second := serveAsync(router)
+	select {
+	case <-entered:
+		t.Fatal("queued request entered the handler while the only slot was taken")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	for i, done := range []<-chan *httptest.ResponseRecorder{first, second} {
+		if w := <-done; w.Code != http.StatusOK {
+			t.Errorf("request %d: status = %d, want 200", i, w.Code)
+		}
+	}
+}
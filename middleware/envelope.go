@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bufferedWriter 缓存响应体，待处理完成后统一改写输出
+type bufferedWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+// 处理器已主动写出头部（流式响应）时不再缓冲，直接写入底层响应
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	if w.ResponseWriter.Written() {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	if w.ResponseWriter.Written() {
+		return w.ResponseWriter.WriteString(s)
+	}
+	return w.body.WriteString(s)
+}
+
+// ResponseEnvelope 响应信封中间件
+// 成功响应包装为 {"data": ..., "meta": {...}}，错误响应包装为 {"error": {...}, "meta": {...}}
+// 非JSON响应原样输出
+func ResponseEnvelope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		original := c.Writer
+		writer := &bufferedWriter{ResponseWriter: original, body: &bytes.Buffer{}}
+		c.Writer = writer
+		defer func() { c.Writer = original }()
+
+		c.Next()
+
+		// 头部已直接写出（如204/304），没有可包装的响应体
+		if original.Written() {
+			return
+		}
+
+		body := writer.body.Bytes()
+		contentType := original.Header().Get("Content-Type")
+		// 处理器已声明Content-Length（如http.ServeContent的分段响应），改写会导致长度不符
+		if original.Header().Get("Content-Length") != "" ||
+			!strings.HasPrefix(contentType, "application/json") || !json.Valid(body) {
+			original.Write(body)
+			return
+		}
+
+		key := "data"
+		if original.Status() >= 400 {
+			key = "error"
+		}
+
+		// 不做HTML转义：内层响应体已按处理器的设置转义（或按server.escape_html保持原样），这里不再改变
+		var wrapped bytes.Buffer
+		encoder := json.NewEncoder(&wrapped)
+		encoder.SetEscapeHTML(false)
+		err := encoder.Encode(gin.H{
+			key: json.RawMessage(body),
+			"meta": gin.H{
+				"request_id": c.GetString("request_id"),
+			},
+		})
+		if err != nil {
+			original.Write(body)
+			return
+		}
+
+		original.Write(bytes.TrimSuffix(wrapped.Bytes(), []byte("\n")))
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestResponseEnvelope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		envelope bool
+		path     string
+		want     string
+	}{
+		{name: "raw", envelope: false, path: "/json/1", want: `{"id":"1"}`},
+		{name: "data", envelope: true, path: "/json/1", want: `{"data":{"id":"1"},"meta":{"request_id":"req-1"}}`},
+		{name: "error", envelope: true, path: "/missing", want: `{"error":{"error":"NOT_FOUND"},"meta":{"request_id":"req-1"}}`},
+		{name: "not json", envelope: true, path: "/text", want: "plain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(func(c *gin.Context) { c.Set("request_id", "req-1") })
+			if tt.envelope {
+				router.Use(ResponseEnvelope())
+			}
+			router.GET("/json/:id", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"id": c.Param("id")}) })
+			router.GET("/missing", func(c *gin.Context) { c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND"}) })
+			router.GET("/text", func(c *gin.Context) { c.String(http.StatusOK, "plain") })
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("body = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
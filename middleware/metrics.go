@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/model"
+)
+
+// AppMetrics 应用层计数器，不依赖外部监控系统
+// 请求计数由Middleware更新，存储相关计数由处理器调用RecordStore更新
+type AppMetrics struct {
+	startTime time.Time
+
+	requests  atomic.Int64
+	inFlight  atomic.Int64
+	status2xx atomic.Int64
+	status4xx atomic.Int64
+	status5xx atomic.Int64
+
+	stores     atomic.Int64
+	dedupHits  atomic.Int64
+	storeNanos atomic.Int64
+}
+
+// NewAppMetrics 创建应用指标
+func NewAppMetrics() *AppMetrics {
+	return &AppMetrics{startTime: time.Now()}
+}
+
+// Middleware 统计请求总数、进行中的请求数和响应状态分布
+func (m *AppMetrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		m.requests.Add(1)
+		m.inFlight.Add(1)
+		defer m.inFlight.Add(-1)
+
+		c.Next()
+
+		switch status := c.Writer.Status(); {
+		case status >= 500:
+			m.status5xx.Add(1)
+		case status >= 400:
+			m.status4xx.Add(1)
+		case status >= 200 && status < 300:
+			m.status2xx.Add(1)
+		}
+	}
+}
+
+// RecordStore 记录一次文档存储，isNew为false表示命中去重返回了已有文档
+// m为nil时不做任何事，便于未开启指标时直接调用
+func (m *AppMetrics) RecordStore(duration time.Duration, isNew bool) {
+	if m == nil {
+		return
+	}
+
+	m.stores.Add(1)
+	m.storeNanos.Add(int64(duration))
+	if !isNew {
+		m.dedupHits.Add(1)
+	}
+}
+
+// Snapshot 返回当前计数
+func (m *AppMetrics) Snapshot() model.AppMetrics {
+	snapshot := model.AppMetrics{
+		UptimeSeconds: time.Since(m.startTime).Seconds(),
+		TotalRequests: m.requests.Load(),
+		InFlight:      m.inFlight.Load(),
+		Status2xx:     m.status2xx.Load(),
+		Status4xx:     m.status4xx.Load(),
+		Status5xx:     m.status5xx.Load(),
+		Stores:        m.stores.Load(),
+		DedupHits:     m.dedupHits.Load(),
+		Timestamp:     time.Now(),
+	}
+
+	if snapshot.Stores > 0 {
+		snapshot.DedupHitRate = float64(snapshot.DedupHits) / float64(snapshot.Stores)
+		avg := time.Duration(m.storeNanos.Load() / snapshot.Stores)
+		snapshot.AvgStoreLatencyMs = float64(avg) / float64(time.Millisecond)
+	}
+
+	return snapshot
+}
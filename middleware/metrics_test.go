@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAppMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	metrics := NewAppMetrics()
+	router := gin.New()
+	router.Use(metrics.Middleware())
+
+	var inFlight int64
+	router.GET("/ok", func(c *gin.Context) {
+		// 处理中的请求计入in_flight
+		inFlight = metrics.Snapshot().InFlight
+		c.Status(http.StatusOK)
+	})
+	router.GET("/created", func(c *gin.Context) { c.Status(http.StatusCreated) })
+	router.GET("/fail", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+
+	// 2个2xx、2个4xx（未注册的路径和方法）、1个5xx
+	for _, path := range []string{"/ok", "/created", "/missing", "/fail"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/ok", nil))
+
+	// 3次存储，其中1次命中去重
+	metrics.RecordStore(10*time.Millisecond, true)
+	metrics.RecordStore(20*time.Millisecond, true)
+	metrics.RecordStore(30*time.Millisecond, false)
+
+	got := metrics.Snapshot()
+	if inFlight != 1 {
+		t.Errorf("in-flight during request = %d, want 1", inFlight)
+	}
+	if got.InFlight != 0 {
+		t.Errorf("in-flight after requests = %d, want 0", got.InFlight)
+	}
+	if got.TotalRequests != 5 || got.Status2xx != 2 || got.Status4xx != 2 || got.Status5xx != 1 {
+		t.Errorf("requests = %d (2xx %d, 4xx %d, 5xx %d), want 5 (2, 2, 1)",
+			got.TotalRequests, got.Status2xx, got.Status4xx, got.Status5xx)
+	}
+	if got.Stores != 3 || got.DedupHits != 1 {
+		t.Errorf("stores = %d, dedup hits = %d, want 3 and 1", got.Stores, got.DedupHits)
+	}
+	if math.Abs(got.DedupHitRate-1.0/3) > 1e-9 {
+		t.Errorf("dedup hit rate = %v, want 1/3", got.DedupHitRate)
+	}
+	if got.AvgStoreLatencyMs != 20 {
+		t.Errorf("average store latency = %vms, want 20ms", got.AvgStoreLatencyMs)
+	}
+}
+
+func TestAppMetricsNil(t *testing.T) {
+	// 未开启指标时处理器直接调用RecordStore
+	var metrics *AppMetrics
+	metrics.RecordStore(time.Millisecond, true)
+}
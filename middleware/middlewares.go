@@ -2,8 +2,12 @@ package middleware
 
 import (
 	"bytes"
+	"crypto/subtle"
+	"encoding/json"
 	"io"
+	"mime"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/leapzhao/json-store/logger"
@@ -61,20 +65,33 @@ func RequestLogger() gin.HandlerFunc {
 }
 
 // RequestID 请求ID中间件
-func RequestID() gin.HandlerFunc {
+// headers 按优先级依次查找请求头，使用第一个存在的值并以同名响应头返回；
+// 都不存在时生成新ID，以第一个请求头名称返回
+func RequestID(headers []string) gin.HandlerFunc {
+	if len(headers) == 0 {
+		headers = []string{"X-Request-ID"}
+	}
+
 	return func(c *gin.Context) {
 		// 尝试从请求头获取请求ID
-		requestID := c.GetHeader("X-Request-ID")
+		header, requestID := headers[0], ""
+		for _, name := range headers {
+			if value := c.GetHeader(name); value != "" {
+				header, requestID = name, value
+				break
+			}
+		}
 		if requestID == "" {
 			// 生成新的请求ID
 			requestID = uuid.New().String()
 		}
 
-		// 设置到上下文
+		// 设置到上下文，同时放入请求context供存储层日志使用
 		c.Set("request_id", requestID)
+		c.Request = c.Request.WithContext(logger.ContextWithRequestID(c.Request.Context(), requestID))
 
 		// 设置响应头
-		c.Header("X-Request-ID", requestID)
+		c.Header(header, requestID)
 
 		c.Next()
 	}
@@ -119,24 +136,23 @@ func BodySizeLimit(maxSize int64) gin.HandlerFunc {
 }
 
 // ValidateJSON JSON验证中间件
-func ValidateJSON() gin.HandlerFunc {
+// allowedTypes 为允许的媒体类型（忽略charset等参数），任意 +json 后缀的类型也会被接受
+func ValidateJSON(allowedTypes []string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if c.Request.Method == "POST" || c.Request.Method == "PUT" {
-			var body map[string]interface{}
-
 			// 检查Content-Type
-			contentType := c.GetHeader("Content-Type")
-			if contentType != "application/json" {
-				c.JSON(400, gin.H{
+			if !isAllowedContentType(c.GetHeader("Content-Type"), allowedTypes) {
+				c.JSON(415, gin.H{
 					"error":   "INVALID_CONTENT_TYPE",
-					"message": "Content-Type must be application/json",
+					"message": "Content-Type must be one of " + strings.Join(allowedTypes, ", ") + " or a +json type",
 				})
 				c.Abort()
 				return
 			}
 
-			// 验证JSON
-			if err := c.ShouldBindJSON(&body); err != nil {
+			// 验证JSON，读取后恢复请求体供后续处理
+			body, err := io.ReadAll(c.Request.Body)
+			if err != nil || !json.Valid(body) {
 				c.JSON(400, gin.H{
 					"error":   "INVALID_JSON",
 					"message": "Invalid JSON format",
@@ -144,33 +160,89 @@ func ValidateJSON() gin.HandlerFunc {
 				c.Abort()
 				return
 			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
 		}
 		c.Next()
 	}
 }
 
-// BasicAuth 基本认证中间件
-func BasicAuth() gin.HandlerFunc {
-	return gin.BasicAuth(gin.Accounts{
-		"admin": "secret", // 实际应用中应该从配置读取
-	})
+// isAllowedContentType 解析媒体类型并判断是否在允许列表中或为 +json 后缀类型
+func isAllowedContentType(header string, allowedTypes []string) bool {
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return false
+	}
+
+	if strings.HasSuffix(mediaType, "+json") {
+		return true
+	}
+
+	for _, allowed := range allowedTypes {
+		if strings.EqualFold(mediaType, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// AdminAccounts 由配置的security.admin_username和security.admin_password构造管理员账号，
+// BasicAuth中间件和处理器内的IsAdmin共用
+func AdminAccounts(username, password string) gin.Accounts {
+	return gin.Accounts{username: password}
+}
+
+// BasicAuth 管理员基本认证中间件
+func BasicAuth(accounts gin.Accounts) gin.HandlerFunc {
+	return gin.BasicAuth(accounts)
+}
+
+// IsAdmin 请求是否带有accounts中的基本认证，用于未挂载BasicAuth的路由按参数鉴权
+func IsAdmin(c *gin.Context, accounts gin.Accounts) bool {
+	user, password, ok := c.Request.BasicAuth()
+	if !ok {
+		return false
+	}
+	want, exists := accounts[user]
+	return exists && subtle.ConstantTimeCompare([]byte(password), []byte(want)) == 1
 }
 
-// RateLimit 限流中间件
-func RateLimit(limit int) gin.HandlerFunc {
-	limiter := make(chan struct{}, limit)
+// WriteLimit 写操作并发限制中间件
+// 最多limit个写请求同时执行，超出部分最多排队queue个，队列满时返回503
+func WriteLimit(limit, queue int) gin.HandlerFunc {
+	slots := make(chan struct{}, limit)
+	waiting := make(chan struct{}, queue)
 
 	return func(c *gin.Context) {
 		select {
-		case limiter <- struct{}{}:
-			defer func() { <-limiter }()
-			c.Next()
+		case slots <- struct{}{}:
 		default:
-			c.JSON(429, gin.H{
-				"error":   "TOO_MANY_REQUESTS",
-				"message": "Rate limit exceeded",
-			})
-			c.Abort()
+			// 没有空闲槽位，进入等待队列
+			select {
+			case waiting <- struct{}{}:
+			default:
+				c.JSON(503, gin.H{
+					"error":   "WRITE_QUEUE_FULL",
+					"message": "Too many concurrent writes, please retry later",
+				})
+				c.Abort()
+				return
+			}
+
+			select {
+			case slots <- struct{}{}:
+				<-waiting
+			case <-c.Request.Context().Done():
+				<-waiting
+				c.JSON(503, gin.H{
+					"error":   "WRITE_QUEUE_TIMEOUT",
+					"message": "Request cancelled while waiting for write slot",
+				})
+				c.Abort()
+				return
+			}
 		}
+
+		defer func() { <-slots }()
+		c.Next()
 	}
 }
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWriteLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	entered := make(chan struct{}, 3)
+	release := make(chan struct{})
+	router := gin.New()
+	router.GET("/json/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.POST("/json", WriteLimit(1, 1), func(c *gin.Context) {
+		entered <- struct{}{}
+		<-release
+		c.Status(http.StatusCreated)
+	})
+	write := func() int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/json", nil))
+		return w.Code
+	}
+
+	// 第一个写请求占用唯一的槽位
+	var wg sync.WaitGroup
+	codes := make([]int, 3)
+	wg.Add(1)
+	go func() { defer wg.Done(); codes[0] = write() }()
+	<-entered
+
+	// 再来两个：一个排队，队列已满的另一个立即返回503
+	full := make(chan int, 2)
+	for i := 1; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = write()
+			if codes[i] == http.StatusServiceUnavailable {
+				full <- i
+			}
+		}(i)
+	}
+	<-full
+
+	// 写入排满时读请求不受影响
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/json/1", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("read status = %d while writes are saturated, want 200", w.Code)
+	}
+
+	close(release)
+	wg.Wait()
+	sort.Ints(codes)
+	if codes[0] != http.StatusCreated || codes[1] != http.StatusCreated || codes[2] != http.StatusServiceUnavailable {
+		t.Errorf("write statuses = %v, want two 201 and one 503", codes)
+	}
+
+	// 完成的请求释放槽位，之后的写请求不再被拒绝
+	if code := write(); code != http.StatusCreated {
+		t.Errorf("write after release = %d, want 201", code)
+	}
+}
+
+func TestValidateJSONContentType(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ValidateJSON([]string{"application/json", "text/plain"}))
+	router.POST("/json", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		contentType string
+		want        int
+	}{
+		{"application/json", http.StatusOK},
+		{"application/json; charset=utf-8", http.StatusOK},
+		{"Application/JSON", http.StatusOK},
+		{"application/vnd.api+json", http.StatusOK},
+		{"text/plain", http.StatusOK},
+		{"application/xml", http.StatusUnsupportedMediaType},
+		{"", http.StatusUnsupportedMediaType},
+		{"application/json; charset", http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/json", strings.NewReader(`{"a":1}`))
+		req.Header.Set("Content-Type", tt.contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("Content-Type %q: status %d, want %d", tt.contentType, w.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PrettyJSON ?pretty=true 时缩进输出JSON响应，便于用curl或浏览器调试
+// 未带参数的请求不做缓冲，保持紧凑输出
+func PrettyJSON() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Query("pretty") != "true" {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		writer := &bufferedWriter{ResponseWriter: original, body: &bytes.Buffer{}}
+		c.Writer = writer
+		defer func() { c.Writer = original }()
+
+		c.Next()
+
+		// 头部已直接写出（如204/304），没有可改写的响应体
+		if original.Written() {
+			return
+		}
+
+		body := writer.body.Bytes()
+		// 处理器已声明Content-Length时原样输出
+		if original.Header().Get("Content-Length") == "" &&
+			strings.HasPrefix(original.Header().Get("Content-Type"), "application/json") {
+			var indented bytes.Buffer
+			if err := json.Indent(&indented, body, "", "  "); err == nil {
+				indented.WriteByte('\n')
+				body = indented.Bytes()
+			}
+		}
+
+		original.Write(body)
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPrettyJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(PrettyJSON())
+	router.GET("/json", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"id": "a", "tags": []string{"x"}})
+	})
+	router.GET("/text", func(c *gin.Context) { c.String(http.StatusOK, `{"id":"a"}`) })
+	router.GET("/empty", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	want := "{\n  \"id\": \"a\",\n  \"tags\": [\n    \"x\"\n  ]\n}\n"
+	if w := get("/json?pretty=true"); w.Body.String() != want {
+		t.Errorf("pretty body = %q, want %q", w.Body.String(), want)
+	}
+	// 默认紧凑输出
+	if w := get("/json"); strings.Contains(w.Body.String(), "\n") {
+		t.Errorf("default body = %q, want compact", w.Body.String())
+	}
+	// 只改写JSON响应
+	if w := get("/text?pretty=true"); w.Body.String() != `{"id":"a"}` {
+		t.Errorf("text body = %q, want unchanged", w.Body.String())
+	}
+	if w := get("/empty?pretty=true"); w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Errorf("204 response = %d %q", w.Code, w.Body.String())
+	}
+}
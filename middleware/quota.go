@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QuotaUsageKey 处理器通过c.Set记录本次请求新建的文档数，未设置时成功的请求按1计
+const QuotaUsageKey = "quota_usage"
+
+// DailyQuota 按客户端IP限制每个UTC自然日新建的文档数，用尽后返回429直到次日零点
+// 计数在内存中，每个实例单独计算；检查在请求前、计数在响应时，并发请求可能略微超出limit
+func DailyQuota(limit int) gin.HandlerFunc {
+	return dailyQuota(limit, time.Now)
+}
+
+// dailyQuota 同DailyQuota，当前时间由now提供，便于测试跨日重置
+func dailyQuota(limit int, now func() time.Time) gin.HandlerFunc {
+	var mu sync.Mutex
+	day := ""
+	used := make(map[string]int)
+
+	// current 返回key今天已用的额度，日期变化时清空所有计数
+	current := func(key string, now time.Time) int {
+		if today := now.Format("2006-01-02"); today != day {
+			day = today
+			used = make(map[string]int)
+		}
+		return used[key]
+	}
+
+	return func(c *gin.Context) {
+		start := now().UTC()
+		key := c.ClientIP()
+
+		mu.Lock()
+		n := current(key, start)
+		mu.Unlock()
+
+		c.Header("X-Quota-Limit", strconv.Itoa(limit))
+		if n >= limit {
+			reset := time.Date(start.Year(), start.Month(), start.Day()+1, 0, 0, 0, 0, time.UTC)
+			c.Header("X-Quota-Remaining", "0")
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(reset.Sub(start).Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":   "QUOTA_EXCEEDED",
+				"message": "Daily document quota exceeded, resets at " + reset.Format(time.RFC3339),
+			})
+			c.Abort()
+			return
+		}
+
+		c.Writer = &quotaWriter{
+			ResponseWriter: c.Writer,
+			c:              c,
+			record: func(count int) int {
+				mu.Lock()
+				defer mu.Unlock()
+				remaining := limit - current(key, now().UTC()) - count
+				used[key] += count
+				return max(remaining, 0)
+			},
+		}
+		c.Next()
+	}
+}
+
+// quotaWriter 在写出响应头前按处理结果计数，并设置X-Quota-Remaining
+type quotaWriter struct {
+	gin.ResponseWriter
+	c        *gin.Context
+	record   func(count int) int
+	recorded bool
+}
+
+func (w *quotaWriter) finish(status int) {
+	if w.recorded {
+		return
+	}
+	w.recorded = true
+
+	count := 0
+	if status >= 200 && status < 300 {
+		count = 1
+		if v, ok := w.c.Get(QuotaUsageKey); ok {
+			count, _ = v.(int)
+		}
+	}
+	w.Header().Set("X-Quota-Remaining", strconv.Itoa(w.record(count)))
+}
+
+func (w *quotaWriter) WriteHeader(code int) {
+	w.finish(code)
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *quotaWriter) WriteHeaderNow() {
+	w.finish(w.Status())
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *quotaWriter) Write(data []byte) (int, error) {
+	w.finish(w.Status())
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *quotaWriter) WriteString(s string) (int, error) {
+	w.finish(w.Status())
+	return w.ResponseWriter.WriteString(s)
+}
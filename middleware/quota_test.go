@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDailyQuota(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	clock := time.Date(2026, 3, 1, 23, 59, 0, 0, time.UTC)
+	router := gin.New()
+	router.Use(dailyQuota(3, func() time.Time { return clock }))
+	router.POST("/api/v1/json", func(c *gin.Context) {
+		// dedup=1 模拟命中去重，不计入配额
+		if c.Query("dedup") == "1" {
+			c.Set(QuotaUsageKey, 0)
+		}
+		c.Status(http.StatusOK)
+	})
+	router.POST("/api/v1/json/batch", func(c *gin.Context) {
+		c.Set(QuotaUsageKey, 2)
+		c.Status(http.StatusOK)
+	})
+	router.POST("/api/v1/json/invalid", func(c *gin.Context) { c.Status(http.StatusBadRequest) })
+
+	post := func(path, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.RemoteAddr = ip + ":12345"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	steps := []struct {
+		path          string
+		wantCode      int
+		wantRemaining string
+	}{
+		{path: "/api/v1/json", wantCode: http.StatusOK, wantRemaining: "2"},
+		// 失败的请求和命中去重的请求不计
+		{path: "/api/v1/json/invalid", wantCode: http.StatusBadRequest, wantRemaining: "2"},
+		{path: "/api/v1/json?dedup=1", wantCode: http.StatusOK, wantRemaining: "2"},
+		// 批量按新建的文档数计
+		{path: "/api/v1/json/batch", wantCode: http.StatusOK, wantRemaining: "0"},
+		{path: "/api/v1/json", wantCode: http.StatusTooManyRequests, wantRemaining: "0"},
+	}
+	for i, step := range steps {
+		w := post(step.path, "192.0.2.1")
+		if w.Code != step.wantCode {
+			t.Fatalf("step %d %s: status = %d, want %d", i, step.path, w.Code, step.wantCode)
+		}
+		if got := w.Header().Get("X-Quota-Remaining"); got != step.wantRemaining {
+			t.Errorf("step %d %s: X-Quota-Remaining = %q, want %q", i, step.path, got, step.wantRemaining)
+		}
+	}
+
+	// 用尽时提示到UTC零点的等待时间
+	w := post("/api/v1/json", "192.0.2.1")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Errorf("exhausted: status = %d Retry-After = %q, want 429 and 60", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// 每个客户端IP单独计数
+	if w := post("/api/v1/json", "192.0.2.2"); w.Code != http.StatusOK {
+		t.Errorf("other client: status = %d, want 200", w.Code)
+	}
+
+	// UTC次日零点后重置
+	clock = clock.Add(time.Minute)
+	w = post("/api/v1/json", "192.0.2.1")
+	if w.Code != http.StatusOK || w.Header().Get("X-Quota-Remaining") != "2" {
+		t.Errorf("after reset: status = %d X-Quota-Remaining = %q, want 200 and 2", w.Code, w.Header().Get("X-Quota-Remaining"))
+	}
+}
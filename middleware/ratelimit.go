@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tokenBucket 单个客户端的令牌桶
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimit 按客户端IP限流的令牌桶中间件
+// 每个window补充requests个令牌，桶容量为burst（<=0时等于requests），令牌耗尽时返回429
+func RateLimit(requests, burst int, window time.Duration) gin.HandlerFunc {
+	if burst <= 0 {
+		burst = requests
+	}
+	rate := float64(requests) / window.Seconds()
+	capacity := float64(burst)
+	// 空闲超过该时间的桶已经补满，与新建的桶没有区别，可以清理
+	idle := time.Duration(capacity / rate * float64(time.Second))
+
+	var mu sync.Mutex
+	buckets := make(map[string]*tokenBucket)
+	lastSweep := time.Now()
+
+	return func(c *gin.Context) {
+		now := time.Now()
+		key := c.ClientIP()
+
+		mu.Lock()
+		if now.Sub(lastSweep) > time.Minute {
+			for k, b := range buckets {
+				if now.Sub(b.last) > idle {
+					delete(buckets, k)
+				}
+			}
+			lastSweep = now
+		}
+
+		bucket, ok := buckets[key]
+		if !ok {
+			bucket = &tokenBucket{tokens: capacity, last: now}
+			buckets[key] = bucket
+		}
+
+		bucket.tokens += now.Sub(bucket.last).Seconds() * rate
+		if bucket.tokens > capacity {
+			bucket.tokens = capacity
+		}
+		bucket.last = now
+
+		allowed := bucket.tokens >= 1
+		var retryAfter time.Duration
+		if allowed {
+			bucket.tokens--
+		} else {
+			retryAfter = time.Duration((1 - bucket.tokens) / rate * float64(time.Second))
+		}
+		mu.Unlock()
+
+		if !allowed {
+			seconds := int(retryAfter/time.Second) + 1
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			c.JSON(429, gin.H{
+				"error":   "TOO_MANY_REQUESTS",
+				"message": "Rate limit exceeded",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// 与生产环境默认值相同：每秒50个，最多累积100个
+	router := gin.New()
+	router.Use(RateLimit(50, 100, time.Second))
+	router.GET("/api/v1/json/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	get := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/json/x", nil)
+		req.RemoteAddr = ip + ":12345"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 100; i++ {
+		if w := get("192.0.2.1"); w.Code != http.StatusOK {
+			t.Fatalf("request %d within burst: status = %d", i+1, w.Code)
+		}
+	}
+	w := get("192.0.2.1")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("request beyond burst: status = %d, want 429", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "1" {
+		t.Errorf("Retry-After = %q, want 1", w.Header().Get("Retry-After"))
+	}
+
+	// 每个客户端IP单独计数
+	if w := get("192.0.2.2"); w.Code != http.StatusOK {
+		t.Errorf("other client: status = %d, want 200", w.Code)
+	}
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/leapzhao/json-store/logger"
+)
+
+func TestRequestIDHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	headers := []string{"X-Correlation-ID", "traceparent", "X-Request-ID"}
+	tests := []struct {
+		name       string
+		request    map[string]string
+		wantHeader string
+		wantID     string
+	}{
+		{name: "custom header", request: map[string]string{"X-Correlation-ID": "corr-1"}, wantHeader: "X-Correlation-ID", wantID: "corr-1"},
+		// 多个都存在时按配置的优先级选择
+		{name: "priority", request: map[string]string{"X-Request-ID": "req-1", "traceparent": "00-abc-def-01"},
+			wantHeader: "traceparent", wantID: "00-abc-def-01"},
+		// 都不存在时生成新ID，以第一个名称返回
+		{name: "generated", wantHeader: "X-Correlation-ID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fromGin, fromContext string
+			router := gin.New()
+			router.Use(RequestID(headers))
+			router.GET("/", func(c *gin.Context) {
+				fromGin = c.GetString("request_id")
+				fromContext = logger.RequestIDFromContext(c.Request.Context())
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for name, value := range tt.request {
+				req.Header.Set(name, value)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			got := w.Header().Get(tt.wantHeader)
+			if tt.wantID != "" && got != tt.wantID {
+				t.Errorf("%s = %q, want %q", tt.wantHeader, got, tt.wantID)
+			}
+			if tt.wantID == "" {
+				if _, err := uuid.Parse(got); err != nil {
+					t.Errorf("generated %s = %q, want a UUID", tt.wantHeader, got)
+				}
+			}
+			if fromGin != got || fromContext != got {
+				t.Errorf("request id in gin context %q and request context %q, want %q", fromGin, fromContext, got)
+			}
+		})
+	}
+}
+
+func TestRequestIDDefaultHeader(t *testing.T) {
+	router := gin.New()
+	router.Use(RequestID(nil))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "abc")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if got := w.Header().Get("X-Request-ID"); got != "abc" {
+		t.Errorf("X-Request-ID = %q, want abc", got)
+	}
+}
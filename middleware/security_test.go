@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name string
+		opts SecurityHeadersOptions
+		want map[string]string
+	}{
+		{
+			name: "all headers",
+			opts: SecurityHeadersOptions{
+				ContentTypeOptions: "nosniff", FrameOptions: "DENY", ReferrerPolicy: "no-referrer",
+				HSTSMaxAge: 31536000, HSTSIncludeSubdomains: true,
+			},
+			want: map[string]string{
+				"X-Content-Type-Options":    "nosniff",
+				"X-Frame-Options":           "DENY",
+				"Referrer-Policy":           "no-referrer",
+				"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+			},
+		},
+		// 值为空的头和max-age为0的HSTS不发送
+		{
+			name: "disabled",
+			opts: SecurityHeadersOptions{ContentTypeOptions: "nosniff"},
+			want: map[string]string{
+				"X-Content-Type-Options":    "nosniff",
+				"X-Frame-Options":           "",
+				"Referrer-Policy":           "",
+				"Strict-Transport-Security": "",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(SecurityHeaders(tt.opts))
+			router.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+			router.GET("/fail", func(c *gin.Context) { c.AbortWithStatus(http.StatusInternalServerError) })
+
+			// 错误响应同样带有安全头
+			for _, path := range []string{"/ok", "/fail"} {
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+				for name, want := range tt.want {
+					if got := w.Header().Get(name); got != want {
+						t.Errorf("%s: %s = %q, want %q", path, name, got, want)
+					}
+				}
+			}
+		})
+	}
+}
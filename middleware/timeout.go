@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ResponseTimeout 处理超过timeout时返回503，用于可能较慢的读接口
+// 请求context带上截止时间，数据库查询随之取消，处理器收到取消错误后返回；
+// 处理器与中间件在同一个goroutine中执行，不像http.TimeoutHandler另起goroutine，避免并发访问gin.Context，
+// 因此不检查context的处理器会推迟503的返回时间
+// 响应先缓冲，超时后丢弃处理器写出的响应体和头部，不会输出半截响应；
+// 已主动写出头部的流式响应（NDJSON、归档）无法替换，只会随context取消提前结束
+func ResponseTimeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		original := c.Writer
+		header := original.Header().Clone()
+		writer := &bufferedWriter{ResponseWriter: original, body: &bytes.Buffer{}}
+		c.Writer = writer
+		defer func() { c.Writer = original }()
+
+		c.Next()
+
+		if original.Written() {
+			return
+		}
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			original.Write(writer.body.Bytes())
+			return
+		}
+
+		// 还原处理器修改前的头部，去掉ETag、Content-Length等只属于原响应的字段
+		current := original.Header()
+		for key := range current {
+			delete(current, key)
+		}
+		for key, values := range header {
+			current[key] = values
+		}
+
+		c.Writer = original
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "RESPONSE_TIMEOUT",
+			"message": "The request did not complete within " + timeout.String(),
+		})
+		c.Abort()
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestResponseTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ResponseTimeout(20 * time.Millisecond))
+	router.GET("/fast", func(c *gin.Context) {
+		c.Header("ETag", `"fast"`)
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	// 模拟按context取消的慢查询：截止后写出错误响应和只属于该响应的头部
+	router.GET("/slow", func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		c.Header("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND"})
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	if w.Code != http.StatusOK || w.Body.String() != `{"ok":true}` || w.Header().Get("ETag") != `"fast"` {
+		t.Errorf("fast request: status %d, body %s, ETag %q; want unchanged 200", w.Code, w.Body, w.Header().Get("ETag"))
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("slow request: status = %d, want 503", w.Code)
+	}
+	// 处理器的响应体和头部均被丢弃，只输出超时错误
+	var resp map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("body %q is not a single JSON object: %v", w.Body, err)
+	}
+	if resp["error"] != "RESPONSE_TIMEOUT" {
+		t.Errorf("error = %q, want RESPONSE_TIMEOUT", resp["error"])
+	}
+	if got := w.Header().Get("Last-Modified"); got != "" {
+		t.Errorf("Last-Modified = %q, want discarded", got)
+	}
+}
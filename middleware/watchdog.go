@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// inflightRequest 进行中的请求
+type inflightRequest struct {
+	method    string
+	path      string
+	requestID string
+	start     time.Time
+	reported  bool
+}
+
+// Watchdog 跟踪进行中的请求，发现超过阈值仍未完成的请求时告警
+// 用于发现死锁等持有数据库连接却不返回的情况，此时普通超时可能已经失效
+type Watchdog struct {
+	threshold    time.Duration
+	dumpStacks   bool
+	failLiveness bool
+
+	mu       sync.Mutex
+	nextID   uint64
+	inflight map[uint64]*inflightRequest
+
+	stuck atomic.Bool
+}
+
+// NewWatchdog 创建请求看门狗
+// dumpStacks 发现卡住的请求时在日志中附带全部goroutine堆栈
+// failLiveness 存在卡住的请求时存活检查返回失败，由编排系统重启实例
+func NewWatchdog(threshold time.Duration, dumpStacks, failLiveness bool) *Watchdog {
+	return &Watchdog{
+		threshold:    threshold,
+		dumpStacks:   dumpStacks,
+		failLiveness: failLiveness,
+		inflight:     make(map[uint64]*inflightRequest),
+	}
+}
+
+// Middleware 登记进行中的请求，exempt中的路由（注册时的路径模板）不登记
+func (w *Watchdog) Middleware(exempt ...string) gin.HandlerFunc {
+	skip := make(map[string]bool, len(exempt))
+	for _, path := range exempt {
+		skip[path] = true
+	}
+
+	return func(c *gin.Context) {
+		if skip[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		w.mu.Lock()
+		w.nextID++
+		id := w.nextID
+		w.inflight[id] = &inflightRequest{
+			method:    c.Request.Method,
+			path:      c.Request.URL.Path,
+			requestID: c.GetString("request_id"),
+			start:     time.Now(),
+		}
+		w.mu.Unlock()
+
+		defer func() {
+			w.mu.Lock()
+			delete(w.inflight, id)
+			w.mu.Unlock()
+		}()
+
+		c.Next()
+	}
+}
+
+// Run 定期检查进行中的请求，直到ctx取消
+func (w *Watchdog) Run(ctx context.Context) {
+	interval := w.threshold / 3
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			w.check(now)
+		}
+	}
+}
+
+// check 检查超过阈值的请求，每个请求只告警一次
+func (w *Watchdog) check(now time.Time) {
+	stuck := false
+	newlyStuck := false
+
+	w.mu.Lock()
+	for _, req := range w.inflight {
+		age := now.Sub(req.start)
+		if age < w.threshold {
+			continue
+		}
+		stuck = true
+		if req.reported {
+			continue
+		}
+		req.reported = true
+		newlyStuck = true
+
+		log.Warn().
+			Str("request_id", req.requestID).
+			Str("method", req.method).
+			Str("path", req.path).
+			Dur("age", age).
+			Dur("threshold", w.threshold).
+			Msg("Request exceeded watchdog threshold, possible deadlock")
+	}
+	w.mu.Unlock()
+
+	w.stuck.Store(stuck)
+
+	if newlyStuck && w.dumpStacks {
+		buf := make([]byte, 1<<20)
+		n := runtime.Stack(buf, true)
+		log.Warn().Str("goroutines", string(buf[:n])).Msg("Goroutine dump")
+	}
+}
+
+// Healthy 开启failLiveness且存在卡住的请求时返回错误
+func (w *Watchdog) Healthy() error {
+	if w.failLiveness && w.stuck.Load() {
+		return fmt.Errorf("requests stuck longer than %s", w.threshold)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWatchdogExemptRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := NewWatchdog(time.Minute, false, false)
+
+	var inflight int
+	router := gin.New()
+	router.Use(w.Middleware("/export/:id"))
+	record := func(c *gin.Context) {
+		w.mu.Lock()
+		inflight = len(w.inflight)
+		w.mu.Unlock()
+	}
+	router.GET("/export/:id", record)
+	router.GET("/json/:id", record)
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/export/1", 0},
+		{"/json/1", 1},
+	}
+	for _, tt := range tests {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if inflight != tt.want {
+			t.Errorf("%s: %d requests tracked, want %d", tt.path, inflight, tt.want)
+		}
+	}
+
+	if len(w.inflight) != 0 {
+		t.Errorf("%d requests still tracked after completion", len(w.inflight))
+	}
+}
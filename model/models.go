@@ -1,35 +1,194 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
 )
 
 type JSONDocument struct {
 	ID          string         `json:"id"`
 	ContentHash string         `json:"content_hash"`
+	ShortHash   string         `json:"short_hash,omitempty"`
+	DocType     string         `json:"doc_type,omitempty"`
 	JSONData    []byte         `json:"json_data"`
 	Size        int64          `json:"size"`
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	Metadata    map[string]any `json:"metadata,omitempty"`
+	Tags        []string       `json:"tags,omitempty"`
+	Compression string         `json:"compression,omitempty"`
+	ChunkCount  int            `json:"chunk_count,omitempty"`
+	// Existing 存储时命中去重，返回的是已有文档
+	Existing bool `json:"-"`
 }
 
+// 文档标签的数量和长度上限，MySQL的标签索引按MaxTagLength个字符建立
+const (
+	MaxTags      = 32
+	MaxTagLength = 64
+)
+
+// StoreRequest 存储请求，json_data直接内嵌JSON文档：{"json_data": {...}}
 type StoreRequest struct {
-	JSONData []byte         `json:"json_data" validate:"required"`
-	Metadata map[string]any `json:"metadata,omitempty"`
+	// ID 可选，客户端指定的文档ID，必须是小写的标准UUID；已被占用时返回409，批量存储不支持
+	ID       string          `json:"id,omitempty" validate:"omitempty,uuid"`
+	JSONData json.RawMessage `json:"json_data" validate:"required"`
+	Type     string          `json:"type,omitempty" validate:"omitempty,max=64"`
+	Metadata map[string]any  `json:"metadata,omitempty"`
+	// Tags 可选，文档标签，用于 ?tag= 过滤；最多MaxTags个，每个1到MaxTagLength个字符
+	Tags []string `json:"tags,omitempty"`
+}
+
+// StoreInput 存储层写入参数
+type StoreInput struct {
+	JSONData []byte
+	DocType  string
+	// ID 客户端指定的文档ID，为空时生成
+	ID string
+	// Metadata 新建文档时写入的metadata，命中去重时不修改已有文档
+	Metadata map[string]any
+	// Tags 新建文档时写入的标签，与Metadata一样命中去重时不修改已有文档
+	Tags []string
+}
+
+// ListFilter 文档列表查询条件
+type ListFilter struct {
+	DocType string
+	// Tag 只列出带有该标签的文档，为空时不过滤
+	Tag    string
+	Limit  int
+	Offset int
+}
+
+type ListResponse struct {
+	Documents []JSONDocument `json:"documents"`
+	Count     int            `json:"count"`
+	Limit     int            `json:"limit"`
+	Offset    int            `json:"offset"`
+	// Total 符合条件的文档总数，仅在请求?total=estimated时返回；TotalApproximate表示来自查询计划的估算
+	Total            *int64 `json:"total,omitempty"`
+	TotalApproximate bool   `json:"total_approximate,omitempty"`
+}
+
+// ChangesResponse 增量同步的一页结果，下次请求以NextSince、NextAfterID作为since、after_id
+type ChangesResponse struct {
+	Documents   []JSONDocument `json:"documents"`
+	Count       int            `json:"count"`
+	NextSince   time.Time      `json:"next_since"`
+	NextAfterID string         `json:"next_after_id,omitempty"`
+	// HasMore 本页已满，可能还有更多修改，应立即继续请求
+	HasMore bool `json:"has_more"`
+}
+
+// CountResponse 符合条件的文档精确数量
+type CountResponse struct {
+	DocType string `json:"doc_type,omitempty"`
+	Count   int64  `json:"count"`
 }
 
 type StoreBatchRequest struct {
 	Documents []StoreRequest `json:"documents" validate:"required,min=1,max=100"`
 }
 
+// MetadataUpdate 单个文档的metadata合并
+type MetadataUpdate struct {
+	ID       string         `json:"id" validate:"required"`
+	Metadata map[string]any `json:"metadata" validate:"required"`
+}
+
+// MetadataBatchRequest 批量合并metadata请求
+type MetadataBatchRequest struct {
+	Updates []MetadataUpdate `json:"updates" validate:"required,min=1,max=100,dive"`
+}
+
+// MetadataUpdateResult 单个文档的合并结果，失败时Error为错误码
+type MetadataUpdateResult struct {
+	ID      string `json:"id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// MetadataBatchResponse 批量合并metadata响应，Results与请求按位置一一对应
+type MetadataBatchResponse struct {
+	SuccessCount int                    `json:"success_count"`
+	FailureCount int                    `json:"failure_count"`
+	Results      []MetadataUpdateResult `json:"results"`
+}
+
 type StoreResponse struct {
 	ID        string    `json:"id"`
+	ShortHash string    `json:"short_hash,omitempty"`
 	IsNew     bool      `json:"is_new"`
 	CreatedAt time.Time `json:"created_at"`
 	Message   string    `json:"message,omitempty"`
 }
 
+// 管理接口EXPLAIN支持的查询模板
+const (
+	ExplainGetByID        = "get_by_id"
+	ExplainGetByHash      = "get_by_hash"
+	ExplainList           = "list"
+	ExplainCount          = "count"
+	ExplainJSONField      = "json_field"
+	ExplainMetadataFilter = "metadata_filter"
+)
+
+// ExplainRequest 对指定查询模板执行EXPLAIN，参数按模板填入，不接受任意SQL
+type ExplainRequest struct {
+	Template string            `json:"template" binding:"required"`
+	Params   map[string]string `json:"params"`
+}
+
+// ExplainResult 查询模板实际执行的SQL及数据库返回的执行计划
+type ExplainResult struct {
+	Template string          `json:"template"`
+	Query    string          `json:"query"`
+	Plan     json.RawMessage `json:"plan"`
+	// Excluded 仅json_field模板：分块存储、该查询匹配不到的文档数
+	Excluded int64 `json:"excluded,omitempty"`
+}
+
+// LabeledDocument 按标签存储的结果，Changed为false表示内容未变、没有产生新版本
+type LabeledDocument struct {
+	Label    string
+	Version  int
+	Changed  bool
+	Document *JSONDocument
+}
+
+// LabelResponse 按标签存储的响应
+type LabelResponse struct {
+	Label     string    `json:"label"`
+	Version   int       `json:"version"`
+	Changed   bool      `json:"changed"`
+	ID        string    `json:"id"`
+	ShortHash string    `json:"short_hash,omitempty"`
+	IsNew     bool      `json:"is_new"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FlattenResponse 文档平铺后的路径和标量值
+type FlattenResponse struct {
+	ID        string         `json:"id"`
+	Separator string         `json:"separator"`
+	Count     int            `json:"count"`
+	Paths     map[string]any `json:"paths"`
+}
+
+// SearchResponse 全文搜索响应，IDs按相关度排序
+type SearchResponse struct {
+	Query string   `json:"query"`
+	Count int      `json:"count"`
+	IDs   []string `json:"ids"`
+}
+
+// StoreTransactionResponse 原子存储响应，IDs与请求中的文档按位置一一对应
+type StoreTransactionResponse struct {
+	Count    int           `json:"count"`
+	IDs      []string      `json:"ids"`
+	Duration time.Duration `json:"duration_ms"`
+}
+
 type StoreBatchResponse struct {
 	SuccessCount int             `json:"success_count"`
 	FailureCount int             `json:"failure_count"`
@@ -39,6 +198,23 @@ type StoreBatchResponse struct {
 	Duration     time.Duration   `json:"duration_ms"`
 }
 
+// UploadResponse multipart上传的结果，按原始文件名索引
+type UploadResponse struct {
+	SuccessCount int                         `json:"success_count"`
+	FailureCount int                         `json:"failure_count"`
+	Results      map[string]UploadFileResult `json:"results"`
+	Duration     time.Duration               `json:"duration_ms"`
+}
+
+// UploadFileResult 单个上传文件的结果，成功时Error为空
+type UploadFileResult struct {
+	ID        string `json:"id,omitempty"`
+	ShortHash string `json:"short_hash,omitempty"`
+	IsNew     bool   `json:"is_new"`
+	Error     string `json:"error,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
 type BatchFailure struct {
 	Index   int    `json:"index"`
 	Error   string `json:"error"`
@@ -46,14 +222,37 @@ type BatchFailure struct {
 }
 
 type GetBatchRequest struct {
-	IDs []string `json:"ids" validate:"required,min=1,max=100"`
+	// IDs 数量上限由server.batch_get_max_ids配置，在handler中检查
+	IDs []string `json:"ids" validate:"required,min=1"`
 }
 
+// GetBatchResponse 批量获取响应，Documents与请求的ID按位置一一对应，未找到的位置为null
 type GetBatchResponse struct {
-	SuccessCount int            `json:"success_count"`
-	FailureCount int            `json:"failure_count"`
-	Documents    []JSONDocument `json:"documents"`
-	Failures     []BatchFailure `json:"failures,omitempty"`
+	SuccessCount int             `json:"success_count"`
+	FailureCount int             `json:"failure_count"`
+	Documents    []*JSONDocument `json:"documents"`
+	Failures     []BatchFailure  `json:"failures,omitempty"`
+}
+
+// MaxExistsHashes 批量检查内容哈希单次请求最多的哈希数
+const MaxExistsHashes = 1000
+
+// HashExistsRequest 批量检查内容哈希是否已存储
+type HashExistsRequest struct {
+	Hashes []string `json:"hashes"`
+}
+
+// HashExists 单个哈希的检查结果，ID为已存储文档的ID（允许重复内容时为最早的一个）
+type HashExists struct {
+	Hash   string `json:"hash"`
+	Exists bool   `json:"exists"`
+	ID     string `json:"id,omitempty"`
+}
+
+// HashExistsResponse Results与请求的哈希按位置一一对应
+type HashExistsResponse struct {
+	Results       []HashExists `json:"results"`
+	ExistingCount int          `json:"existing_count"`
 }
 
 type ErrorResponse struct {
@@ -63,14 +262,43 @@ type ErrorResponse struct {
 }
 
 type DatabaseStats struct {
-	TotalDocuments int64      `json:"total_documents"`
-	TotalSize      int64      `json:"total_size_bytes"`
-	AverageSize    float64    `json:"average_size_bytes"`
-	MaxSize        int64      `json:"max_size_bytes"`
-	MinSize        int64      `json:"min_size_bytes"`
-	DailyCounts    []DayCount `json:"daily_counts,omitempty"`
-	UniqueHashes   int64      `json:"unique_hashes"`
-	LastUpdated    time.Time  `json:"last_updated"`
+	TotalDocuments int64        `json:"total_documents"`
+	TotalSize      int64        `json:"total_size_bytes"`
+	AverageSize    float64      `json:"average_size_bytes"`
+	MaxSize        int64        `json:"max_size_bytes"`
+	MinSize        int64        `json:"min_size_bytes"`
+	DailyCounts    []DayCount   `json:"daily_counts,omitempty"`
+	TypeCounts     []TypeCount  `json:"type_counts,omitempty"`
+	SizeHistogram  []SizeBucket `json:"size_histogram,omitempty"`
+	UniqueHashes   int64        `json:"unique_hashes"`
+	LastUpdated    time.Time    `json:"last_updated"`
+	// StoredSize 实际占用的存储字节数（分块或json_data列，另加原始字节，原始字节可能已压缩）
+	StoredSize int64 `json:"stored_size_bytes"`
+	// CompressedDocuments 原始字节已压缩存储的文档数
+	CompressedDocuments int64 `json:"compressed_documents"`
+	// CompressionRatio 未压缩大小（TotalSize）与StoredSize之比，大于1表示节省了空间
+	CompressionRatio float64 `json:"compression_ratio"`
+	// AgeHistogram 按created_at统计的文档年龄分布，用于规划数据保留策略
+	AgeHistogram []AgeBucket `json:"age_histogram,omitempty"`
+}
+
+// SizeBucket 文档大小直方图桶，Count为大小落在 (上一桶上界, UpperBound] 区间内的文档数
+type SizeBucket struct {
+	UpperBound string `json:"le"`
+	Count      int64  `json:"count"`
+}
+
+// AgeBucket 文档年龄直方图桶，Count、Size为年龄落在 (上一桶上界, UpperBound] 小时区间内的文档数和总大小
+type AgeBucket struct {
+	UpperBound string `json:"le_hours"`
+	Count      int64  `json:"count"`
+	Size       int64  `json:"size_bytes"`
+}
+
+type TypeCount struct {
+	Type  string `json:"type"`
+	Count int64  `json:"count"`
+	Size  int64  `json:"size_bytes"`
 }
 
 type DayCount struct {
@@ -79,6 +307,39 @@ type DayCount struct {
 	Size  int64  `json:"size_bytes"`
 }
 
+// AppMetrics 应用层指标
+type AppMetrics struct {
+	// UptimeSeconds 进程启动以来的秒数
+	UptimeSeconds float64 `json:"uptime_seconds"`
+	TotalRequests int64   `json:"total_requests"`
+	InFlight      int64   `json:"in_flight"`
+	Status2xx     int64   `json:"status_2xx"`
+	Status4xx     int64   `json:"status_4xx"`
+	Status5xx     int64   `json:"status_5xx"`
+	Stores        int64   `json:"stores"`
+	DedupHits     int64   `json:"dedup_hits"`
+	DedupHitRate  float64 `json:"dedup_hit_rate"`
+	// DedupHitRatio 存储层统计的去重命中率，只计入成功写入（原子批量在提交后）的文档，
+	// 还包括备份导入等不经过写接口的写入；DedupHitRate由写接口统计
+	DedupHitRatio     float64   `json:"dedup_hit_ratio"`
+	AvgStoreLatencyMs float64   `json:"avg_store_latency_ms"`
+	Timestamp         time.Time `json:"timestamp"`
+}
+
+// DedupStats 存储层的去重计数
+type DedupStats struct {
+	Inserted int64
+	Deduped  int64
+	// HitRatio 命中去重的文档占全部写入文档的比例，没有写入时为0
+	HitRatio float64
+}
+
+// FacetCountsResponse 按metadata键的取值统计的文档数
+type FacetCountsResponse struct {
+	Key    string           `json:"key"`
+	Facets map[string]int64 `json:"facets"`
+}
+
 type DatabaseMetrics struct {
 	Uptime            time.Duration `json:"uptime_seconds"`
 	ActiveConnections int           `json:"active_connections"`
@@ -86,8 +347,126 @@ type DatabaseMetrics struct {
 	CacheHitRatio     float64       `json:"cache_hit_ratio,omitempty"`
 	QueryPerSecond    float64       `json:"queries_per_second"`
 	SlowQueries       int64         `json:"slow_queries"`
-	Tables            []TableStats  `json:"tables,omitempty"`
-	Timestamp         time.Time     `json:"timestamp"`
+	// DocumentsInserted、DocumentsDeduped 进程启动以来各写入接口新建和命中去重的文档数
+	// DedupHitRatio 命中去重的文档占比，越高说明去重节省的存储越多
+	DocumentsInserted int64        `json:"documents_inserted"`
+	DocumentsDeduped  int64        `json:"documents_deduped"`
+	DedupHitRatio     float64      `json:"dedup_hit_ratio"`
+	Tables            []TableStats `json:"tables,omitempty"`
+	// Replication 连接的是备库时的复制状态
+	Replication *ReplicationStatus `json:"replication,omitempty"`
+	Timestamp   time.Time          `json:"timestamp"`
+}
+
+// ReplicationStatus 备库复制状态
+type ReplicationStatus struct {
+	// LagBytes 已接收但尚未回放的WAL字节数，读请求看到的数据落后于此
+	LagBytes int64 `json:"lag_bytes"`
+	// ReplayedAt 最近回放的事务在主库提交的时间，主库无写入时不代表延迟
+	ReplayedAt *time.Time `json:"replayed_at,omitempty"`
+}
+
+// CompressResult 存量文档压缩任务结果
+type CompressResult struct {
+	Batches     int           `json:"batches"`
+	Compressed  int64         `json:"compressed"`
+	Failed      int64         `json:"failed"`
+	BytesBefore int64         `json:"bytes_before"`
+	BytesAfter  int64         `json:"bytes_after"`
+	Remaining   int64         `json:"remaining"`
+	Done        bool          `json:"done"`
+	Duration    time.Duration `json:"duration_ms"`
+}
+
+// ExportRecord 备份NDJSON中的一行，json_data保持原始JSON以便直接重新导入
+type ExportRecord struct {
+	ID          string          `json:"id"`
+	ContentHash string          `json:"content_hash"`
+	DocType     string          `json:"doc_type,omitempty"`
+	JSONData    json.RawMessage `json:"json_data"`
+	// RawData 编码时json_data会被压缩为紧凑形式，原始字节与之不同（含空白等）时另存一份，导入时优先使用
+	RawData  []byte         `json:"raw_data,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+	Tags     []string       `json:"tags,omitempty"`
+	// Labels 导出时指向该文档的标签，不含标签的历史版本
+	Labels    []string  `json:"labels,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ExportResult 备份导出结果
+type ExportResult struct {
+	Bucket    string        `json:"bucket"`
+	Key       string        `json:"key"`
+	Documents int64         `json:"documents"`
+	Bytes     int64         `json:"bytes"`
+	Since     *time.Time    `json:"since,omitempty"`
+	Duration  time.Duration `json:"duration_ms"`
+}
+
+// ImportResult 备份导入结果，Stored包含重复导入时返回的已有文档
+type ImportResult struct {
+	Bucket  string `json:"bucket"`
+	Key     string `json:"key"`
+	Records int64  `json:"records"`
+	Stored  int64  `json:"stored"`
+	// Skipped ID已被其他内容使用，或内容已由其他ID保存而跳过的记录数
+	Skipped  int64         `json:"skipped"`
+	Failed   int64         `json:"failed"`
+	Duration time.Duration `json:"duration_ms"`
+}
+
+// StorageInfo 单个文档的存储诊断信息
+type StorageInfo struct {
+	RawSize     int64  `json:"raw_size_bytes"`
+	StoredSize  int64  `json:"stored_size_bytes"`
+	Compression string `json:"compression,omitempty"`
+	Chunks      int    `json:"chunks,omitempty"`
+	RawBytes    bool   `json:"raw_bytes_preserved"`
+	// HashAlgorithm 内容哈希算法，基于规范化后的JSON计算
+	HashAlgorithm string `json:"hash_algorithm"`
+	// AccessCount、LastAccessedAt 按ID读取的次数和最近时间，仅开启track_access时更新
+	AccessCount    int64      `json:"access_count"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+}
+
+// DebugDocumentResponse 带存储诊断信息的文档响应
+type DebugDocumentResponse struct {
+	Document *JSONDocument `json:"document"`
+	Storage  *StorageInfo  `json:"storage"`
+}
+
+// NearDuplicate 相似文档，Distance为SimHash汉明距离
+type NearDuplicate struct {
+	ID          string    `json:"id"`
+	ContentHash string    `json:"content_hash"`
+	DocType     string    `json:"doc_type,omitempty"`
+	Size        int64     `json:"size"`
+	Distance    int       `json:"distance"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// DocumentSummary 不含内容的文档摘要
+type DocumentSummary struct {
+	ID          string         `json:"id"`
+	ContentHash string         `json:"content_hash"`
+	DocType     string         `json:"doc_type,omitempty"`
+	Size        int64          `json:"size"`
+	CreatedAt   time.Time      `json:"created_at"`
+	Metadata    map[string]any `json:"metadata,omitempty"`
+}
+
+// LargestDocumentsResponse 按大小降序的文档列表
+type LargestDocumentsResponse struct {
+	Limit     int               `json:"limit"`
+	Documents []DocumentSummary `json:"documents"`
+}
+
+// NearDuplicatesResponse 相似文档查询响应
+type NearDuplicatesResponse struct {
+	ID          string          `json:"id"`
+	MaxDistance int             `json:"max_distance"`
+	Matches     []NearDuplicate `json:"matches"`
 }
 
 type TableStats struct {
@@ -111,10 +490,48 @@ type ReadyResponse struct {
 	Checks    []HealthCheck `json:"checks,omitempty"`
 }
 
+// DrainResponse 排空响应
+type DrainResponse struct {
+	Draining bool   `json:"draining"`
+	Message  string `json:"message"`
+}
+
 type HealthCheck struct {
 	Name   string `json:"name"`
 	Status string `json:"status"`
 	Error  string `json:"error,omitempty"`
+	// LagBytes 仅replication检查项：备库未回放的WAL字节数
+	LagBytes *int64 `json:"lag_bytes,omitempty"`
+}
+
+// Attachment 文档的二进制附件，ContentHash为数据的SHA-256
+type Attachment struct {
+	DocumentID  string    `json:"document_id"`
+	Name        string    `json:"name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	ContentHash string    `json:"content_hash"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Data        []byte    `json:"-"`
+}
+
+// ServiceInfoResponse GET / 返回的服务信息，Links为常用入口的路径
+type ServiceInfoResponse struct {
+	Name        string            `json:"name"`
+	Version     string            `json:"version"`
+	Environment string            `json:"environment"`
+	Links       map[string]string `json:"links"`
+}
+
+// Endpoint 一个可用接口
+type Endpoint struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+}
+
+// EndpointsResponse GET /api/v1 返回的接口列表，按路径和方法排序
+type EndpointsResponse struct {
+	Endpoints []Endpoint `json:"endpoints"`
 }
 
 type VersionResponse struct {
@@ -123,4 +540,5 @@ type VersionResponse struct {
 	GitCommit   string `json:"git_commit,omitempty"`
 	Environment string `json:"environment"`
 	GoVersion   string `json:"go_version"`
+	JSONCodec   string `json:"json_codec"`
 }
@@ -0,0 +1,56 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+)
+
+func TestCORSPreflight(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name            string
+		origins         []string
+		credentials     bool
+		wantOrigin      string
+		wantCredentials string
+	}{
+		{name: "wildcard", origins: []string{"*"}, wantOrigin: "*"},
+		// 开启凭证时回显具体来源，不能是 *
+		{name: "credentials", origins: []string{"https://app.example.com"}, credentials: true,
+			wantOrigin: "https://app.example.com", wantCredentials: "true"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg config.Config
+			cfg.Security.CorsOrigins = tt.origins
+			cfg.Security.CorsAllowCredentials = tt.credentials
+			cfg.Security.CorsMaxAge = 600
+			engine := gin.New()
+			engine.Use(corsMiddleware(cfg))
+			engine.POST("/api/v1/json", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodOptions, "/api/v1/json", nil)
+			req.Header.Set("Origin", "https://app.example.com")
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+
+			if w.Code != http.StatusNoContent {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusNoContent)
+			}
+			if got := w.Header().Get("Access-Control-Max-Age"); got != "600" {
+				t.Errorf("Access-Control-Max-Age = %q, want 600", got)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials"); got != tt.wantCredentials {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want %q", got, tt.wantCredentials)
+			}
+		})
+	}
+}
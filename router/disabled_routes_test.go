@@ -0,0 +1,61 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+func TestInitDisabledRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.Config{Environment: config.EnvTest}
+	cfg.Security.CorsOrigins = []string{"https://app.example.com"}
+	cfg.Server.ServiceInfo = true
+	// 本仓库没有删除接口，以上传和按ID更新原始内容为例
+	cfg.Routes.Disabled = []string{"POST /api/v1/json/upload", "PUT /api/v1/json/:id/raw"}
+	engine := Init(cfg, struct{ database.JSONStore }{}, nil, nil)
+
+	registered := make(map[string]bool)
+	for _, route := range engine.Routes() {
+		registered[route.Method+" "+route.Path] = true
+	}
+	for _, disabled := range cfg.Routes.Disabled {
+		if registered[disabled] {
+			t.Errorf("%s is registered, want it left out", disabled)
+		}
+	}
+	if !registered["POST /api/v1/json"] || !registered["GET /api/v1/json/:id/raw"] {
+		t.Error("routes that were not disabled are missing")
+	}
+
+	for _, target := range []struct{ method, path string }{
+		{http.MethodPost, "/api/v1/json/upload"},
+		{http.MethodPut, "/api/v1/json/00000000-0000-0000-0000-0000000000f3/raw"},
+	} {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(target.method, target.path, strings.NewReader(`{}`)))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s %s: status = %d, want 404", target.method, target.path, w.Code)
+		}
+	}
+
+	// 端点列表中同样不再出现
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1", nil))
+	var resp model.EndpointsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	for _, endpoint := range resp.Endpoints {
+		if name := endpoint.Method + " " + endpoint.Path; name == cfg.Routes.Disabled[0] || name == cfg.Routes.Disabled[1] {
+			t.Errorf("disabled %s is listed", name)
+		}
+	}
+}
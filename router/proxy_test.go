@@ -0,0 +1,62 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTrustedProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name       string
+		proxies    []string
+		remoteAddr string
+		want       string
+	}{
+		// 默认只信任本机代理
+		{name: "trusted loopback proxy", proxies: []string{"127.0.0.1", "::1"}, remoteAddr: "127.0.0.1:4000", want: "203.0.113.7"},
+		{name: "untrusted proxy", proxies: []string{"127.0.0.1", "::1"}, remoteAddr: "198.51.100.2:4000", want: "198.51.100.2"},
+		{name: "trusted network", proxies: []string{"10.0.0.0/8"}, remoteAddr: "10.1.2.3:4000", want: "203.0.113.7"},
+		// 配置无效时不信任任何转发头
+		{name: "invalid config", proxies: []string{"not-an-ip"}, remoteAddr: "127.0.0.1:4000", want: "127.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := gin.New()
+			setTrustedProxies(engine, tt.proxies)
+			var clientIP string
+			engine.GET("/", func(c *gin.Context) {
+				clientIP = c.ClientIP()
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-Forwarded-For", "203.0.113.7")
+			engine.ServeHTTP(httptest.NewRecorder(), req)
+			if clientIP != tt.want {
+				t.Errorf("ClientIP() = %s, want %s", clientIP, tt.want)
+			}
+		})
+	}
+}
+
+func TestOnlyLoopback(t *testing.T) {
+	tests := []struct {
+		proxies []string
+		want    bool
+	}{
+		{proxies: nil, want: true},
+		{proxies: []string{"127.0.0.1", "::1"}, want: true},
+		{proxies: []string{"127.0.0.0/8"}, want: true},
+		{proxies: []string{"127.0.0.1", "10.0.0.0/8"}, want: false},
+	}
+	for _, tt := range tests {
+		if got := onlyLoopback(tt.proxies); got != tt.want {
+			t.Errorf("onlyLoopback(%v) = %v, want %v", tt.proxies, got, tt.want)
+		}
+	}
+}
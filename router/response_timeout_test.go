@@ -0,0 +1,71 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+const slowDocumentID = "00000000-0000-0000-0000-0000000000f1"
+
+// stallingStore 读取slowDocumentID时阻塞到context结束，并记录查询被取消的原因
+type stallingStore struct {
+	database.JSONStore
+	cancelled chan error
+}
+
+func (s *stallingStore) GetJSONByID(ctx context.Context, id string) (*model.JSONDocument, error) {
+	if id == slowDocumentID {
+		<-ctx.Done()
+		s.cancelled <- ctx.Err()
+		return nil, ctx.Err()
+	}
+	return &model.JSONDocument{ID: id, JSONData: []byte(`{}`)}, nil
+}
+
+func TestResponseTimeoutCancelsQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.Config{Environment: config.EnvTest}
+	cfg.Security.CorsOrigins = []string{"https://app.example.com"}
+	cfg.Server.ResponseTimeoutMs.Reads = 30
+	store := &stallingStore{cancelled: make(chan error, 1)}
+	engine := Init(cfg, store, nil, nil)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/json/00000000-0000-0000-0000-0000000000f2", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("fast read: status = %d, want 200", w.Code)
+	}
+
+	start := time.Now()
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/json/"+slowDocumentID, nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("slow read: status = %d, want 503; body %s", w.Code, w.Body)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("503 after %s, want about 30ms", elapsed)
+	}
+	// 处理器因取消而写出的404不会出现在响应中
+	var resp model.ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Error != "RESPONSE_TIMEOUT" {
+		t.Errorf("body %s, want a single RESPONSE_TIMEOUT error", w.Body)
+	}
+	select {
+	case err := <-store.cancelled:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("query ended with %v, want context.DeadlineExceeded", err)
+		}
+	default:
+		t.Error("store query was not cancelled")
+	}
+}
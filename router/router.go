@@ -5,6 +5,12 @@ import (
 	"github.com/leapzhao/json-store/database"
 	"github.com/leapzhao/json-store/handler"
 	"github.com/leapzhao/json-store/middleware"
+	"github.com/leapzhao/json-store/model"
+	"github.com/leapzhao/json-store/search"
+	"net/http/pprof"
+	"net/netip"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -12,40 +18,104 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-// Init 初始化路由
-func Init(cfg config.Config, store database.JSONStore) *gin.Engine {
+// watchdogExemptRoutes 流式输出或读取请求体的接口，耗时取决于数据量，不由看门狗跟踪
+var watchdogExemptRoutes = []string{
+	"/api/v1/json/archive",
+	"/api/admin/backup/export",
+	"/api/admin/backup/import",
+	"/debug/pprof/profile",
+	"/debug/pprof/trace",
+}
+
+// Init 初始化路由，searchClient为nil时搜索接口返回501，watchdog为nil时不跟踪请求
+// watchdog的检查协程由调用方启动和停止
+func Init(cfg config.Config, store database.JSONStore, searchClient *search.Client, watchdog *middleware.Watchdog) *gin.Engine {
 	// 设置Gin模式
 	setGinMode(cfg.Environment)
 
 	// 创建Gin引擎
 	router := gin.New()
 
+	// 只信任配置的代理转发的客户端IP，影响请求日志和限流
+	setTrustedProxies(router, cfg.Security.TrustedProxies)
+
 	// 添加中间件
 	router.Use(middleware.Recovery())
 	router.Use(middleware.RequestLogger())
-	router.Use(middleware.RequestID())
+	router.Use(middleware.RequestID(cfg.Server.RequestIDHeaders))
+
+	// 安全响应头
+	router.Use(securityHeadersMiddleware(cfg))
+
+	// 应用层计数器
+	appMetrics := middleware.NewAppMetrics()
+	router.Use(appMetrics.Middleware())
+
+	// 请求看门狗，发现长时间未完成的请求
+	if watchdog != nil {
+		router.Use(watchdog.Middleware(watchdogExemptRoutes...))
+	}
+
+	// ?pretty=true 时缩进JSON响应，需在信封之前注册以便处理包装后的输出
+	router.Use(middleware.PrettyJSON())
+
+	// 响应信封（可选）
+	if cfg.Server.ResponseEnvelope {
+		router.Use(middleware.ResponseEnvelope())
+	}
 
 	// 添加CORS中间件
-	router.Use(cors.New(cors.Config{
-		AllowOrigins:     cfg.Security.CorsOrigins,
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	}))
+	router.Use(corsMiddleware(cfg))
 
 	// 创建处理器
-	jsonHandler := handler.NewJSONHandler(store)
+	jsonHandler := handler.NewJSONHandler(store, cfg)
+	jsonHandler.SetAppMetrics(appMetrics)
+	jsonHandler.SetSearchClient(searchClient)
+	if watchdog != nil {
+		jsonHandler.SetLivenessCheck(watchdog.Healthy)
+	}
 
 	// 注册路由
 	registerRoutes(router, jsonHandler, cfg)
+	jsonHandler.SetEndpoints(apiEndpoints(router))
+
+	// 性能分析接口（需显式开启）
+	if cfg.Server.EnablePprof {
+		registerPprofRoutes(router, cfg)
+		log.Warn().Msg("pprof endpoints enabled at /debug/pprof")
+	}
 
 	log.Info().Msg("Router initialized")
 
 	return router
 }
 
+// setTrustedProxies 设置可信代理，配置无效时不信任任何转发头，客户端IP取连接的对端地址
+func setTrustedProxies(router *gin.Engine, proxies []string) {
+	if err := router.SetTrustedProxies(proxies); err != nil {
+		log.Error().Err(err).Msg("Invalid trusted proxies, forwarded headers will not be trusted")
+		router.SetTrustedProxies(nil)
+	}
+}
+
+// onlyLoopback 可信代理为空或都是本机地址，部署在其他主机上的代理之后时无法解析出真实客户端IP
+func onlyLoopback(proxies []string) bool {
+	for _, proxy := range proxies {
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			prefix, err := netip.ParsePrefix(proxy)
+			if err != nil {
+				return false
+			}
+			addr = prefix.Addr()
+		}
+		if !addr.IsLoopback() {
+			return false
+		}
+	}
+	return true
+}
+
 // setGinMode 根据环境设置Gin模式
 func setGinMode(env config.Environment) {
 	switch env {
@@ -58,39 +128,165 @@ func setGinMode(env config.Environment) {
 	}
 }
 
+// apiEndpoints 从已注册的路由中取出/api/v1下的接口，按路径和方法排序
+func apiEndpoints(router *gin.Engine) []model.Endpoint {
+	var endpoints []model.Endpoint
+	for _, route := range router.Routes() {
+		if strings.HasPrefix(route.Path, "/api/v1/") {
+			endpoints = append(endpoints, model.Endpoint{Method: route.Method, Path: route.Path})
+		}
+	}
+	sort.Slice(endpoints, func(i, j int) bool {
+		if endpoints[i].Path != endpoints[j].Path {
+			return endpoints[i].Path < endpoints[j].Path
+		}
+		return endpoints[i].Method < endpoints[j].Method
+	})
+	return endpoints
+}
+
 // registerRoutes 注册路由
 func registerRoutes(router *gin.Engine, handler *handler.JSONHandler, cfg config.Config) {
+	// routes.disabled中的接口不注册
+	switches := newRouteSwitch(cfg.Routes.Disabled)
+	root := switches.group(&router.RouterGroup)
+
 	// 健康检查
-	router.GET("/health", handler.HealthCheck)
-	router.GET("/ready", handler.ReadyCheck)
-	router.GET("/version", handler.Version)
+	root.GET("/health", handler.HealthCheck)
+	root.GET("/ready", handler.ReadyCheck)
+	root.GET("/version", handler.Version)
+
+	// 服务信息和接口列表（可关闭）
+	if cfg.Server.ServiceInfo {
+		root.GET("/", handler.ServiceInfo)
+	}
 
 	// API路由组
-	api := router.Group("/api")
+	api := root.Group("/api")
+	if limit := cfg.Security.RateLimit; limit.Requests > 0 {
+		if onlyLoopback(cfg.Security.TrustedProxies) {
+			log.Warn().Strs("trusted_proxies", cfg.Security.TrustedProxies).
+				Msg("Rate limiting by client IP with no non-loopback trusted proxies; behind a load balancer all clients share one limit, set security.trusted_proxies")
+		}
+		api.Use(middleware.RateLimit(limit.Requests, limit.Burst, time.Duration(limit.Window)*time.Second))
+	}
+	// 全局并发上限在限流之后，被限流的请求不占用槽位；健康检查不受限制
+	if cfg.Server.MaxConcurrentRequests > 0 {
+		api.Use(middleware.ConcurrencyLimit(cfg.Server.MaxConcurrentRequests,
+			time.Duration(cfg.Server.RequestQueueTimeoutMs)*time.Millisecond))
+	}
 	{
 		// API版本控制
 		v1 := api.Group("/v1")
 		{
-			v1.POST("/json", handler.StoreJSON)
-			v1.GET("/json/:id", handler.GetJSON)
-			v1.GET("/json", handler.GetJSONByHash)
+			// 读接口的响应超时，超时返回503并取消数据库查询
+			reads := v1.Group("")
+			if timeout := cfg.Server.ResponseTimeoutMs.Reads; timeout > 0 {
+				reads.Use(middleware.ResponseTimeout(time.Duration(timeout) * time.Millisecond))
+			}
+			{
+				if cfg.Server.ServiceInfo {
+					reads.GET("", handler.ListEndpoints)
+				}
+				reads.GET("/json/:id", handler.GetJSON)
+				reads.GET("/json/:id/normalized", handler.GetJSONNormalized)
+				reads.GET("/json/:id/raw", handler.GetJSONRaw)
+				reads.GET("/json/:id/flatten", handler.FlattenJSON)
+				reads.GET("/json/:id/pointer", handler.GetJSONPointer)
+				reads.GET("/json/:id/attachments/:name", handler.GetAttachment)
+				reads.GET("/json", handler.QueryJSON)
+				reads.GET("/json/batch", handler.GetJSONBatch)
+				reads.POST("/json/batch/exists", handler.BatchExists)
+				reads.GET("/json/facets", handler.FacetCounts)
+				reads.GET("/json/count", handler.CountJSON)
+				reads.GET("/json/changes", handler.GetJSONChanges)
+				reads.GET("/json/search", handler.SearchJSON)
+				reads.GET("/json/archive", handler.GetJSONArchive)
+				reads.GET("/json/by-hash/:hash", handler.GetJSONByHashPath)
+				reads.GET("/json/content/:hash", handler.RedirectByContentHash)
+				reads.GET("/json/by-label/*label", handler.GetJSONByLabel)
+			}
+
+			// 写操作（单独限制并发，避免写入洪峰占满连接池影响读请求）
+			// 并发限制和每日配额各只创建一个实例，multipart上传与JSON写接口共用同一份计数
+			var writeLimit, dailyQuota []gin.HandlerFunc
+			if cfg.Server.MaxConcurrentWrites > 0 {
+				writeLimit = append(writeLimit, middleware.WriteLimit(cfg.Server.MaxConcurrentWrites, cfg.Server.WriteQueueSize))
+			}
+			if cfg.Security.DailyQuota > 0 {
+				dailyQuota = append(dailyQuota, middleware.DailyQuota(cfg.Security.DailyQuota))
+			}
+
+			writes := v1.Group("")
+			writes.Use(middleware.ValidateJSON(cfg.Server.AllowedContentTypes))
+			writes.Use(writeLimit...)
+			{
+				writes.POST("/json/batch/metadata", handler.UpdateMetadataBatch)
+				writes.PUT("/json/:id/raw", handler.UpdateJSONRaw)
+			}
 
-			// 批量操作
-			v1.POST("/json/batch", handler.StoreJSONBatch)
-			v1.GET("/json/batch", handler.GetJSONBatch)
+			// 新建文档的接口计入每日配额
+			stores := writes.Group("")
+			stores.Use(dailyQuota...)
+			{
+				stores.POST("/json", handler.StoreJSON)
+				stores.POST("/json/raw", handler.StoreJSONRaw)
+				stores.POST("/json/batch", handler.StoreJSONBatch)
+				stores.POST("/json/transaction", handler.StoreJSONTransaction)
+				stores.PUT("/json/by-label/*label", handler.StoreByLabel)
+			}
+
+			// multipart上传不经过ValidateJSON，由处理器逐个文件校验
+			uploads := v1.Group("")
+			uploads.Use(writeLimit...)
+			uploads.Use(dailyQuota...)
+			{
+				uploads.POST("/json/upload", handler.UploadJSON)
+			}
+
+			// 附件请求体是任意二进制内容，不经过ValidateJSON；不新建文档，不计入每日配额
+			attachments := v1.Group("")
+			attachments.Use(writeLimit...)
+			{
+				attachments.POST("/json/:id/attachments", handler.StoreAttachment)
+			}
 		}
 
 		// 管理接口（生产环境需要认证）
 		if cfg.Environment == config.EnvProduct {
 			admin := api.Group("/admin")
-			admin.Use(middleware.BasicAuth())
+			admin.Use(middleware.BasicAuth(middleware.AdminAccounts(cfg.Security.AdminUsername, cfg.Security.AdminPassword)))
 			{
-				admin.GET("/metrics", handler.Metrics)
-				admin.GET("/stats", handler.Stats)
+				// 只读的管理接口可设置响应超时，维护任务和备份不受限制
+				adminReads := admin.Group("")
+				if timeout := cfg.Server.ResponseTimeoutMs.Admin; timeout > 0 {
+					adminReads.Use(middleware.ResponseTimeout(time.Duration(timeout) * time.Millisecond))
+				}
+				adminReads.GET("/metrics", handler.Metrics)
+				adminReads.GET("/app-metrics", handler.AppMetrics)
+				adminReads.GET("/stats", handler.Stats)
+				adminReads.GET("/json/:id/similar", handler.FindNearDuplicates)
+				adminReads.GET("/json/largest", handler.LargestDocuments)
+				adminReads.POST("/explain", handler.Explain)
+
+				// 维护任务
+				admin.POST("/maintenance/compress", handler.CompressDocuments)
+				admin.POST("/backup/export", handler.ExportBackup)
+				admin.POST("/backup/import", handler.ImportBackup)
+				admin.POST("/drain", handler.Drain)
 			}
 		}
 	}
 
+	if len(cfg.Routes.Disabled) > 0 {
+		log.Info().Strs("routes", cfg.Routes.Disabled).Msg("Routes disabled by configuration")
+	}
+	// 管理接口只在生产环境注册，其他环境下指向它们的配置项也会出现在这里
+	if unmatched := switches.unmatched(); len(unmatched) > 0 {
+		sort.Strings(unmatched)
+		log.Warn().Strs("routes", unmatched).Msg("Disabled routes do not match any registered route")
+	}
+
 	// 404处理
 	router.NoRoute(func(c *gin.Context) {
 		c.JSON(404, gin.H{
@@ -99,3 +295,52 @@ func registerRoutes(router *gin.Engine, handler *handler.JSONHandler, cfg config
 		})
 	})
 }
+
+// securityHeadersMiddleware 按security.headers配置构造安全响应头中间件，HSTS只在启用HTTPS时发送
+func securityHeadersMiddleware(cfg config.Config) gin.HandlerFunc {
+	headers := cfg.Security.Headers
+	hstsMaxAge := 0
+	if cfg.Security.EnableHTTPS {
+		hstsMaxAge = headers.HSTSMaxAge
+	}
+	return middleware.SecurityHeaders(middleware.SecurityHeadersOptions{
+		ContentTypeOptions:    headers.ContentTypeOptions,
+		FrameOptions:          headers.FrameOptions,
+		ReferrerPolicy:        headers.ReferrerPolicy,
+		HSTSMaxAge:            hstsMaxAge,
+		HSTSIncludeSubdomains: headers.HSTSIncludeSubdomains,
+	})
+}
+
+// corsMiddleware 按security配置构造CORS中间件
+// 开启凭证时cors_origins必须是具体来源（启动时校验），响应回显请求的Origin而不是 *
+func corsMiddleware(cfg config.Config) gin.HandlerFunc {
+	return cors.New(cors.Config{
+		AllowOrigins:     cfg.Security.CorsOrigins,
+		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		ExposeHeaders:    []string{"Content-Length"},
+		AllowCredentials: cfg.Security.CorsAllowCredentials,
+		MaxAge:           time.Duration(cfg.Security.CorsMaxAge) * time.Second,
+	})
+}
+
+// registerPprofRoutes 注册pprof性能分析路由（需要管理员认证）
+func registerPprofRoutes(router *gin.Engine, cfg config.Config) {
+	debug := router.Group("/debug/pprof")
+	debug.Use(middleware.BasicAuth(middleware.AdminAccounts(cfg.Security.AdminUsername, cfg.Security.AdminPassword)))
+	{
+		debug.GET("/", gin.WrapF(pprof.Index))
+		debug.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+		debug.GET("/profile", gin.WrapF(pprof.Profile))
+		debug.GET("/symbol", gin.WrapF(pprof.Symbol))
+		debug.POST("/symbol", gin.WrapF(pprof.Symbol))
+		debug.GET("/trace", gin.WrapF(pprof.Trace))
+		debug.GET("/allocs", gin.WrapH(pprof.Handler("allocs")))
+		debug.GET("/block", gin.WrapH(pprof.Handler("block")))
+		debug.GET("/goroutine", gin.WrapH(pprof.Handler("goroutine")))
+		debug.GET("/heap", gin.WrapH(pprof.Handler("heap")))
+		debug.GET("/mutex", gin.WrapH(pprof.Handler("mutex")))
+		debug.GET("/threadcreate", gin.WrapH(pprof.Handler("threadcreate")))
+	}
+}
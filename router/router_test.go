@@ -0,0 +1,48 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+)
+
+func TestPprofRequiresAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var cfg config.Config
+	cfg.Security.AdminUsername = "ops"
+	cfg.Security.AdminPassword = "s3cret"
+	engine := gin.New()
+	registerPprofRoutes(engine, cfg)
+
+	tests := []struct {
+		name     string
+		user     string
+		password string
+		want     int
+	}{
+		{name: "no credentials", want: http.StatusUnauthorized},
+		{name: "wrong password", user: "ops", password: "secret", want: http.StatusUnauthorized},
+		{name: "old hardcoded account", user: "admin", password: "secret", want: http.StatusUnauthorized},
+		{name: "configured account", user: "ops", password: "s3cret", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil)
+			if tt.user != "" {
+				req.SetBasicAuth(tt.user, tt.password)
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+			if tt.want == http.StatusOK && !strings.Contains(w.Body.String(), "goroutine") {
+				t.Errorf("profile index missing from body: %s", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+)
+
+func TestSecurityHeadersHSTS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// HSTS只在启用HTTPS时发送，其余头不受影响
+	for _, https := range []bool{false, true} {
+		var cfg config.Config
+		cfg.Security.EnableHTTPS = https
+		cfg.Security.Headers.ContentTypeOptions = "nosniff"
+		cfg.Security.Headers.HSTSMaxAge = 600
+		engine := gin.New()
+		engine.Use(securityHeadersMiddleware(cfg))
+		engine.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+		wantHSTS := ""
+		if https {
+			wantHSTS = "max-age=600"
+		}
+		if got := w.Header().Get("Strict-Transport-Security"); got != wantHSTS {
+			t.Errorf("enable_https=%v: Strict-Transport-Security = %q, want %q", https, got, wantHSTS)
+		}
+		if got := w.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+			t.Errorf("enable_https=%v: X-Content-Type-Options = %q, want nosniff", https, got)
+		}
+	}
+}
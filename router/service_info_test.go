@@ -0,0 +1,76 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// serviceInfoRouter 完整注册路由的引擎，服务信息接口不访问存储
+func serviceInfoRouter(enabled bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	cfg := config.Config{Environment: config.EnvTest}
+	cfg.Security.CorsOrigins = []string{"https://app.example.com"}
+	cfg.Server.ServiceInfo = enabled
+	return Init(cfg, struct{ database.JSONStore }{}, nil, nil)
+}
+
+func TestServiceInfo(t *testing.T) {
+	engine := serviceInfoRouter(true)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /: status = %d, want 200", w.Code)
+	}
+	var info model.ServiceInfoResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "json-store" || info.Version == "" {
+		t.Errorf("service info = %+v, want name json-store and a version", info)
+	}
+	if info.Links["health"] != "/health" || info.Links["api"] != "/api/v1" {
+		t.Errorf("links = %v", info.Links)
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /api/v1: status = %d, want 200", w.Code)
+	}
+	var resp model.EndpointsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	listed := make(map[model.Endpoint]bool)
+	for _, endpoint := range resp.Endpoints {
+		listed[endpoint] = true
+		if !strings.HasPrefix(endpoint.Path, "/api/v1/") {
+			t.Errorf("endpoint %s %s is outside /api/v1", endpoint.Method, endpoint.Path)
+		}
+	}
+	for _, want := range []model.Endpoint{{Method: http.MethodPost, Path: "/api/v1/json"}, {Method: http.MethodGet, Path: "/api/v1/json/:id"}} {
+		if !listed[want] {
+			t.Errorf("endpoint %s %s not listed", want.Method, want.Path)
+		}
+	}
+}
+
+func TestServiceInfoDisabled(t *testing.T) {
+	engine := serviceInfoRouter(false)
+	for _, path := range []string{"/", "/api/v1"} {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("GET %s with service_info off: status = %d, want 404", path, w.Code)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package router
+
+import (
+	"net/http"
+	"path"
+
+	"github.com/gin-gonic/gin"
+)
+
+// routeSwitch 按routes.disabled跳过关闭的接口，记录实际跳过的接口以便发现拼写错误
+type routeSwitch struct {
+	disabled map[string]bool
+	skipped  map[string]bool
+}
+
+func newRouteSwitch(disabled []string) *routeSwitch {
+	s := &routeSwitch{
+		disabled: make(map[string]bool, len(disabled)),
+		skipped:  make(map[string]bool),
+	}
+	for _, route := range disabled {
+		s.disabled[route] = true
+	}
+	return s
+}
+
+// group 包装路由组，之后通过它注册的接口和子路由组都按配置过滤
+func (s *routeSwitch) group(group *gin.RouterGroup) *routeGroup {
+	return &routeGroup{group: group, switches: s}
+}
+
+// unmatched 返回没有对应任何已注册接口的配置项
+func (s *routeSwitch) unmatched() []string {
+	var routes []string
+	for route := range s.disabled {
+		if !s.skipped[route] {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+// routeGroup 只提供注册接口和中间件的方法，所有注册都经过routeSwitch过滤
+// 不嵌入gin.RouterGroup，避免未覆盖的方法（如Static、Any）绕过routes.disabled
+type routeGroup struct {
+	group    *gin.RouterGroup
+	switches *routeSwitch
+}
+
+// anyMethods Any注册的方法，与gin一致，按方法逐个过滤
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodHead, http.MethodOptions, http.MethodDelete, http.MethodConnect,
+	http.MethodTrace,
+}
+
+func (g *routeGroup) Use(middleware ...gin.HandlerFunc) *routeGroup {
+	g.group.Use(middleware...)
+	return g
+}
+
+func (g *routeGroup) Group(relativePath string, handlers ...gin.HandlerFunc) *routeGroup {
+	return g.switches.group(g.group.Group(relativePath, handlers...))
+}
+
+func (g *routeGroup) BasePath() string {
+	return g.group.BasePath()
+}
+
+func (g *routeGroup) GET(relativePath string, handlers ...gin.HandlerFunc) *routeGroup {
+	return g.Handle(http.MethodGet, relativePath, handlers...)
+}
+
+func (g *routeGroup) POST(relativePath string, handlers ...gin.HandlerFunc) *routeGroup {
+	return g.Handle(http.MethodPost, relativePath, handlers...)
+}
+
+func (g *routeGroup) PUT(relativePath string, handlers ...gin.HandlerFunc) *routeGroup {
+	return g.Handle(http.MethodPut, relativePath, handlers...)
+}
+
+func (g *routeGroup) PATCH(relativePath string, handlers ...gin.HandlerFunc) *routeGroup {
+	return g.Handle(http.MethodPatch, relativePath, handlers...)
+}
+
+func (g *routeGroup) DELETE(relativePath string, handlers ...gin.HandlerFunc) *routeGroup {
+	return g.Handle(http.MethodDelete, relativePath, handlers...)
+}
+
+func (g *routeGroup) HEAD(relativePath string, handlers ...gin.HandlerFunc) *routeGroup {
+	return g.Handle(http.MethodHead, relativePath, handlers...)
+}
+
+func (g *routeGroup) OPTIONS(relativePath string, handlers ...gin.HandlerFunc) *routeGroup {
+	return g.Handle(http.MethodOptions, relativePath, handlers...)
+}
+
+// Any 注册全部方法，关闭的方法单独跳过
+func (g *routeGroup) Any(relativePath string, handlers ...gin.HandlerFunc) *routeGroup {
+	for _, method := range anyMethods {
+		g.Handle(method, relativePath, handlers...)
+	}
+	return g
+}
+
+// Handle 注册接口，"方法 完整路径"在routes.disabled中时跳过
+func (g *routeGroup) Handle(method, relativePath string, handlers ...gin.HandlerFunc) *routeGroup {
+	route := method + " " + path.Join(g.group.BasePath(), relativePath)
+	if g.switches.disabled[route] {
+		g.switches.skipped[route] = true
+		return g
+	}
+	g.group.Handle(method, relativePath, handlers...)
+	return g
+}
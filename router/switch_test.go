@@ -0,0 +1,62 @@
+package router
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRouteSwitch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	switches := newRouteSwitch([]string{
+		"GET /api/v1/json/:id",
+		"DELETE /api/v1/json/:id",
+		"PATCH /api/v1/json/:id",
+		"HEAD /api/v1/any",
+		"POST /api/admin/drain",
+		"GET /api/v1/misspelled",
+	})
+
+	noop := func(c *gin.Context) {}
+	root := switches.group(&engine.RouterGroup)
+	v1 := root.Group("/api").Group("/v1")
+	v1.GET("/json/:id", noop)
+	v1.POST("/json/:id", noop)
+	v1.PUT("/json/:id", noop)
+	v1.DELETE("/json/:id", noop)
+	v1.PATCH("/json/:id", noop)
+	v1.Handle("OPTIONS", "/json/:id", noop)
+	v1.Any("/any", noop)
+	root.Group("/api/admin").Use(noop).POST("/drain", noop).GET("/stats", noop)
+
+	var got []string
+	for _, route := range engine.Routes() {
+		got = append(got, route.Method+" "+route.Path)
+	}
+	sort.Strings(got)
+
+	want := []string{
+		"CONNECT /api/v1/any",
+		"DELETE /api/v1/any",
+		"GET /api/admin/stats",
+		"GET /api/v1/any",
+		"OPTIONS /api/v1/any",
+		"OPTIONS /api/v1/json/:id",
+		"PATCH /api/v1/any",
+		"POST /api/v1/any",
+		"POST /api/v1/json/:id",
+		"PUT /api/v1/any",
+		"PUT /api/v1/json/:id",
+		"TRACE /api/v1/any",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("registered routes = %v, want %v", got, want)
+	}
+
+	if unmatched := switches.unmatched(); !reflect.DeepEqual(unmatched, []string{"GET /api/v1/misspelled"}) {
+		t.Errorf("unmatched = %v, want [GET /api/v1/misspelled]", unmatched)
+	}
+}
@@ -0,0 +1,125 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/leapzhao/json-store/config"
+	"github.com/leapzhao/json-store/model"
+)
+
+// Client OpenSearch/Elasticsearch的最小REST客户端，只使用两者兼容的文档和搜索接口
+type Client struct {
+	baseURL  string
+	index    string
+	username string
+	password string
+	http     *http.Client
+}
+
+// NewClient 根据搜索配置创建客户端
+func NewClient(cfg config.Config) *Client {
+	return &Client{
+		baseURL:  strings.TrimRight(cfg.Search.URL, "/"),
+		index:    cfg.Search.Index,
+		username: cfg.Search.Username,
+		password: cfg.Search.Password,
+		http:     &http.Client{Timeout: time.Duration(cfg.Search.Timeout) * time.Second},
+	}
+}
+
+// indexedDocument 写入索引的文档
+// 内容按文本保存，避免不同文档的同名字段类型不一致导致动态映射冲突
+type indexedDocument struct {
+	DocType     string    `json:"doc_type,omitempty"`
+	ContentHash string    `json:"content_hash"`
+	Content     string    `json:"content"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// IndexDocument 写入或覆盖文档，文档ID与存储中的ID一致
+func (c *Client) IndexDocument(ctx context.Context, doc *model.JSONDocument) error {
+	body, err := json.Marshal(indexedDocument{
+		DocType:     doc.DocType,
+		ContentHash: doc.ContentHash,
+		Content:     string(doc.JSONData),
+		UpdatedAt:   doc.UpdatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode document: %w", err)
+	}
+
+	return c.do(ctx, http.MethodPut, "/"+url.PathEscape(c.index)+"/_doc/"+url.PathEscape(doc.ID), body, nil)
+}
+
+// Search 全文搜索文档内容，返回匹配的文档ID，按相关度排序
+func (c *Client) Search(ctx context.Context, query string, limit int) ([]string, error) {
+	body, err := json.Marshal(map[string]any{
+		"query": map[string]any{
+			"simple_query_string": map[string]any{
+				"query":  query,
+				"fields": []string{"content"},
+			},
+		},
+		"size":    limit,
+		"_source": false,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode query: %w", err)
+	}
+
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				ID string `json:"_id"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/"+url.PathEscape(c.index)+"/_search", body, &result); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(result.Hits.Hits))
+	for _, hit := range result.Hits.Hits {
+		ids = append(ids, hit.ID)
+	}
+	return ids, nil
+}
+
+// do 发送请求，非2xx响应返回带状态码和响应片段的错误；out不为nil时解码响应体
+func (c *Client) do(ctx context.Context, method, path string, body []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("search request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("search request %s %s returned %d: %s", method, path, resp.StatusCode, snippet)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode search response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package search
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/leapzhao/json-store/model"
+
+	"github.com/rs/zerolog/log"
+)
+
+// documentIndexer 写入索引的目标，便于替换为测试桩
+type documentIndexer interface {
+	IndexDocument(ctx context.Context, doc *model.JSONDocument) error
+}
+
+// Indexer 异步把文档同步到搜索索引
+// 写入路径只把文档放入队列，由后台协程发送；索引服务不可用时按指数退避重试，
+// 超过重试次数或队列已满时丢弃并记录日志，不影响存储本身
+type Indexer struct {
+	target     documentIndexer
+	queue      chan *model.JSONDocument
+	maxRetries int
+	baseDelay  time.Duration
+	stop       chan struct{}
+	done       sync.WaitGroup
+	// ctx 发送请求使用，Close等待超时后取消，中断进行中的请求
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewIndexer 创建同步器并启动后台协程
+func NewIndexer(target documentIndexer, queueSize, maxRetries int) *Indexer {
+	ix := &Indexer{
+		target:     target,
+		queue:      make(chan *model.JSONDocument, queueSize),
+		maxRetries: maxRetries,
+		baseDelay:  500 * time.Millisecond,
+		stop:       make(chan struct{}),
+	}
+	ix.ctx, ix.cancel = context.WithCancel(context.Background())
+
+	ix.done.Add(1)
+	go ix.run()
+
+	return ix
+}
+
+// Enqueue 把文档放入同步队列，队列已满时丢弃
+func (ix *Indexer) Enqueue(doc *model.JSONDocument) {
+	select {
+	case ix.queue <- doc:
+	default:
+		log.Warn().Str("id", doc.ID).Msg("Search index queue full, document not indexed")
+	}
+}
+
+// Close 通知后台协程发送完队列中的文档后退出，最多等待到ctx到期
+// 关闭后失败的文档不再重试，超时后中断进行中的请求
+func (ix *Indexer) Close(ctx context.Context) {
+	close(ix.stop)
+	defer ix.cancel()
+
+	finished := make(chan struct{})
+	go func() {
+		ix.done.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		log.Warn().Int("pending", len(ix.queue)).Msg("Search indexer stopped before queue drained")
+	}
+}
+
+func (ix *Indexer) run() {
+	defer ix.done.Done()
+
+	for {
+		select {
+		case doc := <-ix.queue:
+			ix.index(doc)
+		case <-ix.stop:
+			// 关闭时发送完队列中剩余的文档
+			for {
+				select {
+				case doc := <-ix.queue:
+					ix.index(doc)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// index 发送单个文档，失败时按指数退避重试；等待重试期间开始关闭时放弃该文档
+func (ix *Indexer) index(doc *model.JSONDocument) {
+	delay := ix.baseDelay
+	for attempt := 0; ; attempt++ {
+		err := ix.target.IndexDocument(ix.ctx, doc)
+		if err == nil {
+			return
+		}
+
+		if attempt >= ix.maxRetries {
+			log.Error().Err(err).Str("id", doc.ID).Int("attempts", attempt+1).Msg("Failed to index document, giving up")
+			return
+		}
+
+		log.Warn().Err(err).Str("id", doc.ID).Dur("retry_in", delay).Msg("Failed to index document, retrying")
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ix.stop:
+			timer.Stop()
+			log.Error().Err(err).Str("id", doc.ID).Int("attempts", attempt+1).Msg("Failed to index document, shutting down")
+			return
+		}
+		delay *= 2
+	}
+}
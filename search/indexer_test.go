@@ -0,0 +1,44 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/leapzhao/json-store/model"
+)
+
+// failingIndexer 总是返回错误的索引目标，记录调用次数
+type failingIndexer struct {
+	calls atomic.Int32
+}
+
+func (f *failingIndexer) IndexDocument(ctx context.Context, doc *model.JSONDocument) error {
+	f.calls.Add(1)
+	return errors.New("unavailable")
+}
+
+func TestIndexerCloseDuringBackoff(t *testing.T) {
+	target := &failingIndexer{}
+	ix := NewIndexer(target, 10, 5)
+	ix.baseDelay = time.Hour
+
+	ix.Enqueue(&model.JSONDocument{ID: "a"})
+	for target.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	ix.Close(ctx)
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Close took %v, want it to interrupt the retry backoff", elapsed)
+	}
+	if calls := target.calls.Load(); calls != 1 {
+		t.Errorf("IndexDocument called %d times, want 1", calls)
+	}
+}
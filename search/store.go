@@ -0,0 +1,80 @@
+package search
+
+import (
+	"context"
+
+	"github.com/leapzhao/json-store/database"
+	"github.com/leapzhao/json-store/model"
+)
+
+// indexingStore 在写入成功后把新文档和更新后的文档放入搜索索引同步队列
+// 命中去重的文档已经在索引中，不重复同步；其他方法直接委托给底层存储
+// 存储没有删除文档的接口，因此只同步写入；以后加入删除时需同时从索引中删除
+type indexingStore struct {
+	database.JSONStore
+	indexer *Indexer
+}
+
+// NewIndexingStore 包装存储，写入的文档异步同步到搜索索引
+func NewIndexingStore(store database.JSONStore, indexer *Indexer) database.JSONStore {
+	return &indexingStore{JSONStore: store, indexer: indexer}
+}
+
+// Unwrap 返回被包装的存储
+func (s *indexingStore) Unwrap() database.JSONStore {
+	return s.JSONStore
+}
+
+func (s *indexingStore) StoreJSON(ctx context.Context, input model.StoreInput) (*model.JSONDocument, error) {
+	doc, err := s.JSONStore.StoreJSON(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	s.enqueue(doc)
+	return doc, nil
+}
+
+func (s *indexingStore) StoreJSONBatch(ctx context.Context, inputs []model.StoreInput) ([]*model.JSONDocument, error) {
+	docs, err := s.JSONStore.StoreJSONBatch(ctx, inputs)
+	if err != nil {
+		return nil, err
+	}
+	s.enqueue(docs...)
+	return docs, nil
+}
+
+func (s *indexingStore) StoreJSONTransaction(ctx context.Context, inputs []model.StoreInput) ([]*model.JSONDocument, error) {
+	docs, err := s.JSONStore.StoreJSONTransaction(ctx, inputs)
+	if err != nil {
+		return nil, err
+	}
+	s.enqueue(docs...)
+	return docs, nil
+}
+
+func (s *indexingStore) UpdateJSON(ctx context.Context, id string, input model.StoreInput) (*model.JSONDocument, error) {
+	doc, err := s.JSONStore.UpdateJSON(ctx, id, input)
+	if err != nil {
+		return nil, err
+	}
+	s.enqueue(doc)
+	return doc, nil
+}
+
+// StoreByLabel 底层存储在内部调用自身的StoreJSON，不经过包装，需在这里同步
+func (s *indexingStore) StoreByLabel(ctx context.Context, label string, input model.StoreInput) (*model.LabeledDocument, error) {
+	result, err := s.JSONStore.StoreByLabel(ctx, label, input)
+	if err != nil {
+		return nil, err
+	}
+	s.enqueue(result.Document)
+	return result, nil
+}
+
+func (s *indexingStore) enqueue(docs ...*model.JSONDocument) {
+	for _, doc := range docs {
+		if !doc.Existing {
+			s.indexer.Enqueue(doc)
+		}
+	}
+}
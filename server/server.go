@@ -3,7 +3,10 @@ package server
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/leapzhao/json-store/config"
@@ -15,27 +18,59 @@ import (
 type Server struct {
 	httpServer *http.Server
 	config     config.Config
-	router     *gin.Engine
+	// handler 业务路由，就绪前为nil，所有请求返回503
+	handler atomic.Pointer[gin.Engine]
 }
 
-// New 创建HTTP服务器
-func New(cfg config.Config, router *gin.Engine) *Server {
-	return &Server{
+// New 创建HTTP服务器，调用SetHandler之前服务处于未就绪状态
+func New(cfg config.Config) *Server {
+	s := &Server{
 		config: cfg,
-		router: router,
 	}
+
+	s.httpServer = &http.Server{
+		Addr:         fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port),
+		Handler:      s,
+		ReadTimeout:  time.Duration(cfg.Server.ReadTimeout) * time.Second,
+		WriteTimeout: time.Duration(cfg.Server.WriteTimeout) * time.Second,
+		IdleTimeout:  time.Duration(cfg.Server.IdleTimeout) * time.Second,
+	}
+
+	return s
+}
+
+// SetHandler 设置业务路由并标记服务就绪
+func (s *Server) SetHandler(router *gin.Engine) {
+	s.handler.Store(router)
+	log.Info().Msg("HTTP server is ready to serve traffic")
+}
+
+// IsReady 服务是否已就绪
+func (s *Server) IsReady() bool {
+	return s.handler.Load() != nil
+}
+
+// ServeHTTP 就绪前拒绝所有请求，避免负载均衡将流量路由到未完成迁移的实例
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	router := s.handler.Load()
+	if router == nil {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Retry-After", "5")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"error":"SERVICE_NOT_READY","message":"Service is starting up"}`))
+		return
+	}
+
+	router.ServeHTTP(w, r)
 }
 
 // Start 启动HTTP服务器
 func (s *Server) Start() error {
-	addr := fmt.Sprintf("%s:%s", s.config.Server.Host, s.config.Server.Port)
+	addr := s.httpServer.Addr
 
-	s.httpServer = &http.Server{
-		Addr:         addr,
-		Handler:      s.router,
-		ReadTimeout:  time.Duration(s.config.Server.ReadTimeout) * time.Second,
-		WriteTimeout: time.Duration(s.config.Server.WriteTimeout) * time.Second,
-		IdleTimeout:  time.Duration(s.config.Server.IdleTimeout) * time.Second,
+	// 配置了Unix套接字时不监听TCP
+	if s.config.Server.UnixSocket != "" {
+		return s.startUnix()
 	}
 
 	log.Info().
@@ -59,6 +94,63 @@ func (s *Server) startHTTP() error {
 	return nil
 }
 
+// startUnix 在Unix域套接字上启动服务器
+func (s *Server) startUnix() error {
+	path := s.config.Server.UnixSocket
+
+	if err := removeStaleSocket(path); err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("failed to listen on unix socket %s: %w", path, err)
+	}
+
+	log.Info().
+		Str("socket", path).
+		Str("environment", string(s.config.Environment)).
+		Msg("Starting HTTP server on unix socket")
+
+	if s.config.Security.EnableHTTPS {
+		err = s.httpServer.ServeTLS(listener, s.config.Security.CertFile, s.config.Security.KeyFile)
+	} else {
+		err = s.httpServer.Serve(listener)
+	}
+
+	if err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("failed to serve on unix socket: %w", err)
+	}
+	return nil
+}
+
+// removeStaleSocket 清理上次异常退出遗留的套接字文件
+func removeStaleSocket(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat unix socket %s: %w", path, err)
+	}
+
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("unix socket path %s exists and is not a socket", path)
+	}
+
+	// 能连接说明仍有进程在监听，不能删除
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("unix socket %s is already in use", path)
+	}
+
+	log.Warn().Str("socket", path).Msg("Removing stale unix socket")
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove stale unix socket %s: %w", path, err)
+	}
+	return nil
+}
+
 // startHTTPS 启动HTTPS服务器
 func (s *Server) startHTTPS() error {
 	if s.config.Security.CertFile == "" || s.config.Security.KeyFile == "" {
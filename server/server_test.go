@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leapzhao/json-store/config"
+)
+
+func TestStartUnixSocket(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	path := filepath.Join(t.TempDir(), "json-store.sock")
+
+	// 上次异常退出遗留的套接字文件：已无进程监听
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	var cfg config.Config
+	cfg.Server.UnixSocket = path
+	s := New(cfg)
+	router := gin.New()
+	router.GET("/health", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"status": "healthy"}) })
+	s.SetHandler(router)
+
+	done := make(chan error, 1)
+	go func() { done <- s.Start() }()
+	t.Cleanup(func() {
+		s.Shutdown(context.Background())
+		if err := <-done; err != nil {
+			t.Errorf("Start() error = %v", err)
+		}
+	})
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	var resp *http.Response
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		resp, err = client.Get("http://unix/health")
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+	}
+	if err != nil {
+		t.Fatalf("GET /health over unix socket: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != `{"status":"healthy"}` {
+		t.Errorf("response = %d %s, want 200 {\"status\":\"healthy\"}", resp.StatusCode, body)
+	}
+}
+
+func TestRemoveStaleSocketInUse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "busy.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	// 仍有进程监听时不能删除
+	if err := removeStaleSocket(path); err == nil {
+		t.Error("removeStaleSocket succeeded on a socket in use")
+	}
+}
+
+func TestServeHTTPBeforeReady(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s := New(config.Config{})
+	router := gin.New()
+	router.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	// 迁移和首次健康检查完成前，所有路由都返回503
+	for _, path := range []string{"/health", "/api/v1/json/1"} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+			t.Errorf("%s before ready = %d (Retry-After %q), want 503 with Retry-After", path, w.Code, w.Header().Get("Retry-After"))
+		}
+	}
+	if s.IsReady() {
+		t.Error("IsReady() = true before SetHandler")
+	}
+
+	s.SetHandler(router)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("/health after ready = %d, want 200", w.Code)
+	}
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// ControlCharError JSON字符串（键或值）中含有不允许的控制字符
+type ControlCharError struct {
+	// Char 控制字符
+	Char rune
+	// Offset 转义序列在文档中的字节偏移
+	Offset int
+}
+
+func (e *ControlCharError) Error() string {
+	return fmt.Sprintf("string contains control character U+%04X at byte offset %d", e.Char, e.Offset)
+}
+
+// controlEscape 表示控制字符的转义序列，[start, end)为其在文档中的位置
+type controlEscape struct {
+	start, end int
+	char       rune
+}
+
+// FindControlChar 返回data的字符串中第一个控制字符，没有时返回nil
+// 控制字符指U+0000至U+001F中除\t、\n、\r以外的字符，nullOnly为true时只检查U+0000
+// 合法JSON的字符串中控制字符只能以转义形式出现；data不是合法JSON时返回nil，由后续校验处理
+func FindControlChar(data []byte, nullOnly bool) *ControlCharError {
+	escapes := controlEscapes(data, nullOnly, true)
+	if len(escapes) == 0 {
+		return nil
+	}
+	return &ControlCharError{Char: escapes[0].char, Offset: escapes[0].start}
+}
+
+// StripControlChars 删除data的字符串中的控制字符（范围同FindControlChar），其余字节保持不变
+// 没有控制字符时原样返回data；删除后键名可能重复，与解码时一致取最后一个
+func StripControlChars(data []byte, nullOnly bool) []byte {
+	escapes := controlEscapes(data, nullOnly, false)
+	if len(escapes) == 0 {
+		return data
+	}
+
+	stripped := make([]byte, 0, len(data))
+	last := 0
+	for _, e := range escapes {
+		stripped = append(stripped, data[last:e.start]...)
+		last = e.end
+	}
+	return append(stripped, data[last:]...)
+}
+
+// controlEscapes 逐字节扫描字符串中的转义序列，找出表示控制字符的部分；first为true时找到一个即返回
+func controlEscapes(data []byte, nullOnly, first bool) []controlEscape {
+	// 没有\u00、\b、\f时不可能含控制字符，跳过扫描和JSON校验
+	if !bytes.Contains(data, []byte(`\u00`)) &&
+		(nullOnly || (!bytes.Contains(data, []byte(`\b`)) && !bytes.Contains(data, []byte(`\f`)))) {
+		return nil
+	}
+	if !ValidateJSON(data) {
+		return nil
+	}
+
+	var escapes []controlEscape
+	inString := false
+	for i := 0; i < len(data); i++ {
+		switch {
+		case !inString:
+			inString = data[i] == '"'
+		case data[i] == '"':
+			inString = false
+		case data[i] == '\\':
+			if i+1 >= len(data) {
+				return escapes
+			}
+			char, end := rune(-1), i+2
+			switch data[i+1] {
+			case 'b':
+				char = '\b'
+			case 'f':
+				char = '\f'
+			case 'u':
+				end = i + 6
+				if end > len(data) {
+					return escapes
+				}
+				if value, err := strconv.ParseUint(string(data[i+2:end]), 16, 16); err == nil {
+					char = rune(value)
+				}
+			}
+			if isControlChar(char, nullOnly) {
+				escapes = append(escapes, controlEscape{start: i, end: end, char: char})
+				if first {
+					return escapes
+				}
+			}
+			i = end - 1
+		}
+	}
+	return escapes
+}
+
+// isControlChar \t、\n、\r是常见的文本内容，不视为控制字符
+func isControlChar(char rune, nullOnly bool) bool {
+	if nullOnly {
+		return char == 0
+	}
+	return char >= 0 && char < 0x20 && char != '\t' && char != '\n' && char != '\r'
+}
@@ -0,0 +1,59 @@
+package utils
+
+import "testing"
+
+func TestFindControlChar(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		nullOnly   bool
+		wantChar   rune
+		wantOffset int
+		wantNone   bool
+	}{
+		{name: "null in value", data: `{"a":"x\u0000"}`, nullOnly: true, wantChar: 0, wantOffset: 7},
+		{name: "null in key", data: `{"\u0000":1}`, nullOnly: true, wantChar: 0, wantOffset: 2},
+		// 只检查U+0000时其余控制字符照常通过
+		{name: "other control with nullOnly", data: `{"a":"\u0001\b"}`, nullOnly: true, wantNone: true},
+		{name: "unicode control", data: `{"a":"\u001F"}`, wantChar: 0x1f, wantOffset: 6},
+		{name: "short escape", data: `{"a":"x\f"}`, wantChar: '\f', wantOffset: 7},
+		{name: "tab newline allowed", data: `{"a":"\t\n\r\u0009"}`, wantNone: true},
+		// 转义的反斜杠后面的u0000是普通文本
+		{name: "escaped backslash", data: `{"a":"\\u0000"}`, wantNone: true},
+		{name: "outside strings", data: `[1, 2]`, wantNone: true},
+		{name: "invalid JSON", data: `{"a":"\u0000"`, nullOnly: true, wantNone: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := FindControlChar([]byte(tt.data), tt.nullOnly)
+			if tt.wantNone {
+				if err != nil {
+					t.Errorf("FindControlChar(%s) = %v, want nil", tt.data, err)
+				}
+				return
+			}
+			if err == nil || err.Char != tt.wantChar || err.Offset != tt.wantOffset {
+				t.Errorf("FindControlChar(%s) = %v, want U+%04X at %d", tt.data, err, tt.wantChar, tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestStripControlChars(t *testing.T) {
+	tests := []struct {
+		data     string
+		nullOnly bool
+		want     string
+	}{
+		{data: `{"a\u0000":"x\u0000y"}`, nullOnly: true, want: `{"a":"xy"}`},
+		{data: `{"a":"\u0001\b\u0000"}`, nullOnly: true, want: `{"a":"\u0001\b"}`},
+		{data: `{"a":"\u0001x\by\u0000\t"}`, want: `{"a":"xy\t"}`},
+		// 其余字节（包括空白和键顺序）保持不变
+		{data: `{ "b" : "\\u0000", "a" : 1 }`, want: `{ "b" : "\\u0000", "a" : 1 }`},
+	}
+	for _, tt := range tests {
+		if got := string(StripControlChars([]byte(tt.data), tt.nullOnly)); got != tt.want {
+			t.Errorf("StripControlChars(%s, %v) = %s, want %s", tt.data, tt.nullOnly, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+)
+
+// FlattenJSON 把JSON展开为“路径 -> 标量值”的平铺映射，如 {"a":{"b":[1]}} 展开为 {"a.b.0": 1}
+// 数组元素使用下标作为路径段，separator为路径分隔符；数字按原始文本保留（json.Number）
+// 空对象和空数组保留为 {} 和 []，避免展开后丢失；顶层为标量时路径为空字符串
+// 键名本身包含分隔符时展开结果存在歧义，调用方可换用不会出现在键名中的分隔符
+func FlattenJSON(data []byte, separator string) (map[string]any, error) {
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]any)
+	flattenValue("", value, separator, result)
+	return result, nil
+}
+
+func flattenValue(path string, value any, separator string, result map[string]any) {
+	switch v := value.(type) {
+	case map[string]any:
+		if len(v) == 0 {
+			result[path] = map[string]any{}
+			return
+		}
+		for key, child := range v {
+			flattenValue(joinPath(path, key, separator), child, separator, result)
+		}
+	case []any:
+		if len(v) == 0 {
+			result[path] = []any{}
+			return
+		}
+		for i, child := range v {
+			flattenValue(joinPath(path, strconv.Itoa(i), separator), child, separator, result)
+		}
+	default:
+		result[path] = v
+	}
+}
+
+// joinPath 拼接路径段，顶层不加分隔符
+func joinPath(path, segment, separator string) string {
+	if path == "" {
+		return segment
+	}
+	return path + separator + segment
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestFlattenJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		separator string
+		want      string
+	}{
+		{name: "nested objects", data: `{"a":{"b":{"c":1}},"d":"x"}`, separator: ".", want: `{"a.b.c":1,"d":"x"}`},
+		{name: "arrays", data: `{"a":[10,[20,30]]}`, separator: ".", want: `{"a.0":10,"a.1.0":20,"a.1.1":30}`},
+		{name: "mixed", data: `{"users":[{"name":"x","tags":["t"]},{"name":null,"ok":true}]}`, separator: ".",
+			want: `{"users.0.name":"x","users.0.tags.0":"t","users.1.name":null,"users.1.ok":true}`},
+		// 空容器保留，不会在展开后消失
+		{name: "empty containers", data: `{"a":{},"b":[],"c":{"d":[]}}`, separator: ".", want: `{"a":{},"b":[],"c.d":[]}`},
+		{name: "custom separator", data: `{"a":{"b":[1]}}`, separator: "/", want: `{"a/b/0":1}`},
+		{name: "top-level scalar", data: `42`, separator: ".", want: `{"":42}`},
+		// 大整数按原始文本保留
+		{name: "large number", data: `{"id":9223372036854775807}`, separator: ".", want: `{"id":9223372036854775807}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flat, err := FlattenJSON([]byte(tt.data), tt.separator)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := json.Marshal(flat)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("FlattenJSON(%s) = %s, want %s", tt.data, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := FlattenJSON([]byte(`{"a":`), "."); err == nil {
+		t.Error("FlattenJSON accepted invalid JSON")
+	}
+}
+
+func TestFlattenJSONDeep(t *testing.T) {
+	const depth = 500
+	data := strings.Repeat(`{"k":`, depth) + `1` + strings.Repeat(`}`, depth)
+	flat, err := FlattenJSON([]byte(data), ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.TrimSuffix(strings.Repeat("k.", depth), ".")
+	if len(flat) != 1 || flat[want] == nil {
+		t.Errorf("deeply nested document flattened to %d paths, want the single path of depth %d", len(flat), depth)
+	}
+}
@@ -1,28 +1,61 @@
 package utils
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"strings"
 )
 
 // NormalizeJSON 规范化JSON（排序键名、去除空格）
+// 数字按原始文本保留（UseNumber），避免大于2^53的整数经float64转换后丢失精度
 func NormalizeJSON(data []byte) ([]byte, error) {
+	return normalizeJSON(data, MarshalJSON)
+}
+
+// NormalizeJSONNoEscape 与NormalizeJSON相同但不转义 <、>、&，用于输出和store_normalized存储；内容哈希使用NormalizeForHash
+func NormalizeJSONNoEscape(data []byte) ([]byte, error) {
+	return normalizeJSON(data, marshalJSONNoEscape)
+}
+
+// normalizeJSON 解码后用marshal重新编码
+func normalizeJSON(data []byte, marshal func(any) ([]byte, error)) ([]byte, error) {
 	// 尝试解码为通用类型
 	var obj interface{}
-	if err := json.Unmarshal(data, &obj); err != nil {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&obj); err != nil {
 		return nil, err
 	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid JSON: unexpected data after top-level value")
+	}
 
 	// 重新编码，确保键名排序一致
+	return marshal(obj)
+}
+
+// NormalizeForHash 计算内容哈希使用的规范化形式，转义 <、>、&
+// exactNumbers为false时数字经float64转换，超过2^53的相邻整数规范化结果相同，与引入exact之前存储的哈希一致；
+// 为true时数字按原始文本保留，同一文档在两种方式下的哈希可能不同
+func NormalizeForHash(data []byte, exactNumbers bool) ([]byte, error) {
+	if exactNumbers {
+		return NormalizeJSON(data)
+	}
+
+	var obj interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
 	return json.Marshal(obj)
 }
 
-// CalculateHash 计算JSON哈希值
-func CalculateHash(data []byte) (string, error) {
-	normalized, err := NormalizeJSON(data)
+// CalculateHash 计算JSON哈希值，exactNumbers见NormalizeForHash
+func CalculateHash(data []byte, exactNumbers bool) (string, error) {
+	normalized, err := NormalizeForHash(data, exactNumbers)
 	if err != nil {
 		// 如果无法规范化，使用原始数据
 		normalized = data
@@ -32,7 +65,71 @@ func CalculateHash(data []byte) (string, error) {
 	return hex.EncodeToString(hash[:]), nil
 }
 
-// ValidateJSON 验证JSON格式
+// CalculateHashStrict 与CalculateHash相同，但无法规范化时返回错误而不是按原始字节计算
+func CalculateHashStrict(data []byte, exactNumbers bool) (string, error) {
+	normalized, err := NormalizeForHash(data, exactNumbers)
+	if err != nil {
+		return "", fmt.Errorf("failed to normalize JSON: %w", err)
+	}
+
+	hash := sha256.Sum256(normalized)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// ValidateMaxElements 逐token遍历JSON统计值的总数，超过limit时返回错误
+// 对象、数组和标量各计为一个值，对象的键不计；limit<=0表示不限制
+// 用于拒绝字节数不大但元素极多、处理代价很高的文档（如百万个小元素的数组）
+func ValidateMaxElements(data []byte, limit int) error {
+	if limit <= 0 {
+		return nil
+	}
+
+	type frame struct {
+		object    bool
+		expectKey bool
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var stack []frame
+	count := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		delim, isDelim := token.(json.Delim)
+		if isDelim && (delim == ']' || delim == '}') {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		// 对象内键和值交替出现，键不计数
+		if n := len(stack); n > 0 && stack[n-1].object {
+			if stack[n-1].expectKey {
+				stack[n-1].expectKey = false
+				continue
+			}
+			stack[n-1].expectKey = true
+		}
+
+		count++
+		if count > limit {
+			return fmt.Errorf("document exceeds the maximum of %d elements", limit)
+		}
+
+		if isDelim {
+			stack = append(stack, frame{object: delim == '{', expectKey: delim == '{'})
+		}
+	}
+}
+
+// ValidateJSON 验证JSON格式，总是使用encoding/json，接受的文档不随构建标签变化
 func ValidateJSON(data []byte) bool {
 	return json.Valid(data)
 }
@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestNormalizeJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "sorts keys", input: `{"b":1,"a":2}`, want: `{"a":2,"b":1}`},
+		{name: "removes whitespace", input: " {\n\t\"a\" : [ 1 , 2 ]\n} ", want: `{"a":[1,2]}`},
+		{name: "sorts nested keys", input: `{"x":{"z":true,"y":null}}`, want: `{"x":{"y":null,"z":true}}`},
+		{name: "keeps large integer", input: `{"id": 9223372036854775807}`, want: `{"id":9223372036854775807}`},
+		{name: "keeps integer above 2^53", input: `[9007199254740993]`, want: `[9007199254740993]`},
+		{name: "keeps number text", input: `[1.0, 1e2, -0]`, want: `[1.0,1e2,-0]`},
+		{name: "escapes html", input: `{"a":"<&>"}`, want: `{"a":"\u003c\u0026\u003e"}`},
+		{name: "scalar", input: `"text"`, want: `"text"`},
+		{name: "invalid", input: `{"a":`, wantErr: true},
+		{name: "trailing data", input: `{} {}`, wantErr: true},
+		{name: "empty", input: ``, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeJSON([]byte(tt.input))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("NormalizeJSON(%q) = %s, want error", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NormalizeJSON(%q) error: %v", tt.input, err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("NormalizeJSON(%q) = %s, want %s", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeJSONNoEscape(t *testing.T) {
+	got, err := NormalizeJSONNoEscape([]byte(`{"b":"<&>","a":9223372036854775807}`))
+	if err != nil {
+		t.Fatalf("NormalizeJSONNoEscape error: %v", err)
+	}
+	if want := `{"a":9223372036854775807,"b":"<&>"}`; string(got) != want {
+		t.Errorf("NormalizeJSONNoEscape = %s, want %s", got, want)
+	}
+}
+
+func TestCalculateHash(t *testing.T) {
+	for _, exact := range []bool{false, true} {
+		a, _ := CalculateHash([]byte(`{"a":1,"b":[1,2]}`), exact)
+		b, _ := CalculateHash([]byte(`{ "b": [1, 2], "a": 1 }`), exact)
+		if a != b {
+			t.Errorf("exact=%v: hashes of equivalent documents differ: %s != %s", exact, a, b)
+		}
+
+		// 无法规范化时按原始字节计算
+		if _, err := CalculateHashStrict([]byte(`{"a":`), exact); err == nil {
+			t.Errorf("exact=%v: CalculateHashStrict accepted invalid JSON", exact)
+		}
+		if hash, err := CalculateHash([]byte(`{"a":`), exact); err != nil || hash == "" {
+			t.Errorf("exact=%v: CalculateHash(invalid) = %q, %v, want raw byte hash", exact, hash, err)
+		}
+	}
+}
+
+func TestCalculateHashNumbers(t *testing.T) {
+	c, _ := CalculateHash([]byte(`{"id":9007199254740993}`), false)
+	d, _ := CalculateHash([]byte(`{"id":9007199254740992}`), false)
+	// 默认按float64规范化，已存储的哈希保持不变
+	if c != d {
+		t.Errorf("float64 hashes of integers above 2^53 differ: %s != %s", c, d)
+	}
+	// 与引入exact之前的规范化结果一致
+	legacy := sha256.Sum256([]byte(`{"id":9007199254740992}`))
+	if want := hex.EncodeToString(legacy[:]); c != want {
+		t.Errorf("float64 hash = %s, want %s", c, want)
+	}
+
+	// exact时超过2^53的整数不能因float64转换而与相邻整数哈希相同
+	e, _ := CalculateHash([]byte(`{"id":9007199254740993}`), true)
+	f, _ := CalculateHash([]byte(`{"id":9007199254740992}`), true)
+	if e == f {
+		t.Errorf("exact hashes of different large integers are equal: %s", e)
+	}
+}
+
+func TestValidateMaxElements(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		limit   int
+		wantErr bool
+	}{
+		// 数组本身计1，三个元素各计1
+		{name: "flat array at limit", data: `[1,2,3]`, limit: 4},
+		{name: "flat array beyond limit", data: `[1,2,3,4]`, limit: 4, wantErr: true},
+		// 对象的键不计数
+		{name: "object keys not counted", data: `{"a":1,"b":2}`, limit: 3},
+		{name: "nested values counted", data: `{"a":[1,{"b":null}]}`, limit: 4, wantErr: true},
+		{name: "nested at limit", data: `{"a":[1,{"b":null}]}`, limit: 5},
+		{name: "disabled", data: `[1,2,3,4,5,6,7,8]`, limit: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMaxElements([]byte(tt.data), tt.limit)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateMaxElements(%s, %d) = %v, wantErr %v", tt.data, tt.limit, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCalculateHashUnnormalizable(t *testing.T) {
+	// 合法的JSON，但数字超出float64范围，按float64规范化失败
+	data := []byte(`{"a": 1e400}`)
+	raw := sha256.Sum256(data)
+
+	hash, err := CalculateHash(data, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != hex.EncodeToString(raw[:]) {
+		t.Errorf("CalculateHash = %s, want the raw bytes hash", hash)
+	}
+	if _, err := CalculateHashStrict(data, false); err == nil {
+		t.Error("CalculateHashStrict succeeded, want normalization error")
+	}
+	// 按原始文本保留数字时可以规范化
+	if _, err := CalculateHashStrict(data, true); err != nil {
+		t.Errorf("CalculateHashStrict with exact numbers: %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	stdjson "encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	gojson "github.com/goccy/go-json"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// benchmarkCodecs 参与比较的编解码实现，与构建标签无关，三者在同一次运行中对比：
+//
+//	go test ./utils -run '^$' -bench Codec -benchmem
+var benchmarkCodecs = []struct {
+	name      string
+	marshal   func(any) ([]byte, error)
+	unmarshal func([]byte, any) error
+	valid     func([]byte) bool
+}{
+	{"encoding/json", stdjson.Marshal, stdjson.Unmarshal, stdjson.Valid},
+	{"jsoniter", jsoniter.ConfigCompatibleWithStandardLibrary.Marshal, jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal, jsoniter.ConfigCompatibleWithStandardLibrary.Valid},
+	{"go-json", gojson.Marshal, gojson.Unmarshal, gojson.Valid},
+}
+
+// benchmarkPayload 有代表性的文档：约40KB，200条记录，包含大整数、嵌套对象、数组和需要转义的字符串
+func benchmarkPayload() []byte {
+	var b strings.Builder
+	b.WriteString(`{"source":"bench","generated_at":"2024-01-01T00:00:00Z","items":[`)
+	for i := 0; i < 200; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `{"id":%d,"name":"item-%d","price":%d.%02d,"active":%t,`+
+			`"tags":["a","b","tag-%d"],"owner":{"id":%d,"email":"user%d@example.com"},`+
+			`"note":"line1\nline2 <b>&</b> \"quoted\"","extra":null}`,
+			9007199254740993+i, i, i*3, i%100, i%2 == 0, i%7, i%50, i%50)
+	}
+	b.WriteString(`]}`)
+	return []byte(b.String())
+}
+
+func BenchmarkCodecUnmarshal(b *testing.B) {
+	data := benchmarkPayload()
+	for _, codec := range benchmarkCodecs {
+		b.Run(codec.name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var v any
+				if err := codec.unmarshal(data, &v); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCodecMarshal(b *testing.B) {
+	data := benchmarkPayload()
+	var v any
+	if err := stdjson.Unmarshal(data, &v); err != nil {
+		b.Fatal(err)
+	}
+	for _, codec := range benchmarkCodecs {
+		b.Run(codec.name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := codec.marshal(v); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCodecValid(b *testing.B) {
+	data := benchmarkPayload()
+	for _, codec := range benchmarkCodecs {
+		b.Run(codec.name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if !codec.valid(data) {
+					b.Fatal("payload is not valid JSON")
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkNormalizeJSON 规范化（内容哈希的主要开销）使用构建标签选择的实现，
+// 分别以默认、-tags=jsoniter、-tags=go_json运行比较
+func BenchmarkNormalizeJSON(b *testing.B) {
+	data := benchmarkPayload()
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := NormalizeJSON(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+//go:build go_json
+
+package utils
+
+import (
+	"bytes"
+
+	json "github.com/goccy/go-json"
+)
+
+// JSONCodec 当前使用的JSON编解码实现
+const JSONCodec = "go-json"
+
+// MarshalJSON 使用当前编解码实现序列化
+func MarshalJSON(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// marshalJSONNoEscape 序列化但不转义 <、>、&
+// go-json的MarshalNoEscape指v不逃逸到堆上，仍会转义HTML字符，因此与标准库一样使用Encoder
+func marshalJSONNoEscape(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	// Encode在末尾追加换行
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// UnmarshalJSON 使用当前编解码实现反序列化
+func UnmarshalJSON(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,33 @@
+//go:build jsoniter
+
+package utils
+
+import jsoniter "github.com/json-iterator/go"
+
+// JSONCodec 当前使用的JSON编解码实现
+const JSONCodec = "jsoniter"
+
+// 使用与标准库兼容的配置：键名排序、HTML转义与标准库一致，保证规范化后的哈希不变
+var jsoniterAPI = jsoniter.ConfigCompatibleWithStandardLibrary
+
+// jsoniterNoEscapeAPI 与jsoniterAPI相同但不转义 <、>、&
+var jsoniterNoEscapeAPI = jsoniter.Config{
+	EscapeHTML:             false,
+	SortMapKeys:            true,
+	ValidateJsonRawMessage: true,
+}.Froze()
+
+// MarshalJSON 使用当前编解码实现序列化
+func MarshalJSON(v any) ([]byte, error) {
+	return jsoniterAPI.Marshal(v)
+}
+
+// marshalJSONNoEscape 序列化但不转义 <、>、&
+func marshalJSONNoEscape(v any) ([]byte, error) {
+	return jsoniterNoEscapeAPI.Marshal(v)
+}
+
+// UnmarshalJSON 使用当前编解码实现反序列化
+func UnmarshalJSON(data []byte, v any) error {
+	return jsoniterAPI.Unmarshal(data, v)
+}
@@ -0,0 +1,34 @@
+//go:build !jsoniter && !go_json
+
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// JSONCodec 当前使用的JSON编解码实现，默认标准库
+// 构建时指定 -tags=jsoniter 或 -tags=go_json 可切换为更快的实现，gin的绑定和渲染使用相同的构建标签
+const JSONCodec = "encoding/json"
+
+// MarshalJSON 使用当前编解码实现序列化
+func MarshalJSON(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// marshalJSONNoEscape 序列化但不转义 <、>、&
+func marshalJSONNoEscape(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	// Encode在末尾追加换行
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// UnmarshalJSON 使用当前编解码实现反序列化
+func UnmarshalJSON(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidPointer 不符合RFC 6901语法的JSON Pointer
+	ErrInvalidPointer = errors.New("invalid JSON pointer")
+	// ErrPointerNotFound 文档中不存在JSON Pointer指向的值
+	ErrPointerNotFound = errors.New("JSON pointer target not found")
+)
+
+// pointerUnescaper 先替换~1再替换~0，Replacer单次从左到右扫描，"~01"得到"~1"而不是"/"
+var pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// ParsePointer 把RFC 6901 JSON Pointer拆分为引用记号，~1还原为"/"，~0还原为"~"
+// 空字符串表示整个文档，返回空切片；其余必须以"/"开头，"~"后只能是0或1
+func ParsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: must be empty or start with \"/\"", ErrInvalidPointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		for j := strings.IndexByte(token, '~'); j >= 0; j = strings.IndexByte(token, '~') {
+			if j+1 >= len(token) || (token[j+1] != '0' && token[j+1] != '1') {
+				return nil, fmt.Errorf("%w: \"~\" must be followed by 0 or 1 in %q", ErrInvalidPointer, tokens[i])
+			}
+			token = token[j+2:]
+		}
+		tokens[i] = pointerUnescaper.Replace(tokens[i])
+	}
+	return tokens, nil
+}
+
+// EvaluatePointer 返回data中JSON Pointer指向的值，保留该值在原文档中的字节
+// 数组下标必须是不带前导零的十进制数；"-"（数组末尾之后）和越界下标视为不存在
+// 对象键名重复时与解码整个文档一致，取最后一个
+func EvaluatePointer(data []byte, pointer string) (json.RawMessage, error) {
+	tokens, err := ParsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	current := json.RawMessage(data)
+	if !json.Valid(current) {
+		return nil, errors.New("invalid JSON document")
+	}
+
+	for i, token := range tokens {
+		next, ok, err := pointerChild(current, token)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrPointerNotFound, "/"+strings.Join(rawTokens(pointer, i+1), "/"))
+		}
+		current = next
+	}
+	return current, nil
+}
+
+// pointerChild 取value中token对应的子值，value是标量或找不到时ok为false
+func pointerChild(value json.RawMessage, token string) (json.RawMessage, bool, error) {
+	switch firstNonSpace(value) {
+	case '{':
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(value, &object); err != nil {
+			return nil, false, err
+		}
+		child, ok := object[token]
+		return child, ok, nil
+	case '[':
+		index, ok := arrayIndex(token)
+		if !ok {
+			return nil, false, nil
+		}
+		var array []json.RawMessage
+		if err := json.Unmarshal(value, &array); err != nil {
+			return nil, false, err
+		}
+		if index >= len(array) {
+			return nil, false, nil
+		}
+		return array[index], true, nil
+	default:
+		return nil, false, nil
+	}
+}
+
+// arrayIndex 按RFC 6901解析数组下标："0"或不以0开头的数字
+func arrayIndex(token string) (int, bool) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, false
+	}
+	for i := 0; i < len(token); i++ {
+		if token[i] < '0' || token[i] > '9' {
+			return 0, false
+		}
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, false
+	}
+	return index, true
+}
+
+// rawTokens 返回pointer中前n个未转义的引用记号，用于在错误中指出第一个不存在的位置
+func rawTokens(pointer string, n int) []string {
+	return strings.SplitN(pointer[1:], "/", n+1)[:n]
+}
+
+func firstNonSpace(data []byte) byte {
+	for _, b := range data {
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b
+	}
+	return 0
+}
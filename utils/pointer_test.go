@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParsePointer(t *testing.T) {
+	tests := []struct {
+		pointer string
+		want    []string
+		wantErr bool
+	}{
+		{pointer: "", want: []string{}},
+		{pointer: "/", want: []string{""}},
+		{pointer: "/a/b/0", want: []string{"a", "b", "0"}},
+		// ~1先于~0还原，"~01"是"~1"而不是"/"
+		{pointer: "/a~1b/m~0n/~01", want: []string{"a/b", "m~n", "~1"}},
+		{pointer: "a/b", wantErr: true},
+		{pointer: "/a~2", wantErr: true},
+		{pointer: "/a~", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePointer(tt.pointer)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidPointer) {
+				t.Errorf("ParsePointer(%q) error = %v, want ErrInvalidPointer", tt.pointer, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParsePointer(%q) error = %v", tt.pointer, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParsePointer(%q) = %q, want %q", tt.pointer, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluatePointer(t *testing.T) {
+	// RFC 6901第5节的示例文档
+	doc := []byte(`{
+		"foo": ["bar", "baz"],
+		"": 0,
+		"a/b": 1,
+		"m~n": 8,
+		"nested": {"list": [{"id": 10}, {"id": 1.50}]}
+	}`)
+	tests := []struct {
+		name    string
+		pointer string
+		want    string
+		wantErr error
+	}{
+		{name: "object member", pointer: "/nested/list", want: `[{"id": 10}, {"id": 1.50}]`},
+		{name: "array element", pointer: "/foo/0", want: `"bar"`},
+		{name: "object in array", pointer: "/nested/list/1/id", want: `1.50`},
+		{name: "empty key", pointer: "/", want: `0`},
+		{name: "escaped slash", pointer: "/a~1b", want: `1`},
+		{name: "escaped tilde", pointer: "/m~0n", want: `8`},
+		{name: "missing key", pointer: "/nested/missing/id", wantErr: ErrPointerNotFound},
+		{name: "index out of range", pointer: "/foo/2", wantErr: ErrPointerNotFound},
+		{name: "index with leading zero", pointer: "/foo/01", wantErr: ErrPointerNotFound},
+		{name: "past the end", pointer: "/foo/-", wantErr: ErrPointerNotFound},
+		{name: "into a scalar", pointer: "/m~0n/x", wantErr: ErrPointerNotFound},
+		{name: "malformed", pointer: "foo", wantErr: ErrInvalidPointer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EvaluatePointer(doc, tt.pointer)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("EvaluatePointer(%q) error = %v, want %v", tt.pointer, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("EvaluatePointer(%q) = %s, want %s", tt.pointer, got, tt.want)
+			}
+		})
+	}
+
+	if got, err := EvaluatePointer(doc, ""); err != nil || string(got) != string(doc) {
+		t.Errorf("EvaluatePointer(\"\") = %s, %v, want the whole document", got, err)
+	}
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+)
+
+// SimHash 计算JSON文档的64位SimHash，用于查找结构和内容相近的文档
+// 特征为每个键路径以及每个标量的“路径=值”，数组元素共享同一路径，与键顺序和空白无关
+func SimHash(data []byte) (uint64, error) {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return 0, err
+	}
+
+	var weights [64]int
+	simHashFeatures("$", value, func(feature string) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		for i := 0; i < 64; i++ {
+			if sum&(1<<uint(i)) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	})
+
+	var result uint64
+	for i, weight := range weights {
+		if weight > 0 {
+			result |= 1 << uint(i)
+		}
+	}
+	return result, nil
+}
+
+// simHashFeatures 遍历JSON值并输出特征
+func simHashFeatures(path string, value interface{}, emit func(string)) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			childPath := path + "." + key
+			emit(childPath)
+			simHashFeatures(childPath, child, emit)
+		}
+	case []interface{}:
+		for _, child := range v {
+			simHashFeatures(path+"[]", child, emit)
+		}
+	default:
+		emit(fmt.Sprintf("%s=%v", path, v))
+	}
+}
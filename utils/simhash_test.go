@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"math/bits"
+	"testing"
+)
+
+func TestSimHash(t *testing.T) {
+	base, err := SimHash([]byte(`{"name":"a","tags":["x","y"],"owner":{"id":1,"email":"a@example.com"},"active":true}`))
+	if err != nil {
+		t.Fatalf("SimHash error: %v", err)
+	}
+
+	// 键顺序和空白不影响结果
+	reordered, _ := SimHash([]byte(`{ "active": true, "owner": {"email": "a@example.com", "id": 1}, "tags": ["x", "y"], "name": "a" }`))
+	if reordered != base {
+		t.Errorf("SimHash of reordered document = %x, want %x", reordered, base)
+	}
+
+	similar, _ := SimHash([]byte(`{"name":"b","tags":["x","y"],"owner":{"id":1,"email":"a@example.com"},"active":true}`))
+	different, _ := SimHash([]byte(`[1,2,3,{"unrelated":"content"}]`))
+	near := bits.OnesCount64(base ^ similar)
+	far := bits.OnesCount64(base ^ different)
+	if near >= far {
+		t.Errorf("distance to similar document = %d, to different document = %d, want similar closer", near, far)
+	}
+
+	if _, err := SimHash([]byte(`{"a":`)); err == nil {
+		t.Error("SimHash accepted invalid JSON")
+	}
+}